@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/des-ant/2024-article-api/internal/codec"
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// articleRecord is an article as it is exported and imported in bulk: the
+// fields clients supply, without the ones derived from them.
+type articleRecord struct {
+	ID    int64            `json:"id"`
+	Title string           `json:"title"`
+	Date  data.ArticleDate `json:"date"`
+	Body  string           `json:"body"`
+	Tags  []string         `json:"tags"`
+}
+
+// articleRecordColumns are the CSV columns of an articleRecord, in order.
+var articleRecordColumns = []string{"id", "title", "date", "body", "tags"}
+
+// exportFormats lists the formats the store can be exported in, the first
+// being the default.
+var exportFormats = []responseFormat{
+	{
+		name:       "ndjson",
+		mediaTypes: []string{"application/x-ndjson", "application/ndjson"},
+	},
+	{
+		name:       "csv",
+		mediaTypes: []string{"text/csv"},
+	},
+}
+
+// exportMediaTypes lists the media types of the export formats, which bulk
+// imports also accept.
+func exportMediaTypes() []string {
+	mediaTypes := make([]string, len(exportFormats))
+	for i, f := range exportFormats {
+		mediaTypes[i] = f.mediaTypes[0]
+	}
+	return mediaTypes
+}
+
+// Conflict modes decide what a bulk import does with a record whose ID is
+// already stored.
+const (
+	conflictFail   = "fail"
+	conflictSkip   = "skip"
+	conflictUpsert = "upsert"
+)
+
+// maxBulkImportErrors limits the number of errors listed in a bulk import
+// report. Records beyond it are still counted as failed.
+const maxBulkImportErrors = 1000
+
+// bulkImportError describes a record a bulk import could not store.
+type bulkImportError struct {
+	Line    int               `json:"line"`
+	ID      int64             `json:"id,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// bulkImportReport summarises a bulk import.
+type bulkImportReport struct {
+	Mode    string `json:"mode"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+	Skipped int    `json:"skipped"`
+	Failed  int    `json:"failed"`
+	// Error explains why the import stopped before the end of the body. The
+	// records before it have still been stored.
+	Error  string            `json:"error,omitempty"`
+	Errors []bulkImportError `json:"errors"`
+}
+
+// fail records that the record on a line could not be stored.
+func (report *bulkImportReport) fail(e bulkImportError) {
+	report.Failed++
+	if len(report.Errors) < maxBulkImportErrors {
+		report.Errors = append(report.Errors, e)
+	}
+}
+
+// adminExportHandler streams every article as NDJSON or CSV, ordered by ID.
+// The articles are copied from the store under its lock before the response
+// starts, so the export is a consistent snapshot however long it takes to send.
+func (app *application) adminExportHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := negotiate(r, exportFormats, true)
+	if err != nil {
+		message := fmt.Sprintf("the resource can only be sent as %s", strings.Join(exportMediaTypes(), ", "))
+		app.errorResponse(w, r, http.StatusNotAcceptable, message)
+		return
+	}
+
+	articles := app.daos.Articles.GetAll()
+
+	// Large exports can take longer than the server's write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", format.mediaTypes[0])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="articles.%s"`, format.name))
+	w.WriteHeader(http.StatusOK)
+
+	if format.name == "csv" {
+		err = writeArticlesCSV(w, articles)
+	} else {
+		err = writeArticlesNDJSON(w, articles)
+	}
+	if err != nil {
+		// The response has started, so the client can only be told by the
+		// export being cut short.
+		app.logError(r, err)
+	}
+}
+
+// writeArticlesNDJSON writes one article record per line.
+func writeArticlesNDJSON(w io.Writer, articles []data.Article) error {
+	enc := json.NewEncoder(w)
+	for _, article := range articles {
+		err := enc.Encode(newArticleRecord(article))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArticlesCSV writes a header row followed by one row per article, with
+// tags joined as in other CSV responses.
+func writeArticlesCSV(w io.Writer, articles []data.Article) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(articleRecordColumns)
+	if err != nil {
+		return err
+	}
+
+	for _, article := range articles {
+		err = cw.Write([]string{
+			strconv.FormatInt(article.ID, 10),
+			article.Title,
+			article.Date.String(),
+			article.Body,
+			strings.Join(article.Tags, codec.CSVListSeparator),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// newArticleRecord returns the bulk record for an article.
+func newArticleRecord(article data.Article) articleRecord {
+	return articleRecord{
+		ID:    article.ID,
+		Title: article.Title,
+		Date:  article.Date,
+		Body:  article.Body,
+		Tags:  article.Tags,
+	}
+}
+
+// adminImportHandler stores the articles in an NDJSON or CSV body, such as one
+// produced by adminExportHandler. The body is read a record at a time, so its
+// size is not limited, but each line must fit in an ordinary request body.
+//
+// Records are validated and sanitized as they would be on create, and the
+// response reports the failures by line number. The on_conflict parameter
+// decides what happens to records whose IDs are already stored: "fail" (the
+// default) stops the import with a 409 Conflict response, "skip" leaves the
+// stored article alone, and "upsert" replaces it. Records before a failure are
+// stored either way.
+func (app *application) adminImportHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	mode := app.readString(r.URL.Query(), "on_conflict", conflictFail)
+	v.Check(validator.PermittedValue(mode, conflictFail, conflictSkip, conflictUpsert), "on_conflict", "must be one of fail, skip or upsert")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if contentType == "" {
+		mediaType, err = "application/x-ndjson", nil
+	}
+
+	var records recordReader
+	switch {
+	case err != nil:
+	case slices.Contains(exportFormats[0].mediaTypes, mediaType):
+		records = newNDJSONRecordReader(r.Body)
+	case mediaType == "text/csv":
+		records, err = newCSVRecordReader(r.Body)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if records == nil {
+		app.unsupportedMediaTypeResponse(w, r, exportMediaTypes())
+		return
+	}
+
+	// Large imports can take longer than the server's read timeout.
+	_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	report := &bulkImportReport{Mode: mode, Errors: []bulkImportError{}}
+	status := http.StatusOK
+
+	for {
+		line, record, err := records.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var recordErr *recordError
+		if errors.As(err, &recordErr) {
+			report.fail(bulkImportError{Line: line, Message: recordErr.message})
+			continue
+		}
+
+		if err != nil {
+			report.Error = fmt.Sprintf("line %d: %s", line, bodyReadError(err))
+			status = http.StatusBadRequest
+			break
+		}
+
+		if app.importRecord(report, line, record) {
+			report.Error = fmt.Sprintf("line %d: article %d already exists", line, record.ID)
+			status = http.StatusConflict
+			break
+		}
+	}
+
+	err = app.writeResponse(w, r, status, envelope{"import": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// importRecord validates and stores one record of a bulk import, updating the
+// report. It returns true if the record's ID is already stored and the import
+// should stop because of it.
+func (app *application) importRecord(report *bulkImportReport, line int, record articleRecord) bool {
+	article := &data.Article{
+		ID:    record.ID,
+		Title: record.Title,
+		Date:  record.Date,
+		Body:  record.Body,
+		Tags:  record.Tags,
+	}
+
+	v := validator.New()
+
+	app.sanitizeBody(v, article)
+
+	if data.ValidateArticle(v, article); !v.Valid() {
+		report.fail(bulkImportError{Line: line, ID: record.ID, Errors: v.Errors})
+		return false
+	}
+
+	duplicates, err := app.insertArticles(article)
+	switch {
+	case err == nil:
+		report.Created++
+		return false
+	case errors.Is(err, data.ErrNearDuplicate):
+		report.fail(bulkImportError{Line: line, ID: record.ID, Message: fmt.Sprintf("article is a near-duplicate of existing article %d", duplicates[0].ID)})
+		return false
+	}
+
+	switch report.Mode {
+	case conflictSkip:
+		report.Skipped++
+	case conflictUpsert:
+		// The stored article may have been deleted since the insert found
+		// it, in which case the record is created after all.
+		err = app.daos.Articles.Update(article)
+		if errors.Is(err, data.ErrRecordNotFound) {
+			_, err = app.insertArticles(article)
+			if err == nil {
+				report.Created++
+				return false
+			}
+		}
+		if err != nil {
+			report.fail(bulkImportError{Line: line, ID: record.ID, Message: err.Error()})
+			return false
+		}
+		report.Updated++
+	default:
+		report.fail(bulkImportError{Line: line, ID: record.ID, Message: "article already exists"})
+		return true
+	}
+
+	return false
+}
+
+// recordReader reads the records of a bulk import one at a time. next returns
+// the line each record starts on, a *recordError for records that can't be
+// decoded, after which reading can continue, and io.EOF at the end.
+type recordReader interface {
+	next() (int, articleRecord, error)
+}
+
+// recordError describes a record that could not be decoded.
+type recordError struct {
+	message string
+}
+
+func (e *recordError) Error() string {
+	return e.message
+}
+
+// ndjsonRecordReader reads one JSON record per line, ignoring blank lines.
+type ndjsonRecordReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONRecordReader(r io.Reader) *ndjsonRecordReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRequestBytes)
+	return &ndjsonRecordReader{scanner: scanner}
+}
+
+func (rr *ndjsonRecordReader) next() (int, articleRecord, error) {
+	for rr.scanner.Scan() {
+		rr.line++
+
+		text := strings.TrimSpace(rr.scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var value any
+		err := json.Unmarshal([]byte(text), &value)
+		if err != nil {
+			return rr.line, articleRecord{}, &recordError{"badly-formed JSON"}
+		}
+
+		var record articleRecord
+		err = decodeValue(value, &record, "JSON")
+		if err != nil {
+			return rr.line, articleRecord{}, &recordError{recordMessage(err)}
+		}
+
+		return rr.line, record, nil
+	}
+
+	err := rr.scanner.Err()
+	switch {
+	case errors.Is(err, bufio.ErrTooLong):
+		return rr.line + 1, articleRecord{}, fmt.Errorf("must not be longer than %d bytes", maxRequestBytes)
+	case err != nil:
+		return rr.line + 1, articleRecord{}, err
+	}
+	return rr.line, articleRecord{}, io.EOF
+}
+
+// csvRecordReader reads records from CSV rows, with columns named by a header
+// row.
+type csvRecordReader struct {
+	reader  *csv.Reader
+	columns []string
+	// line is the line the last record read started on.
+	line int
+}
+
+// newCSVRecordReader reads the header row of a CSV import and returns a reader
+// for the records following it.
+func newCSVRecordReader(r io.Reader) (*csvRecordReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body must not be empty")
+		}
+		return nil, fmt.Errorf("body contains badly-formed CSV (%s)", bodyReadError(err))
+	}
+
+	columns := slices.Clone(header)
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column)
+		if !slices.Contains(articleRecordColumns, columns[i]) {
+			return nil, fmt.Errorf("body contains unknown column %q", columns[i])
+		}
+	}
+
+	return &csvRecordReader{reader: reader, columns: columns, line: 1}, nil
+}
+
+func (rr *csvRecordReader) next() (int, articleRecord, error) {
+	row, err := rr.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, articleRecord{}, &recordError{fmt.Sprintf("badly-formed CSV (%s)", parseErr.Err)}
+		}
+		return rr.line + 1, articleRecord{}, err
+	}
+	rr.line, _ = rr.reader.FieldPos(0)
+	line := rr.line
+
+	obj := make(map[string]any, len(rr.columns))
+	for i, column := range rr.columns {
+		cell := row[i]
+		switch column {
+		case "id":
+			if _, err := strconv.ParseInt(cell, 10, 64); err == nil {
+				obj[column] = json.Number(cell)
+				continue
+			}
+		case "tags":
+			tags := []any{}
+			for _, tag := range strings.Split(cell, codec.CSVListSeparator) {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			obj[column] = tags
+			continue
+		}
+		obj[column] = cell
+	}
+
+	var record articleRecord
+	err = decodeValue(obj, &record, "CSV")
+	if err != nil {
+		return line, articleRecord{}, &recordError{recordMessage(err)}
+	}
+
+	return line, record, nil
+}
+
+// recordMessage rewords a request body decoding error to describe a single
+// record.
+func recordMessage(err error) string {
+	message := err.Error()
+	if strings.HasPrefix(message, "body contains ") {
+		return "record contains " + strings.TrimPrefix(message, "body contains ")
+	}
+	return message
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"sort"
 
 	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/markup"
 	"github.com/des-ant/2024-article-api/internal/validator"
 )
 
@@ -20,9 +22,19 @@ func (app *application) createArticleHandler(w http.ResponseWriter, r *http.Requ
 		Tags  []string         `json:"tags"`
 	}
 
-	err := app.readJSON(w, r, &input)
+	err := app.readBody(w, r, &input)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		switch {
+		case errors.Is(err, errUnsupportedMediaType):
+			app.unsupportedMediaTypeResponse(w, r, supportedRequestMediaTypes)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	include, ok := app.readIncludes(w, r)
+	if !ok {
 		return
 	}
 
@@ -36,26 +48,124 @@ func (app *application) createArticleHandler(w http.ResponseWriter, r *http.Requ
 
 	v := validator.New()
 
-	if data.ValidateArticle(v, article); !v.Valid() {
+	prepared, err := app.createArticle(v, article)
+	switch {
+	case !v.Valid():
 		app.failedValidationResponse(w, r, v.Errors)
 		return
-	}
-
-	err = app.daos.Articles.Insert(article)
-	if err != nil {
+	case errors.Is(err, data.ErrNearDuplicate):
+		app.nearDuplicateResponse(w, r, prepared.duplicates[0])
+		return
+	case errors.Is(err, data.ErrDuplicateID):
+		app.duplicateIDResponse(w, r)
+		return
+	case err != nil:
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	applyIncludes(article, include)
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/articles/%d", article.ID))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"article": article}, headers)
+	err = app.writeResponse(w, r, http.StatusCreated, prepared.envelope(article), headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// createArticle prepares an article with prepareArticle and stores it. Nothing
+// is stored if the article fails validation, which is recorded in v, or if the
+// near-duplicate policy refuses it, in which case it returns
+// data.ErrNearDuplicate.
+func (app *application) createArticle(v *validator.Validator, article *data.Article) (preparedArticle, error) {
+	prepared := app.prepareArticle(v, article)
+	if !v.Valid() {
+		return prepared, nil
+	}
+
+	if prepared.rejected(app.config.dedupe.policy) {
+		return prepared, data.ErrNearDuplicate
+	}
+
+	duplicates, err := app.insertArticles(article)
+	if errors.Is(err, data.ErrNearDuplicate) {
+		prepared.duplicates = duplicates
+	}
+
+	return prepared, err
+}
+
+// insertArticles stores articles all at once. Under the reject near-duplicate
+// policy the store checks them for near-duplicates again as it inserts them,
+// as a similar article may have been created since prepareArticle looked, and
+// returns the near-duplicates with data.ErrNearDuplicate if it finds any.
+func (app *application) insertArticles(articles ...*data.Article) ([]data.NearDuplicate, error) {
+	if app.config.dedupe.policy != dedupePolicyReject {
+		return nil, app.daos.Articles.InsertAll(articles)
+	}
+
+	return app.daos.Articles.InsertDistinct(articles, app.config.dedupe.threshold)
+}
+
+// preparedArticle records what prepareArticle did to an article, and found
+// out about it, on the way to creating it.
+type preparedArticle struct {
+	suggestedTags []string
+	sanitized     []markup.Change
+	duplicates    []data.NearDuplicate
+}
+
+// rejected reports whether the near-duplicate policy refuses the article.
+func (p preparedArticle) rejected(policy string) bool {
+	return len(p.duplicates) > 0 && policy == dedupePolicyReject
+}
+
+// envelope returns the response to creating an article, with what was found
+// out while preparing it.
+func (p preparedArticle) envelope(article *data.Article) envelope {
+	env := envelope{"article": article}
+	if len(p.duplicates) > 0 {
+		env["near_duplicates"] = p.duplicates
+	}
+	if len(p.suggestedTags) > 0 {
+		env["suggested_tags"] = p.suggestedTags
+	}
+	if len(p.sanitized) > 0 {
+		env["sanitized"] = p.sanitized
+	}
+	return env
+}
+
+// prepareArticle gets an article ready to be created: it tops up sparse tags
+// with suggestions, sanitizes the body and validates the article, recording
+// any errors in v, then looks for stored articles it closely resembles.
+func (app *application) prepareArticle(v *validator.Validator, article *data.Article) preparedArticle {
+	var prepared preparedArticle
+
+	// Top up sparsely tagged articles with suggestions learned from the store.
+	// Articles sent without tags at all are left for ValidateArticle to refuse.
+	if app.config.tags.autofill > 0 && article.Tags != nil && len(article.Tags) < app.config.tags.autofill {
+		prepared.suggestedTags = app.suggestMissingTags(article, app.config.tags.autofill)
+		article.Tags = append(article.Tags, prepared.suggestedTags...)
+	}
+
+	prepared.sanitized = app.sanitizeBody(v, article)
+
+	if data.ValidateArticle(v, article); !v.Valid() {
+		return prepared
+	}
+
+	// Look for stored articles that closely resemble this one, unless the
+	// policy is to accept everything anyway.
+	if app.config.dedupe.policy != dedupePolicyAccept {
+		prepared.duplicates = app.daos.Articles.FindNearDuplicates(article, app.config.dedupe.threshold)
+	}
+
+	return prepared
+}
+
 // showArticleHandler retrieves an article by ID.
 func (app *application) showArticleHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
@@ -64,6 +174,16 @@ func (app *application) showArticleHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	include, ok := app.readIncludes(w, r)
+	if !ok {
+		return
+	}
+
+	bodyFormat, ok := app.readBodyFormat(w, r)
+	if !ok {
+		return
+	}
+
 	article, err := app.daos.Articles.Get(id)
 	if err != nil {
 		switch {
@@ -75,12 +195,78 @@ func (app *application) showArticleHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"article": article}, nil)
+	applyIncludes(article, include)
+	app.applyBodyFormat(article, bodyFormat)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"article": article}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// listArticlesHandler returns a page of articles, optionally filtered by their
+// content metrics, or with ?ids= the articles with those IDs.
+func (app *application) listArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.ArticleFilter
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	// A list of IDs asks for those articles rather than a page of them.
+	if qs.Has("ids") {
+		app.showArticlesByID(w, r, v, app.readIDs(qs, "ids", v))
+		return
+	}
+
+	input.Language = app.readString(qs, "language", "")
+	input.MinWordCount = app.readInt(qs, "min_word_count", 0, v)
+	input.MaxWordCount = app.readInt(qs, "max_word_count", 0, v)
+	input.MaxReadingTime = app.readInt(qs, "max_reading_time", 0, v)
+	if qs.Has("min_reading_ease") {
+		minReadingEase := app.readFloat(qs, "min_reading_ease", 0, v)
+		input.MinReadingEase = &minReadingEase
+	}
+	if qs.Has("max_reading_ease") {
+		maxReadingEase := app.readFloat(qs, "max_reading_ease", 0, v)
+		input.MaxReadingEase = &maxReadingEase
+	}
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	if data.ValidateArticleFilter(v, input.ArticleFilter); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	include, ok := app.readIncludes(w, r)
+	if !ok {
+		return
+	}
+
+	articles, metadata := app.daos.Articles.List(input.ArticleFilter, input.Filters)
+	for i := range articles {
+		applyIncludes(&articles[i], include)
+	}
+
+	err := app.writeResponse(w, r, http.StatusOK, envelope{"articles": articles, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// tagSummaryKeywords is the number of top keywords included in a tag summary.
+const tagSummaryKeywords = 5
+
 // getArticlesByTagAndDateHandler retrieves articles by tag and date.
 func (app *application) getArticlesByTagAndDateHandler(w http.ResponseWriter, r *http.Request) {
 	tagName, date, err := app.readTagAndDateParams(r)
@@ -89,12 +275,32 @@ func (app *application) getArticlesByTagAndDateHandler(w http.ResponseWriter, r
 		return
 	}
 
-	articles, err := app.daos.Articles.GetArticlesByTagAndDate(tagName, date)
+	tagSummary, err := app.summarizeTag(tagName, date)
 	if err != nil {
-		app.notFoundResponse(w, r)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"tag_summary": tagSummary}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// summarizeTag summarises the articles with a tag on a date: the latest 10 of
+// them, the other tags they have and their top keywords. It returns
+// data.ErrRecordNotFound if there are none.
+func (app *application) summarizeTag(tagName string, date data.ArticleDate) (data.TagSummary, error) {
+	articles, err := app.daos.Articles.GetArticlesByTagAndDate(tagName, date)
+	if err != nil {
+		return data.TagSummary{}, data.ErrRecordNotFound
+	}
+
 	// Sort articles by ID in descending order to get the latest articles first.
 	sort.Slice(articles, func(i, j int) bool {
 		return articles[i].ID > articles[j].ID
@@ -119,15 +325,77 @@ func (app *application) getArticlesByTagAndDateHandler(w http.ResponseWriter, r
 		return t != tagName
 	})
 
-	tagSummary := data.TagSummary{
+	return data.TagSummary{
 		Tag:         tagName,
 		Count:       totalTagCount,
 		Articles:    articleIDs,
 		RelatedTags: relatedTags,
+		TopKeywords: data.TopKeywords(articles, tagSummaryKeywords),
+	}, nil
+}
+
+// readIncludes reads the "include" query parameter, which lists optional
+// article fields the client wants in the response. It sends a 422 response and
+// returns false if an unknown field is requested.
+func (app *application) readIncludes(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	include := app.readCSV(r.URL.Query(), "include", []string{})
+
+	v := validator.New()
+	validateIncludes(v, include)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return nil, false
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"tag_summary": tagSummary}, nil)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+	return include, true
+}
+
+// validateIncludes checks that include only lists optional article fields.
+func validateIncludes(v *validator.Validator, include []string) {
+	for _, field := range include {
+		v.Check(validator.PermittedValue(field, "metrics"), "include", "must only contain metrics")
+	}
+}
+
+// applyIncludes removes the optional fields from an article that were not
+// listed in include.
+func applyIncludes(article *data.Article, include []string) {
+	if !slices.Contains(include, "metrics") {
+		article.Metrics = nil
+	}
+}
+
+// readBodyFormat reads the "body_format" query parameter, which selects how an
+// article body is rendered: "raw" (the default) as stored, "html" as sanitized
+// HTML or "text" as plain text. It sends a 422 response and returns false for
+// any other value.
+func (app *application) readBodyFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	bodyFormat := app.readString(r.URL.Query(), "body_format", "raw")
+
+	v := validator.New()
+	validateBodyFormat(v, bodyFormat)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return "", false
+	}
+
+	return bodyFormat, true
+}
+
+// validateBodyFormat checks that bodyFormat is one of the formats an article
+// body can be rendered in.
+func validateBodyFormat(v *validator.Validator, bodyFormat string) {
+	v.Check(validator.PermittedValue(bodyFormat, "raw", "html", "text"), "body_format", "must be one of raw, html or text")
+}
+
+// applyBodyFormat renders an article's markup body in the given format.
+func (app *application) applyBodyFormat(article *data.Article, bodyFormat string) {
+	switch bodyFormat {
+	case "html":
+		article.Body = markup.RenderHTML(markup.Parse(article.Body), app.htmlPolicy())
+	case "text":
+		article.Body = markup.RenderText(markup.Parse(article.Body))
 	}
 }
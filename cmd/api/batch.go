@@ -0,0 +1,315 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/text"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// Batch modes decide what a batch create does when some of its articles can't
+// be created: batchAtomic creates none of them, and batchPartial creates the
+// rest.
+const (
+	batchAtomic  = "atomic"
+	batchPartial = "partial"
+)
+
+// batchResult describes what happened to one article in a batch create. Status
+// is the status code a single create of the article would have had.
+type batchResult struct {
+	Index          int                  `json:"index"`
+	Status         int                  `json:"status"`
+	ID             int64                `json:"id,omitempty"`
+	Location       string               `json:"location,omitempty"`
+	Message        string               `json:"message,omitempty"`
+	Errors         map[string]string    `json:"errors,omitempty"`
+	NearDuplicates []data.NearDuplicate `json:"near_duplicates,omitempty"`
+	SuggestedTags  []string             `json:"suggested_tags,omitempty"`
+	Sanitized      []markup.Change      `json:"sanitized,omitempty"`
+}
+
+// batchReport summarises a batch create, with a result for each article in the
+// order they were sent.
+type batchReport struct {
+	Mode    string        `json:"mode"`
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	Results []batchResult `json:"results"`
+}
+
+// createArticlesBatchHandler creates several articles in one request. Each is
+// prepared and validated as createArticleHandler would, and the ?mode= query
+// parameter decides whether one failing stops the others being created.
+func (app *application) createArticlesBatchHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	mode := app.readString(r.URL.Query(), "mode", batchAtomic)
+	v.Check(validator.PermittedValue(mode, batchAtomic, batchPartial), "mode", "must be one of atomic or partial")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			app.unsupportedMediaTypeResponse(w, r, []string{"application/json"})
+			return
+		}
+	}
+
+	var input struct {
+		Articles []articleRecord `json:"articles"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v.Check(len(input.Articles) > 0, "articles", "must contain at least 1 article")
+	v.Check(len(input.Articles) <= app.config.batch.maxItems, "articles", fmt.Sprintf("must not contain more than %d articles", app.config.batch.maxItems))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	report := &batchReport{Mode: mode, Results: make([]batchResult, len(input.Articles))}
+	var articles []*data.Article
+
+	// Indexes of the articles in the batch by ID, to catch the same ID twice.
+	indexes := make(map[int64]int)
+
+	// Fingerprints of the articles that can be created so far, by index, to
+	// catch two near-duplicates in the same batch.
+	fingerprints := make(map[int]uint64)
+
+	for i, record := range input.Articles {
+		article := &data.Article{
+			ID:    record.ID,
+			Title: record.Title,
+			Date:  record.Date,
+			Body:  record.Body,
+			Tags:  record.Tags,
+		}
+
+		v := validator.New()
+		prepared := app.prepareArticle(v, article)
+
+		if j, ok := indexes[article.ID]; ok {
+			v.AddError("id", fmt.Sprintf("must not be the same as the ID of article %d in the batch", j))
+		}
+		indexes[article.ID] = i
+
+		result := batchResult{
+			Index:          i,
+			ID:             article.ID,
+			NearDuplicates: prepared.duplicates,
+			SuggestedTags:  prepared.suggestedTags,
+			Sanitized:      prepared.sanitized,
+		}
+
+		switch {
+		case !v.Valid():
+			result.Status = http.StatusUnprocessableEntity
+			result.Errors = v.Errors
+		case app.articleExists(article.ID):
+			result.Status = http.StatusConflict
+			result.Message = "article already exists"
+		case prepared.rejected(app.config.dedupe.policy):
+			result.Status = http.StatusConflict
+			result.Message = fmt.Sprintf("article is a near-duplicate of existing article %d", prepared.duplicates[0].ID)
+		default:
+			fingerprint := data.Fingerprint(article)
+			if j, duplicate, ok := app.nearDuplicateInBatch(input.Articles, fingerprints, fingerprint); ok {
+				result.Status = http.StatusConflict
+				result.Message = fmt.Sprintf("article is a near-duplicate of article %d in the batch", j)
+				result.NearDuplicates = append(result.NearDuplicates, duplicate)
+				break
+			}
+
+			fingerprints[i] = fingerprint
+			result.Status = http.StatusCreated
+			articles = append(articles, article)
+		}
+
+		report.Results[i] = result
+	}
+
+	var status int
+	switch mode {
+	case batchAtomic:
+		status = app.createBatchAtomically(report, articles)
+	default:
+		status = app.createBatchPartially(report, articles)
+	}
+	if status == http.StatusConflict {
+		message := "an article in the batch conflicts with one created by another request, so none were created"
+		app.errorResponse(w, r, status, message)
+		return
+	}
+
+	err = app.writeResponse(w, r, status, envelope{"batch": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createBatchAtomically creates the batch's articles only if every one of them
+// can be, and returns the status code of the response. Otherwise the articles
+// that could have been created are marked as failing because of the others.
+func (app *application) createBatchAtomically(report *batchReport, articles []*data.Article) int {
+	if len(articles) < len(report.Results) {
+		for i := range report.Results {
+			result := &report.Results[i]
+			if result.Status == http.StatusCreated {
+				result.Status = http.StatusFailedDependency
+				result.Message = "not created because other articles in the batch failed"
+			}
+		}
+		report.Failed = len(report.Results)
+		return http.StatusUnprocessableEntity
+	}
+
+	// Another request may have created an article with one of the IDs, or one
+	// the near-duplicate policy refuses, since they were checked.
+	_, err := app.insertArticles(articles...)
+	if err != nil {
+		return http.StatusConflict
+	}
+
+	for i := range report.Results {
+		report.Results[i].Location = fmt.Sprintf("/v1/articles/%d", report.Results[i].ID)
+	}
+	report.Created = len(articles)
+	return http.StatusCreated
+}
+
+// createBatchPartially creates each of the batch's articles that can be, and
+// returns the status code of the response.
+func (app *application) createBatchPartially(report *batchReport, articles []*data.Article) int {
+	next := 0
+	for i := range report.Results {
+		result := &report.Results[i]
+		if result.Status != http.StatusCreated {
+			report.Failed++
+			continue
+		}
+
+		article := articles[next]
+		next++
+
+		duplicates, err := app.insertArticles(article)
+		switch {
+		case errors.Is(err, data.ErrDuplicateID):
+			result.Status = http.StatusConflict
+			result.Message = "article already exists"
+			report.Failed++
+			continue
+		case errors.Is(err, data.ErrNearDuplicate):
+			result.Status = http.StatusConflict
+			result.Message = fmt.Sprintf("article is a near-duplicate of existing article %d", duplicates[0].ID)
+			result.NearDuplicates = duplicates
+			report.Failed++
+			continue
+		}
+
+		result.Location = fmt.Sprintf("/v1/articles/%d", article.ID)
+		report.Created++
+	}
+
+	return http.StatusMultiStatus
+}
+
+// nearDuplicateInBatch returns the index of the earliest article, of those
+// with fingerprints, that the near-duplicate policy would refuse an article
+// with the fingerprint for, and how the two compare. It reports false if there
+// is none.
+func (app *application) nearDuplicateInBatch(records []articleRecord, fingerprints map[int]uint64, fingerprint uint64) (int, data.NearDuplicate, bool) {
+	if app.config.dedupe.policy != dedupePolicyReject {
+		return 0, data.NearDuplicate{}, false
+	}
+
+	for j := range records {
+		earlier, ok := fingerprints[j]
+		if !ok {
+			continue
+		}
+
+		similarity := text.Similarity(fingerprint, earlier)
+		if similarity >= app.config.dedupe.threshold {
+			return j, data.NearDuplicate{ID: records[j].ID, Title: records[j].Title, Similarity: similarity}, true
+		}
+	}
+
+	return 0, data.NearDuplicate{}, false
+}
+
+// articleExists reports whether an article with the ID is stored.
+func (app *application) articleExists(id int64) bool {
+	_, err := app.daos.Articles.Get(id)
+	return err == nil
+}
+
+// getArticlesBatchHandler returns the articles with the IDs listed in the
+// request body, for lists too long for GET /v1/articles?ids=.
+func (app *application) getArticlesBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs []int64 `json:"ids"`
+	}
+
+	err := app.readBody(w, r, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnsupportedMediaType):
+			app.unsupportedMediaTypeResponse(w, r, supportedRequestMediaTypes)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	for _, id := range input.IDs {
+		v.Check(id > 0, "ids", "must only contain positive integers")
+	}
+
+	app.showArticlesByID(w, r, v, uniqueIDs(input.IDs))
+}
+
+// showArticlesByID sends the articles with the given IDs, in the same order,
+// and the IDs of any that aren't stored, which are left out rather than
+// failing the request.
+func (app *application) showArticlesByID(w http.ResponseWriter, r *http.Request, v *validator.Validator, ids []int64) {
+	v.Check(len(ids) > 0, "ids", "must contain at least 1 ID")
+	v.Check(len(ids) <= app.config.batch.maxItems, "ids", fmt.Sprintf("must not contain more than %d IDs", app.config.batch.maxItems))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	include, ok := app.readIncludes(w, r)
+	if !ok {
+		return
+	}
+
+	articles, missing := app.daos.Articles.GetMany(ids)
+	for i := range articles {
+		applyIncludes(&articles[i], include)
+	}
+
+	err := app.writeResponse(w, r, http.StatusOK, envelope{"articles": articles, "missing_ids": missing}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
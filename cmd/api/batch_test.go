@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateArticlesBatch(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.batch.maxItems = 3
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	article := func(id int64, title string) map[string]any {
+		return map[string]any{"id": id, "title": title, "date": "2016-09-22", "body": "Some text about " + title, "tags": []string{"health"}}
+	}
+
+	type batchResponse struct {
+		Batch struct {
+			Mode    string `json:"mode"`
+			Created int    `json:"created"`
+			Failed  int    `json:"failed"`
+			Results []struct {
+				Index    int               `json:"index"`
+				Status   int               `json:"status"`
+				ID       int64             `json:"id"`
+				Location string            `json:"location"`
+				Message  string            `json:"message"`
+				Errors   map[string]string `json:"errors"`
+			} `json:"results"`
+		} `json:"batch"`
+	}
+
+	statuses := func(res batchResponse) []int {
+		var statuses []int
+		for _, result := range res.Batch.Results {
+			statuses = append(statuses, result.Status)
+		}
+		return statuses
+	}
+
+	t.Run("Atomic", func(t *testing.T) {
+		statusCode, _, body := ts.postJSON(t, "/v1/articles/batch", map[string]any{
+			"articles": []map[string]any{article(1, "potatoes"), article(2, "carrots")},
+		})
+		require.Equal(t, http.StatusCreated, statusCode, body)
+
+		var res batchResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &res))
+		assert.Equal(t, "atomic", res.Batch.Mode)
+		assert.Equal(t, 2, res.Batch.Created)
+		assert.Equal(t, []int{http.StatusCreated, http.StatusCreated}, statuses(res))
+		assert.Equal(t, "/v1/articles/2", res.Batch.Results[1].Location)
+
+		statusCode, _, _ = ts.get(t, "/v1/articles/2")
+		assert.Equal(t, http.StatusOK, statusCode)
+	})
+
+	t.Run("AtomicFailure", func(t *testing.T) {
+		invalid := article(4, "")
+		statusCode, _, body := ts.postJSON(t, "/v1/articles/batch?mode=atomic", map[string]any{
+			"articles": []map[string]any{article(3, "beetroot"), invalid, article(1, "potatoes again")},
+		})
+		require.Equal(t, http.StatusUnprocessableEntity, statusCode, body)
+
+		var res batchResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &res))
+		assert.Equal(t, 0, res.Batch.Created)
+		assert.Equal(t, 3, res.Batch.Failed)
+		assert.Equal(t, []int{http.StatusFailedDependency, http.StatusUnprocessableEntity, http.StatusConflict}, statuses(res))
+		assert.Equal(t, "must be provided", res.Batch.Results[1].Errors["title"])
+		assert.Equal(t, "article already exists", res.Batch.Results[2].Message)
+
+		// None of the articles were created.
+		statusCode, _, _ = ts.get(t, "/v1/articles/3")
+		assert.Equal(t, http.StatusNotFound, statusCode)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		statusCode, _, body := ts.postJSON(t, "/v1/articles/batch?mode=partial", map[string]any{
+			"articles": []map[string]any{article(3, "beetroot"), article(3, "parsnips"), article(1, "potatoes again")},
+		})
+		require.Equal(t, http.StatusMultiStatus, statusCode, body)
+
+		var res batchResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &res))
+		assert.Equal(t, 1, res.Batch.Created)
+		assert.Equal(t, 2, res.Batch.Failed)
+		assert.Equal(t, []int{http.StatusCreated, http.StatusUnprocessableEntity, http.StatusConflict}, statuses(res))
+		assert.Equal(t, "must not be the same as the ID of article 0 in the batch", res.Batch.Results[1].Errors["id"])
+
+		statusCode, _, body = ts.get(t, "/v1/articles/3")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Contains(t, body, "beetroot")
+	})
+
+	t.Run("InvalidRequest", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			url          string
+			body         any
+			expectedCode int
+			expectedBody string
+		}{
+			{"Empty", "/v1/articles/batch", map[string]any{"articles": []any{}}, http.StatusUnprocessableEntity, "must contain at least 1 article"},
+			{"TooMany", "/v1/articles/batch", map[string]any{"articles": []any{article(5, "a"), article(6, "b"), article(7, "c"), article(8, "d")}}, http.StatusUnprocessableEntity, "must not contain more than 3 articles"},
+			{"InvalidMode", "/v1/articles/batch?mode=some", map[string]any{"articles": []any{article(5, "a")}}, http.StatusUnprocessableEntity, "must be one of atomic or partial"},
+			{"UnknownKey", "/v1/articles/batch", map[string]any{"items": []any{article(5, "a")}}, http.StatusBadRequest, `body contains unknown key \"items\"`},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				statusCode, _, body := ts.postJSON(t, tt.url, tt.body)
+				assert.Equal(t, tt.expectedCode, statusCode)
+				assert.Contains(t, body, tt.expectedBody)
+			})
+		}
+
+		statusCode, _, _ := ts.post(t, "/v1/articles/batch", "application/yaml", strings.NewReader("articles: []"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, statusCode)
+	})
+
+	t.Run("BodyLimit", func(t *testing.T) {
+		// The batch route has its own limit, bigger than that of other routes.
+		big := article(9, "big")
+		big["body"] = strings.Repeat("word ", maxRequestBytes/5)
+
+		statusCode, _, body := ts.postJSON(t, "/v1/articles", big)
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		assert.Contains(t, body, fmt.Sprintf("body must not be larger than %d bytes", maxRequestBytes))
+
+		statusCode, _, body = ts.postJSON(t, "/v1/articles/batch", map[string]any{"articles": []any{big}})
+		assert.Equal(t, http.StatusCreated, statusCode, body)
+
+		app.config.batch.maxBytes = 1024
+		limited := newTestServer(t, app.routes())
+		defer limited.Close()
+
+		statusCode, _, body = limited.postJSON(t, "/v1/articles/batch", map[string]any{"articles": []any{big}})
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		assert.Contains(t, body, "body must not be larger than 1024 bytes")
+	})
+}
+
+func TestCreateArticlesBatchNearDuplicates(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.dedupe.policy = dedupePolicyReject
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	article := func(id int64, title string) map[string]any {
+		return map[string]any{"id": id, "title": title, "date": "2016-09-22", "body": "a new species of bird has been found in the pacific", "tags": []string{"science"}}
+	}
+
+	type batchResponse struct {
+		Batch struct {
+			Created int `json:"created"`
+			Results []struct {
+				Status         int    `json:"status"`
+				Message        string `json:"message"`
+				NearDuplicates []struct {
+					ID int64 `json:"id"`
+				} `json:"near_duplicates"`
+			} `json:"results"`
+		} `json:"batch"`
+	}
+
+	tests := []struct {
+		name           string
+		mode           string
+		expectedCode   int
+		expectedStatus []int
+	}{
+		{"Atomic", batchAtomic, http.StatusUnprocessableEntity, []int{http.StatusFailedDependency, http.StatusConflict}},
+		{"Partial", batchPartial, http.StatusMultiStatus, []int{http.StatusCreated, http.StatusConflict}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Neither article is stored yet, so only comparing them with each
+			// other finds that the second is a near-duplicate of the first.
+			first, second := int64(2*i+1), int64(2*i+2)
+			statusCode, _, body := ts.postJSON(t, "/v1/articles/batch?mode="+tt.mode, map[string]any{
+				"articles": []map[string]any{article(first, "new species of bird found"), article(second, "new species of bird found!")},
+			})
+			require.Equal(t, tt.expectedCode, statusCode, body)
+
+			var res batchResponse
+			require.NoError(t, json.Unmarshal([]byte(body), &res))
+			require.Len(t, res.Batch.Results, 2)
+			assert.Equal(t, tt.expectedStatus[0], res.Batch.Results[0].Status)
+			assert.Equal(t, tt.expectedStatus[1], res.Batch.Results[1].Status)
+			assert.Equal(t, "article is a near-duplicate of article 0 in the batch", res.Batch.Results[1].Message)
+			require.Len(t, res.Batch.Results[1].NearDuplicates, 1)
+			assert.Equal(t, first, res.Batch.Results[1].NearDuplicates[0].ID)
+
+			statusCode, _, _ = ts.get(t, fmt.Sprintf("/v1/articles/%d", second))
+			assert.Equal(t, http.StatusNotFound, statusCode)
+		})
+	}
+
+	// The atomic batch created neither article, and the partial one only the
+	// first.
+	assert.Len(t, app.daos.Articles.GetAll(), 1)
+}
+
+func TestGetArticlesByIDs(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.batch.maxItems = 4
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	type articlesResponse struct {
+		Articles []struct {
+			ID      int64          `json:"id"`
+			Metrics map[string]any `json:"metrics"`
+		} `json:"articles"`
+		MissingIDs []int64 `json:"missing_ids"`
+	}
+
+	ids := func(res articlesResponse) []int64 {
+		ids := []int64{}
+		for _, article := range res.Articles {
+			ids = append(ids, article.ID)
+		}
+		return ids
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		statusCode, _, body := ts.get(t, "/v1/articles?ids=3,1,999,3,2&include=metrics")
+		require.Equal(t, http.StatusOK, statusCode, body)
+
+		var res articlesResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &res))
+		assert.Equal(t, []int64{3, 1, 2}, ids(res))
+		assert.Equal(t, []int64{999}, res.MissingIDs)
+		assert.NotEmpty(t, res.Articles[0].Metrics)
+	})
+
+	t.Run("Post", func(t *testing.T) {
+		statusCode, _, body := ts.postJSON(t, "/v1/articles/batch-get", map[string]any{"ids": []int64{998, 5, 4}})
+		require.Equal(t, http.StatusOK, statusCode, body)
+
+		var res articlesResponse
+		require.NoError(t, json.Unmarshal([]byte(body), &res))
+		assert.Equal(t, []int64{5, 4}, ids(res))
+		assert.Equal(t, []int64{998}, res.MissingIDs)
+	})
+
+	t.Run("AllMissing", func(t *testing.T) {
+		statusCode, _, body := ts.get(t, "/v1/articles?ids=999")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"articles": [], "missing_ids": [999]}`, body)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			get          string
+			post         any
+			expectedBody string
+		}{
+			{name: "NotAnInteger", get: "/v1/articles?ids=1,x", expectedBody: "must be a comma-separated list of positive integers"},
+			{name: "Negative", get: "/v1/articles?ids=-1", expectedBody: "must be a comma-separated list of positive integers"},
+			{name: "Empty", get: "/v1/articles?ids=", expectedBody: "must contain at least 1 ID"},
+			{name: "TooMany", get: "/v1/articles?ids=1,2,3,4,5", expectedBody: "must not contain more than 4 IDs"},
+			{name: "PostNegative", post: map[string]any{"ids": []int64{1, 0}}, expectedBody: "must only contain positive integers"},
+			{name: "PostEmpty", post: map[string]any{"ids": []int64{}}, expectedBody: "must contain at least 1 ID"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var statusCode int
+				var body string
+				if tt.post != nil {
+					statusCode, _, body = ts.postJSON(t, "/v1/articles/batch-get", tt.post)
+				} else {
+					statusCode, _, body = ts.get(t, tt.get)
+				}
+				assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+				assert.Contains(t, body, tt.expectedBody)
+			})
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+const (
+	// maxPooledBufferSize stops the buffers of unusually large responses from
+	// being kept in the pool.
+	maxPooledBufferSize = 1 << 16
+	// chunkedListThreshold is the number of items above which a JSON list
+	// response is written in chunks rather than buffered whole.
+	chunkedListThreshold = 50
+	// chunkSize is how much encoded output is buffered before being
+	// flushed to the client while writing a chunked response.
+	chunkSize = 32 << 10
+)
+
+// bufferPool holds buffers reused for encoding responses.
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// prettyJSON reports whether JSON responses to the request should be indented,
+// which they are only when "?pretty=true" is set.
+func (app *application) prettyJSON(r *http.Request) bool {
+	pretty, err := strconv.ParseBool(r.URL.Query().Get("pretty"))
+	return err == nil && pretty
+}
+
+// shouldChunk reports whether data is a list response long enough to be
+// written in chunks.
+func shouldChunk(data envelope) bool {
+	list, ok := listPayload(data)
+	return ok && reflect.ValueOf(list).Len() > chunkedListThreshold
+}
+
+// writeChunkedJSON writes a list response as JSON, encoding and flushing the
+// list items in chunks instead of buffering the whole encoded response. The
+// list itself is the page the handler already holds in memory; only its
+// encoding is sent piece by piece. The output is identical to writeJSON's. No
+// Content-Length is sent, so the response uses chunked transfer encoding.
+//
+// Errors that occur before anything has been sent are returned as usual. Once
+// the response has started, errors are logged and the response is cut short,
+// since the status can no longer be changed.
+func (app *application) writeChunkedJSON(w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header, pretty bool) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	cw := &chunkedJSONWriter{
+		w:      w,
+		rc:     http.NewResponseController(w),
+		buf:    buf,
+		enc:    json.NewEncoder(buf),
+		pretty: pretty,
+		start: func() {
+			for key, value := range headers {
+				w.Header()[key] = value
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+		},
+	}
+
+	err := cw.writeEnvelope(data)
+	if err != nil {
+		if !cw.started {
+			return err
+		}
+		app.logError(r, err)
+		return nil
+	}
+
+	return cw.flush()
+}
+
+// chunkedJSONWriter encodes an envelope into a buffer, flushing the buffer to
+// the client whenever it grows past chunkSize.
+type chunkedJSONWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	buf     *bytes.Buffer
+	enc     *json.Encoder
+	pretty  bool
+	start   func()
+	started bool
+}
+
+// writeEnvelope writes data with its keys sorted, as encoding/json does for
+// maps, flushing the items of its list value in chunks.
+func (cw *chunkedJSONWriter) writeEnvelope(data envelope) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	cw.buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			cw.buf.WriteByte(',')
+		}
+		cw.newline("\t")
+
+		err := cw.encode(key, "")
+		if err != nil {
+			return err
+		}
+		cw.buf.WriteByte(':')
+		if cw.pretty {
+			cw.buf.WriteByte(' ')
+		}
+
+		value := reflect.ValueOf(data[key])
+		if key == "metadata" || value.Kind() != reflect.Slice || value.Len() == 0 {
+			err = cw.encode(data[key], "\t")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = cw.writeList(value)
+		if err != nil {
+			return err
+		}
+	}
+	cw.newline("")
+	cw.buf.WriteString("}\n")
+
+	return nil
+}
+
+// writeList writes the items of a non-empty slice, flushing as it goes.
+func (cw *chunkedJSONWriter) writeList(items reflect.Value) error {
+	cw.buf.WriteByte('[')
+	for i := range items.Len() {
+		if i > 0 {
+			cw.buf.WriteByte(',')
+		}
+		cw.newline("\t\t")
+
+		err := cw.encode(items.Index(i).Interface(), "\t\t")
+		if err != nil {
+			return err
+		}
+
+		if cw.buf.Len() >= chunkSize {
+			err = cw.flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.newline("\t")
+	cw.buf.WriteByte(']')
+
+	return nil
+}
+
+// encode appends the JSON encoding of v, indented with the given prefix when
+// pretty printing.
+func (cw *chunkedJSONWriter) encode(v any, prefix string) error {
+	if cw.pretty {
+		cw.enc.SetIndent(prefix, "\t")
+	}
+
+	err := cw.enc.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	// Drop the newline the encoder appends after every value.
+	cw.buf.Truncate(cw.buf.Len() - 1)
+	return nil
+}
+
+// newline starts a new indented line when pretty printing.
+func (cw *chunkedJSONWriter) newline(indent string) {
+	if cw.pretty {
+		cw.buf.WriteByte('\n')
+		cw.buf.WriteString(indent)
+	}
+}
+
+// flush sends the buffered output to the client, writing the response headers
+// first if they haven't been sent yet.
+func (cw *chunkedJSONWriter) flush() error {
+	if !cw.started {
+		cw.start()
+		cw.started = true
+	}
+
+	_, err := cw.buf.WriteTo(cw.w)
+	if err != nil {
+		return err
+	}
+
+	// Not every ResponseWriter supports flushing, in which case the output is
+	// sent when the handler returns.
+	err = cw.rc.Flush()
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+)
+
+// discardResponseWriter is an http.ResponseWriter that throws away everything
+// written to it, so benchmarks only measure encoding.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// benchmarkEnvelope builds a list response of n articles.
+func benchmarkEnvelope(n int) envelope {
+	articles := make([]data.Article, n)
+	for i := range articles {
+		articles[i] = data.Article{
+			ID:    int64(i + 1),
+			Title: fmt.Sprintf("article %d", i+1),
+			Date:  data.ArticleDate(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Body:  strings.Repeat("lorem ipsum dolor sit amet ", 20),
+			Tags:  []string{"health", "science"},
+		}
+	}
+	return envelope{"articles": articles, "metadata": data.Metadata{CurrentPage: 1, PageSize: n}}
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	app := newTestApplication(nil)
+	r := httptest.NewRequest(http.MethodGet, "/v1/articles", nil)
+
+	for _, n := range []int{1, 20, 100, 1000} {
+		data := benchmarkEnvelope(n)
+
+		// MarshalIndent is how responses were written before the pooled
+		// encoder, and serves as the baseline.
+		b.Run(fmt.Sprintf("MarshalIndent/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				w := &discardResponseWriter{header: make(http.Header)}
+				js, err := json.MarshalIndent(data, "", "\t")
+				if err != nil {
+					b.Fatal(err)
+				}
+				w.Write(append(js, '\n'))
+			}
+		})
+
+		b.Run(fmt.Sprintf("Compact/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				w := &discardResponseWriter{header: make(http.Header)}
+				err := app.writeJSON(w, http.StatusOK, data, nil, false)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Chunked/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				w := &discardResponseWriter{header: make(http.Header)}
+				err := app.writeChunkedJSON(w, r, http.StatusOK, data, nil, false)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
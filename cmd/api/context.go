@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is the type of the keys the application stores in request
+// contexts, so that they can't collide with keys set by other packages.
+type contextKey string
+
+const bodyLimitContextKey = contextKey("bodyLimit")
+
+// contextSetBodyLimit returns a copy of the request whose body may be up to
+// maxBytes long.
+func (app *application) contextSetBodyLimit(r *http.Request, maxBytes int64) *http.Request {
+	ctx := context.WithValue(r.Context(), bodyLimitContextKey, maxBytes)
+	return r.WithContext(ctx)
+}
+
+// contextGetBodyLimit returns the maximum length of the request's body: the
+// limit set for its route, or maxRequestBytes.
+func (app *application) contextGetBodyLimit(r *http.Request) int64 {
+	maxBytes, ok := r.Context().Value(bodyLimitContextKey).(int64)
+	if !ok {
+		return maxRequestBytes
+	}
+	return maxBytes
+}
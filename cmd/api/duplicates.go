@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// listDuplicateClustersHandler reports groups of near-duplicate articles across
+// the whole store. The similarity threshold defaults to the configured one and
+// can be overridden with the "threshold" query parameter.
+func (app *application) listDuplicateClustersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	threshold := app.readFloat(r.URL.Query(), "threshold", app.config.dedupe.threshold, v)
+	v.Check(threshold > 0 && threshold <= 1, "threshold", "must be greater than 0 and at most 1")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	clusters := app.daos.Articles.GetDuplicateClusters(threshold)
+	if clusters == nil {
+		clusters = []data.DuplicateCluster{}
+	}
+
+	err := app.writeResponse(w, r, http.StatusOK, envelope{"duplicate_clusters": clusters}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/des-ant/2024-article-api/internal/epub"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// tagEPUBHandler serves GET /v1/tags/:tagName/export.epub, an e-book with a
+// chapter for each of the tag's articles, oldest first. The from and to query
+// parameters limit it to articles dated within a range.
+func (app *application) tagEPUBHandler(w http.ResponseWriter, r *http.Request) {
+	tagName := httprouter.ParamsFromContext(r.Context()).ByName("tagName")
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	from := app.readDate(qs, "from", v)
+	to := app.readDate(qs, "to", v)
+	v.Check(from.IsZero() || to.IsZero() || !to.Before(from), "to", "must not be before from")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	articles := app.daos.Articles.GetByTagBetween(tagName, from, to)
+	if len(articles) == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	book := epub.Book{
+		Identifier: app.baseURL(r) + r.URL.RequestURI(),
+		Title:      fmt.Sprintf("%s: %s", app.config.feed.title, tagName),
+		Creator:    app.config.feed.title,
+		Modified:   app.daos.Articles.LastModified(),
+	}
+	for _, article := range articles {
+		// Rendered bodies only contain well-formed elements with escaped
+		// text, so they are valid XHTML.
+		content := fmt.Sprintf("<p><time datetime=\"%[1]s\">%[1]s</time></p>\n", article.Date)
+		content += markup.RenderHTML(markup.Parse(article.Body), app.htmlPolicy())
+		book.Chapters = append(book.Chapters, epub.Chapter{Title: article.Title, ContentXHTML: content})
+	}
+
+	var buf bytes.Buffer
+	err := epub.Write(&buf, book)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	w.Header().Set("Content-Type", epub.MediaType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": tagName + ".epub"}))
+	w.Header().Set("ETag", strconv.Quote(hex.EncodeToString(sum[:16])))
+
+	http.ServeContent(w, r, "", app.daos.Articles.LastModified(), bytes.NewReader(buf.Bytes()))
+}
@@ -3,6 +3,9 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/data"
 )
 
 // logError logs an error message along with the current request method and URL.
@@ -15,12 +18,18 @@ func (app *application) logError(r *http.Request, err error) {
 	app.logger.Error(err.Error(), "method", method, "uri", uri)
 }
 
-// errorResponse() sends a JSON response containing a generic error message.
+// errorResponse() sends a response containing a generic error message, in the
+// format negotiated for the request, or JSON if none of the formats is acceptable.
 // Use any type for message to allow flexible response values.
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := envelope{"error": message}
 
-	err := app.writeJSON(w, status, env, nil)
+	format, err := negotiateFormat(r, env)
+	if err != nil {
+		format = responseFormats[0]
+	}
+
+	err = app.writeFormat(w, r, format, status, env, nil)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(500)
@@ -54,3 +63,51 @@ func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Reques
 func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
+
+// invalidAdminTokenResponse sends a 401 Unauthorized status code and JSON response,
+// asking for the admin token as a bearer token.
+func (app *application) invalidAdminTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+
+	message := "invalid or missing admin token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+// adminDisabledResponse sends a 403 Forbidden status code and JSON response for
+// requests to admin routes when no admin token is configured.
+func (app *application) adminDisabledResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the admin endpoints are disabled, set an admin token to enable them"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+// duplicateIDResponse sends a 409 Conflict status code and JSON response when an
+// article with the submitted ID is already stored.
+func (app *application) duplicateIDResponse(w http.ResponseWriter, r *http.Request) {
+	message := "article already exists"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// nearDuplicateResponse sends a 409 Conflict status code and JSON response pointing the
+// client at the existing article that the submitted one duplicates.
+func (app *application) nearDuplicateResponse(w http.ResponseWriter, r *http.Request, duplicate data.NearDuplicate) {
+	message := map[string]any{
+		"message":     "article is a near-duplicate of an existing article",
+		"existing_id": duplicate.ID,
+		"similarity":  duplicate.Similarity,
+	}
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// notAcceptableResponse sends a 406 Not Acceptable status code and a response listing
+// the media types the response could have been sent as.
+func (app *application) notAcceptableResponse(w http.ResponseWriter, r *http.Request, data envelope) {
+	message := fmt.Sprintf("the resource can only be sent as %s", strings.Join(supportedMediaTypes(data), ", "))
+	app.errorResponse(w, r, http.StatusNotAcceptable, message)
+}
+
+// unsupportedMediaTypeResponse sends a 415 Unsupported Media Type status code and a response
+// listing the request body formats the endpoint accepts.
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, supported []string) {
+	message := fmt.Sprintf("the %s content type is not supported, use one of %s", r.Header.Get("Content-Type"), strings.Join(supported, ", "))
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
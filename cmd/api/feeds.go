@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/feed"
+	"github.com/des-ant/2024-article-api/internal/markup"
+)
+
+// feedFormat describes a syndication format feeds can be served in.
+type feedFormat struct {
+	mediaType string
+	write     func(w io.Writer, f feed.Feed) error
+}
+
+// feedFormats maps feed file extensions to their formats.
+var feedFormats = map[string]feedFormat{
+	".atom": {mediaType: feed.AtomMediaType, write: feed.WriteAtom},
+	".rss":  {mediaType: feed.RSSMediaType, write: feed.WriteRSS},
+	".json": {mediaType: feed.JSONFeedMediaType, write: feed.WriteJSONFeed},
+}
+
+// siteFeedHandler serves a feed of the newest articles across the site, in
+// the format named by the path's extension.
+func (app *application) siteFeedHandler(w http.ResponseWriter, r *http.Request) {
+	format, ok := feedFormats[path.Ext(r.URL.Path)]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	articles := app.daos.Articles.Recent("", app.config.feed.items)
+
+	f := app.newFeed(r, articles, app.config.feed.title, basePathV1+"/articles")
+	app.serveFeed(w, r, format, f)
+}
+
+// tagFeedHandler serves a feed of the newest articles with a tag, in the
+// format named by the path's extension. Tags without articles don't exist, so
+// requests for their feeds get a 404 response.
+func (app *application) tagFeedHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	format, ok := feedFormats[path.Ext(params.ByName("date"))]
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	tagName := params.ByName("tagName")
+
+	articles := app.daos.Articles.Recent(tagName, app.config.feed.items)
+	if len(articles) == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	title := fmt.Sprintf("%s: %s", app.config.feed.title, tagName)
+	f := app.newFeed(r, articles, title, basePathV1+"/articles")
+	app.serveFeed(w, r, format, f)
+}
+
+// newFeed describes articles, which must be ordered newest first, as a feed
+// linking to the resource at link.
+func (app *application) newFeed(r *http.Request, articles []data.Article, title, link string) feed.Feed {
+	baseURL := app.baseURL(r)
+
+	f := feed.Feed{
+		Title:       title,
+		Description: title,
+		Link:        baseURL + link,
+		SelfLink:    baseURL + r.URL.Path,
+		Author:      app.config.feed.title,
+	}
+
+	for _, article := range articles {
+		link := fmt.Sprintf("%s%s/articles/%d", baseURL, basePathV1, article.ID)
+		f.Items = append(f.Items, feed.Item{
+			Link:        link,
+			Title:       article.Title,
+			Published:   article.Date.ToTime(),
+			Updated:     article.Modified,
+			Categories:  article.Tags,
+			Summary:     article.Summary,
+			ContentHTML: markup.RenderHTML(markup.Parse(article.Body), app.htmlPolicy()),
+		})
+	}
+
+	// A feed was last updated when the last of its items was, so that
+	// articles changed elsewhere in the store don't change it.
+	for _, item := range f.Items {
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+	}
+
+	return f
+}
+
+// serveFeed writes f in the given format. The response carries an ETag and a
+// Last-Modified time so that clients can poll with conditional requests and
+// get a 304 Not Modified response while the feed is unchanged.
+func (app *application) serveFeed(w http.ResponseWriter, r *http.Request, format feedFormat, f feed.Feed) {
+	var buf bytes.Buffer
+	err := format.write(&buf, f)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+
+	w.Header().Set("Content-Type", format.mediaType+"; charset=utf-8")
+	w.Header().Set("ETag", strconv.Quote(hex.EncodeToString(sum[:16])))
+
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(buf.Bytes()))
+}
+
+// baseURL returns the scheme and host that links in responses start with:
+// the configured base URL, or else the one the request was made to.
+func (app *application) baseURL(r *http.Request) string {
+	if app.config.baseURL != "" {
+		return strings.TrimSuffix(app.config.baseURL, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,671 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/graphql"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// graphQLMaxFirst caps the "first" argument of list fields.
+const graphQLMaxFirst = 100
+
+// graphqlHandler executes a GraphQL query sent as JSON. Once a request is
+// well-formed, the response is always 200 OK, with any errors listed in it as
+// the GraphQL spec describes.
+func (app *application) graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			app.graphqlErrorResponse(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+			return
+		}
+	}
+
+	var req graphql.Request
+
+	err := app.readJSON(w, r, &req)
+	if err != nil {
+		app.graphqlErrorResponse(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		app.graphqlErrorResponse(w, r, http.StatusBadRequest, "query must be provided")
+		return
+	}
+
+	resp := app.schema.Execute(r.Context(), req)
+
+	err = app.writeJSON(w, http.StatusOK, resp, nil, app.prettyJSON(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// graphqlErrorResponse sends a request that isn't a well-formed GraphQL
+// request back with its error in the GraphQL response format.
+func (app *application) graphqlErrorResponse(w http.ResponseWriter, r *http.Request, status int, message string) {
+	resp := &graphql.Response{Errors: []*graphql.Error{{Message: message}}}
+
+	err := app.writeJSON(w, status, resp, nil, app.prettyJSON(r))
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// graphQLInputError converts failed validation of a field's arguments into a
+// GraphQL error. The messages are listed under the "errors" extension, keyed
+// by argument.
+func graphQLInputError(errs map[string]string) *graphql.Error {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, len(keys))
+	for i, key := range keys {
+		messages[i] = key + " " + errs[key]
+	}
+
+	e := graphql.NewError(graphql.CodeBadUserInput, strings.Join(messages, "; "))
+	e.Extensions["errors"] = errs
+	return e
+}
+
+// graphQLSimilarArticle is a stored article similar to another one.
+type graphQLSimilarArticle struct {
+	similarity float64
+	article    data.Article
+}
+
+// graphQLTagSummary is a tag summary along with the date it summarises.
+type graphQLTagSummary struct {
+	summary data.TagSummary
+	date    data.ArticleDate
+}
+
+// graphQLConnection is a page of articles listed by the articles query.
+type graphQLConnection struct {
+	articles []data.Article
+	total    int
+	more     bool
+}
+
+// encodeCursor returns the opaque cursor of an article in a listing.
+func encodeCursor(id int64) string {
+	return base64.StdEncoding.EncodeToString([]byte("article:" + strconv.FormatInt(id, 10)))
+}
+
+// decodeCursor returns the article ID held by a cursor.
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+
+	s, ok := strings.CutPrefix(string(b), "article:")
+	if !ok {
+		return 0, errors.New("invalid cursor")
+	}
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return id, nil
+}
+
+// listComplexity is the complexity of a field listing up to its "first"
+// argument of items.
+func listComplexity(args map[string]any, childComplexity int) int {
+	first, _ := args["first"].(int)
+	return 1 + max(first, 0)*childComplexity
+}
+
+// boundedListComplexity returns the complexity function of a field listing
+// up to n items.
+func boundedListComplexity(n int) graphql.ComplexityFunc {
+	return func(args map[string]any, childComplexity int) int {
+		return 1 + n*childComplexity
+	}
+}
+
+var graphQLDate = &graphql.Scalar{
+	Name:        "Date",
+	Description: "A calendar date in the format YYYY-MM-DD.",
+	Serialize: func(v any) (any, error) {
+		date, ok := v.(data.ArticleDate)
+		if !ok {
+			return nil, fmt.Errorf("Date cannot represent value: %v", v)
+		}
+		return time.Time(date).Format("2006-01-02"), nil
+	},
+	ParseValue: func(v any) (any, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("Date must be a string in the format YYYY-MM-DD")
+		}
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, errors.New("Date must be a string in the format YYYY-MM-DD")
+		}
+		return data.ArticleDate(t), nil
+	},
+}
+
+var graphQLBodyFormat = &graphql.Enum{
+	Name:        "BodyFormat",
+	Description: "How an article body is rendered: as stored, as sanitized HTML, or as plain text.",
+	Values:      []string{"RAW", "HTML", "TEXT"},
+}
+
+var graphQLArticleMetrics = &graphql.Object{
+	Name:        "ArticleMetrics",
+	Description: "Statistics derived from an article's content.",
+	Fields: map[string]*graphql.Field{
+		"wordCount": {
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*data.ArticleMetrics).WordCount, nil
+			},
+		},
+		"readingTimeMinutes": {
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*data.ArticleMetrics).ReadingTimeMinutes, nil
+			},
+		},
+		"readingEase": {
+			Type:        graphql.NewNonNull(graphql.Float),
+			Description: "The Flesch reading-ease score of the body.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*data.ArticleMetrics).ReadingEase, nil
+			},
+		},
+		"language": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*data.ArticleMetrics).Language, nil
+			},
+		},
+	},
+}
+
+var graphQLTag = &graphql.Object{
+	Name: "Tag",
+	Fields: map[string]*graphql.Field{
+		"name": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(data.TagCount).Tag, nil
+			},
+		},
+		"articleCount": {
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(data.TagCount).Count, nil
+			},
+		},
+	},
+}
+
+var graphQLPageInfo = &graphql.Object{
+	Name: "PageInfo",
+	Fields: map[string]*graphql.Field{
+		"hasNextPage": {
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLConnection).more, nil
+			},
+		},
+		"endCursor": {
+			Type:        graphql.String,
+			Description: "The cursor of the last article on the page, to pass as after for the next page.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				articles := p.Source.(graphQLConnection).articles
+				if len(articles) == 0 {
+					return nil, nil
+				}
+				return encodeCursor(articles[len(articles)-1].ID), nil
+			},
+		},
+	},
+}
+
+var graphQLArticleFilter = &graphql.InputObject{
+	Name:        "ArticleFilter",
+	Description: "Narrows down a listing of articles using their content metrics.",
+	Fields: map[string]*graphql.InputField{
+		"language":       {Type: graphql.String},
+		"minWordCount":   {Type: graphql.Int},
+		"maxWordCount":   {Type: graphql.Int},
+		"maxReadingTime": {Type: graphql.Int},
+		"minReadingEase": {Type: graphql.Float},
+		"maxReadingEase": {Type: graphql.Float},
+	},
+}
+
+// readArticleFilter converts an ArticleFilter argument into a data.ArticleFilter
+// and validates it as listArticlesHandler does.
+func readArticleFilter(arg any) (data.ArticleFilter, error) {
+	var filter data.ArticleFilter
+
+	fields, _ := arg.(map[string]any)
+	if language, ok := fields["language"].(string); ok {
+		filter.Language = language
+	}
+	if n, ok := fields["minWordCount"].(int); ok {
+		filter.MinWordCount = n
+	}
+	if n, ok := fields["maxWordCount"].(int); ok {
+		filter.MaxWordCount = n
+	}
+	if n, ok := fields["maxReadingTime"].(int); ok {
+		filter.MaxReadingTime = n
+	}
+	if f, ok := fields["minReadingEase"].(float64); ok {
+		filter.MinReadingEase = &f
+	}
+	if f, ok := fields["maxReadingEase"].(float64); ok {
+		filter.MaxReadingEase = &f
+	}
+
+	v := validator.New()
+	data.ValidateArticleFilter(v, filter)
+
+	if !v.Valid() {
+		// Report the fields by their GraphQL names.
+		errs := make(map[string]string, len(v.Errors))
+		for key, message := range v.Errors {
+			errs["filter."+camelCase(key)] = message
+		}
+		return filter, graphQLInputError(errs)
+	}
+
+	return filter, nil
+}
+
+// camelCase converts a snake_case name to camelCase.
+func camelCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// newGraphQLSchema builds the schema served at /v1/graphql. Its resolvers use
+// the same store and helpers as the REST handlers.
+func (app *application) newGraphQLSchema() *graphql.Schema {
+	article := &graphql.Object{
+		Name:        "Article",
+		Description: "An article in the store.",
+	}
+	similarArticle := &graphql.Object{
+		Name:        "SimilarArticle",
+		Description: "A stored article similar to another one, as found by near-duplicate detection.",
+	}
+	tagSummary := &graphql.Object{
+		Name:        "TagSummary",
+		Description: "A summary of the articles with a tag on a date.",
+	}
+	articleEdge := &graphql.Object{
+		Name: "ArticleEdge",
+	}
+	articleConnection := &graphql.Object{
+		Name:        "ArticleConnection",
+		Description: "A page of articles, ordered by ID.",
+	}
+
+	article.Fields = map[string]*graphql.Field{
+		"id": {
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(data.Article).ID, nil
+			},
+		},
+		"title": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(data.Article).Title, nil
+			},
+		},
+		"date": {
+			Type: graphql.NewNonNull(graphQLDate),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(data.Article).Date, nil
+			},
+		},
+		"body": {
+			Type: graphql.NewNonNull(graphql.String),
+			Args: map[string]*graphql.Argument{
+				"format": {Type: graphQLBodyFormat, Default: "RAW"},
+			},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				article := p.Source.(data.Article)
+				format, _ := p.Args["format"].(string)
+				app.applyBodyFormat(&article, strings.ToLower(format))
+				return article.Body, nil
+			},
+		},
+		"tags": {
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(data.Article).Tags, nil
+			},
+		},
+		"summary": {
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "An extractive summary of the body.",
+			Args: map[string]*graphql.Argument{
+				"sentences": {Type: graphql.Int, Default: 3},
+			},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				article := p.Source.(data.Article)
+				sentences, _ := p.Args["sentences"].(int)
+
+				v := validator.New()
+				validateSummarySentences(v, sentences)
+				if !v.Valid() {
+					return nil, graphQLInputError(v.Errors)
+				}
+
+				return data.SummarizeArticle(&article, sentences).Text, nil
+			},
+		},
+		"metrics": {
+			Type: graphql.NewNonNull(graphQLArticleMetrics),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				article := p.Source.(data.Article)
+				if article.Metrics == nil {
+					return data.ComputeMetrics(&article), nil
+				}
+				return article.Metrics, nil
+			},
+		},
+		"similar": {
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(similarArticle))),
+			Description: "Stored articles at least threshold similar to this one, most similar first.",
+			Args: map[string]*graphql.Argument{
+				"first":     {Type: graphql.Int, Default: 5},
+				"threshold": {Type: graphql.Float, Default: app.config.dedupe.threshold},
+			},
+			Complexity: listComplexity,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				article := p.Source.(data.Article)
+				first, _ := p.Args["first"].(int)
+				threshold, _ := p.Args["threshold"].(float64)
+
+				v := validator.New()
+				v.Check(first >= 1 && first <= graphQLMaxFirst, "first", fmt.Sprintf("must be between 1 and %d", graphQLMaxFirst))
+				v.Check(threshold > 0 && threshold <= 1, "threshold", "must be greater than 0 and at most 1")
+				if !v.Valid() {
+					return nil, graphQLInputError(v.Errors)
+				}
+
+				duplicates := app.daos.Articles.FindNearDuplicates(&article, threshold)
+				duplicates = duplicates[:min(first, len(duplicates))]
+
+				ids := make([]int64, len(duplicates))
+				for i, duplicate := range duplicates {
+					ids[i] = duplicate.ID
+				}
+				// Articles deleted since they were found are left out.
+				found, _ := app.daos.Articles.GetMany(ids)
+				byID := make(map[int64]data.Article, len(found))
+				for _, a := range found {
+					byID[a.ID] = a
+				}
+
+				result := make([]graphQLSimilarArticle, 0, len(duplicates))
+				for _, duplicate := range duplicates {
+					if a, ok := byID[duplicate.ID]; ok {
+						result = append(result, graphQLSimilarArticle{similarity: duplicate.Similarity, article: a})
+					}
+				}
+				return result, nil
+			},
+		},
+		"tagSummaries": {
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(tagSummary))),
+			Description: "The summaries of the article's tags on its date.",
+			Complexity:  boundedListComplexity(data.MaxArticleTags),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				article := p.Source.(data.Article)
+
+				result := make([]graphQLTagSummary, 0, len(article.Tags))
+				for _, tag := range article.Tags {
+					summary, err := app.summarizeTag(tag, article.Date)
+					if err != nil {
+						// The article may have been deleted since it was read.
+						continue
+					}
+					result = append(result, graphQLTagSummary{summary: summary, date: article.Date})
+				}
+				return result, nil
+			},
+		},
+	}
+
+	similarArticle.Fields = map[string]*graphql.Field{
+		"similarity": {
+			Type:        graphql.NewNonNull(graphql.Float),
+			Description: "How similar the article is, from 0 to 1.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLSimilarArticle).similarity, nil
+			},
+		},
+		"article": {
+			Type: graphql.NewNonNull(article),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLSimilarArticle).article, nil
+			},
+		},
+	}
+
+	tagSummary.Fields = map[string]*graphql.Field{
+		"tag": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLTagSummary).summary.Tag, nil
+			},
+		},
+		"date": {
+			Type: graphql.NewNonNull(graphQLDate),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLTagSummary).date, nil
+			},
+		},
+		"count": {
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "The number of tags on the articles, including this one.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLTagSummary).summary.Count, nil
+			},
+		},
+		"articleIds": {
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+			Description: "The IDs of the latest 10 articles, newest first.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLTagSummary).summary.Articles, nil
+			},
+		},
+		"articles": {
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(article))),
+			Description: "The latest 10 articles, newest first.",
+			Complexity:  boundedListComplexity(10),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				articles, _ := app.daos.Articles.GetMany(p.Source.(graphQLTagSummary).summary.Articles)
+				return articles, nil
+			},
+		},
+		"relatedTags": {
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLTagSummary).summary.RelatedTags, nil
+			},
+		},
+		"topKeywords": {
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLTagSummary).summary.TopKeywords, nil
+			},
+		},
+	}
+
+	articleEdge.Fields = map[string]*graphql.Field{
+		"cursor": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return encodeCursor(p.Source.(data.Article).ID), nil
+			},
+		},
+		"node": {
+			Type: graphql.NewNonNull(article),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source, nil
+			},
+		},
+	}
+
+	articleConnection.Fields = map[string]*graphql.Field{
+		"edges": {
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(articleEdge))),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLConnection).articles, nil
+			},
+		},
+		"nodes": {
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(article))),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLConnection).articles, nil
+			},
+		},
+		"pageInfo": {
+			Type: graphql.NewNonNull(graphQLPageInfo),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source, nil
+			},
+		},
+		"totalCount": {
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "The number of articles matching the filter.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLConnection).total, nil
+			},
+		},
+	}
+
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: map[string]*graphql.Field{
+			"article": {
+				Type: article,
+				Args: map[string]*graphql.Argument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := strconv.ParseInt(p.Args["id"].(string), 10, 64)
+					if err != nil {
+						return nil, nil
+					}
+
+					article, err := app.daos.Articles.Get(id)
+					if err != nil {
+						switch {
+						case errors.Is(err, data.ErrRecordNotFound):
+							return nil, nil
+						default:
+							return nil, err
+						}
+					}
+					return *article, nil
+				},
+			},
+			"articles": {
+				Type:        graphql.NewNonNull(articleConnection),
+				Description: "Lists articles by ID, first at a time, starting after the article whose cursor is given.",
+				Args: map[string]*graphql.Argument{
+					"filter": {Type: graphQLArticleFilter},
+					"first":  {Type: graphql.Int, Default: 20},
+					"after":  {Type: graphql.String},
+				},
+				Complexity: listComplexity,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					first, _ := p.Args["first"].(int)
+
+					v := validator.New()
+					v.Check(first >= 1 && first <= graphQLMaxFirst, "first", fmt.Sprintf("must be between 1 and %d", graphQLMaxFirst))
+
+					var after int64
+					if cursor, ok := p.Args["after"].(string); ok {
+						var err error
+						after, err = decodeCursor(cursor)
+						v.Check(err == nil, "after", "must be a cursor returned by a previous page")
+					}
+
+					if !v.Valid() {
+						return nil, graphQLInputError(v.Errors)
+					}
+
+					filter, err := readArticleFilter(p.Args["filter"])
+					if err != nil {
+						return nil, err
+					}
+
+					articles, total, more := app.daos.Articles.ListAfter(filter, after, first)
+					return graphQLConnection{articles: articles, total: total, more: more}, nil
+				},
+			},
+			"tagSummary": {
+				Type: tagSummary,
+				Args: map[string]*graphql.Argument{
+					"tag":  {Type: graphql.NewNonNull(graphql.String)},
+					"date": {Type: graphql.NewNonNull(graphQLDate)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					tag := p.Args["tag"].(string)
+					date := p.Args["date"].(data.ArticleDate)
+
+					summary, err := app.summarizeTag(tag, date)
+					if err != nil {
+						switch {
+						case errors.Is(err, data.ErrRecordNotFound):
+							return nil, nil
+						default:
+							return nil, err
+						}
+					}
+					return graphQLTagSummary{summary: summary, date: date}, nil
+				},
+			},
+			"tags": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphQLTag))),
+				Description: "Every tag in the store, in alphabetical order.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return app.daos.Articles.TagCounts(), nil
+				},
+			},
+		},
+	}
+
+	return &graphql.Schema{
+		Query:         query,
+		MaxDepth:      app.config.graphql.maxDepth,
+		MaxComplexity: app.config.graphql.maxComplexity,
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQL(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	// query posts a GraphQL request and decodes the response.
+	query := func(t *testing.T, request map[string]any) map[string]any {
+		code, _, body := ts.postJSON(t, "/v1/graphql", request)
+		require.Equal(t, http.StatusOK, code, body)
+
+		var response map[string]any
+		require.NoError(t, json.Unmarshal([]byte(body), &response))
+		return response
+	}
+
+	t.Run("ArticleInOneRoundTrip", func(t *testing.T) {
+		response := query(t, map[string]any{"query": `{
+			article(id: 1) {
+				id
+				title
+				date
+				body(format: TEXT)
+				metrics { wordCount }
+				similar(first: 2, threshold: 0.5) { similarity article { id } }
+				tagSummaries { tag count articleIds articles { id } }
+			}
+		}`})
+		require.NotContains(t, response, "errors")
+
+		article := response["data"].(map[string]any)["article"].(map[string]any)
+		assert.Equal(t, "1", article["id"])
+		assert.Equal(t, "2016-09-22", article["date"])
+		assert.Equal(t, map[string]any{"wordCount": float64(10)}, article["metrics"])
+
+		similar := article["similar"].([]any)
+		require.Len(t, similar, 2)
+		first, second := similar[0].(map[string]any), similar[1].(map[string]any)
+		assert.GreaterOrEqual(t, first["similarity"], second["similarity"])
+		assert.NotEqual(t, "1", first["article"].(map[string]any)["id"])
+
+		summaries := article["tagSummaries"].([]any)
+		require.Len(t, summaries, 3)
+		science := summaries[2].(map[string]any)
+		assert.Equal(t, "science", science["tag"])
+		assert.Equal(t, float64(6), science["count"])
+		assert.Equal(t, []any{"18", "3", "2", "1"}, science["articleIds"])
+		assert.Equal(t, []any{
+			map[string]any{"id": "18"},
+			map[string]any{"id": "3"},
+			map[string]any{"id": "2"},
+			map[string]any{"id": "1"},
+		}, science["articles"])
+	})
+
+	t.Run("MissingArticle", func(t *testing.T) {
+		response := query(t, map[string]any{"query": `{ article(id: 999) { id } }`})
+		assert.Equal(t, map[string]any{"data": map[string]any{"article": nil}}, response)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		page := `query Page($after: String) {
+			articles(first: 2, after: $after) {
+				totalCount
+				nodes { id }
+				pageInfo { hasNextPage endCursor }
+			}
+		}`
+
+		response := query(t, map[string]any{"query": page})
+		articles := response["data"].(map[string]any)["articles"].(map[string]any)
+		assert.Equal(t, float64(27), articles["totalCount"])
+		assert.Equal(t, []any{map[string]any{"id": "1"}, map[string]any{"id": "2"}}, articles["nodes"])
+		pageInfo := articles["pageInfo"].(map[string]any)
+		assert.Equal(t, true, pageInfo["hasNextPage"])
+
+		response = query(t, map[string]any{"query": page, "variables": map[string]any{"after": pageInfo["endCursor"]}})
+		articles = response["data"].(map[string]any)["articles"].(map[string]any)
+		assert.Equal(t, []any{map[string]any{"id": "3"}, map[string]any{"id": "4"}}, articles["nodes"])
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		response := query(t, map[string]any{"query": `{
+			articles(filter: {maxWordCount: 10}) { totalCount edges { node { id } } }
+		}`})
+		articles := response["data"].(map[string]any)["articles"].(map[string]any)
+		assert.Equal(t, float64(len(articles["edges"].([]any))), articles["totalCount"])
+		assert.Contains(t, articles["edges"], map[string]any{"node": map[string]any{"id": "1"}})
+	})
+
+	t.Run("TagSummaryAndTags", func(t *testing.T) {
+		response := query(t, map[string]any{"query": `{
+			science: tagSummary(tag: "science", date: "2016-09-22") { tag date count articleIds }
+			missing: tagSummary(tag: "science", date: "2001-01-01") { tag }
+			tags { name articleCount }
+		}`})
+		data := response["data"].(map[string]any)
+		assert.Equal(t, map[string]any{
+			"tag":        "science",
+			"date":       "2016-09-22",
+			"count":      float64(6),
+			"articleIds": []any{"18", "3", "2", "1"},
+		}, data["science"])
+		assert.Nil(t, data["missing"])
+		assert.Contains(t, data["tags"], map[string]any{"name": "science", "articleCount": float64(6)})
+	})
+
+	t.Run("VariablesFragmentsAndDirectives", func(t *testing.T) {
+		response := query(t, map[string]any{
+			"query": `query Article($id: ID!, $withTags: Boolean = false) {
+				article(id: $id) {
+					__typename
+					...Names
+					tags @include(if: $withTags)
+					... on Article { date @skip(if: true) }
+				}
+			}
+			fragment Names on Article { id title }`,
+			"operationName": "Article",
+			"variables":     map[string]any{"id": 2},
+		})
+		assert.Equal(t, map[string]any{"data": map[string]any{"article": map[string]any{
+			"__typename": "Article",
+			"id":         "2",
+			"title":      "breakthrough in sleep science",
+		}}}, response)
+	})
+
+	t.Run("RequestErrors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			request  map[string]any
+			code     string
+			message  string
+			location map[string]any
+		}{
+			{
+				name:     "Syntax",
+				request:  map[string]any{"query": "{\n  article(id: 1) {"},
+				code:     "GRAPHQL_PARSE_FAILED",
+				message:  "Syntax Error: Expected Name, found <EOF>.",
+				location: map[string]any{"line": float64(2), "column": float64(19)},
+			},
+			{
+				name:     "UnknownField",
+				request:  map[string]any{"query": "{ article(id: 1) { colour } }"},
+				code:     "GRAPHQL_VALIDATION_FAILED",
+				message:  `Cannot query field "colour" on type "Article".`,
+				location: map[string]any{"line": float64(1), "column": float64(20)},
+			},
+			{
+				name:     "MissingArgument",
+				request:  map[string]any{"query": "{ article { id } }"},
+				code:     "GRAPHQL_VALIDATION_FAILED",
+				message:  `Field "article" argument "id" of type "ID!" is required, but it was not provided.`,
+				location: map[string]any{"line": float64(1), "column": float64(3)},
+			},
+			{
+				name:     "Mutation",
+				request:  map[string]any{"query": "mutation { article(id: 1) { id } }"},
+				code:     "GRAPHQL_VALIDATION_FAILED",
+				message:  "Schema is not configured to execute mutation operation.",
+				location: map[string]any{"line": float64(1), "column": float64(1)},
+			},
+			{
+				name:     "MissingVariable",
+				request:  map[string]any{"query": "query ($id: ID!) { article(id: $id) { id } }"},
+				code:     "BAD_USER_INPUT",
+				message:  `Variable "$id" of required type "ID!" was not provided.`,
+				location: map[string]any{"line": float64(1), "column": float64(8)},
+			},
+			{
+				name:     "TooDeep",
+				request:  map[string]any{"query": "{ article(id: 1) {" + strings.Repeat(" similar { article {", 5) + " id" + strings.Repeat(" } }", 5) + " } }"},
+				code:     "QUERY_TOO_DEEP",
+				message:  "Query depth exceeds the maximum of 10.",
+				location: map[string]any{"line": float64(1), "column": float64(1)},
+			},
+			{
+				name:     "TooComplex",
+				request:  map[string]any{"query": "{ articles(first: 100) { nodes { similar(first: 100) { article { id } } } } }"},
+				code:     "QUERY_TOO_COMPLEX",
+				message:  "Query complexity 20201 exceeds the maximum of 1000.",
+				location: map[string]any{"line": float64(1), "column": float64(1)},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				response := query(t, tt.request)
+
+				// Requests that can't be executed have no data at all.
+				assert.NotContains(t, response, "data")
+
+				errs := response["errors"].([]any)
+				require.Len(t, errs, 1)
+				err := errs[0].(map[string]any)
+				assert.Equal(t, tt.message, err["message"])
+				assert.Equal(t, []any{tt.location}, err["locations"])
+				assert.Equal(t, map[string]any{"code": tt.code}, err["extensions"])
+			})
+		}
+	})
+
+	t.Run("FieldErrors", func(t *testing.T) {
+		response := query(t, map[string]any{"query": `{
+			article(id: 1) { id summary(sentences: 0) }
+			other: article(id: 2) { id }
+		}`})
+
+		// The nullable article is set to null, but the rest of the query runs.
+		assert.Equal(t, map[string]any{"article": nil, "other": map[string]any{"id": "2"}}, response["data"])
+		assert.Equal(t, []any{map[string]any{
+			"message":   "sentences must be between 1 and 20",
+			"locations": []any{map[string]any{"line": float64(2), "column": float64(24)}},
+			"path":      []any{"article", "summary"},
+			"extensions": map[string]any{
+				"code":   "BAD_USER_INPUT",
+				"errors": map[string]any{"sentences": "must be between 1 and 20"},
+			},
+		}}, response["errors"])
+	})
+
+	t.Run("MalformedRequests", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			contentType string
+			body        string
+			status      int
+			message     string
+		}{
+			{"BadJSON", "application/json", `{"query": `, http.StatusBadRequest, "body contains badly-formed JSON"},
+			{"NoQuery", "application/json", `{"variables": {}}`, http.StatusBadRequest, "query must be provided"},
+			{"UnknownKey", "application/json", `{"query": "{ tags { name } }", "colour": 1}`, http.StatusBadRequest, `body contains unknown key "colour"`},
+			{"NotJSON", "application/graphql", `{ tags { name } }`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				code, _, body := ts.post(t, "/v1/graphql", tt.contentType, strings.NewReader(tt.body))
+				assert.Equal(t, tt.status, code)
+				assert.JSONEq(t, fmt.Sprintf(`{"errors": [{"message": %q}]}`, tt.message), body)
+			})
+		}
+	})
+}
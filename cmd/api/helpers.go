@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -47,23 +49,33 @@ func (app *application) readTagAndDateParams(r *http.Request) (string, data.Arti
 // envelope is a generic type that we can use to hold the response envelope.
 type envelope map[string]any
 
-// writeJSON writes the provided data to the http.ResponseWriter as JSON.
-func (app *application) writeJSON(w http.ResponseWriter, status int, data any, headers http.Header) error {
-	js, err := json.MarshalIndent(data, "", "\t")
+// writeJSON writes the provided data to the http.ResponseWriter as JSON,
+// indented with tabs if pretty is set. The response is encoded into a pooled
+// buffer so that its Content-Length can be sent.
+func (app *application) writeJSON(w http.ResponseWriter, status int, data any, headers http.Header, pretty bool) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	enc := json.NewEncoder(buf)
+	if pretty {
+		enc.SetIndent("", "\t")
+	}
+
+	// The encoder ends the output with a newline, which makes it easier to
+	// view in terminal applications.
+	err := enc.Encode(data)
 	if err != nil {
 		return err
 	}
 
-	// Append a newline to make it easier to view in terminal applications.
-	js = append(js, '\n')
-
 	for key, value := range headers {
 		w.Header()[key] = value
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
 	w.WriteHeader(status)
-	_, err = w.Write(js)
+	_, err = buf.WriteTo(w)
 	if err != nil {
 		return err
 	}
@@ -73,9 +85,9 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data any, h
 
 // readJSON decodes the request body into the provided destination.
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
-	// Limit the size of the request body to prevent potential denial-of-service attacks.
-	maxBytes := 1_048_576
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	// Limit the size of the request body to prevent potential denial-of-service
+	// attacks. The limit is maxRequestBytes unless the route sets another.
+	r.Body = http.MaxBytesReader(w, r.Body, app.contextGetBodyLimit(r))
 
 	dec := json.NewDecoder(r.Body)
 	// Require that JSON keys in the request body must match the destination struct fields.
@@ -114,7 +126,7 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
 			return fmt.Errorf("body contains unknown key %s", fieldName)
 
-		// Handle body size limit exceeded to inform clients that the body must not exceed 1MB.
+		// Handle body size limit exceeded to inform clients of the route's limit.
 		case errors.As(err, &maxBytesError):
 			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
 
@@ -136,6 +148,113 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// readString returns a string value from the query string, or the provided
+// default value if no matching key could be found.
+func (app *application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	return s
+}
+
+// readCSV reads a comma-separated string value from the query string and splits
+// it into a slice. If no matching key exists it returns the provided default value.
+func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+	if csv == "" {
+		return defaultValue
+	}
+
+	return strings.Split(csv, ",")
+}
+
+// readIDs reads a comma-separated list of article IDs from the query string,
+// dropping repeated IDs. If no matching key exists it returns nil. If any of
+// the IDs is not a positive integer, it records an error in the provided
+// Validator instance.
+func (app *application) readIDs(qs url.Values, key string, v *validator.Validator) []int64 {
+	var ids []int64
+	for _, s := range app.readCSV(qs, key, nil) {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil || id < 1 {
+			v.AddError(key, "must be a comma-separated list of positive integers")
+			return nil
+		}
+		ids = append(ids, id)
+	}
+
+	return uniqueIDs(ids)
+}
+
+// uniqueIDs returns ids without repeats, keeping the first of each.
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	var unique []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// readInt reads an integer value from the query string. If no matching key exists
+// it returns the provided default value. If the value cannot be converted, it
+// records an error in the provided Validator instance.
+func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "must be an integer value")
+		return defaultValue
+	}
+
+	return i
+}
+
+// readFloat reads a float value from the query string. If no matching key exists
+// it returns the provided default value. If the value cannot be converted, it
+// records an error in the provided Validator instance.
+func (app *application) readFloat(qs url.Values, key string, defaultValue float64, v *validator.Validator) float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return defaultValue
+	}
+
+	return f
+}
+
+// readDate reads a date in the format "2006-01-02" from the query string. If no
+// matching key exists it returns the zero time. If the value cannot be parsed,
+// it records an error in the provided Validator instance.
+func (app *application) readDate(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return time.Time{}
+	}
+
+	date, err := data.ParseArticleDate(s)
+	if err != nil {
+		v.AddError(key, "must be a date in the format YYYY-MM-DD")
+		return time.Time{}
+	}
+
+	return date.ToTime()
+}
+
 // filter returns a new slice containing only the elements of slice that satisfy the predicate.
 func filter(slice []string, predicate func(string) bool) []string {
 	var result []string
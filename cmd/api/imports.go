@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/importer"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// maxImportBytes limits the size of uploaded import files, which are expected
+// to be much larger than other request bodies.
+const maxImportBytes = 64 << 20
+
+// importMediaTypes lists the media types accepted for import files, besides
+// multipart/form-data uploads.
+var importMediaTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/xml",
+	"text/xml",
+	"multipart/form-data",
+}
+
+// Import statuses report what happened to each item in an import file.
+const (
+	importCreated = "created"
+	importSkipped = "skipped"
+	importInvalid = "invalid"
+)
+
+// importResult reports what happened to one item in an import file.
+type importResult struct {
+	Item      int               `json:"item"`
+	Title     string            `json:"title,omitempty"`
+	ID        int64             `json:"id,omitempty"`
+	Status    string            `json:"status"`
+	Reason    string            `json:"reason,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	Sanitized []markup.Change   `json:"sanitized,omitempty"`
+}
+
+// importReport summarises an import, item by item.
+type importReport struct {
+	Format  importer.Format `json:"format"`
+	Created int             `json:"created"`
+	Skipped int             `json:"skipped"`
+	Invalid int             `json:"invalid"`
+	// Error explains why the import stopped before the end of the file. The
+	// items before it have still been imported.
+	Error string         `json:"error,omitempty"`
+	Items []importResult `json:"items"`
+}
+
+// importFeedHandler imports the articles in an uploaded RSS, Atom or
+// WordPress export file and reports what happened to each item. The file is
+// sent either as the request body or as the "file" field of a multipart form.
+func (app *application) importFeedHandler(w http.ResponseWriter, r *http.Request) {
+	file, err := app.readImportFile(w, r)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnsupportedMediaType):
+			app.unsupportedMediaTypeResponse(w, r, importMediaTypes)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	report := app.importArticles(file)
+	if report.Error != "" && len(report.Items) == 0 {
+		app.badRequestResponse(w, r, errors.New(report.Error))
+		return
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"import": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readImportFile returns a reader for the import file in the request body,
+// without reading it into memory.
+func (app *application) readImportFile(w http.ResponseWriter, r *http.Request) (io.Reader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return r.Body, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errUnsupportedMediaType
+	}
+
+	switch mediaType {
+	case "application/rss+xml", "application/atom+xml", "application/xml", "text/xml":
+		return r.Body, nil
+	case "multipart/form-data":
+	default:
+		return nil, errUnsupportedMediaType
+	}
+
+	if params["boundary"] == "" {
+		return nil, errors.New("body contains badly-formed multipart data (missing boundary)")
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body must contain a file field")
+		}
+		if err != nil {
+			return nil, bodyReadError(err)
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// importArticles reads the items in an import file, validates each as it
+// would be on create, and stores the valid ones.
+//
+// Items already stored, either under the same WordPress post ID or with the
+// same title and date, are skipped, so that an interrupted import can simply
+// be run again. Items without a post ID are given IDs after the highest one in
+// use.
+func (app *application) importArticles(file io.Reader) *importReport {
+	report := &importReport{Items: []importResult{}}
+
+	imported := make(map[string]int64)
+	nextID := int64(1)
+	for _, article := range app.daos.Articles.GetAll() {
+		imported[importKey(article)] = article.ID
+		nextID = max(nextID, article.ID+1)
+	}
+
+	dec := importer.NewDecoder(file)
+	for {
+		item, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			report.Error = bodyReadError(err).Error()
+			break
+		}
+
+		result := app.importItem(item, imported, &nextID)
+
+		switch result.Status {
+		case importCreated:
+			report.Created++
+		case importSkipped:
+			report.Skipped++
+		case importInvalid:
+			report.Invalid++
+		}
+		report.Items = append(report.Items, result)
+	}
+
+	report.Format = dec.Format()
+	return report
+}
+
+// importItem validates and stores one item from an import file. imported maps
+// the title and date of stored articles to their IDs, and nextID is the ID to
+// give the next article without one. Both are updated as articles are stored.
+func (app *application) importItem(item importer.Item, imported map[string]int64, nextID *int64) importResult {
+	article := item.Article
+	result := importResult{Item: item.Index, Title: article.Title}
+
+	skip := func(id int64, reason string) importResult {
+		result.ID = id
+		result.Status = importSkipped
+		result.Reason = reason
+		return result
+	}
+
+	if item.Skip != "" {
+		return skip(0, item.Skip)
+	}
+
+	if id, ok := imported[importKey(article)]; ok {
+		return skip(id, fmt.Sprintf("already stored as article %d", id))
+	}
+
+	hasID := article.ID != 0
+	if hasID {
+		if _, err := app.daos.Articles.Get(article.ID); err == nil {
+			return skip(article.ID, fmt.Sprintf("article %d already exists", article.ID))
+		}
+	} else {
+		article.ID = *nextID
+	}
+
+	v := validator.New()
+
+	result.Sanitized = app.sanitizeBody(v, &article)
+
+	if data.ValidateArticle(v, &article); !v.Valid() {
+		if hasID {
+			result.ID = article.ID
+		}
+		result.Status = importInvalid
+		result.Errors = v.Errors
+		result.Sanitized = nil
+		return result
+	}
+
+	// Another request may take an ID between choosing it and storing the
+	// article, in which case the next one is tried. The near-duplicate
+	// policy applies as it does to creates.
+	for {
+		duplicates, err := app.insertArticles(&article)
+		if errors.Is(err, data.ErrNearDuplicate) {
+			return skip(duplicates[0].ID, fmt.Sprintf("near-duplicate of article %d", duplicates[0].ID))
+		}
+		if !errors.Is(err, data.ErrDuplicateID) {
+			break
+		}
+		if hasID {
+			return skip(article.ID, fmt.Sprintf("article %d already exists", article.ID))
+		}
+		article.ID++
+	}
+
+	*nextID = max(*nextID, article.ID+1)
+	imported[importKey(article)] = article.ID
+
+	result.ID = article.ID
+	result.Status = importCreated
+	return result
+}
+
+// importKey identifies an article by its title and date, which imported items
+// are matched on to avoid storing them twice.
+func importKey(article data.Article) string {
+	return article.Date.String() + " " + article.Title
+}
+
+// runImport imports each of the named files in turn, writing a JSON report for
+// each to w. It stops at the first file that can't be read to the end.
+func (app *application) runImport(w io.Writer, files []string) error {
+	if len(files) == 0 {
+		return errors.New("import: no files given")
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	for _, name := range files {
+		report, err := app.importFile(name)
+		if err != nil {
+			return err
+		}
+
+		err = enc.Encode(envelope{"file": name, "import": report})
+		if err != nil {
+			return err
+		}
+
+		if report.Error != "" {
+			return fmt.Errorf("import: %s: %s", name, report.Error)
+		}
+	}
+
+	return nil
+}
+
+// importFile imports the articles in the named file.
+func (app *application) importFile(name string) (*importReport, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("import: %w", err)
+	}
+	defer f.Close()
+
+	return app.importArticles(f), nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// showArticleKeywordsHandler returns the top keyphrases of an article body and
+// the entity candidates in its title and body. The number of keyphrases
+// defaults to 10 and can be set with "limit".
+func (app *application) showArticleKeywordsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 10, v)
+	v.Check(limit >= 1 && limit <= 50, "limit", "must be between 1 and 50")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	article, err := app.daos.Articles.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	keywords := data.ExtractKeywords(article, limit)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"keywords": keywords}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -2,11 +2,20 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/graphql"
+	"github.com/des-ant/2024-article-api/internal/jsonrpc"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/openapi"
+	"github.com/des-ant/2024-article-api/internal/sitemap"
 )
 
 // Declare a string containing the application version number.
@@ -17,46 +26,348 @@ const version = "1.0.0"
 // Currently includes:
 // - Network port for the server
 // - Operating environment (development, staging, production, etc.)
+// - Near-duplicate detection policy and similarity threshold
+// - Minimum tag count below which suggested tags are added on create
+// - Number of summary sentences stored on each article
+// - HTML policy for article bodies: allowed elements and the site's own hosts
+// - Base URL for absolute links, and the title and length of feeds
+// - Directory of Markdown articles to keep the store in step with
+// - Static site output directory and index page size
+// - Number of articles and body size allowed in a batch create
+// - Depth and complexity limits of GraphQL queries
+// - Whether a subcommand goes on to start the server
+// - Whether requests are validated against the OpenAPI document
+// - Token required by the admin endpoints
 type config struct {
-	port int
-	env  string
+	port             int
+	env              string
+	baseURL          string
+	serve            bool
+	validateRequests bool
+	dedupe           struct {
+		policy    string
+		threshold float64
+	}
+	tags struct {
+		autofill int
+	}
+	summary struct {
+		sentences int
+	}
+	html struct {
+		policy        string
+		elements      []string
+		internalHosts []string
+	}
+	feed struct {
+		title string
+		items int
+	}
+	sync struct {
+		dir      string
+		interval time.Duration
+		delete   bool
+		dryRun   bool
+	}
+	site struct {
+		dir      string
+		pageSize int
+	}
+	batch struct {
+		maxItems int
+		maxBytes int64
+	}
+	graphql struct {
+		maxDepth      int
+		maxComplexity int
+	}
+	admin struct {
+		token string
+	}
 }
 
+// Near-duplicate policies decide what happens when a new article closely
+// resembles one that is already stored.
+const (
+	dedupePolicyAccept = "accept"
+	dedupePolicyWarn   = "warn"
+	dedupePolicyReject = "reject"
+)
+
+// HTML policies decide what happens to article bodies containing HTML that
+// isn't allowed.
+const (
+	htmlPolicyStrip  = "strip"
+	htmlPolicyReject = "reject"
+)
+
 // Define an application struct to hold the dependencies for our HTTP handlers,
 // helpers, and middleware.
 type application struct {
-	config config
-	logger *slog.Logger
-	daos   *data.DAOs
-	wg     sync.WaitGroup
+	config  config
+	logger  *slog.Logger
+	daos    *data.DAOs
+	sitemap *sitemap.Sitemap
+	schema  *graphql.Schema
+	rpc     *jsonrpc.Server
+	// registered holds the routes added to the router by routes.
+	registered []route
+	// apiDocument returns the OpenAPI document of the routes, built the
+	// first time it's needed.
+	apiDocument func() (*openapi.Document, error)
+	wg          sync.WaitGroup
 }
 
-// parseFlags reads the value of the port and env command-line flags into the config struct.
-func parseFlags(cfg *config) {
+// Subcommands run a task before, or instead of, starting the server:
+// cmdImport imports the RSS, Atom and WordPress export files named after the
+// flags, cmdSync syncs the store with a directory of Markdown articles, and
+// cmdSite renders the store into a static site.
+const (
+	cmdImport = "import"
+	cmdSync   = "sync"
+	cmdSite   = "site"
+)
+
+// parseFlags reads the command-line flags into the config struct. It returns
+// the subcommand being run, if any, and the arguments following the flags.
+// Subcommands accept the same flags as the server.
+func parseFlags(cfg *config) (string, []string) {
+	args := os.Args[1:]
+
+	var command string
+	if len(args) > 0 && (args[0] == cmdImport || args[0] == cmdSync || args[0] == cmdSite) {
+		command, args = args[0], args[1:]
+		flag.BoolVar(&cfg.serve, "serve", false, "Start the server once the "+command+" is done")
+	}
+	if command == cmdSync {
+		flag.BoolVar(&cfg.sync.dryRun, "dry-run", false, "Report the changes a sync would make without making them")
+	}
+
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
-	flag.Parse()
+
+	flag.StringVar(&cfg.dedupe.policy, "dedupe-policy", dedupePolicyWarn, "Near-duplicate policy (accept|warn|reject)")
+	flag.Float64Var(&cfg.dedupe.threshold, "dedupe-threshold", 0.9, "Near-duplicate similarity threshold (0-1]")
+
+	flag.IntVar(&cfg.tags.autofill, "tags-autofill", 0, "Add suggested tags on create until an article has this many (0 disables)")
+
+	flag.IntVar(&cfg.summary.sentences, "summary-sentences", 0, "Store a summary of this many sentences on each article (0 disables)")
+
+	flag.StringVar(&cfg.html.policy, "html-policy", htmlPolicyStrip, "Policy for disallowed HTML in article bodies (strip|reject)")
+	cfg.html.elements = markup.DefaultElements
+	flag.Func("html-elements", "Comma-separated HTML elements allowed in article bodies (default "+strings.Join(markup.DefaultElements, ",")+")", func(s string) error {
+		cfg.html.elements = splitList(s)
+		return nil
+	})
+	flag.Func("html-internal-hosts", "Comma-separated hosts of this site, whose links are not treated as external", func(s string) error {
+		cfg.html.internalHosts = splitList(s)
+		return nil
+	})
+
+	flag.StringVar(&cfg.baseURL, "base-url", "", "Base URL for absolute links, e.g. https://example.com (default: the URL requested)")
+
+	flag.StringVar(&cfg.feed.title, "feed-title", "Articles", "Title of the site's feeds")
+	flag.IntVar(&cfg.feed.items, "feed-items", 20, "Number of articles in each feed")
+
+	flag.StringVar(&cfg.sync.dir, "sync-dir", "", "Directory of Markdown articles with front matter to keep the store in step with")
+	flag.DurationVar(&cfg.sync.interval, "sync-interval", 30*time.Second, "How often to check the sync directory for changes")
+	flag.BoolVar(&cfg.sync.delete, "sync-delete", false, "Delete stored articles that have no file in the sync directory")
+
+	flag.IntVar(&cfg.batch.maxItems, "batch-max-items", 100, "Maximum number of articles in a batch create, or IDs in a batch get")
+	flag.Int64Var(&cfg.batch.maxBytes, "batch-max-bytes", 10<<20, "Maximum size in bytes of a batch create request body")
+
+	flag.IntVar(&cfg.site.pageSize, "site-page-size", 20, "Number of articles on each index page of the static site")
+
+	flag.IntVar(&cfg.graphql.maxDepth, "graphql-max-depth", 10, "Maximum depth of GraphQL queries")
+	flag.IntVar(&cfg.graphql.maxComplexity, "graphql-max-complexity", 1000, "Maximum complexity of GraphQL queries")
+
+	flag.BoolVar(&cfg.validateRequests, "validate-requests", false, "Validate request parameters and JSON bodies against the OpenAPI document")
+
+	flag.StringVar(&cfg.admin.token, "admin-token", "", "Bearer token required by the /v1/admin endpoints (default: the endpoints are disabled)")
+
+	// The default flag set exits on errors.
+	_ = flag.CommandLine.Parse(args)
+
+	return command, flag.Args()
+}
+
+// validateConfig checks that the configuration values are usable.
+func validateConfig(cfg config) error {
+	switch cfg.dedupe.policy {
+	case dedupePolicyAccept, dedupePolicyWarn, dedupePolicyReject:
+	default:
+		return fmt.Errorf("invalid dedupe policy %q", cfg.dedupe.policy)
+	}
+
+	if cfg.dedupe.threshold <= 0 || cfg.dedupe.threshold > 1 {
+		return fmt.Errorf("dedupe threshold must be greater than 0 and at most 1")
+	}
+
+	if cfg.tags.autofill < 0 || cfg.tags.autofill > data.MaxArticleTags {
+		return fmt.Errorf("tags autofill must be between 0 and %d", data.MaxArticleTags)
+	}
+
+	if cfg.summary.sentences < 0 {
+		return fmt.Errorf("summary sentences must not be negative")
+	}
+
+	switch cfg.html.policy {
+	case htmlPolicyStrip, htmlPolicyReject:
+	default:
+		return fmt.Errorf("invalid html policy %q", cfg.html.policy)
+	}
+
+	for _, element := range cfg.html.elements {
+		if !markup.CanAllow(element) {
+			return fmt.Errorf("html element %q cannot be allowed", element)
+		}
+	}
+
+	if cfg.baseURL != "" {
+		u, err := url.Parse(cfg.baseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("base URL must be an absolute http or https URL")
+		}
+	}
+
+	if cfg.feed.items < 1 {
+		return fmt.Errorf("feed items must be at least 1")
+	}
+
+	if cfg.sync.interval <= 0 {
+		return fmt.Errorf("sync interval must be positive")
+	}
+
+	if cfg.batch.maxItems < 1 {
+		return fmt.Errorf("batch max items must be at least 1")
+	}
+
+	if cfg.batch.maxBytes < 1 {
+		return fmt.Errorf("batch max bytes must be at least 1")
+	}
+
+	if cfg.site.pageSize < 1 {
+		return fmt.Errorf("site page size must be at least 1")
+	}
+
+	if cfg.graphql.maxDepth < 1 {
+		return fmt.Errorf("graphql max depth must be at least 1")
+	}
+
+	if cfg.graphql.maxComplexity < 1 {
+		return fmt.Errorf("graphql max complexity must be at least 1")
+	}
+
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries and
+// surrounding whitespace.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
 
 func main() {
 	var cfg config
 
-	parseFlags(&cfg)
+	command, args := parseFlags(&cfg)
 
 	// Initialize a new structured logger which writes log entries to the standard
 	// out stream.
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
+	err := validateConfig(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	daos := data.NewDAOs()
+	daos.Articles.StoreSummaries(cfg.summary.sentences)
+
 	// Declare an instance of the application struct, containing the config struct
 	// and the logger.
 	app := &application{
-		config: cfg,
-		logger: logger,
-		daos:   data.NewDAOs(),
+		config:  cfg,
+		logger:  logger,
+		daos:    daos,
+		sitemap: sitemap.New(sitemap.MaxURLs),
+	}
+	app.schema = app.newGraphQLSchema()
+	app.rpc = app.newRPCServer()
+
+	app.generateSitemap()
+
+	// The store is held in memory, so imported articles are only served if the
+	// server is started by the same process.
+	if command == cmdImport {
+		err = app.runImport(os.Stdout, args)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		if !cfg.serve {
+			return
+		}
+	}
+
+	if command == cmdSync {
+		switch len(args) {
+		case 0:
+		case 1:
+			app.config.sync.dir = args[0]
+		default:
+			logger.Error("sync: only one directory can be synced")
+			os.Exit(1)
+		}
+		err = app.runSync(os.Stdout)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		if !cfg.serve || cfg.sync.dryRun {
+			return
+		}
+	}
+
+	// The store starts empty, so the site is rendered from the sync directory.
+	// When serving, it's rendered again after each sync that changes the store.
+	if command == cmdSite {
+		if len(args) != 1 {
+			logger.Error("site: give one output directory")
+			os.Exit(1)
+		}
+		app.config.site.dir = args[0]
+
+		if app.config.sync.dir != "" {
+			err = app.runSync(os.Stdout)
+			if err != nil {
+				logger.Error(err.Error())
+				os.Exit(1)
+			}
+		}
+		err = app.buildSite(os.Stdout)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		if !cfg.serve {
+			return
+		}
+	}
+
+	if app.config.sync.dir != "" {
+		app.watchSyncDir()
 	}
 
 	// Start the HTTP server.
-	err := app.serve()
+	err = app.serve()
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
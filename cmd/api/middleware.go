@@ -1,8 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/openapi"
+	"github.com/des-ant/2024-article-api/internal/validator"
+	"github.com/julienschmidt/httprouter"
 )
 
 // recoverPanic recovers from any panics that occur during the request lifecycle.
@@ -22,3 +31,137 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireAdmin refuses requests to next that don't carry the admin token as
+// a bearer token in the Authorization header. Without an admin token
+// configured the admin routes are disabled, and every request is refused.
+func (app *application) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.config.admin.token == "" {
+			app.adminDisabledResponse(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(app.config.admin.token)) != 1 {
+			app.invalidAdminTokenResponse(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// limitBody lets requests to next have bodies up to maxBytes long, instead of
+// the usual maxRequestBytes, for routes that accept larger payloads.
+func (app *application) limitBody(maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, app.contextSetBodyLimit(r, maxBytes))
+	}
+}
+
+// validateRequest checks requests to a route against the operation the
+// OpenAPI document describes it with, before they reach next. Path and query
+// parameters that break their schemas, and JSON bodies that do, are refused
+// with 422 Unprocessable Entity. Parameters are keyed by name in the
+// response, and members of the body by their JSON pointer, such as
+// "/tags/0", or "body" for the body as a whole. JSON bodies that can't be
+// decoded are refused with 400 Bad Request.
+//
+// Handlers still validate what they're sent, as the document can't describe
+// every rule, and bodies in other media types are left to them.
+func (app *application) validateRequest(method, routePath string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc, err := app.apiDocument()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		params := httprouter.ParamsFromContext(r.Context())
+		op := doc.Operation(method, openAPIPath(app.endpointPath(routePath, params)))
+		if op == nil || !validatesRequests(op) {
+			next(w, r)
+			return
+		}
+
+		v := validator.New()
+		qs := r.URL.Query()
+
+		for _, param := range op.Parameters {
+			param, err := doc.Parameter(param)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			// Empty query parameters are treated as missing, as handlers
+			// use their defaults for them.
+			raw := qs.Get(param.Name)
+			if param.In == "path" {
+				raw = params.ByName(param.Name)
+			}
+			if raw == "" {
+				v.Check(!param.Required, param.Name, "must be provided")
+				continue
+			}
+
+			for _, e := range doc.ValidateParameter(param, raw) {
+				v.AddError(param.Name, e.Message)
+			}
+		}
+
+		if schema := jsonBodySchema(op, r); schema != nil {
+			// Bodies over the route's limit are left for readJSON to refuse.
+			body, err := io.ReadAll(io.LimitReader(r.Body, app.contextGetBodyLimit(r)+1))
+			if err != nil {
+				app.badRequestResponse(w, r, bodyReadError(err))
+				return
+			}
+
+			var value any
+			decoded := r.WithContext(r.Context())
+			decoded.Body = io.NopCloser(bytes.NewReader(body))
+
+			err = app.readJSON(w, decoded, &value)
+			if err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+
+			for _, e := range doc.Validate(schema, value) {
+				key := e.Pointer
+				if key == "" {
+					key = "body"
+				}
+				v.AddError(key, e.Message)
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// jsonBodySchema returns the schema of the request's body, if the body is
+// JSON and the operation describes it.
+func jsonBodySchema(op *openapi.Operation, r *http.Request) *openapi.Schema {
+	if op.RequestBody == nil {
+		return nil
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return nil
+		}
+	}
+
+	return op.RequestBody.Content["application/json"].Schema
+}
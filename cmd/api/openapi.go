@@ -0,0 +1,779 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/epub"
+	"github.com/des-ant/2024-article-api/internal/feed"
+	"github.com/des-ant/2024-article-api/internal/graphql"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/openapi"
+	"github.com/des-ant/2024-article-api/internal/text"
+)
+
+// openAPIHandler serves the OpenAPI document describing the API.
+func (app *application) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	doc, err := app.apiDocument()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, doc, nil, app.prettyJSON(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// routeParam matches the httprouter parameters in a path.
+var routeParam = regexp.MustCompile(`[:*](\w+)`)
+
+// openAPIPath converts an httprouter path, such as /v1/articles/:id, to an
+// OpenAPI path template, such as /v1/articles/{id}.
+func openAPIPath(path string) string {
+	return routeParam.ReplaceAllString(path, "{$1}")
+}
+
+// operationKey identifies the operation documenting a route.
+func operationKey(method, path string) string {
+	return method + " " + path
+}
+
+// openAPIDocument describes every endpoint with the operation documenting it
+// in apiOperations. It returns an error naming any endpoint that isn't
+// documented, or operation that doesn't document an endpoint.
+func (app *application) openAPIDocument() (*openapi.Document, error) {
+	g := newSchemaGenerator()
+	operations := app.apiOperations(g)
+
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title: "Article API",
+			Description: "Stores articles and summarises them by tag. Responses are described as JSON, but " +
+				"the Accept header or the format query parameter can ask for XML, YAML, NDJSON or MessagePack instead.",
+			Version: version,
+		},
+		Paths: make(map[string]*openapi.PathItem),
+		Components: openapi.Components{
+			Schemas:    g.Schemas,
+			Parameters: responseParameters(),
+			Responses:  errorResponses(),
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				adminSecurityScheme: {Type: "http", Scheme: "bearer", Description: "The token set with -admin-token."},
+			},
+		},
+	}
+
+	var undocumented []string
+	for _, endpoint := range app.endpoints() {
+		key := operationKey(endpoint.method, endpoint.path)
+
+		op, ok := operations[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			continue
+		}
+		delete(operations, key)
+
+		path := openAPIPath(endpoint.path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &openapi.PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(endpoint.method)] = op
+
+		if app.config.validateRequests && validatesRequests(op) {
+			documentValidation(op)
+		}
+		if endpoint.admin {
+			documentAdmin(op)
+		}
+	}
+
+	if len(undocumented) > 0 {
+		return nil, fmt.Errorf("openapi: routes without an operation: %s", strings.Join(undocumented, ", "))
+	}
+	if len(operations) > 0 {
+		return nil, fmt.Errorf("openapi: operations without a route: %s", strings.Join(slices.Sorted(maps.Keys(operations)), ", "))
+	}
+
+	return doc, nil
+}
+
+// unvalidatedOperations are the operations whose protocols have their own
+// error responses, which request validation would get in the way of.
+var unvalidatedOperations = []string{"graphql", "rpc"}
+
+// validatesRequests reports whether requests for an operation are validated
+// against it, when request validation is on.
+func validatesRequests(op *openapi.Operation) bool {
+	return !slices.Contains(unvalidatedOperations, op.OperationID)
+}
+
+// documentValidation adds the responses request validation may send to an
+// operation: 422 for parameters or JSON bodies that break their schemas, and
+// 400 for JSON bodies that can't be decoded.
+func documentValidation(op *openapi.Operation) {
+	hasJSONBody := op.RequestBody != nil && op.RequestBody.Content["application/json"].Schema != nil
+
+	if _, ok := op.Responses["422"]; !ok && (len(op.Parameters) > 0 || hasJSONBody) {
+		op.Responses["422"] = errorRef("FailedValidation")
+	}
+	if _, ok := op.Responses["400"]; !ok && hasJSONBody {
+		op.Responses["400"] = errorRef("BadRequest")
+	}
+}
+
+// adminSecurityScheme names the security scheme of the admin operations.
+const adminSecurityScheme = "adminToken"
+
+// documentAdmin marks an operation as needing the admin token, and adds the
+// responses to requests without it.
+func documentAdmin(op *openapi.Operation) {
+	op.Security = []map[string][]string{{adminSecurityScheme: {}}}
+	op.Responses["401"] = errorRef("Unauthorized")
+	op.Responses["403"] = errorRef("AdminDisabled")
+}
+
+// newSchemaGenerator returns a generator of schemas for the API's types.
+func newSchemaGenerator() *openapi.Generator {
+	g := openapi.NewGenerator()
+	g.Define(data.ArticleDate{}, &openapi.Schema{Type: "string", Format: "date"})
+	g.Name(graphql.Request{}, "GraphQLRequest")
+	g.Name(graphql.Error{}, "GraphQLError")
+	g.Name(graphql.Location{}, "GraphQLLocation")
+	return g
+}
+
+// articleFieldsSchema describes the fields of an article sent to be created,
+// without the rules ValidateArticle checks them against.
+func articleFieldsSchema() *openapi.Schema {
+	return &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"id":    {Type: "integer", Format: "int64"},
+			"title": {Type: "string"},
+			"date":  {Type: "string", Format: "date"},
+			"body":  {Type: "string"},
+			"tags":  {Type: "array", Items: &openapi.Schema{Type: "string"}},
+		},
+	}
+}
+
+// articleInputSchema describes an article sent to be created, with the rules
+// ValidateArticle checks it against.
+func (app *application) articleInputSchema() *openapi.Schema {
+	s := articleFieldsSchema()
+	s.Description = "An article to create. Tags may be added by autofill before the article is validated."
+	s.Required = []string{"body", "date", "id", "tags", "title"}
+
+	props := s.Properties
+	props["id"].Minimum = openapi.Ptr(1.0)
+	props["title"].Description = fmt.Sprintf("At most %d bytes long once encoded as UTF-8.", data.MaxArticleTitleBytes)
+	props["title"].MinLength = openapi.Ptr(1)
+	props["title"].MaxLength = openapi.Ptr(data.MaxArticleTitleBytes)
+	props["body"].Description = "Markdown, which may contain a subset of HTML. Disallowed HTML is removed, or refused under the reject HTML policy."
+	props["body"].MinLength = openapi.Ptr(1)
+	props["tags"].MaxItems = openapi.Ptr(data.MaxArticleTags)
+	props["tags"].UniqueItems = true
+
+	// Autofill tops up articles sent with too few tags, even an empty list.
+	if app.config.tags.autofill == 0 {
+		props["tags"].MinItems = openapi.Ptr(1)
+	}
+
+	return s
+}
+
+// responseParameters returns the query parameters every response accepts,
+// defined once as components.
+func responseParameters() map[string]*openapi.Parameter {
+	formats := make([]any, 0, len(responseFormats))
+	for _, format := range responseFormats {
+		formats = append(formats, format.name)
+	}
+
+	return map[string]*openapi.Parameter{
+		"format": queryParam("format", "The response format, instead of the one negotiated from the Accept header.",
+			&openapi.Schema{Type: "string", Enum: formats}),
+		"pretty": queryParam("pretty", "Whether JSON responses are indented. Defaults to false.",
+			&openapi.Schema{Type: "boolean"}),
+		"fields": queryParam("fields", "Comma-separated attributes to keep in the resources of the response, as dotted paths for nested objects.",
+			&openapi.Schema{Type: "string"}),
+		"expand": queryParam("expand", "Replace lists of article IDs with the articles.",
+			&openapi.Schema{Type: "string", Enum: []any{"articles"}}),
+	}
+}
+
+// withResponseParameters appends references to the response parameters.
+func withResponseParameters(params ...*openapi.Parameter) []*openapi.Parameter {
+	for _, name := range []string{"format", "pretty", "fields", "expand"} {
+		params = append(params, &openapi.Parameter{Ref: "#/components/parameters/" + name})
+	}
+	return params
+}
+
+// errorResponses returns the error responses shared by operations, defined
+// once as components.
+func errorResponses() map[string]*openapi.Response {
+	message := openapi.Object(map[string]*openapi.Schema{"error": {Type: "string"}})
+
+	return map[string]*openapi.Response{
+		"BadRequest":    errorResponse("The request body or parameters couldn't be read.", message),
+		"NotFound":      errorResponse("The requested resource could not be found.", message),
+		"Unauthorized":  errorResponse("The request doesn't carry the admin token.", message),
+		"AdminDisabled": errorResponse("The admin endpoints are disabled, as no admin token is set.", message),
+		"ArticleConflict": errorResponse("An article with the ID is already stored, or the article is a near-duplicate of a stored one, which the near-duplicate policy refuses.",
+			openapi.Object(map[string]*openapi.Schema{
+				"error": {OneOf: []*openapi.Schema{
+					{Type: "string"},
+					openapi.Object(map[string]*openapi.Schema{
+						"message":     {Type: "string"},
+						"existing_id": {Type: "integer", Format: "int64"},
+						"similarity":  {Type: "number"},
+					}),
+				}},
+			})),
+		"NotAcceptable":        errorResponse("None of the acceptable media types can be produced.", message),
+		"UnsupportedMediaType": errorResponse("The request body is in an unsupported media type.", message),
+		"FailedValidation": errorResponse("The request failed validation.",
+			openapi.Object(map[string]*openapi.Schema{
+				"error": {
+					Type:                 "object",
+					Description:          "Messages keyed by the field or parameter they concern.",
+					AdditionalProperties: &openapi.Schema{Type: "string"},
+				},
+			})),
+		"ServerError": errorResponse("The server encountered a problem.", message),
+	}
+}
+
+// errorResponse describes an error response whose body has the schema s.
+func errorResponse(description string, s *openapi.Schema) *openapi.Response {
+	return &openapi.Response{Description: description, Content: openapi.Content(s, "application/json")}
+}
+
+// errorRef refers to one of the errorResponses.
+func errorRef(name string) *openapi.Response {
+	return &openapi.Response{Ref: "#/components/responses/" + name}
+}
+
+// envelopeResponse describes a JSON response enveloping the given properties.
+func envelopeResponse(description string, properties map[string]*openapi.Schema) *openapi.Response {
+	return &openapi.Response{Description: description, Content: openapi.Content(openapi.Object(properties), "application/json")}
+}
+
+// fileResponse describes a response that isn't JSON, in one of the given
+// media types.
+func fileResponse(description string, mediaTypes ...string) *openapi.Response {
+	return &openapi.Response{Description: description, Content: openapi.Content(nil, mediaTypes...)}
+}
+
+// responses adds the error responses every operation may send to a
+// response map, keyed by status code.
+func responses(rs map[string]*openapi.Response) map[string]*openapi.Response {
+	rs["500"] = errorRef("ServerError")
+	return rs
+}
+
+func queryParam(name, description string, s *openapi.Schema) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+func pathParam(name, description string, s *openapi.Schema) *openapi.Parameter {
+	return &openapi.Parameter{Name: name, In: "path", Description: description, Required: true, Schema: s}
+}
+
+// languageParam describes the language filter, listing the languages
+// articles can be detected to be in.
+func languageParam() *openapi.Parameter {
+	var languages []any
+	for _, code := range text.Languages() {
+		languages = append(languages, code)
+	}
+
+	return queryParam("language", "Only list articles detected to be in this language, given as an ISO 639-1 code.",
+		&openapi.Schema{Type: "string", Enum: languages})
+}
+
+// jsonBody describes a request body sent as JSON.
+func jsonBody(s *openapi.Schema) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: openapi.Content(s, "application/json")}
+}
+
+// intRange returns the schema of an integer between minimum and maximum,
+// defaulting to def.
+func intRange(minimum, maximum, def int) *openapi.Schema {
+	return &openapi.Schema{
+		Type:    "integer",
+		Minimum: openapi.Ptr(float64(minimum)),
+		Maximum: openapi.Ptr(float64(maximum)),
+		Default: def,
+	}
+}
+
+// apiOperations documents the operation of every endpoint, keyed by method
+// and router path. Schemas of the Go types in requests and responses are
+// generated by g.
+func (app *application) apiOperations(g *openapi.Generator) map[string]*openapi.Operation {
+	article := g.Schema(data.Article{})
+	articleInput := app.articleInputSchema()
+	articleIDs := &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "integer", Format: "int64"}}
+
+	idParam := pathParam("id", "The article ID.", &openapi.Schema{Type: "integer", Format: "int64", Minimum: openapi.Ptr(1.0)})
+	tagParam := pathParam("tagName", "The tag.", &openapi.Schema{Type: "string"})
+	includeParam := queryParam("include", "Comma-separated optional article fields to include.",
+		&openapi.Schema{Type: "string", Enum: []any{"metrics"}})
+	dateParam := func(name, description string) *openapi.Parameter {
+		return queryParam(name, description, &openapi.Schema{Type: "string", Format: "date"})
+	}
+	nonNegative := func(name, description string) *openapi.Parameter {
+		return queryParam(name, description, &openapi.Schema{Type: "integer", Minimum: openapi.Ptr(0.0)})
+	}
+
+	feedResponses := func(mediaType string) map[string]*openapi.Response {
+		return responses(map[string]*openapi.Response{
+			"200": fileResponse("The feed, with ETag and Last-Modified headers.", mediaType),
+			"304": {Description: "The feed hasn't changed since the conditional request's validators."},
+		})
+	}
+	feedOperation := func(id, summary, mediaType string) *openapi.Operation {
+		return &openapi.Operation{OperationID: id, Summary: summary, Tags: []string{"feeds"}, Responses: feedResponses(mediaType)}
+	}
+	tagFeedOperation := func(id, summary, mediaType string) *openapi.Operation {
+		rs := feedResponses(mediaType)
+		rs["404"] = errorRef("NotFound")
+		return &openapi.Operation{OperationID: id, Summary: summary, Tags: []string{"feeds"}, Parameters: []*openapi.Parameter{tagParam}, Responses: rs}
+	}
+
+	batchResponse := envelopeResponse("The batch report, with a result for each article in the order they were sent.",
+		map[string]*openapi.Schema{"batch": g.Schema(batchReport{})})
+	bulkImportResponse := envelopeResponse("The import report.", map[string]*openapi.Schema{"import": g.Schema(bulkImportReport{})})
+
+	graphQLResponse := &openapi.Schema{
+		Type:        "object",
+		Description: "A GraphQL response: data is left out when the request couldn't be executed.",
+		Properties: map[string]*openapi.Schema{
+			"data":   {Description: "The data selected by the query."},
+			"errors": {Type: "array", Items: g.Schema(graphql.Error{})},
+		},
+	}
+
+	rpcRequest := openapi.Object(map[string]*openapi.Schema{
+		"jsonrpc": {Type: "string", Enum: []any{"2.0"}},
+		"method":  {Type: "string", Enum: []any{"articles.create", "articles.get", "articles.getMany", "articles.list", "tags.summary", "tags.list"}},
+		"params":  {Type: []string{"object", "array"}},
+		"id":      {Type: []string{"string", "number", "null"}, Description: "Left out for notifications."},
+	})
+	rpcRequest.Required = []string{"jsonrpc", "method"}
+	rpcResponse := &openapi.Schema{
+		Type:     "object",
+		Required: []string{"jsonrpc", "id"},
+		Properties: map[string]*openapi.Schema{
+			"jsonrpc": {Type: "string", Enum: []any{"2.0"}},
+			"result":  {},
+			"error": openapi.Object(map[string]*openapi.Schema{
+				"code":    {Type: "integer"},
+				"message": {Type: "string"},
+				"data":    {},
+			}),
+			"id": {Type: []string{"string", "number", "null"}},
+		},
+	}
+	rpcResponse.Properties["error"].Required = []string{"code", "message"}
+
+	return map[string]*openapi.Operation{
+		operationKey(http.MethodGet, "/v1/healthcheck"): {
+			OperationID: "healthcheck",
+			Summary:     "Report the status of the API",
+			Tags:        []string{"meta"},
+			Parameters:  withResponseParameters(),
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("The API is available.", map[string]*openapi.Schema{
+					"status": {Type: "string", Enum: []any{"available"}},
+					"system_info": openapi.Object(map[string]*openapi.Schema{
+						"environment": {Type: "string"},
+						"version":     {Type: "string"},
+					}),
+				}),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/articles"): {
+			OperationID: "listArticles",
+			Summary:     "List articles",
+			Description: "Lists a page of articles ordered by ID, filtered by their content metrics. With ids, lists the articles with those IDs instead, ignoring the other parameters apart from include.",
+			Tags:        []string{"articles"},
+			Parameters: withResponseParameters(
+				queryParam("ids", fmt.Sprintf("Comma-separated IDs of up to %d articles to get.", app.config.batch.maxItems), &openapi.Schema{Type: "string"}),
+				languageParam(),
+				nonNegative("min_word_count", "Only list articles with at least this many words."),
+				nonNegative("max_word_count", "Only list articles with at most this many words."),
+				nonNegative("max_reading_time", "Only list articles taking at most this many minutes to read."),
+				queryParam("min_reading_ease", "Only list articles with at least this Flesch reading-ease score.", &openapi.Schema{Type: "number"}),
+				queryParam("max_reading_ease", "Only list articles with at most this Flesch reading-ease score.", &openapi.Schema{Type: "number"}),
+				queryParam("page", "The page to list.", intRange(1, 10_000_000, 1)),
+				queryParam("page_size", "The number of articles on a page.", intRange(1, 100, 20)),
+				includeParam,
+			),
+			Responses: responses(map[string]*openapi.Response{
+				"200": {
+					Description: "The articles, with pagination metadata or, for ids, the IDs of missing articles.",
+					Content: openapi.Content(&openapi.Schema{
+						Type:     "object",
+						Required: []string{"articles"},
+						Properties: map[string]*openapi.Schema{
+							"articles":    {Type: "array", Items: article},
+							"metadata":    g.Schema(data.Metadata{}),
+							"missing_ids": articleIDs,
+						},
+					}, "application/json"),
+				},
+				"406": errorRef("NotAcceptable"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodPost, "/v1/articles"): {
+			OperationID: "createArticle",
+			Summary:     "Create an article",
+			Tags:        []string{"articles"},
+			Parameters:  withResponseParameters(includeParam),
+			RequestBody: &openapi.RequestBody{
+				Required: true,
+				Content:  openapi.Content(articleInput, supportedRequestMediaTypes...),
+			},
+			Responses: responses(map[string]*openapi.Response{
+				"201": {
+					Description: "The article was created. Near-duplicates are listed under the warn near-duplicate policy.",
+					Content: openapi.Content(&openapi.Schema{
+						Type:     "object",
+						Required: []string{"article"},
+						Properties: map[string]*openapi.Schema{
+							"article":         article,
+							"near_duplicates": {Type: "array", Items: g.Schema(data.NearDuplicate{})},
+							"suggested_tags":  {Type: "array", Items: &openapi.Schema{Type: "string"}},
+							"sanitized":       {Type: "array", Items: g.Schema(markup.Change{})},
+						},
+					}, "application/json"),
+				},
+				"400": errorRef("BadRequest"),
+				"409": errorRef("ArticleConflict"),
+				"415": errorRef("UnsupportedMediaType"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodPost, "/v1/articles/batch"): {
+			OperationID: "createArticles",
+			Summary:     "Create several articles",
+			Description: "Each article is validated as a single create would be, and reported on with the status that would have had.",
+			Tags:        []string{"articles"},
+			Parameters: withResponseParameters(
+				queryParam("mode", "Whether one article failing stops the others being created (atomic) or not (partial).",
+					&openapi.Schema{Type: "string", Enum: []any{batchAtomic, batchPartial}, Default: batchAtomic}),
+			),
+			RequestBody: jsonBody(openapi.Object(map[string]*openapi.Schema{
+				"articles": {
+					Type:        "array",
+					Description: "The articles to create, as createArticle takes them. Each is checked against the rules on its own, and any errors reported in the batch report.",
+					Items:       articleFieldsSchema(),
+					MinItems:    openapi.Ptr(1),
+					MaxItems:    openapi.Ptr(app.config.batch.maxItems),
+				},
+			})),
+			Responses: responses(map[string]*openapi.Response{
+				"201": batchResponse,
+				"207": batchResponse,
+				"400": errorRef("BadRequest"),
+				"409": batchResponse,
+				"415": errorRef("UnsupportedMediaType"),
+				"422": batchResponse,
+			}),
+		},
+
+		operationKey(http.MethodPost, "/v1/articles/batch-get"): {
+			OperationID: "getArticles",
+			Summary:     "Get several articles by ID",
+			Tags:        []string{"articles"},
+			Parameters:  withResponseParameters(includeParam),
+			RequestBody: jsonBody(openapi.Object(map[string]*openapi.Schema{
+				"ids": {
+					Type:     "array",
+					Items:    &openapi.Schema{Type: "integer", Format: "int64", Minimum: openapi.Ptr(1.0)},
+					MinItems: openapi.Ptr(1),
+					MaxItems: openapi.Ptr(app.config.batch.maxItems),
+				},
+			})),
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("The articles, in the order of their IDs, and the IDs of missing articles.", map[string]*openapi.Schema{
+					"articles":    {Type: "array", Items: article},
+					"missing_ids": articleIDs,
+				}),
+				"400": errorRef("BadRequest"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodPost, "/v1/articles/suggest-tags"): {
+			OperationID: "suggestTags",
+			Summary:     "Suggest tags for an article",
+			Tags:        []string{"articles"},
+			Parameters:  withResponseParameters(),
+			RequestBody: &openapi.RequestBody{
+				Required: true,
+				Content: openapi.Content(&openapi.Schema{
+					Type:        "object",
+					Description: "A title or body must be provided.",
+					Properties: map[string]*openapi.Schema{
+						"title": {Type: "string"},
+						"body":  {Type: "string"},
+						"tags":  {Type: "array", Description: "Tags not to suggest.", Items: &openapi.Schema{Type: "string"}},
+						"limit": intRange(1, data.MaxArticleTags, 5),
+					},
+				}, supportedRequestMediaTypes...),
+			},
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("Tags ranked by how well they fit.", map[string]*openapi.Schema{
+					"suggestions": {Type: "array", Items: g.Schema(text.TagSuggestion{})},
+				}),
+				"400": errorRef("BadRequest"),
+				"415": errorRef("UnsupportedMediaType"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/articles/:id"): {
+			OperationID: "showArticle",
+			Summary:     "Get an article",
+			Tags:        []string{"articles"},
+			Parameters: withResponseParameters(
+				idParam,
+				includeParam,
+				queryParam("body_format", "How the body is rendered: as stored, as sanitized HTML or as plain text.",
+					&openapi.Schema{Type: "string", Enum: []any{"raw", "html", "text"}, Default: "raw"}),
+			),
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("The article.", map[string]*openapi.Schema{"article": article}),
+				"404": errorRef("NotFound"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/articles/:id/summary"): {
+			OperationID: "showArticleSummary",
+			Summary:     "Summarise an article",
+			Tags:        []string{"articles"},
+			Parameters: withResponseParameters(
+				idParam,
+				queryParam("sentences", "The number of sentences in the summary.", intRange(1, maxSummarySentences, 3)),
+			),
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("An extractive summary of the article body.", map[string]*openapi.Schema{"summary": g.Schema(data.ArticleSummary{})}),
+				"404": errorRef("NotFound"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/articles/:id/keywords"): {
+			OperationID: "showArticleKeywords",
+			Summary:     "Extract an article's keywords",
+			Tags:        []string{"articles"},
+			Parameters: withResponseParameters(
+				idParam,
+				queryParam("limit", "The number of keyphrases.", intRange(1, 50, 10)),
+			),
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("The article's keyphrases and entity candidates.", map[string]*openapi.Schema{"keywords": g.Schema(data.ArticleKeywords{})}),
+				"404": errorRef("NotFound"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/tags/:tagName/:date"): {
+			OperationID: "showTagSummary",
+			Summary:     "Summarise the articles with a tag on a date",
+			Tags:        []string{"tags"},
+			Parameters: withResponseParameters(
+				tagParam,
+				pathParam("date", "The date, as YYYYMMDD.", &openapi.Schema{Type: "string", Pattern: `^\d{8}$`}),
+			),
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("The tag summary.", map[string]*openapi.Schema{"tag_summary": g.Schema(data.TagSummary{})}),
+				"404": errorRef("NotFound"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/tags/:tagName/feed.atom"): tagFeedOperation("tagFeedAtom", "Atom feed of the newest articles with a tag", feed.AtomMediaType),
+		operationKey(http.MethodGet, "/v1/tags/:tagName/feed.rss"):  tagFeedOperation("tagFeedRSS", "RSS feed of the newest articles with a tag", feed.RSSMediaType),
+		operationKey(http.MethodGet, "/v1/tags/:tagName/feed.json"): tagFeedOperation("tagFeedJSON", "JSON Feed of the newest articles with a tag", feed.JSONFeedMediaType),
+
+		operationKey(http.MethodGet, "/v1/tags/:tagName/export.epub"): {
+			OperationID: "exportTagEPUB",
+			Summary:     "Export a tag's articles as an EPUB e-book",
+			Tags:        []string{"tags"},
+			Parameters: []*openapi.Parameter{
+				tagParam,
+				dateParam("from", "Only include articles dated on or after this date."),
+				dateParam("to", "Only include articles dated on or before this date."),
+			},
+			Responses: responses(map[string]*openapi.Response{
+				"200": fileResponse("The e-book, with ETag and Last-Modified headers.", epub.MediaType),
+				"304": {Description: "The e-book hasn't changed since the conditional request's validators."},
+				"404": errorRef("NotFound"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/feeds/all.atom"): feedOperation("siteFeedAtom", "Atom feed of the newest articles", feed.AtomMediaType),
+		operationKey(http.MethodGet, "/v1/feeds/all.rss"):  feedOperation("siteFeedRSS", "RSS feed of the newest articles", feed.RSSMediaType),
+		operationKey(http.MethodGet, "/v1/feeds/all.json"): feedOperation("siteFeedJSON", "JSON Feed of the newest articles", feed.JSONFeedMediaType),
+
+		operationKey(http.MethodGet, "/v1/sitemap.xml"): {
+			OperationID: "sitemap",
+			Summary:     "Sitemap of every article, or a sitemap index once there are too many for one file",
+			Tags:        []string{"feeds"},
+			Responses: responses(map[string]*openapi.Response{
+				"200": fileResponse("The sitemap or sitemap index.", "application/xml"),
+				"304": {Description: "The sitemap hasn't changed since the conditional request's validators."},
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/sitemaps/:file"): {
+			OperationID: "sitemapFile",
+			Summary:     "A sitemap file listed in the sitemap index",
+			Tags:        []string{"feeds"},
+			Parameters:  []*openapi.Parameter{pathParam("file", "The number of the file, from 1, followed by .xml.", &openapi.Schema{Type: "string"})},
+			Responses: responses(map[string]*openapi.Response{
+				"200": fileResponse("The sitemap file.", "application/xml"),
+				"304": {Description: "The sitemap hasn't changed since the conditional request's validators."},
+				"404": errorRef("NotFound"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/admin/export"): {
+			OperationID: "exportArticles",
+			Summary:     "Export every article",
+			Tags:        []string{"admin"},
+			Parameters: []*openapi.Parameter{
+				queryParam("format", "The export format, instead of the one negotiated from the Accept header.",
+					&openapi.Schema{Type: "string", Enum: []any{"ndjson", "csv"}, Default: "ndjson"}),
+			},
+			Responses: responses(map[string]*openapi.Response{
+				"200": fileResponse("Every article, ordered by ID.", exportMediaTypes()...),
+				"406": errorRef("NotAcceptable"),
+			}),
+		},
+
+		operationKey(http.MethodPost, "/v1/admin/import"): {
+			OperationID: "importArticles",
+			Summary:     "Import articles from an export",
+			Tags:        []string{"admin"},
+			Parameters: withResponseParameters(
+				queryParam("on_conflict", "What to do with articles whose IDs are already stored.",
+					&openapi.Schema{Type: "string", Enum: []any{conflictFail, conflictSkip, conflictUpsert}, Default: conflictFail}),
+			),
+			RequestBody: &openapi.RequestBody{Required: true, Content: openapi.Content(nil, exportMediaTypes()...)},
+			Responses: responses(map[string]*openapi.Response{
+				"200": bulkImportResponse,
+				"400": bulkImportResponse,
+				"409": bulkImportResponse,
+				"415": errorRef("UnsupportedMediaType"),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodPost, "/v1/admin/import/feed"): {
+			OperationID: "importFeed",
+			Summary:     "Import articles from an RSS, Atom or WordPress export file",
+			Tags:        []string{"admin"},
+			Parameters:  withResponseParameters(),
+			RequestBody: &openapi.RequestBody{
+				Required: true,
+				Content: map[string]openapi.MediaType{
+					"application/rss+xml":  {},
+					"application/atom+xml": {},
+					"application/xml":      {},
+					"text/xml":             {},
+					"multipart/form-data": {Schema: openapi.Object(map[string]*openapi.Schema{
+						"file": {Type: "string", Format: "binary"},
+					})},
+				},
+			},
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("The import report, with a result for each item.", map[string]*openapi.Schema{"import": g.Schema(importReport{})}),
+				"400": errorRef("BadRequest"),
+				"415": errorRef("UnsupportedMediaType"),
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/duplicates"): {
+			OperationID: "listDuplicateClusters",
+			Summary:     "List clusters of near-duplicate articles",
+			Tags:        []string{"articles"},
+			Parameters: withResponseParameters(
+				queryParam("threshold", "The similarity at which articles count as near-duplicates.", &openapi.Schema{
+					Type:             "number",
+					ExclusiveMinimum: openapi.Ptr(0.0),
+					Maximum:          openapi.Ptr(1.0),
+					Default:          app.config.dedupe.threshold,
+				}),
+			),
+			Responses: responses(map[string]*openapi.Response{
+				"200": envelopeResponse("The clusters.", map[string]*openapi.Schema{
+					"duplicate_clusters": {Type: "array", Items: g.Schema(data.DuplicateCluster{})},
+				}),
+				"422": errorRef("FailedValidation"),
+			}),
+		},
+
+		operationKey(http.MethodPost, "/v1/graphql"): {
+			OperationID: "graphql",
+			Summary:     "Execute a GraphQL query",
+			Tags:        []string{"graphql"},
+			RequestBody: jsonBody(g.Schema(graphql.Request{})),
+			Responses: responses(map[string]*openapi.Response{
+				"200": {Description: "The result of the query, with any errors.", Content: openapi.Content(graphQLResponse, "application/json")},
+				"400": {Description: "The body isn't a GraphQL request.", Content: openapi.Content(graphQLResponse, "application/json")},
+				"415": {Description: "The body isn't JSON.", Content: openapi.Content(graphQLResponse, "application/json")},
+			}),
+		},
+
+		operationKey(http.MethodGet, "/v1/openapi.json"): {
+			OperationID: "openapi",
+			Summary:     "This OpenAPI document",
+			Tags:        []string{"meta"},
+			Responses: responses(map[string]*openapi.Response{
+				"200": fileResponse("The OpenAPI document.", "application/json"),
+			}),
+		},
+
+		operationKey(http.MethodPost, "/rpc"): {
+			OperationID: "rpc",
+			Summary:     "Make JSON-RPC 2.0 calls",
+			Description: fmt.Sprintf("A call, or a batch of up to %d calls.", app.config.batch.maxItems),
+			Tags:        []string{"rpc"},
+			RequestBody: jsonBody(&openapi.Schema{OneOf: []*openapi.Schema{
+				rpcRequest,
+				{Type: "array", Items: rpcRequest, MinItems: openapi.Ptr(1), MaxItems: openapi.Ptr(app.config.batch.maxItems)},
+			}}),
+			Responses: responses(map[string]*openapi.Response{
+				"200": {Description: "The responses to the calls that weren't notifications.", Content: openapi.Content(&openapi.Schema{OneOf: []*openapi.Schema{
+					rpcResponse,
+					{Type: "array", Items: rpcResponse},
+				}}, "application/json")},
+				"204": {Description: "Every call was a notification."},
+				"400": errorRef("BadRequest"),
+				"415": errorRef("UnsupportedMediaType"),
+			}),
+		},
+	}
+}
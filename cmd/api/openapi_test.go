@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, body := ts.get(t, "/v1/openapi.json")
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(body), &doc))
+	assert.Equal(t, "3.1.0", doc["openapi"])
+
+	t.Run("EveryRouteDocumented", func(t *testing.T) {
+		paths := doc["paths"].(map[string]any)
+		for _, endpoint := range app.endpoints() {
+			item, ok := paths[openAPIPath(endpoint.path)].(map[string]any)
+			if assert.True(t, ok, "%s is not documented", endpoint.path) {
+				assert.Contains(t, item, strings.ToLower(endpoint.method), "%s %s is not documented", endpoint.method, endpoint.path)
+			}
+		}
+
+		for _, path := range []string{"/v1/tags/{tagName}/export.epub", "/v1/tags/{tagName}/feed.rss", "/v1/articles/batch", "/v1/articles/batch-get", "/v1/graphql", "/rpc"} {
+			assert.Contains(t, paths, path)
+		}
+	})
+
+	t.Run("UndocumentedRoute", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.routes()
+		app.registered = append(app.registered, route{method: http.MethodDelete, path: "/v1/articles/:id"})
+
+		_, err := app.openAPIDocument()
+		assert.ErrorContains(t, err, "routes without an operation: DELETE /v1/articles/:id")
+	})
+
+	t.Run("PathParameters", func(t *testing.T) {
+		for path, item := range doc["paths"].(map[string]any) {
+			templated := routeParam.FindAllStringSubmatch(strings.NewReplacer("{", ":", "}", "").Replace(path), -1)
+			for method, op := range item.(map[string]any) {
+				var params []string
+				parameters, _ := op.(map[string]any)["parameters"].([]any)
+				for _, param := range parameters {
+					if param.(map[string]any)["in"] == "path" {
+						params = append(params, param.(map[string]any)["name"].(string))
+					}
+				}
+				var expected []string
+				for _, match := range templated {
+					expected = append(expected, match[1])
+				}
+				assert.ElementsMatch(t, expected, params, "%s %s", method, path)
+			}
+		}
+	})
+
+	t.Run("ReferencesResolve", func(t *testing.T) {
+		var walk func(v any)
+		walk = func(v any) {
+			switch v := v.(type) {
+			case map[string]any:
+				if ref, ok := v["$ref"].(string); ok {
+					var target any = doc
+					for _, name := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+						target = target.(map[string]any)[name]
+					}
+					assert.NotNil(t, target, "%s does not resolve", ref)
+				}
+				for _, child := range v {
+					walk(child)
+				}
+			case []any:
+				for _, child := range v {
+					walk(child)
+				}
+			}
+		}
+		walk(doc)
+	})
+
+	t.Run("GoTypesAndValidationRules", func(t *testing.T) {
+		schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+
+		article := schemas["Article"].(map[string]any)
+		assert.Equal(t, []any{"body", "date", "id", "tags", "title"}, article["required"])
+		assert.Equal(t, map[string]any{"type": "string", "format": "date"}, article["properties"].(map[string]any)["date"])
+		assert.Equal(t, map[string]any{"$ref": "#/components/schemas/ArticleMetrics"}, article["properties"].(map[string]any)["metrics"])
+		assert.Contains(t, schemas, "TagSummary")
+
+		create := doc["paths"].(map[string]any)["/v1/articles"].(map[string]any)["post"].(map[string]any)
+		input := create["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+		properties := input["properties"].(map[string]any)
+		assert.Equal(t, float64(data.MaxArticleTags), properties["tags"].(map[string]any)["maxItems"])
+		assert.Equal(t, true, properties["tags"].(map[string]any)["uniqueItems"])
+		assert.Equal(t, float64(data.MaxArticleTitleBytes), properties["title"].(map[string]any)["maxLength"])
+		assert.Equal(t, float64(1), properties["id"].(map[string]any)["minimum"])
+	})
+}
+
+func TestRequestValidation(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.validateRequests = true
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "PathParamType",
+			method:         http.MethodGet,
+			path:           "/v1/articles/abc",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"id": "must be an integer"}}`,
+		},
+		{
+			name:           "PathParamMinimum",
+			method:         http.MethodGet,
+			path:           "/v1/articles/0/summary",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"id": "must be at least 1"}}`,
+		},
+		{
+			name:           "PathParamPattern",
+			method:         http.MethodGet,
+			path:           "/v1/tags/health/2016-09-22",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"date": "must match the pattern ^\\d{8}$"}}`,
+		},
+		{
+			name:           "QueryParams",
+			method:         http.MethodGet,
+			path:           "/v1/articles?page=0&page_size=ten&include=metrics,links&pretty=maybe",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"page": "must be at least 1", "page_size": "must be an integer", "include": "must be one of metrics", "pretty": "must be a boolean"}}`,
+		},
+		{
+			name:           "ExclusiveMinimum",
+			method:         http.MethodGet,
+			path:           "/v1/duplicates?threshold=0",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"threshold": "must be greater than 0"}}`,
+		},
+		{
+			name:           "BodyRules",
+			method:         http.MethodPost,
+			path:           "/v1/articles",
+			body:           `{"id": 0, "title": "", "date": "22-09-2016", "body": "Text", "tags": ["health", "health", 5]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `{"error": {
+				"/id": "must be at least 1",
+				"/title": "must not be empty",
+				"/date": "must be a date in the format YYYY-MM-DD",
+				"/tags": "must not contain duplicate values",
+				"/tags/2": "must be a string"
+			}}`,
+		},
+		{
+			name:           "BodyRequired",
+			method:         http.MethodPost,
+			path:           "/v1/articles",
+			body:           `{"title": "Title", "tags": []}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `{"error": {
+				"/id": "must be provided",
+				"/date": "must be provided",
+				"/body": "must be provided",
+				"/tags": "must contain at least 1 item"
+			}}`,
+		},
+		{
+			name:           "BodyType",
+			method:         http.MethodPost,
+			path:           "/v1/articles/batch-get",
+			body:           `[1, 2]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"body": "must be an object"}}`,
+		},
+		{
+			name:           "BodyItems",
+			method:         http.MethodPost,
+			path:           "/v1/articles/batch-get",
+			body:           `{"ids": [1, -2, 2.5]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"/ids/1": "must be at least 1", "/ids/2": "must be an integer"}}`,
+		},
+		{
+			name:           "MalformedBody",
+			method:         http.MethodPost,
+			path:           "/v1/articles",
+			body:           `{"id": 1,`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body contains badly-formed JSON"}`,
+		},
+		{
+			name:           "Valid",
+			method:         http.MethodGet,
+			path:           "/v1/articles/6?include=metrics&page=1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "TagResource",
+			method:         http.MethodGet,
+			path:           "/v1/tags/health/feed.json",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			var body string
+			if tt.method == http.MethodPost {
+				code, _, body = ts.post(t, tt.path, "application/json", strings.NewReader(tt.body))
+			} else {
+				code, _, body = ts.get(t, tt.path)
+			}
+
+			assert.Equal(t, tt.expectedStatus, code, body)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, body)
+			}
+		})
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		code, _, body := ts.postJSON(t, "/v1/articles", map[string]any{
+			"id": 100, "title": "Validated", "date": "2024-01-02", "body": "A body that passes validation.", "tags": []string{"validation"},
+		})
+		assert.Equal(t, http.StatusCreated, code, body)
+	})
+
+	// Articles in a batch are checked one at a time, so that a partial batch
+	// still reports on each of them.
+	t.Run("PartialBatch", func(t *testing.T) {
+		code, _, body := ts.postJSON(t, "/v1/articles/batch?mode=partial", map[string]any{
+			"articles": []map[string]any{
+				{"id": 101, "title": "Fine", "date": "2024-01-02", "body": "A body for the batch.", "tags": []string{"batch"}},
+				{"id": 0, "title": "", "date": "2024-01-02", "body": "Another body.", "tags": []string{}},
+			},
+		})
+		assert.Equal(t, http.StatusMultiStatus, code, body)
+	})
+
+	// Bodies are checked up to the route's own limit, not only the usual one.
+	t.Run("LargeBody", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.validateRequests = true
+		app.config.batch.maxItems = 2
+		app.config.batch.maxBytes = 2 * maxRequestBytes
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		article := func(id int) map[string]any {
+			return map[string]any{"id": id, "title": "Large", "date": "2024-01-02", "body": strings.Repeat("word ", maxRequestBytes/15), "tags": []string{"batch"}}
+		}
+
+		code, _, body := ts.postJSON(t, "/v1/articles/batch", map[string]any{
+			"articles": []map[string]any{article(1), article(2), article(3)},
+		})
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.Contains(t, body, `"/articles"`)
+
+		code, _, body = ts.postJSON(t, "/v1/articles/batch", map[string]any{
+			"articles": []map[string]any{article(1), article(2), article(3), article(4), article(5), article(6), article(7)},
+		})
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, body, "body must not be larger than")
+	})
+
+	// JSON-RPC answers invalid calls itself, in its own format.
+	t.Run("RPC", func(t *testing.T) {
+		code, _, body := ts.post(t, "/rpc", "application/json", strings.NewReader(`{"jsonrpc": "1.0", "method": "tags.list", "id": 1}`))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"code":-32600`)
+	})
+
+	t.Run("Documented", func(t *testing.T) {
+		code, _, body := ts.get(t, "/v1/openapi.json")
+		require.Equal(t, http.StatusOK, code)
+
+		var doc struct {
+			Paths map[string]map[string]struct {
+				Responses map[string]any `json:"responses"`
+			} `json:"paths"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &doc))
+		assert.Contains(t, doc.Paths["/v1/tags/{tagName}/{date}"]["get"].Responses, "422")
+		assert.Contains(t, doc.Paths["/v1/articles/batch-get"]["post"].Responses, "400")
+		assert.NotContains(t, doc.Paths["/v1/graphql"]["post"].Responses, "422")
+	})
+
+	t.Run("Autofill", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.validateRequests = true
+		app.config.tags.autofill = 2
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		// The handler refuses the article, as the store has no tags to top it
+		// up with, but validation lets it through.
+		code, _, body := ts.post(t, "/v1/articles", "application/json", strings.NewReader(`{"id": 1, "title": "Title", "date": "2024-01-02", "body": "Body", "tags": []}`))
+		assert.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.JSONEq(t, `{"error": {"tags": "must contain at least 1 tag"}}`, body)
+	})
+}
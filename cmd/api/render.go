@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/codec"
+)
+
+var errNotAcceptable = errors.New("no acceptable response format")
+
+// responseFormat describes a format responses can be rendered in.
+type responseFormat struct {
+	// name is the value of the "format" query parameter selecting this format.
+	name string
+	// mediaTypes are matched against the Accept header. The first one is sent
+	// as the Content-Type.
+	mediaTypes []string
+	// listsOnly marks formats that can only represent list responses.
+	listsOnly bool
+	// encode writes the response data in this format.
+	encode func(w io.Writer, data envelope) error
+}
+
+// responseFormats lists the supported formats in order of server preference,
+// which decides between formats a client accepts equally.
+var responseFormats = []responseFormat{
+	{
+		name:       "json",
+		mediaTypes: []string{"application/json"},
+	},
+	{
+		name:       "xml",
+		mediaTypes: []string{"application/xml", "text/xml"},
+		encode: func(w io.Writer, data envelope) error {
+			return codec.EncodeXML(w, "response", data)
+		},
+	},
+	{
+		name:       "yaml",
+		mediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"},
+		encode: func(w io.Writer, data envelope) error {
+			return codec.EncodeYAML(w, data)
+		},
+	},
+	{
+		name:       "ndjson",
+		mediaTypes: []string{"application/x-ndjson", "application/ndjson"},
+		encode:     encodeNDJSON,
+	},
+	{
+		name:       "msgpack",
+		mediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+		encode: func(w io.Writer, data envelope) error {
+			return codec.EncodeMsgpack(w, data)
+		},
+	},
+	{
+		name:       "csv",
+		mediaTypes: []string{"text/csv"},
+		listsOnly:  true,
+		encode: func(w io.Writer, data envelope) error {
+			list, _ := listPayload(data)
+			return codec.EncodeCSV(w, list)
+		},
+	},
+}
+
+// writeResponse writes data in the format negotiated from the request's
+// "format" query parameter or Accept header, shaped by its "fields" and
+// "expand" query parameters. If no supported format is acceptable, it sends a
+// 406 Not Acceptable response instead.
+func (app *application) writeResponse(w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header) error {
+	data, err := app.shapeResponse(r, data)
+	if err != nil {
+		return err
+	}
+
+	format, err := negotiateFormat(r, data)
+	if err != nil {
+		app.notAcceptableResponse(w, r, data)
+		return nil
+	}
+
+	return app.writeFormat(w, r, format, status, data, headers)
+}
+
+// writeFormat writes data to the http.ResponseWriter in the given format.
+// Long JSON lists are written in chunks; every other response is buffered so that
+// encoding errors can still be reported with a 500 response.
+func (app *application) writeFormat(w http.ResponseWriter, r *http.Request, format responseFormat, status int, data envelope, headers http.Header) error {
+	w.Header().Add("Vary", "Accept")
+
+	if format.encode == nil {
+		if shouldChunk(data) {
+			return app.writeChunkedJSON(w, r, status, data, headers, app.prettyJSON(r))
+		}
+		return app.writeJSON(w, status, data, headers, app.prettyJSON(r))
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	err := format.encode(buf, data)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("Content-Type", format.mediaTypes[0])
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	_, err = buf.WriteTo(w)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// negotiateFormat picks the response format for the request. An explicit
+// "format" query parameter wins over the Accept header, and requests without
+// either get JSON.
+func negotiateFormat(r *http.Request, data envelope) (responseFormat, error) {
+	_, isList := listPayload(data)
+	return negotiate(r, responseFormats, isList)
+}
+
+// negotiate picks the response format for the request from formats, the
+// first of which is the default. Formats that can only represent lists are
+// skipped unless isList is set.
+func negotiate(r *http.Request, formats []responseFormat, isList bool) (responseFormat, error) {
+	usable := func(f responseFormat) bool {
+		return !f.listsOnly || isList
+	}
+
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, f := range formats {
+			if f.name == name && usable(f) {
+				return f, nil
+			}
+		}
+		return responseFormat{}, errNotAcceptable
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formats[0], nil
+	}
+
+	for _, mediaRange := range parseAccept(accept) {
+		for _, f := range formats {
+			if !usable(f) {
+				continue
+			}
+			for _, mediaType := range f.mediaTypes {
+				if mediaRangeMatches(mediaRange, mediaType) {
+					return f, nil
+				}
+			}
+		}
+	}
+
+	return responseFormat{}, errNotAcceptable
+}
+
+// parseAccept parses an Accept header into media ranges ordered by quality,
+// then by specificity. Ranges with a quality of zero are dropped.
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		value       string
+		quality     float64
+		specificity int
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		specificity := 2
+		switch {
+		case mediaType == "*/*":
+			specificity = 0
+		case strings.HasSuffix(mediaType, "/*"):
+			specificity = 1
+		}
+
+		ranges = append(ranges, mediaRange{mediaType, quality, specificity})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].quality != ranges[j].quality {
+			return ranges[i].quality > ranges[j].quality
+		}
+		return ranges[i].specificity > ranges[j].specificity
+	})
+
+	values := make([]string, len(ranges))
+	for i, mr := range ranges {
+		values[i] = mr.value
+	}
+	return values
+}
+
+// mediaRangeMatches reports whether a media range from an Accept header,
+// possibly containing wildcards, matches a media type.
+func mediaRangeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	return rangeSubtype == "*" && rangeType == typ
+}
+
+// listPayload returns the list held by a list response: an envelope whose
+// only value, apart from pagination metadata, is a slice.
+func listPayload(data envelope) (any, bool) {
+	var list any
+	for key, value := range data {
+		if key == "metadata" {
+			continue
+		}
+		if list != nil {
+			return nil, false
+		}
+		list = value
+	}
+
+	if list == nil {
+		return nil, false
+	}
+
+	// Shaped responses hold normalised objects, which are slices too.
+	if _, ok := list.(codec.Object); ok {
+		return nil, false
+	}
+
+	kind := reflect.TypeOf(list).Kind()
+	return list, kind == reflect.Slice || kind == reflect.Array
+}
+
+// encodeNDJSON writes one JSON document per line: one per item for list
+// responses, otherwise a single line holding the whole envelope.
+func encodeNDJSON(w io.Writer, data envelope) error {
+	enc := json.NewEncoder(w)
+
+	list, ok := listPayload(data)
+	if !ok {
+		return enc.Encode(data)
+	}
+
+	items := reflect.ValueOf(list)
+	for i := range items.Len() {
+		err := enc.Encode(items.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// supportedMediaTypes lists the media types of every format usable for data.
+func supportedMediaTypes(data envelope) []string {
+	_, isList := listPayload(data)
+
+	var mediaTypes []string
+	for _, f := range responseFormats {
+		if !f.listsOnly || isList {
+			mediaTypes = append(mediaTypes, f.mediaTypes[0])
+		}
+	}
+	return mediaTypes
+}
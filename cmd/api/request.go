@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/des-ant/2024-article-api/internal/codec"
+)
+
+// maxRequestBytes limits the size of request bodies to prevent potential
+// denial-of-service attacks. Routes may set a different limit with limitBody.
+const maxRequestBytes = 1_048_576
+
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// supportedRequestMediaTypes lists the request body media types readBody accepts.
+var supportedRequestMediaTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"multipart/form-data",
+	"application/yaml",
+	"application/msgpack",
+}
+
+// readBody decodes the request body into dst, choosing a decoder from the
+// Content-Type header. Requests without a Content-Type are treated as JSON.
+// It returns errUnsupportedMediaType for any other media type.
+//
+// Every format is decoded with the same rules as JSON: keys must match the
+// JSON names of dst's fields, and unknown keys are rejected.
+func (app *application) readBody(w http.ResponseWriter, r *http.Request, dst any) error {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return app.readJSON(w, r, dst)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return errUnsupportedMediaType
+	}
+
+	switch mediaType {
+	case "application/json":
+		return app.readJSON(w, r, dst)
+	case "application/x-www-form-urlencoded":
+		return app.readForm(w, r, dst)
+	case "multipart/form-data":
+		return app.readMultipart(w, r, params["boundary"], dst)
+	case "application/yaml", "application/x-yaml", "text/yaml":
+		return app.readYAML(w, r, dst)
+	case "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+		return app.readMsgpack(w, r, dst)
+	}
+
+	return errUnsupportedMediaType
+}
+
+// readForm decodes a URL-encoded form body into dst. Repeat a key to supply
+// several values for a list field, e.g. "tags=health&tags=science".
+func (app *application) readForm(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, app.contextGetBodyLimit(r))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return bodyReadError(err)
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return errors.New("body contains badly-formed form data")
+	}
+
+	return decodeFormValues(values, nil, dst, "form")
+}
+
+// readMultipart decodes a multipart/form-data body into dst. Fields are read
+// like a URL-encoded form, except that the "body" field may instead be
+// uploaded as a plain text or Markdown file.
+func (app *application) readMultipart(w http.ResponseWriter, r *http.Request, boundary string, dst any) error {
+	if boundary == "" {
+		return errors.New("body contains badly-formed multipart data (missing boundary)")
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, app.contextGetBodyLimit(r))
+
+	values := make(url.Values)
+	files := make(map[string]string)
+
+	mr := multipart.NewReader(r.Body, boundary)
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return bodyReadError(err)
+			}
+			return errors.New("body contains badly-formed multipart data")
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return bodyReadError(err)
+		}
+
+		name := part.FormName()
+		if part.FileName() == "" {
+			values.Add(name, string(content))
+			continue
+		}
+
+		if name != "body" {
+			return fmt.Errorf("body contains unexpected file for field %q", name)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if !isTextUpload(mediaType) || !utf8.Valid(content) {
+			return fmt.Errorf("body file %q must be a UTF-8 text or Markdown file", part.FileName())
+		}
+
+		files[name] = string(content)
+	}
+
+	return decodeFormValues(values, files, dst, "form")
+}
+
+// readYAML decodes a YAML body into dst.
+func (app *application) readYAML(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, app.contextGetBodyLimit(r))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return bodyReadError(err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(body))
+
+	var doc yaml.Node
+	err = dec.Decode(&doc)
+	if err != nil {
+		switch {
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		default:
+			return fmt.Errorf("body contains badly-formed YAML (%s)", strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+	}
+
+	// Ensure the request body only contains a single YAML document.
+	var extra yaml.Node
+	err = dec.Decode(&extra)
+	if !errors.Is(err, io.EOF) {
+		return errors.New("body must only contain a single YAML document")
+	}
+
+	// A document of nothing but comments has no content.
+	if len(doc.Content) == 0 {
+		return errors.New("body must not be empty")
+	}
+
+	value, err := yamlValue(doc.Content[0])
+	if err != nil {
+		return err
+	}
+
+	return decodeValue(value, dst, "YAML")
+}
+
+// readMsgpack decodes a MessagePack body into dst.
+func (app *application) readMsgpack(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, app.contextGetBodyLimit(r))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return bodyReadError(err)
+	}
+
+	if len(body) == 0 {
+		return errors.New("body must not be empty")
+	}
+
+	value, err := codec.DecodeMsgpack(body)
+	if err != nil {
+		return fmt.Errorf("body contains badly-formed MessagePack (%s)", strings.TrimPrefix(err.Error(), codec.ErrMsgpackSyntax.Error()+": "))
+	}
+
+	return decodeValue(msgpackValue(value), dst, "MessagePack")
+}
+
+// msgpackValue converts binary data in a decoded MessagePack value to strings,
+// since clients commonly send text fields using the bin types.
+func msgpackValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case []any:
+		for i := range v {
+			v[i] = msgpackValue(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = msgpackValue(v[k])
+		}
+	}
+	return value
+}
+
+// yamlValue converts a YAML node into a value that encodes to equivalent JSON.
+// Scalars that YAML would read as timestamps are kept as strings so that
+// types such as data.ArticleDate can parse them themselves.
+//
+// Aliases are refused: an alias may refer to a node containing itself, or
+// expand a small body into a huge value, and articles have no need of them.
+func yamlValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return nil, fmt.Errorf("body contains badly-formed YAML (line %d: aliases are not supported)", node.Line)
+	case yaml.MappingNode:
+		obj := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("body contains badly-formed YAML (line %d: keys must be strings)", key.Line)
+			}
+			value, err := yamlValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			obj[key.Value] = value
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		list := make([]any, 0, len(node.Content))
+		for _, child := range node.Content {
+			value, err := yamlValue(child)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		err := node.Decode(&b)
+		return b, err
+	case "!!int", "!!float":
+		// Only plain decimal numbers are valid JSON numbers. Other YAML
+		// notations, such as hexadecimal, are passed on as strings.
+		if json.Valid([]byte(node.Value)) {
+			return json.Number(node.Value), nil
+		}
+		return node.Value, nil
+	}
+
+	return node.Value, nil
+}
+
+// decodeFormValues converts form values and uploaded text files into a value
+// shaped like dst and decodes it. Keys are matched to dst's JSON field names,
+// list fields collect every value for their key, and numeric and boolean
+// fields are parsed from their text.
+func decodeFormValues(values url.Values, files map[string]string, dst any, format string) error {
+	if len(values) == 0 && len(files) == 0 {
+		return errors.New("body must not be empty")
+	}
+
+	fields := make(map[string]codec.Field)
+	for _, f := range codec.Fields(reflect.TypeOf(dst).Elem()) {
+		fields[f.Name] = f
+	}
+
+	obj := make(map[string]any, len(values)+len(files))
+	for key, vals := range values {
+		if _, ok := files[key]; ok {
+			return fmt.Errorf("body contains multiple values for field %q", key)
+		}
+
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("body contains unknown key %q", key)
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+			list := make([]any, len(vals))
+			for i, v := range vals {
+				list[i] = formScalar(v, ft.Elem())
+			}
+			obj[key] = list
+			continue
+		}
+
+		if len(vals) > 1 {
+			return fmt.Errorf("body contains multiple values for field %q", key)
+		}
+		obj[key] = formScalar(vals[0], ft)
+	}
+
+	for key, content := range files {
+		if _, ok := fields[key]; !ok {
+			return fmt.Errorf("body contains unknown key %q", key)
+		}
+		obj[key] = content
+	}
+
+	return decodeValue(obj, dst, format)
+}
+
+// formScalar converts a single form value into a JSON-compatible value of the
+// kind expected by t. Values that cannot be parsed are passed through as
+// strings so that decoding reports a type error for the field.
+func formScalar(s string, t reflect.Type) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			return json.Number(s)
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+// decodeValue decodes a JSON-compatible value into dst, reporting errors in the
+// same way readJSON does but naming the original request format.
+func decodeValue(value any, dst any, format string) error {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(dst)
+	if err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var invalidUnmarshalError *json.InvalidUnmarshalError
+
+		switch {
+		case errors.As(err, &unmarshalTypeError):
+			if unmarshalTypeError.Field != "" {
+				return fmt.Errorf("body contains incorrect %s type for field %q", format, unmarshalTypeError.Field)
+			}
+			return fmt.Errorf("body contains incorrect %s type", format)
+
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("body contains unknown key %s", fieldName)
+
+		case errors.As(err, &invalidUnmarshalError):
+			panic(err)
+
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bodyReadError describes an error that occurred while reading a request body.
+func bodyReadError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+	}
+	return err
+}
+
+// isTextUpload reports whether an uploaded file's media type is acceptable
+// for an article body. Files sent without a media type are allowed.
+func isTextUpload(mediaType string) bool {
+	switch mediaType {
+	case "", "text/plain", "text/markdown", "text/x-markdown", "application/octet-stream":
+		return true
+	}
+	return false
+}
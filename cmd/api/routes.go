@@ -1,40 +1,159 @@
 package main
 
 import (
+	"maps"
 	"net/http"
 	"path"
+	"slices"
+	"sync"
 
 	"github.com/julienschmidt/httprouter"
 )
 
 const (
 	basePathV1 = "/v1"
+	// tagRoute is the route of a tag's summaries and resources, which
+	// tagHandler dispatches between.
+	tagRoute = "/tags/:tagName/:date"
 )
 
+// route is a method and path the router handles. Only administrators may
+// use admin routes. Requests to the route may have bodies up to maxBytes
+// long, or maxRequestBytes if it's 0.
+type route struct {
+	method   string
+	path     string
+	admin    bool
+	maxBytes int64
+}
+
 // routes sets up and returns the main router for the application.
 // It delegates the addition of specific API version routes to separate methods.
 func (app *application) routes() http.Handler {
 	router := httprouter.New()
+	app.registered = nil
+	// The document is only built once every route has been added.
+	app.apiDocument = sync.OnceValues(app.openAPIDocument)
 
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
 	app.addV1Routes(router)
 
+	// JSON-RPC calls name methods rather than resources, so the endpoint isn't
+	// versioned along with the REST API. Batches may be as large as batch
+	// creates.
+	app.handle(router, route{method: http.MethodPost, path: "/rpc", maxBytes: app.config.batch.maxBytes}, app.rpcHandler)
+
 	return app.recoverPanic(router)
 }
 
 // addV1Routes adds all routes for the v1 version of the API to the provided router.
 func (app *application) addV1Routes(router *httprouter.Router) {
 	app.addRoute(router, http.MethodGet, "/healthcheck", app.healthcheckHandler)
+	app.addRoute(router, http.MethodGet, "/articles", app.listArticlesHandler)
 	app.addRoute(router, http.MethodPost, "/articles", app.createArticleHandler)
+	app.handle(router, route{method: http.MethodPost, path: path.Join(basePathV1, "/articles/batch"), maxBytes: app.config.batch.maxBytes}, app.createArticlesBatchHandler)
+	app.addRoute(router, http.MethodPost, "/articles/batch-get", app.getArticlesBatchHandler)
+	app.addRoute(router, http.MethodPost, "/articles/suggest-tags", app.suggestTagsHandler)
 	app.addRoute(router, http.MethodGet, "/articles/:id", app.showArticleHandler)
-	app.addRoute(router, http.MethodGet, "/tags/:tagName/:date", app.getArticlesByTagAndDateHandler)
+	app.addRoute(router, http.MethodGet, "/articles/:id/summary", app.showArticleSummaryHandler)
+	app.addRoute(router, http.MethodGet, "/articles/:id/keywords", app.showArticleKeywordsHandler)
+	app.addRoute(router, http.MethodGet, tagRoute, app.tagHandler)
+	app.addRoute(router, http.MethodGet, "/feeds/all.atom", app.siteFeedHandler)
+	app.addRoute(router, http.MethodGet, "/feeds/all.rss", app.siteFeedHandler)
+	app.addRoute(router, http.MethodGet, "/feeds/all.json", app.siteFeedHandler)
+	app.addRoute(router, http.MethodGet, "/sitemap.xml", app.sitemapHandler)
+	app.addRoute(router, http.MethodGet, "/sitemaps/:file", app.sitemapFileHandler)
+	app.addAdminRoute(router, http.MethodGet, "/admin/export", app.adminExportHandler)
+	app.addAdminRoute(router, http.MethodPost, "/admin/import", app.adminImportHandler)
+	app.addAdminRoute(router, http.MethodPost, "/admin/import/feed", app.importFeedHandler)
+	app.addRoute(router, http.MethodGet, "/duplicates", app.listDuplicateClustersHandler)
+	app.addRoute(router, http.MethodPost, "/graphql", app.graphqlHandler)
+	app.addRoute(router, http.MethodGet, "/openapi.json", app.openAPIHandler)
+}
+
+// tagResources returns the handlers for resources nested under a tag, such as
+// its feeds, keyed by name. httprouter doesn't allow static path segments
+// beside the :date wildcard, so tagHandler dispatches to them instead.
+func (app *application) tagResources() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"feed.atom":   app.tagFeedHandler,
+		"feed.rss":    app.tagFeedHandler,
+		"feed.json":   app.tagFeedHandler,
+		"export.epub": app.tagEPUBHandler,
+	}
+}
+
+// tagHandler serves GET /v1/tags/:tagName/:date, where :date is either a date
+// or the name of one of the tag's resources.
+func (app *application) tagHandler(w http.ResponseWriter, r *http.Request) {
+	name := httprouter.ParamsFromContext(r.Context()).ByName("date")
+
+	if handler, ok := app.tagResources()[name]; ok {
+		handler(w, r)
+		return
+	}
+
+	app.getArticlesByTagAndDateHandler(w, r)
 }
 
 // addRoute is a helper method that adds a route to the router with the proper base path.
 // It joins the base path with the provided route using path.Join to ensure correct formatting.
-func (app *application) addRoute(router *httprouter.Router, method, route string, handler http.HandlerFunc) {
-	fullPath := path.Join(basePathV1, route)
-	router.HandlerFunc(method, fullPath, handler)
+func (app *application) addRoute(router *httprouter.Router, method, routePath string, handler http.HandlerFunc) {
+	app.handle(router, route{method: method, path: path.Join(basePathV1, routePath)}, handler)
+}
+
+// addAdminRoute adds a route only administrators may use, as addRoute does.
+func (app *application) addAdminRoute(router *httprouter.Router, method, routePath string, handler http.HandlerFunc) {
+	app.handle(router, route{method: method, path: path.Join(basePathV1, routePath), admin: true}, handler)
+}
+
+// handle adds a route to the router and records it, so that the OpenAPI
+// document can be checked against every route. Requests to the route are
+// validated against the document if the config says so, within the route's
+// body limit. Requests to admin routes must carry the admin token, which is
+// checked first.
+func (app *application) handle(router *httprouter.Router, rt route, handler http.HandlerFunc) {
+	app.registered = append(app.registered, rt)
+	if app.config.validateRequests {
+		handler = app.validateRequest(rt.method, rt.path, handler)
+	}
+	if rt.maxBytes > 0 {
+		handler = app.limitBody(rt.maxBytes, handler)
+	}
+	if rt.admin {
+		handler = app.requireAdmin(handler)
+	}
+	router.HandlerFunc(rt.method, rt.path, handler)
+}
+
+// endpoints returns every method and path clients can request: the
+// registered routes, plus a path for each of the resources tagHandler
+// dispatches to.
+func (app *application) endpoints() []route {
+	var endpoints []route
+	for _, rt := range app.registered {
+		endpoints = append(endpoints, rt)
+
+		if rt.path == path.Join(basePathV1, tagRoute) {
+			for _, name := range slices.Sorted(maps.Keys(app.tagResources())) {
+				endpoints = append(endpoints, route{method: rt.method, path: path.Join(path.Dir(rt.path), name)})
+			}
+		}
+	}
+	return endpoints
+}
+
+// endpointPath returns the path of the endpoint a request to a route is for,
+// as endpoints lists it: the route's path, unless the request is for one of
+// the resources tagHandler dispatches to.
+func (app *application) endpointPath(routePath string, params httprouter.Params) string {
+	if routePath == path.Join(basePathV1, tagRoute) {
+		name := params.ByName("date")
+		if _, ok := app.tagResources()[name]; ok {
+			return path.Join(path.Dir(routePath), name)
+		}
+	}
+	return routePath
 }
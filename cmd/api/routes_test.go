@@ -1,14 +1,33 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/des-ant/2024-article-api/internal/codec"
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/data/mocks"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/sitemap"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-
-	"github.com/des-ant/2024-article-api/internal/data/mocks"
 )
 
 func TestHealthcheck(t *testing.T) {
@@ -289,7 +308,8 @@ func TestGetArticlesByTagAndDateHandler(t *testing.T) {
 							"tag": "health",
 							"count": 17,
 							"articles": [17, 19, 20, 21, 22, 23, 24, 25, 26, 27],
-							"related_tags": ["diet", "exercise", "fitness", "hydration", "lifestyle", "meditation", "mental health", "mindfulness", "mobility", "nutrition", "science", "self-care", "sleep", "stress management", "wellness", "yoga"]
+							"related_tags": ["diet", "exercise", "fitness", "hydration", "lifestyle", "meditation", "mental health", "mindfulness", "mobility", "nutrition", "science", "self-care", "sleep", "stress management", "wellness", "yoga"],
+							"top_keywords": ["good health", "developing healthy eating", "fall asleep faster", "maintaining good health", "potentially containing simple"]
 					}
 			}`,
 		},
@@ -319,3 +339,2491 @@ func TestGetArticlesByTagAndDateHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateArticleNearDuplicate(t *testing.T) {
+	original := map[string]interface{}{
+		"id":    1,
+		"title": "new species of bird found",
+		"date":  "2016-09-22",
+		"body":  "a new species of bird has been found in the pacific",
+		"tags":  []string{"biology", "animals", "science"},
+	}
+	resubmitted := map[string]interface{}{
+		"id":    2,
+		"title": "new species of bird found",
+		"date":  "2016-09-22",
+		"body":  "a new species of bird has been found in the pacific",
+		"tags":  []string{"science"},
+	}
+
+	tests := []struct {
+		name           string
+		policy         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Accept Policy",
+			policy:         dedupePolicyAccept,
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{
+							"article": {
+									"id": 2,
+									"title": "new species of bird found",
+									"date": "2016-09-22",
+									"body": "a new species of bird has been found in the pacific",
+									"tags": ["science"]
+							}
+					}`,
+		},
+		{
+			name:           "Warn Policy",
+			policy:         dedupePolicyWarn,
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{
+							"article": {
+									"id": 2,
+									"title": "new species of bird found",
+									"date": "2016-09-22",
+									"body": "a new species of bird has been found in the pacific",
+									"tags": ["science"]
+							},
+							"near_duplicates": [
+									{"id": 1, "title": "new species of bird found", "similarity": 1}
+							]
+					}`,
+		},
+		{
+			name:           "Reject Policy",
+			policy:         dedupePolicyReject,
+			expectedStatus: http.StatusConflict,
+			expectedBody: `{
+							"error": {
+									"message": "article is a near-duplicate of an existing article",
+									"existing_id": 1,
+									"similarity": 1
+							}
+					}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.dedupe.policy = tt.policy
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			statusCode, _, _ := ts.postJSON(t, "/v1/articles", original)
+			require.Equal(t, http.StatusCreated, statusCode)
+
+			statusCode, _, body := ts.postJSON(t, "/v1/articles", resubmitted)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			require.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestCreateArticleNearDuplicateConcurrently(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.dedupe.policy = dedupePolicyReject
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Near-identical articles created at once: the reject policy must let only
+	// one of them in, even though each is prepared before any is stored.
+	const requests = 20
+	codes := make([]int, requests)
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			body := fmt.Sprintf(`{"id": %d, "title": "new species of bird found", "date": "2016-09-22", "body": "a new species of bird has been found in the pacific", "tags": ["science"]}`, i+1)
+			rs, err := ts.Client().Post(ts.URL+"/v1/articles", "application/json", strings.NewReader(body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rs.Body.Close()
+			codes[i] = rs.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		} else {
+			assert.Equal(t, http.StatusConflict, code)
+		}
+	}
+	assert.Equal(t, 1, created)
+	assert.Len(t, app.daos.Articles.GetAll(), 1)
+}
+
+func TestListDuplicateClustersHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Default Threshold",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+					"duplicate_clusters": [
+							{"articles": [3, 18], "similarity": 1},
+							{"articles": [12, 27], "similarity": 1}
+					]
+			}`,
+		},
+		{
+			name:           "Invalid Threshold",
+			query:          "?threshold=1.5",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"threshold": "must be greater than 0 and at most 1"}}`,
+		},
+		{
+			name:           "Non-numeric Threshold",
+			query:          "?threshold=abc",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"threshold": "must be a number"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.get(t, "/v1/duplicates"+tt.query)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			require.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestSuggestTagsHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	tests := []struct {
+		name           string
+		data           map[string]interface{}
+		expectedStatus int
+		expectedTags   []string
+		expectedBody   string
+	}{
+		{
+			name: "Ranked Suggestions",
+			data: map[string]interface{}{
+				"title": "rockets to mars",
+				"body":  "space agencies explore the solar system with new technology",
+				"tags":  []string{"space"},
+				"limit": 2,
+			},
+			expectedStatus: http.StatusOK,
+			expectedTags:   []string{"technology", "exploration"},
+		},
+		{
+			name:           "Missing Title And Body",
+			data:           map[string]interface{}{"tags": []string{"space"}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"body": "title or body must be provided"}}`,
+		},
+		{
+			name: "Invalid Limit",
+			data: map[string]interface{}{
+				"title": "rockets to mars",
+				"limit": 11,
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"limit": "must be between 1 and 10"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.postJSON(t, "/v1/articles/suggest-tags", tt.data)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, body)
+				return
+			}
+
+			var response struct {
+				Suggestions []struct {
+					Tag   string  `json:"tag"`
+					Score float64 `json:"score"`
+				} `json:"suggestions"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(body), &response))
+
+			var tags []string
+			for _, suggestion := range response.Suggestions {
+				tags = append(tags, suggestion.Tag)
+				assert.Greater(t, suggestion.Score, 0.0)
+			}
+			assert.Equal(t, tt.expectedTags, tags)
+		})
+	}
+}
+
+func TestCreateArticleTagAutofill(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.tags.autofill = 3
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	article := map[string]interface{}{
+		"id":    100,
+		"title": "rockets to mars",
+		"date":  "2016-09-22",
+		"body":  "space agencies explore the solar system with new technology",
+		"tags":  []string{"space"},
+	}
+
+	statusCode, _, body := ts.postJSON(t, "/v1/articles", article)
+	assert.Equal(t, http.StatusCreated, statusCode)
+	require.JSONEq(t, `{
+			"article": {
+					"id": 100,
+					"title": "rockets to mars",
+					"date": "2016-09-22",
+					"body": "space agencies explore the solar system with new technology",
+					"tags": ["space", "technology", "exploration"]
+			},
+			"suggested_tags": ["technology", "exploration"]
+	}`, body)
+
+	// An article sent without tags is refused rather than filled in.
+	delete(article, "tags")
+	article["id"] = 101
+	statusCode, _, body = ts.postJSON(t, "/v1/articles", article)
+	assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+	assert.JSONEq(t, `{"error": {"tags": "must be provided"}}`, body)
+}
+
+func TestSuggestTagsAfterWrites(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	suggest := func(t *testing.T) []string {
+		t.Helper()
+
+		code, _, body := ts.postJSON(t, "/v1/articles/suggest-tags", map[string]any{"body": "telescopes watch distant galaxies", "limit": 1})
+		require.Equal(t, http.StatusOK, code)
+
+		var response struct {
+			Suggestions []struct {
+				Tag string `json:"tag"`
+			} `json:"suggestions"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &response))
+
+		var tags []string
+		for _, suggestion := range response.Suggestions {
+			tags = append(tags, suggestion.Tag)
+		}
+		return tags
+	}
+
+	assert.NotEqual(t, []string{"astronomy"}, suggest(t))
+
+	// The classifier learns from articles stored after it was first used.
+	article := map[string]any{
+		"id":    100,
+		"title": "telescopes",
+		"date":  "2016-09-22",
+		"body":  "telescopes watch distant galaxies",
+		"tags":  []string{"astronomy"},
+	}
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+	require.Equal(t, http.StatusCreated, statusCode)
+	assert.Equal(t, []string{"astronomy"}, suggest(t))
+
+	// And forgets the ones deleted since.
+	require.NoError(t, app.daos.Articles.Delete(100))
+	assert.NotEqual(t, []string{"astronomy"}, suggest(t))
+}
+
+func TestShowArticleSummaryHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	article := map[string]interface{}{
+		"id":    1,
+		"title": "breakthrough in sleep science",
+		"date":  "2016-09-22",
+		"body": "Scientists have discovered a new way to help you fall asleep faster. " +
+			"The study tracked sleep patterns of volunteers over six months. " +
+			"Volunteers who kept a regular bedtime fell asleep faster than the rest. " +
+			"The weather was mild during the study.",
+		"tags": []string{"health", "science"},
+	}
+
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Two Sentences",
+			url:            "/v1/articles/1/summary?sentences=2",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+					"summary": {
+							"id": 1,
+							"sentences": [
+									"The study tracked sleep patterns of volunteers over six months.",
+									"Volunteers who kept a regular bedtime fell asleep faster than the rest."
+							],
+							"text": "The study tracked sleep patterns of volunteers over six months. Volunteers who kept a regular bedtime fell asleep faster than the rest."
+					}
+			}`,
+		},
+		{
+			name:           "Invalid Sentences",
+			url:            "/v1/articles/1/summary?sentences=0",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"sentences": "must be between 1 and 20"}}`,
+		},
+		{
+			name:           "Non-existent ID",
+			url:            "/v1/articles/999/summary",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"the requested resource could not be found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.get(t, tt.url)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			require.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestCreateArticleStoredSummary(t *testing.T) {
+	app := newTestApplication(t)
+	app.daos.Articles.StoreSummaries(1)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	article := map[string]interface{}{
+		"id":    1,
+		"title": "olympics are coming",
+		"date":  "2016-09-23",
+		"body":  "The olympics are a time of great excitement. Athletes train for years. The olympics bring great excitement to athletes and fans.",
+		"tags":  []string{"sports"},
+	}
+
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	statusCode, _, body := ts.get(t, "/v1/articles/1")
+	assert.Equal(t, http.StatusOK, statusCode)
+	require.JSONEq(t, `{
+			"article": {
+					"id": 1,
+					"title": "olympics are coming",
+					"date": "2016-09-23",
+					"body": "The olympics are a time of great excitement. Athletes train for years. The olympics bring great excitement to athletes and fans.",
+					"tags": ["sports"],
+					"summary": "The olympics bring great excitement to athletes and fans."
+			}
+	}`, body)
+}
+
+func TestShowArticleMetrics(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Include Metrics",
+			url:            "/v1/articles/1?include=metrics",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+					"article": {
+							"id": 1,
+							"title": "latest science shows that potato chips are better for you than sugar",
+							"date": "2016-09-22",
+							"body": "some text, potentially containing simple markup about how potato chip",
+							"tags": ["health", "fitness", "science"],
+							"metrics": {
+									"word_count": 10,
+									"reading_time_minutes": 1,
+									"flesch_reading_ease": 27.5,
+									"language": "en"
+							}
+					}
+			}`,
+		},
+		{
+			name:           "Unknown Include",
+			url:            "/v1/articles/1?include=secrets",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"include": "must only contain metrics"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.get(t, tt.url)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			require.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestListArticlesHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	french := map[string]interface{}{
+		"id":    100,
+		"title": "une nouvelle espèce d'oiseau découverte",
+		"date":  "2016-09-22",
+		"body":  "Les scientifiques ont découvert une nouvelle espèce d'oiseau dans le Pacifique.",
+		"tags":  []string{"science"},
+	}
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", french)
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedIDs    []int64
+		expectedBody   string
+	}{
+		{
+			name:           "First Page",
+			query:          "?page_size=3",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{1, 2, 3},
+		},
+		{
+			name:           "Language Filter",
+			query:          "?language=fr",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{100},
+		},
+		{
+			name:           "Word Count Filter",
+			query:          "?min_word_count=13",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{9, 11},
+		},
+		{
+			name:           "Readability Filter",
+			query:          "?min_reading_ease=85&language=en",
+			expectedStatus: http.StatusOK,
+			expectedIDs:    []int64{3, 18, 20},
+		},
+		{
+			name:           "Invalid Page Size",
+			query:          "?page_size=101",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"page_size": "must be a maximum of 100"}}`,
+		},
+		{
+			name:           "Invalid Word Count",
+			query:          "?min_word_count=-1",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"min_word_count": "must not be negative"}}`,
+		},
+		{
+			name:           "Unknown Language",
+			query:          "?language=english",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"language": "must be one of de, en, es, fr, it, nl or pt"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.get(t, "/v1/articles"+tt.query)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+
+			if tt.expectedBody != "" {
+				require.JSONEq(t, tt.expectedBody, body)
+				return
+			}
+
+			var response struct {
+				Articles []map[string]interface{} `json:"articles"`
+				Metadata map[string]interface{}   `json:"metadata"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(body), &response))
+
+			var ids []int64
+			for _, article := range response.Articles {
+				ids = append(ids, int64(article["id"].(float64)))
+				assert.NotContains(t, article, "metrics")
+			}
+			assert.Equal(t, tt.expectedIDs, ids)
+		})
+	}
+}
+
+func TestShowArticleKeywordsHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	article := map[string]interface{}{
+		"id":    1,
+		"title": "Advancements in AI Technology",
+		"date":  "2022-05-01",
+		"body":  "The European Space Agency and NASA announced a joint mission. Recent advancements in AI technology helped plan the mission.",
+		"tags":  []string{"technology", "space"},
+	}
+
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Keywords And Entities",
+			url:            "/v1/articles/1/keywords?limit=3",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{
+					"keywords": {
+							"id": 1,
+							"keywords": [
+									{"phrase": "ai technology helped", "score": 9},
+									{"phrase": "european space agency", "score": 9},
+									{"phrase": "nasa announced", "score": 4}
+							],
+							"entities": ["AI Technology", "European Space Agency", "NASA", "AI"]
+					}
+			}`,
+		},
+		{
+			name:           "Invalid Limit",
+			url:            "/v1/articles/1/keywords?limit=0",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"error": {"limit": "must be between 1 and 50"}}`,
+		},
+		{
+			name:           "Non-existent ID",
+			url:            "/v1/articles/999/keywords",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"the requested resource could not be found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.get(t, tt.url)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			require.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	article := map[string]interface{}{
+		"id":    1,
+		"title": "potato chips & sugar",
+		"date":  "2016-09-22",
+		"body":  "some text about potato chips",
+		"tags":  []string{"health", "science"},
+	}
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	tests := []struct {
+		name                string
+		url                 string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "Default JSON",
+			url:                 "/v1/articles/1",
+			accept:              "",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody: `{
+	"article": {
+		"id": 1,
+		"title": "potato chips & sugar",
+		"date": "2016-09-22",
+		"body": "some text about potato chips",
+		"tags": [
+			"health",
+			"science"
+		]
+	}
+}`,
+		},
+		{
+			name:                "XML",
+			url:                 "/v1/articles/1",
+			accept:              "application/xml",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml",
+			expectedBody: `<?xml version="1.0" encoding="UTF-8"?>
+<response>
+	<article>
+		<id>1</id>
+		<title>potato chips &amp; sugar</title>
+		<date>2016-09-22</date>
+		<body>some text about potato chips</body>
+		<tags>
+			<tag>health</tag>
+			<tag>science</tag>
+		</tags>
+	</article>
+</response>`,
+		},
+		{
+			name:                "YAML With Quality Values",
+			url:                 "/v1/articles/1",
+			accept:              "application/json;q=0.5, application/yaml",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/yaml",
+			expectedBody: `article:
+  id: 1
+  title: potato chips & sugar
+  date: "2016-09-22"
+  body: some text about potato chips
+  tags:
+    - health
+    - science`,
+		},
+		{
+			name:                "CSV List",
+			url:                 "/v1/articles",
+			accept:              "text/csv",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedBody: `id,title,date,body,tags
+1,potato chips & sugar,2016-09-22,some text about potato chips,health;science`,
+		},
+		{
+			name:                "NDJSON List",
+			url:                 "/v1/articles",
+			accept:              "application/x-ndjson",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody:        `{"id":1,"title":"potato chips \u0026 sugar","date":"2016-09-22","body":"some text about potato chips","tags":["health","science"]}`,
+		},
+		{
+			name:                "Format Override",
+			url:                 "/v1/articles?format=csv",
+			accept:              "application/json",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			expectedBody: `id,title,date,body,tags
+1,potato chips & sugar,2016-09-22,some text about potato chips,health;science`,
+		},
+		{
+			name:                "CSV Single Resource",
+			url:                 "/v1/articles/1",
+			accept:              "text/csv",
+			expectedStatus:      http.StatusNotAcceptable,
+			expectedContentType: "application/json",
+			expectedBody:        `{"error": "the resource can only be sent as application/json, application/xml, application/yaml, application/x-ndjson, application/msgpack"}`,
+		},
+		{
+			name:                "Unknown Format",
+			url:                 "/v1/articles/1?format=pdf",
+			accept:              "",
+			expectedStatus:      http.StatusNotAcceptable,
+			expectedContentType: "application/json",
+			expectedBody:        `{"error": "the resource can only be sent as application/json, application/xml, application/yaml, application/x-ndjson, application/msgpack"}`,
+		},
+		{
+			name:                "XML Error",
+			url:                 "/v1/articles/999",
+			accept:              "text/xml",
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/xml",
+			expectedBody: `<?xml version="1.0" encoding="UTF-8"?>
+<response>
+	<error>the requested resource could not be found</error>
+</response>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			if tt.accept != "" {
+				header.Set("Accept", tt.accept)
+			}
+
+			statusCode, headers, body := ts.getWithHeader(t, tt.url, header)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			assert.Equal(t, tt.expectedContentType, headers.Get("Content-Type"))
+
+			if tt.expectedContentType == "application/json" {
+				require.JSONEq(t, tt.expectedBody, body)
+				return
+			}
+			assert.Equal(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestCreateArticleRequestFormats(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// multipartBody builds a multipart/form-data body with the article body
+	// uploaded as a file of the given content type.
+	multipartBody := func(id, fileContentType, fileContent string) (string, io.Reader) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		require.NoError(t, mw.WriteField("id", id))
+		require.NoError(t, mw.WriteField("title", "breakthrough in sleep science"))
+		require.NoError(t, mw.WriteField("date", "2016-09-22"))
+		require.NoError(t, mw.WriteField("tags", "health"))
+		require.NoError(t, mw.WriteField("tags", "science"))
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="body"; filename="body.md"`)
+		header.Set("Content-Type", fileContentType)
+		part, err := mw.CreatePart(header)
+		require.NoError(t, err)
+		_, err = io.WriteString(part, fileContent)
+		require.NoError(t, err)
+
+		require.NoError(t, mw.Close())
+		return mw.FormDataContentType(), &buf
+	}
+
+	multipartContentType, multipartReader := multipartBody("3", "text/markdown", "scientists have discovered a *new* way to fall asleep")
+	binaryContentType, binaryReader := multipartBody("4", "image/png", "\x89PNG")
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           io.Reader
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Form Encoded",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           strings.NewReader("id=1&title=olympics+are+coming&date=2016-09-23&body=the+olympics+are+exciting&tags=sports&tags=world"),
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{
+					"article": {
+							"id": 1,
+							"title": "olympics are coming",
+							"date": "2016-09-23",
+							"body": "the olympics are exciting",
+							"tags": ["sports", "world"]
+					}
+			}`,
+		},
+		{
+			name:        "YAML",
+			contentType: "application/yaml",
+			body: strings.NewReader(`id: 2
+title: new species of bird found
+date: 2016-09-22
+body: a new species of bird has been found in the pacific
+tags:
+  - biology
+  - science
+`),
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{
+					"article": {
+							"id": 2,
+							"title": "new species of bird found",
+							"date": "2016-09-22",
+							"body": "a new species of bird has been found in the pacific",
+							"tags": ["biology", "science"]
+					}
+			}`,
+		},
+		{
+			name:           "Multipart Upload",
+			contentType:    multipartContentType,
+			body:           multipartReader,
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{
+					"article": {
+							"id": 3,
+							"title": "breakthrough in sleep science",
+							"date": "2016-09-22",
+							"body": "scientists have discovered a *new* way to fall asleep",
+							"tags": ["health", "science"]
+					}
+			}`,
+		},
+		{
+			name:           "Multipart Binary Upload",
+			contentType:    binaryContentType,
+			body:           binaryReader,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body file \"body.md\" must be a UTF-8 text or Markdown file"}`,
+		},
+		{
+			name:           "Form Unknown Key",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           strings.NewReader("id=5&author=someone"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body contains unknown key \"author\""}`,
+		},
+		{
+			name:           "Form Incorrect Type",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           strings.NewReader("id=abc"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body contains incorrect form type for field \"id\""}`,
+		},
+		{
+			name:           "Form Invalid Date",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           strings.NewReader("id=6&date=22-09-2016"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "invalid date format"}`,
+		},
+		{
+			name:           "YAML Incorrect Type",
+			contentType:    "application/yaml",
+			body:           strings.NewReader("id: 7\ntags: health\n"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body contains incorrect YAML type for field \"tags\""}`,
+		},
+		{
+			name:           "YAML Badly Formed",
+			contentType:    "application/yaml",
+			body:           strings.NewReader("id: 8\ntitle: [unclosed\n"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body contains badly-formed YAML (line 1: did not find expected ',' or ']')"}`,
+		},
+		{
+			name:           "YAML Multiple Documents",
+			contentType:    "application/yaml",
+			body:           strings.NewReader("id: 9\n---\nid: 10\n"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body must only contain a single YAML document"}`,
+		},
+		{
+			name:           "YAML Recursive Alias",
+			contentType:    "application/yaml",
+			body:           strings.NewReader("a: &a [*a]\n"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body contains badly-formed YAML (line 1: aliases are not supported)"}`,
+		},
+		{
+			name:        "YAML Alias Expansion",
+			contentType: "application/yaml",
+			body: strings.NewReader("a: &a [x, x, x, x, x, x, x, x, x]\n" +
+				"b: &b [*a, *a, *a, *a, *a, *a, *a, *a, *a]\n" +
+				"c: &c [*b, *b, *b, *b, *b, *b, *b, *b, *b]\n" +
+				"d: &d [*c, *c, *c, *c, *c, *c, *c, *c, *c]\n" +
+				"e: [*d, *d, *d, *d, *d, *d, *d, *d, *d]\n"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body contains badly-formed YAML (line 2: aliases are not supported)"}`,
+		},
+		{
+			name:           "YAML Comments Only",
+			contentType:    "application/yaml",
+			body:           strings.NewReader("# nothing to see here\n"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error": "body must not be empty"}`,
+		},
+		{
+			name:           "Unsupported Media Type",
+			contentType:    "text/plain",
+			body:           strings.NewReader("hello"),
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedBody:   `{"error": "the text/plain content type is not supported, use one of application/json, application/x-www-form-urlencoded, multipart/form-data, application/yaml, application/msgpack"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.post(t, "/v1/articles", tt.contentType, tt.body)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			require.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestMessagePackEncoding(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// toJSON converts a MessagePack body to JSON so it can be compared with
+	// the JSON representation of the same response.
+	toJSON := func(t *testing.T, body string) string {
+		value, err := codec.DecodeMsgpack([]byte(body))
+		require.NoError(t, err)
+		js, err := json.Marshal(value)
+		require.NoError(t, err)
+		return string(js)
+	}
+
+	article := map[string]any{
+		"id":    1,
+		"title": "potato chips & sugar",
+		"date":  "2016-09-22",
+		"body":  "some text about potato chips",
+		"tags":  []any{"health", "science"},
+	}
+	var reqBody bytes.Buffer
+	require.NoError(t, codec.EncodeMsgpack(&reqBody, article))
+
+	statusCode, headers, _ := ts.post(t, "/v1/articles", "application/msgpack", &reqBody)
+	assert.Equal(t, http.StatusCreated, statusCode)
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, "/v1/articles/1", headers.Get("Location"))
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{name: "Article", url: "/v1/articles/1", expectedStatus: http.StatusOK},
+		{name: "Article List", url: "/v1/articles", expectedStatus: http.StatusOK},
+		{name: "Tag Summary", url: "/v1/tags/health/20160922", expectedStatus: http.StatusOK},
+		{name: "Error", url: "/v1/articles/999", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			header.Set("Accept", "application/msgpack")
+
+			statusCode, headers, body := ts.getWithHeader(t, tt.url, header)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+			assert.Equal(t, "application/msgpack", headers.Get("Content-Type"))
+
+			_, _, jsonBody := ts.get(t, tt.url)
+			require.JSONEq(t, jsonBody, toJSON(t, body))
+		})
+	}
+
+	t.Run("Badly-Formed Body", func(t *testing.T) {
+		statusCode, _, body := ts.post(t, "/v1/articles", "application/msgpack", strings.NewReader("\x81\xa2id"))
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		require.JSONEq(t, `{"error": "body contains badly-formed MessagePack (unexpected end of data at byte 4)"}`, body)
+	})
+
+	t.Run("Incorrect Type", func(t *testing.T) {
+		var reqBody bytes.Buffer
+		require.NoError(t, codec.EncodeMsgpack(&reqBody, map[string]any{"id": "one"}))
+
+		statusCode, _, body := ts.post(t, "/v1/articles", "application/msgpack", &reqBody)
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		require.JSONEq(t, `{"error": "body contains incorrect MessagePack type for field \"id\""}`, body)
+	})
+}
+
+func TestJSONResponseWriter(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	t.Run("Compact By Default", func(t *testing.T) {
+		_, headers, body := ts.get(t, "/v1/healthcheck")
+		assert.Equal(t, `{"status":"available","system_info":{"environment":"test","version":"1.0.0"}}`, body)
+		assert.Equal(t, strconv.Itoa(len(body)+1), headers.Get("Content-Length"))
+	})
+
+	t.Run("Pretty", func(t *testing.T) {
+		_, headers, body := ts.get(t, "/v1/healthcheck?pretty=true")
+		assert.Equal(t, "{\n\t\"status\": \"available\",\n\t\"system_info\": {\n\t\t\"environment\": \"test\",\n\t\t\"version\": \"1.0.0\"\n\t}\n}", body)
+		assert.Equal(t, strconv.Itoa(len(body)+1), headers.Get("Content-Length"))
+	})
+
+	t.Run("Compact In Development", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.env = "development"
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		_, _, body := ts.get(t, "/v1/healthcheck")
+		assert.Contains(t, body, `{"status":"available"`)
+
+		_, _, body = ts.get(t, "/v1/healthcheck?pretty=true")
+		assert.Contains(t, body, "\n\t\"status\": \"available\"")
+	})
+
+	// Add enough articles for a full page to be written in chunks.
+	for i := range chunkedListThreshold + 10 {
+		err := app.daos.Articles.Insert(&data.Article{
+			ID:    int64(100 + i),
+			Title: fmt.Sprintf("article %d", i),
+			Date:  data.ArticleDate(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Body:  strings.Repeat("chunked article body ", 40),
+			Tags:  []string{"chunked"},
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("Chunked List", func(t *testing.T) {
+		statusCode, headers, body := ts.get(t, "/v1/articles?page_size=100")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "application/json", headers.Get("Content-Type"))
+		assert.Empty(t, headers.Get("Content-Length"))
+
+		var response struct {
+			Articles []data.Article `json:"articles"`
+			Metadata data.Metadata  `json:"metadata"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &response))
+		total := len(mocks.InitMockArticles()) + chunkedListThreshold + 10
+		assert.Len(t, response.Articles, total)
+		assert.Equal(t, total, response.Metadata.TotalRecords)
+
+		// A chunked response must match what encoding/json produces.
+		expected, err := json.Marshal(response)
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), body)
+
+		var indented bytes.Buffer
+		require.NoError(t, json.Indent(&indented, []byte(body), "", "\t"))
+		_, _, prettyBody := ts.get(t, "/v1/articles?page_size=100&pretty=true")
+		assert.Equal(t, indented.String(), prettyBody)
+	})
+}
+
+func TestShowArticleBodyFormat(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	body := "# Potato *chips*\n\n" +
+		"Chips are **not** a `health_food`, see [the study](https://example.com/study \"Study\").\n" +
+		"Don't click [this](javascript:alert(1)) or run <script>alert(1)</script>.\n\n" +
+		"> Everything in moderation\n\n" +
+		"- salt\n- sugar\n  1. cane\n  2. beet\n\n" +
+		"```go\nfmt.Println(\"<chips>\")\n```"
+
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", map[string]any{
+		"id":    1,
+		"title": "chips",
+		"date":  "2016-09-22",
+		"body":  body,
+		"tags":  []string{"health"},
+	})
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	// The script in the prose is removed when the article is created, but
+	// the one in the code block is left alone.
+	stored := strings.Replace(body, "<script>alert(1)</script>", "", 1)
+
+	tests := []struct {
+		name           string
+		bodyFormat     string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Default",
+			expectedStatus: http.StatusOK,
+			expectedBody:   stored,
+		},
+		{
+			name:           "Raw",
+			bodyFormat:     "raw",
+			expectedStatus: http.StatusOK,
+			expectedBody:   stored,
+		},
+		{
+			name:           "HTML",
+			bodyFormat:     "html",
+			expectedStatus: http.StatusOK,
+			expectedBody: "<h1>Potato <em>chips</em></h1>\n" +
+				"<p>Chips are <strong>not</strong> a <code>health_food</code>, see <a href=\"https://example.com/study\" title=\"Study\" rel=\"noopener nofollow\">the study</a>.\n" +
+				"Don&#39;t click this or run .</p>\n" +
+				"<blockquote>\n<p>Everything in moderation</p>\n</blockquote>\n" +
+				"<ul>\n<li>salt</li>\n<li>sugar\n<ol>\n<li>cane</li>\n<li>beet</li>\n</ol>\n</li>\n</ul>\n" +
+				"<pre><code class=\"language-go\">fmt.Println(&#34;&lt;chips&gt;&#34;)\n</code></pre>\n",
+		},
+		{
+			name:           "Text",
+			bodyFormat:     "text",
+			expectedStatus: http.StatusOK,
+			expectedBody: "Potato chips\n\n" +
+				"Chips are not a health_food, see the study. Don't click this or run .\n\n" +
+				"Everything in moderation\n\n" +
+				"salt\nsugar\ncane\nbeet\n\n" +
+				"fmt.Println(\"<chips>\")",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := "/v1/articles/1"
+			if tt.bodyFormat != "" {
+				url += "?body_format=" + tt.bodyFormat
+			}
+
+			statusCode, _, respBody := ts.get(t, url)
+			assert.Equal(t, tt.expectedStatus, statusCode)
+
+			var response struct {
+				Article data.Article `json:"article"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(respBody), &response))
+			assert.Equal(t, tt.expectedBody, response.Article.Body)
+		})
+	}
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		statusCode, _, respBody := ts.get(t, "/v1/articles/1?body_format=pdf")
+		assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+		require.JSONEq(t, `{"error": {"body_format": "must be one of raw, html or text"}}`, respBody)
+	})
+}
+
+func TestShowArticleBodyFormatHTMLLinks(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.html.internalHosts = []string{"example.com"}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	statusCode, _, body := ts.postJSON(t, "/v1/articles", map[string]any{
+		"id":    1,
+		"title": "links",
+		"date":  "2016-09-22",
+		"body":  "See [the study](https://other.org/study), [our notes](https://example.com/notes) and <a href=\"https://other.org/data\" onclick=\"x()\">the <b>data</b></a>.",
+		"tags":  []string{"health"},
+	})
+	require.Equal(t, http.StatusCreated, statusCode, body)
+
+	statusCode, _, body = ts.get(t, "/v1/articles/1?body_format=html")
+	require.Equal(t, http.StatusOK, statusCode)
+
+	var res struct {
+		Article struct {
+			Body string `json:"body"`
+		} `json:"article"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &res))
+
+	// External links get rel="noopener nofollow" whether they are written in
+	// Markdown or HTML, and sanitized HTML is rendered as HTML, not text.
+	expected := "<p>See <a href=\"https://other.org/study\" rel=\"noopener nofollow\">the study</a>, " +
+		"<a href=\"https://example.com/notes\">our notes</a> and " +
+		"<a href=\"https://other.org/data\" rel=\"noopener nofollow\">the <b>data</b></a>.</p>\n"
+	assert.Equal(t, expected, res.Article.Body)
+}
+
+func TestCreateArticleSanitizesHTML(t *testing.T) {
+	body := `<p onclick="steal()">Chips <b>are</b> <span>fine</span>.</p>` +
+		`<script>alert("<p>")</script><!-- note -->` +
+		`<a href="javascript:alert(1)">bad</a> <a href="https://example.org/study">study</a> ` +
+		`<a href="/v1/articles/2">related</a> <a href="https://chips.example/about">about</a>`
+
+	newArticle := func(id int) map[string]any {
+		return map[string]any{
+			"id":    id,
+			"title": "chips",
+			"date":  "2016-09-22",
+			"body":  body,
+			"tags":  []string{"health"},
+		}
+	}
+
+	t.Run("Strip", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.html.internalHosts = []string{"chips.example"}
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		statusCode, _, respBody := ts.postJSON(t, "/v1/articles", newArticle(1))
+		assert.Equal(t, http.StatusCreated, statusCode)
+
+		var response struct {
+			Article   data.Article    `json:"article"`
+			Sanitized []markup.Change `json:"sanitized"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(respBody), &response))
+
+		assert.Equal(t, `<p>Chips <b>are</b> fine.</p>`+
+			`<a>bad</a> <a href="https://example.org/study" rel="noopener nofollow">study</a> `+
+			`<a href="/v1/articles/2">related</a> <a href="https://chips.example/about">about</a>`, response.Article.Body)
+		assert.Equal(t, []markup.Change{
+			{Action: markup.RemovedAttribute, Element: "p", Attribute: "onclick", Count: 1},
+			{Action: markup.RemovedElement, Element: "span", Count: 1},
+			{Action: markup.RemovedElement, Element: "script", Count: 1},
+			{Action: markup.RemovedComment, Count: 1},
+			{Action: markup.RemovedAttribute, Element: "a", Attribute: "href", Count: 1},
+			{Action: markup.SetLinkRel, Element: "a", Attribute: "rel", Count: 1},
+		}, response.Sanitized)
+
+		// The sanitized body is what gets stored.
+		_, _, respBody = ts.get(t, "/v1/articles/1")
+		assert.Contains(t, respBody, `rel=\"noopener nofollow\"`)
+		assert.NotContains(t, respBody, "script")
+	})
+
+	t.Run("Clean Body", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		statusCode, _, respBody := ts.postJSON(t, "/v1/articles", map[string]any{
+			"id":    1,
+			"title": "chips",
+			"date":  "2016-09-22",
+			"body":  "Chips & dips <b>rule</b>, see <https://example.org> and 1 < 2.",
+			"tags":  []string{"health"},
+		})
+		assert.Equal(t, http.StatusCreated, statusCode)
+
+		var response map[string]any
+		require.NoError(t, json.Unmarshal([]byte(respBody), &response))
+		assert.Equal(t, "Chips & dips <b>rule</b>, see <https://example.org> and 1 < 2.", response["article"].(map[string]any)["body"])
+		assert.NotContains(t, response, "sanitized")
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.html.policy = htmlPolicyReject
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		statusCode, _, respBody := ts.postJSON(t, "/v1/articles", newArticle(1))
+		assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+		require.JSONEq(t, `{"error": {"body": "must not contain the onclick attribute on <p>, <span> elements, <script> elements, HTML comments, the href attribute on <a>"}}`, respBody)
+
+		// Adding rel to external links isn't a reason to reject an article.
+		statusCode, _, respBody = ts.postJSON(t, "/v1/articles", map[string]any{
+			"id":    2,
+			"title": "chips",
+			"date":  "2016-09-22",
+			"body":  `<a href="https://example.org/study">study</a>`,
+			"tags":  []string{"health"},
+		})
+		assert.Equal(t, http.StatusCreated, statusCode)
+		assert.Contains(t, respBody, `"sanitized":[{"action":"set_link_rel","element":"a","attribute":"rel","count":1}]`)
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		tests := []struct {
+			name      string
+			body      string
+			expected  string
+			sanitized []markup.Change
+		}{
+			{
+				name:     "Code Span",
+				body:     "Use `<script>` tags carefully. More text here.",
+				expected: "Use `<script>` tags carefully. More text here.",
+			},
+			{
+				name:     "Code Block",
+				body:     "Wrap it:\n\n```html\n<div class=\"chips\">\n```\n\nThen serve.",
+				expected: "Wrap it:\n\n```html\n<div class=\"chips\">\n```\n\nThen serve.",
+			},
+			{
+				name:      "Unknown Tag",
+				body:      "x<y>z",
+				expected:  "x&lt;y>z",
+				sanitized: []markup.Change{{Action: markup.EscapedTag, Element: "y", Count: 1}},
+			},
+			{
+				name:      "Unclosed Script",
+				body:      "Run <script>alert(1) and more text.",
+				expected:  "Run alert(1) and more text.",
+				sanitized: []markup.Change{{Action: markup.RemovedElement, Element: "script", Count: 1}},
+			},
+		}
+
+		for i, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				statusCode, _, respBody := ts.postJSON(t, "/v1/articles", map[string]any{
+					"id":    i + 1,
+					"title": "chips",
+					"date":  "2016-09-22",
+					"body":  tt.body,
+					"tags":  []string{"health"},
+				})
+				assert.Equal(t, http.StatusCreated, statusCode)
+
+				var response struct {
+					Article   data.Article    `json:"article"`
+					Sanitized []markup.Change `json:"sanitized"`
+				}
+				require.NoError(t, json.Unmarshal([]byte(respBody), &response))
+				assert.Equal(t, tt.expected, response.Article.Body)
+				assert.Equal(t, tt.sanitized, response.Sanitized)
+			})
+		}
+	})
+
+	t.Run("Reject Escapes", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.html.policy = htmlPolicyReject
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		// Escaping a tag keeps its text, so it isn't a reason to reject an
+		// article either.
+		statusCode, _, _ := ts.postJSON(t, "/v1/articles", map[string]any{
+			"id":    1,
+			"title": "chips",
+			"date":  "2016-09-22",
+			"body":  "Use List<String> for `<b>` tags.",
+			"tags":  []string{"health"},
+		})
+		assert.Equal(t, http.StatusCreated, statusCode)
+	})
+}
+
+func TestFeeds(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.feed.items = 2
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	for _, article := range []map[string]any{
+		{"id": 1, "title": "Oldest", "date": "2016-09-20", "body": "Some **old** news", "tags": []string{"health"}},
+		{"id": 2, "title": "Newest", "date": "2016-09-22", "body": "Some new news", "tags": []string{"health", "science"}},
+		{"id": 3, "title": "Middle", "date": "2016-09-21", "body": "Some news", "tags": []string{"science"}},
+	} {
+		statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+		require.Equal(t, http.StatusCreated, statusCode)
+	}
+
+	// Items are updated when their articles were last stored, and feeds when
+	// the last of their items was.
+	modified := func(id int64) time.Time {
+		article, err := app.daos.Articles.Get(id)
+		require.NoError(t, err)
+		return article.Modified
+	}
+	latest := modified(2)
+	if modified(3).After(latest) {
+		latest = modified(3)
+	}
+
+	type atomFeed struct {
+		ID      string `xml:"id"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			ID         string `xml:"id"`
+			Title      string `xml:"title"`
+			Updated    string `xml:"updated"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+
+	type rssFeed struct {
+		Channel struct {
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title   string `xml:"title"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+
+	t.Run("Atom", func(t *testing.T) {
+		statusCode, header, body := ts.get(t, "/v1/feeds/all.atom")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "application/atom+xml; charset=utf-8", header.Get("Content-Type"))
+		assert.NotEmpty(t, header.Get("ETag"))
+		assert.Equal(t, latest.UTC().Format(http.TimeFormat), header.Get("Last-Modified"))
+
+		var feed atomFeed
+		require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+		assert.Equal(t, ts.URL+"/v1/feeds/all.atom", feed.ID)
+		assert.Equal(t, latest.UTC().Format(time.RFC3339), feed.Updated)
+		require.Len(t, feed.Entries, 2)
+		assert.Equal(t, ts.URL+"/v1/articles/2", feed.Entries[0].ID)
+		assert.Equal(t, "Newest", feed.Entries[0].Title)
+		assert.Equal(t, modified(2).UTC().Format(time.RFC3339), feed.Entries[0].Updated)
+		require.Len(t, feed.Entries[0].Categories, 2)
+		assert.Equal(t, "science", feed.Entries[0].Categories[1].Term)
+		assert.Equal(t, "Middle", feed.Entries[1].Title)
+	})
+
+	t.Run("RSS", func(t *testing.T) {
+		statusCode, header, body := ts.get(t, "/v1/feeds/all.rss")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "application/rss+xml; charset=utf-8", header.Get("Content-Type"))
+
+		var feed rssFeed
+		require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+		assert.Equal(t, latest.UTC().Format(time.RFC1123Z), feed.Channel.LastBuildDate)
+		require.Len(t, feed.Channel.Items, 2)
+		assert.Equal(t, ts.URL+"/v1/articles/2", feed.Channel.Items[0].GUID)
+		assert.Equal(t, "Wed, 21 Sep 2016 00:00:00 +0000", feed.Channel.Items[1].PubDate)
+	})
+
+	t.Run("JSONFeed", func(t *testing.T) {
+		statusCode, header, body := ts.get(t, "/v1/feeds/all.json")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "application/feed+json; charset=utf-8", header.Get("Content-Type"))
+
+		var feed struct {
+			Version string `json:"version"`
+			FeedURL string `json:"feed_url"`
+			Items   []struct {
+				ID            string   `json:"id"`
+				Title         string   `json:"title"`
+				ContentHTML   string   `json:"content_html"`
+				DatePublished string   `json:"date_published"`
+				Tags          []string `json:"tags"`
+			} `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &feed))
+		assert.Equal(t, "https://jsonfeed.org/version/1.1", feed.Version)
+		assert.Equal(t, ts.URL+"/v1/feeds/all.json", feed.FeedURL)
+		require.Len(t, feed.Items, 2)
+		assert.Equal(t, ts.URL+"/v1/articles/2", feed.Items[0].ID)
+		assert.Equal(t, "2016-09-22T00:00:00Z", feed.Items[0].DatePublished)
+		assert.Equal(t, []string{"health", "science"}, feed.Items[0].Tags)
+		assert.Equal(t, "<p>Some new news</p>\n", feed.Items[0].ContentHTML)
+
+		statusCode, _, body = ts.get(t, "/v1/tags/health/feed.json")
+		require.Equal(t, http.StatusOK, statusCode)
+		require.NoError(t, json.Unmarshal([]byte(body), &feed))
+		require.Len(t, feed.Items, 2)
+		assert.Equal(t, "Oldest", feed.Items[1].Title)
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		statusCode, _, body := ts.get(t, "/v1/tags/health/feed.atom")
+		require.Equal(t, http.StatusOK, statusCode)
+
+		var feed atomFeed
+		require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+		require.Len(t, feed.Entries, 2)
+		assert.Equal(t, "Newest", feed.Entries[0].Title)
+		assert.Equal(t, "Oldest", feed.Entries[1].Title)
+		assert.Equal(t, "<p>Some <strong>old</strong> news</p>\n", feed.Entries[1].Content)
+
+		statusCode, _, body = ts.get(t, "/v1/tags/science/feed.rss")
+		require.Equal(t, http.StatusOK, statusCode)
+
+		var rss rssFeed
+		require.NoError(t, xml.Unmarshal([]byte(body), &rss))
+		require.Len(t, rss.Channel.Items, 2)
+		assert.Equal(t, "Newest", rss.Channel.Items[0].Title)
+		assert.Equal(t, "Middle", rss.Channel.Items[1].Title)
+
+		statusCode, _, _ = ts.get(t, "/v1/tags/missing/feed.atom")
+		assert.Equal(t, http.StatusNotFound, statusCode)
+
+		statusCode, _, _ = ts.get(t, "/v1/tags/health/feed.txt")
+		assert.Equal(t, http.StatusNotFound, statusCode)
+
+		// Dates still reach the tag summary.
+		statusCode, _, _ = ts.get(t, "/v1/tags/health/20160922")
+		assert.Equal(t, http.StatusOK, statusCode)
+	})
+
+	t.Run("ConditionalGet", func(t *testing.T) {
+		_, header, _ := ts.get(t, "/v1/feeds/all.atom")
+		etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+
+		statusCode, _, body := ts.getWithHeader(t, "/v1/feeds/all.atom", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusNotModified, statusCode)
+		assert.Empty(t, body)
+
+		statusCode, _, _ = ts.getWithHeader(t, "/v1/feeds/all.atom", http.Header{"If-Modified-Since": {lastModified}})
+		assert.Equal(t, http.StatusNotModified, statusCode)
+
+		statusCode, _, _ = ts.getWithHeader(t, "/v1/feeds/all.atom", http.Header{"If-None-Match": {`"stale"`}})
+		assert.Equal(t, http.StatusOK, statusCode)
+
+		// A new article changes the feed, so the old ETag no longer matches.
+		statusCode, _, _ = ts.postJSON(t, "/v1/articles", map[string]any{
+			"id": 4, "title": "Latest", "date": "2016-09-23", "body": "Breaking news", "tags": []string{"health"},
+		})
+		require.Equal(t, http.StatusCreated, statusCode)
+
+		statusCode, _, _ = ts.getWithHeader(t, "/v1/feeds/all.atom", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusOK, statusCode)
+	})
+
+	// Edits to an article show in the feed, such as those made by an admin
+	// import's upsert or a directory sync.
+	t.Run("Edited", func(t *testing.T) {
+		article, err := app.daos.Articles.Get(2)
+		require.NoError(t, err)
+		article.Title = "Newest, revised"
+		require.NoError(t, app.daos.Articles.Update(article))
+
+		statusCode, header, body := ts.get(t, "/v1/feeds/all.atom")
+		require.Equal(t, http.StatusOK, statusCode)
+
+		var feed atomFeed
+		require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+		require.Len(t, feed.Entries, 2)
+		assert.Equal(t, "Newest, revised", feed.Entries[1].Title)
+		assert.Equal(t, article.Modified.UTC().Format(time.RFC3339), feed.Entries[1].Updated)
+		assert.Equal(t, article.Modified.UTC().Format(time.RFC3339), feed.Updated)
+		assert.Equal(t, article.Modified.UTC().Format(http.TimeFormat), header.Get("Last-Modified"))
+	})
+}
+
+func TestTagEPUB(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	for _, article := range []map[string]any{
+		{"id": 1, "title": "Newest", "date": "2016-09-22", "body": "Some *new* news", "tags": []string{"health"}},
+		{"id": 2, "title": "Oldest", "date": "2016-09-20", "body": "Some old news", "tags": []string{"health", "science"}},
+		{"id": 3, "title": "Middle", "date": "2016-09-21", "body": "Some <b>news</b> & more", "tags": []string{"health"}},
+		{"id": 4, "title": "Elsewhere", "date": "2016-09-21", "body": "Other news", "tags": []string{"science"}},
+	} {
+		statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+		require.Equal(t, http.StatusCreated, statusCode)
+	}
+
+	// chapterTitles opens an EPUB file and returns the titles in its table of
+	// contents.
+	chapterTitles := func(t *testing.T, body string) []string {
+		t.Helper()
+		zr, err := zip.NewReader(strings.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		require.Equal(t, "mimetype", zr.File[0].Name)
+
+		var titles []string
+		for _, f := range zr.File {
+			if !strings.HasSuffix(f.Name, "nav.xhtml") {
+				continue
+			}
+			rc, err := f.Open()
+			require.NoError(t, err)
+			defer rc.Close()
+
+			var nav struct {
+				Links []string `xml:"body>nav>ol>li>a"`
+			}
+			require.NoError(t, xml.NewDecoder(rc).Decode(&nav))
+			titles = nav.Links
+		}
+		return titles
+	}
+
+	t.Run("Export", func(t *testing.T) {
+		statusCode, header, body := ts.get(t, "/v1/tags/health/export.epub")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "application/epub+zip", header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=health.epub`, header.Get("Content-Disposition"))
+		assert.NotEmpty(t, header.Get("ETag"))
+
+		assert.Equal(t, []string{"Oldest", "Middle", "Newest"}, chapterTitles(t, body))
+	})
+
+	t.Run("DateRange", func(t *testing.T) {
+		statusCode, _, body := ts.get(t, "/v1/tags/health/export.epub?from=2016-09-21&to=2016-09-21")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, []string{"Middle"}, chapterTitles(t, body))
+
+		statusCode, _, body = ts.get(t, "/v1/tags/health/export.epub?from=2016-09-22")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, []string{"Newest"}, chapterTitles(t, body))
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		statusCode, _, body := ts.get(t, "/v1/tags/health/export.epub?from=22-09-2016")
+		assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+		assert.Contains(t, body, "must be a date in the format YYYY-MM-DD")
+
+		statusCode, _, body = ts.get(t, "/v1/tags/health/export.epub?from=2016-09-22&to=2016-09-21")
+		assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+		assert.Contains(t, body, "must not be before from")
+	})
+
+	t.Run("NoArticles", func(t *testing.T) {
+		statusCode, _, _ := ts.get(t, "/v1/tags/missing/export.epub")
+		assert.Equal(t, http.StatusNotFound, statusCode)
+
+		statusCode, _, _ = ts.get(t, "/v1/tags/health/export.epub?to=2016-01-01")
+		assert.Equal(t, http.StatusNotFound, statusCode)
+	})
+}
+
+func TestSitemap(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.baseURL = "https://example.com"
+	app.sitemap = sitemap.New(2)
+	app.generateSitemap()
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	type urlset struct {
+		URLs []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"url"`
+	}
+
+	statusCode, header, body := ts.get(t, "/v1/sitemap.xml")
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "application/xml; charset=utf-8", header.Get("Content-Type"))
+
+	var set urlset
+	require.NoError(t, xml.Unmarshal([]byte(body), &set))
+	assert.Empty(t, set.URLs)
+
+	statusCode, _, _ = ts.postJSON(t, "/v1/articles", map[string]any{
+		"id": 7, "title": "First", "date": "2016-09-20", "body": "Some news", "tags": []string{"health"},
+	})
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	// The sitemap is updated in the background.
+	require.Eventually(t, func() bool {
+		_, _, body := ts.get(t, "/v1/sitemap.xml")
+		return strings.Contains(body, "/v1/articles/7")
+	}, time.Second, 10*time.Millisecond)
+
+	_, _, body = ts.get(t, "/v1/sitemap.xml")
+	require.NoError(t, xml.Unmarshal([]byte(body), &set))
+	require.Len(t, set.URLs, 1)
+	assert.Equal(t, "https://example.com/v1/articles/7", set.URLs[0].Loc)
+	assert.Equal(t, "2016-09-20", set.URLs[0].LastMod)
+
+	for _, article := range []map[string]any{
+		{"id": 3, "title": "Second", "date": "2016-09-22", "body": "More news", "tags": []string{"health"}},
+		{"id": 5, "title": "Third", "date": "2016-09-21", "body": "Other news", "tags": []string{"science"}},
+	} {
+		statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+		require.Equal(t, http.StatusCreated, statusCode)
+	}
+
+	// Past two URLs per file the sitemap is split, and an index is served.
+	require.Eventually(t, func() bool {
+		return app.sitemap.Len() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	statusCode, header, body = ts.get(t, "/v1/sitemap.xml")
+	require.Equal(t, http.StatusOK, statusCode)
+
+	var index struct {
+		XMLName  xml.Name
+		Sitemaps []struct {
+			Loc     string `xml:"loc"`
+			LastMod string `xml:"lastmod"`
+		} `xml:"sitemap"`
+	}
+	require.NoError(t, xml.Unmarshal([]byte(body), &index))
+	assert.Equal(t, "sitemapindex", index.XMLName.Local)
+	require.Len(t, index.Sitemaps, 2)
+	assert.Equal(t, "https://example.com/v1/sitemaps/1.xml", index.Sitemaps[0].Loc)
+	assert.Equal(t, "2016-09-22", index.Sitemaps[0].LastMod)
+	assert.Equal(t, "https://example.com/v1/sitemaps/2.xml", index.Sitemaps[1].Loc)
+	assert.Equal(t, "2016-09-21", index.Sitemaps[1].LastMod)
+
+	sitemapLocs := func(t *testing.T, path string) []string {
+		t.Helper()
+
+		statusCode, _, body := ts.get(t, path)
+		require.Equal(t, http.StatusOK, statusCode)
+
+		var set urlset
+		require.NoError(t, xml.Unmarshal([]byte(body), &set))
+
+		var locs []string
+		for _, u := range set.URLs {
+			locs = append(locs, u.Loc)
+		}
+		return locs
+	}
+
+	// New articles are added to the end, so existing ones stay in their files.
+	assert.Equal(t, []string{"https://example.com/v1/articles/7", "https://example.com/v1/articles/3"}, sitemapLocs(t, "/v1/sitemaps/1.xml"))
+	assert.Equal(t, []string{"https://example.com/v1/articles/5"}, sitemapLocs(t, "/v1/sitemaps/2.xml"))
+
+	for _, path := range []string{"/v1/sitemaps/3.xml", "/v1/sitemaps/0.xml", "/v1/sitemaps/1.txt"} {
+		statusCode, _, _ = ts.get(t, path)
+		assert.Equal(t, http.StatusNotFound, statusCode, path)
+	}
+
+	statusCode, _, _ = ts.getWithHeader(t, "/v1/sitemap.xml", http.Header{"If-Modified-Since": {header.Get("Last-Modified")}})
+	assert.Equal(t, http.StatusNotModified, statusCode)
+
+	// Updated articles get their new date.
+	article, err := app.daos.Articles.Get(7)
+	require.NoError(t, err)
+	article.Date = data.ArticleDate(time.Date(2016, 9, 25, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, app.daos.Articles.Update(article))
+
+	require.Eventually(t, func() bool {
+		_, _, body := ts.get(t, "/v1/sitemaps/1.xml")
+		return strings.Contains(body, "2016-09-25")
+	}, time.Second, 10*time.Millisecond)
+
+	// Deleted articles drop out, with the last article taking their place, and
+	// the index goes with the second file.
+	require.NoError(t, app.daos.Articles.Delete(3))
+
+	require.Eventually(t, func() bool {
+		return app.sitemap.Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"https://example.com/v1/articles/7", "https://example.com/v1/articles/5"}, sitemapLocs(t, "/v1/sitemap.xml"))
+
+	statusCode, _, _ = ts.get(t, "/v1/sitemaps/2.xml")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	// Without -base-url, links use the URL requested, as they do in feeds.
+	app.config.baseURL = ""
+	assert.Equal(t, []string{ts.URL + "/v1/articles/7", ts.URL + "/v1/articles/5"}, sitemapLocs(t, "/v1/sitemap.xml"))
+}
+
+func TestImportFeed(t *testing.T) {
+	rss := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+	<title>Legacy</title>
+	<item>
+		<title>Potatoes&nbsp;rule</title>
+		<pubDate>Thu, 22 Sep 2016 09:30:00 +1000</pubDate>
+		<description>Short</description>
+		<content:encoded><![CDATA[<p>Potatoes are <b>great</b><script>alert(1)</script></p>]]></content:encoded>
+		<category>health</category>
+		<category>food</category>
+	</item>
+	<item>
+		<title>Untagged</title>
+		<pubDate>Fri, 23 Sep 2016 09:30:00 GMT</pubDate>
+		<description>No categories here</description>
+	</item>
+	<item>
+		<title>Existing</title>
+		<pubDate>Wed, 21 Sep 2016 00:00:00 +0000</pubDate>
+		<description>Already stored</description>
+		<category>health</category>
+	</item>
+</channel>
+</rss>`
+
+	t.Run("RSS", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		statusCode, _, _ := ts.postJSON(t, "/v1/articles", map[string]any{
+			"id": 5, "title": "Existing", "date": "2016-09-21", "body": "Already stored", "tags": []string{"health"},
+		})
+		require.Equal(t, http.StatusCreated, statusCode)
+
+		statusCode, _, body := ts.post(t, "/v1/admin/import/feed", "application/rss+xml", strings.NewReader(rss))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"import": {
+			"format": "rss", "created": 1, "skipped": 1, "invalid": 1,
+			"items": [
+				{"item": 1, "title": "Potatoes`+"\u00a0"+`rule", "id": 6, "status": "created",
+					"sanitized": [{"action": "removed_element", "element": "script", "count": 1}]},
+				{"item": 2, "title": "Untagged", "status": "invalid", "errors": {"tags": "must contain at least 1 tag"}},
+				{"item": 3, "title": "Existing", "id": 5, "status": "skipped", "reason": "already stored as article 5"}
+			]
+		}}`, body)
+
+		article, err := app.daos.Articles.Get(6)
+		require.NoError(t, err)
+		assert.Equal(t, "2016-09-22", article.Date.String())
+		assert.Equal(t, "<p>Potatoes are <b>great</b></p>", article.Body)
+		assert.Equal(t, []string{"health", "food"}, article.Tags)
+
+		// Running the import again doesn't store anything twice.
+		statusCode, _, body = ts.post(t, "/v1/admin/import/feed", "application/rss+xml", strings.NewReader(rss))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Contains(t, body, `"created":0,"skipped":2,"invalid":1`)
+	})
+
+	t.Run("Atom", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		atom := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Legacy</title>
+	<entry>
+		<title>Chips</title>
+		<updated>2016-09-23T10:00:00Z</updated>
+		<published>2016-09-20T23:00:00-05:00</published>
+		<category term="food"/>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Crunchy</p></div></content>
+	</entry>
+	<entry>
+		<title>Dips</title>
+		<updated>2016-09-24T10:00:00Z</updated>
+		<category term="food"/>
+		<summary type="html">&lt;p&gt;Creamy&lt;/p&gt;</summary>
+	</entry>
+</feed>`
+
+		var reqBody bytes.Buffer
+		mw := multipart.NewWriter(&reqBody)
+		fw, err := mw.CreateFormFile("file", "legacy.atom")
+		require.NoError(t, err)
+		_, err = io.WriteString(fw, atom)
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+
+		statusCode, _, body := ts.post(t, "/v1/admin/import/feed", mw.FormDataContentType(), &reqBody)
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Contains(t, body, `"format":"atom","created":2,"skipped":0,"invalid":0`)
+
+		chips, err := app.daos.Articles.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "Chips", chips.Title)
+		assert.Equal(t, "2016-09-20", chips.Date.String())
+		assert.Contains(t, chips.Body, "Crunchy")
+
+		dips, err := app.daos.Articles.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "2016-09-24", dips.Date.String())
+		assert.Equal(t, "<p>Creamy</p>", dips.Body)
+	})
+
+	t.Run("WXR", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		wxr := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<wp:wxr_version>1.2</wp:wxr_version>
+	<item>
+		<title>Hello world</title>
+		<pubDate>Thu, 22 Sep 2016 09:30:00 +0000</pubDate>
+		<content:encoded><![CDATA[Welcome to WordPress.]]></content:encoded>
+		<wp:post_id>42</wp:post_id>
+		<wp:post_date>2016-09-22 19:30:00</wp:post_date>
+		<wp:post_date_gmt>2016-09-22 09:30:00</wp:post_date_gmt>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<category domain="category" nicename="news"><![CDATA[news]]></category>
+		<category domain="post_tag" nicename="news"><![CDATA[news]]></category>
+		<category domain="post_tag" nicename="first"><![CDATA[first]]></category>
+	</item>
+	<item>
+		<title>Draft</title>
+		<content:encoded><![CDATA[Not yet]]></content:encoded>
+		<wp:post_id>43</wp:post_id>
+		<wp:post_date_gmt>0000-00-00 00:00:00</wp:post_date_gmt>
+		<wp:status>draft</wp:status>
+		<wp:post_type>post</wp:post_type>
+	</item>
+	<item>
+		<title>logo.png</title>
+		<wp:post_id>44</wp:post_id>
+		<wp:status>inherit</wp:status>
+		<wp:post_type>attachment</wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+		statusCode, _, body := ts.post(t, "/v1/admin/import/feed", "application/xml", strings.NewReader(wxr))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"import": {
+			"format": "wxr", "created": 1, "skipped": 2, "invalid": 0,
+			"items": [
+				{"item": 1, "title": "Hello world", "id": 42, "status": "created"},
+				{"item": 2, "title": "Draft", "status": "skipped", "reason": "not published (status draft)"},
+				{"item": 3, "title": "logo.png", "status": "skipped", "reason": "not a post (type attachment)"}
+			]
+		}}`, body)
+
+		article, err := app.daos.Articles.Get(42)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"news", "first"}, article.Tags)
+		assert.Equal(t, "2016-09-22", article.Date.String())
+	})
+
+	t.Run("BadFiles", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		statusCode, _, body := ts.post(t, "/v1/admin/import/feed", "application/xml", strings.NewReader(`<html><body>Hi</body></html>`))
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		assert.JSONEq(t, `{"error": "not an RSS, Atom or WordPress export file"}`, body)
+
+		statusCode, _, body = ts.post(t, "/v1/admin/import/feed", "application/xml", strings.NewReader(`<rss><channel><item><title>Oops</item>`))
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		assert.Contains(t, body, "badly-formed XML on line 1")
+
+		statusCode, _, body = ts.post(t, "/v1/admin/import/feed", "application/json", strings.NewReader(`{}`))
+		assert.Equal(t, http.StatusUnsupportedMediaType, statusCode)
+		assert.Contains(t, body, "application/rss+xml")
+	})
+
+	t.Run("CLI", func(t *testing.T) {
+		app := newTestApplication(t)
+
+		name := filepath.Join(t.TempDir(), "legacy.xml")
+		require.NoError(t, os.WriteFile(name, []byte(rss), 0o600))
+
+		var out bytes.Buffer
+		require.NoError(t, app.runImport(&out, []string{name}))
+		assert.Contains(t, out.String(), `"created": 2`)
+		assert.Contains(t, out.String(), `"file": "`+name+`"`)
+
+		_, err := app.daos.Articles.Get(1)
+		assert.NoError(t, err)
+
+		err = app.runImport(&out, []string{filepath.Join(t.TempDir(), "missing.xml")})
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestAdminExportImport(t *testing.T) {
+	source := newTestApplication(t)
+	sourceServer := newTestServer(t, source.routes())
+	defer sourceServer.Close()
+	sourceServer.enableAdmin(source)
+	sourceServer.postMockArticles(t)
+
+	stored := source.daos.Articles.GetAll()
+
+	statusCode, header, ndjson := sourceServer.get(t, "/v1/admin/export")
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "application/x-ndjson", header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="articles.ndjson"`, header.Get("Content-Disposition"))
+
+	lines := strings.Split(ndjson, "\n")
+	require.Len(t, lines, len(stored))
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, []string{"body", "date", "id", "tags", "title"}, slices.Sorted(maps.Keys(first)))
+
+	statusCode, header, csvBody := sourceServer.get(t, "/v1/admin/export?format=csv")
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "text/csv", header.Get("Content-Type"))
+
+	rows, err := csv.NewReader(strings.NewReader(csvBody)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, len(stored)+1)
+	assert.Equal(t, []string{"id", "title", "date", "body", "tags"}, rows[0])
+	assert.Equal(t, strings.Join(stored[0].Tags, ";"), rows[1][4])
+
+	statusCode, _, _ = sourceServer.getWithHeader(t, "/v1/admin/export", http.Header{"Accept": {"application/xml"}})
+	assert.Equal(t, http.StatusNotAcceptable, statusCode)
+
+	for _, tt := range []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{name: "NDJSON", contentType: "application/x-ndjson", body: ndjson},
+		{name: "CSV", contentType: "text/csv", body: csvBody},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+			ts.enableAdmin(app)
+
+			statusCode, _, body := ts.post(t, "/v1/admin/import", tt.contentType, strings.NewReader(tt.body))
+			require.Equal(t, http.StatusOK, statusCode)
+			assert.JSONEq(t, fmt.Sprintf(`{"import": {"mode": "fail", "created": %d, "updated": 0, "skipped": 0, "failed": 0, "errors": []}}`, len(stored)), body)
+
+			for _, article := range app.daos.Articles.GetAll() {
+				original, err := source.daos.Articles.Get(article.ID)
+				require.NoError(t, err)
+				assert.Equal(t, newArticleRecord(*original), newArticleRecord(article))
+			}
+
+			// Importing the same records again conflicts with the stored ones.
+			statusCode, _, body = ts.post(t, "/v1/admin/import", tt.contentType, strings.NewReader(tt.body))
+			require.Equal(t, http.StatusConflict, statusCode)
+			assert.Contains(t, body, `"created":0,"updated":0,"skipped":0,"failed":1,"error":"line `)
+
+			statusCode, _, body = ts.post(t, "/v1/admin/import?on_conflict=skip", tt.contentType, strings.NewReader(tt.body))
+			require.Equal(t, http.StatusOK, statusCode)
+			assert.Contains(t, body, fmt.Sprintf(`"created":0,"updated":0,"skipped":%d,"failed":0`, len(stored)))
+		})
+	}
+
+	t.Run("Upsert", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		body := `{"id": 1, "title": "First", "date": "2016-09-22", "body": "Body", "tags": ["a"]}` + "\n" +
+			`{"id": 1, "title": "Second", "date": "2016-09-23", "body": "Body", "tags": ["b"]}` + "\n"
+
+		statusCode, _, respBody := ts.post(t, "/v1/admin/import?on_conflict=upsert", "application/x-ndjson", strings.NewReader(body))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Contains(t, respBody, `"created":1,"updated":1`)
+
+		article, err := app.daos.Articles.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "Second", article.Title)
+		assert.Equal(t, []string{"b"}, article.Tags)
+		require.NotNil(t, article.Metrics)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		body := `{"id": 1, "title": "Valid", "date": "2016-09-22", "body": "Body", "tags": ["a"]}` + "\n" +
+			"\n" +
+			`{"id": 2, "title": "Broken"` + "\n" +
+			`{"id": 3, "title": "Untagged", "date": "2016-09-22", "body": "Body", "tags": []}` + "\n" +
+			`{"id": 4, "colour": "red"}` + "\n" +
+			`{"id": 5, "title": "Valid", "date": "2016-09-22", "body": "Body", "tags": ["a"]}`
+
+		statusCode, _, respBody := ts.post(t, "/v1/admin/import", "application/x-ndjson", strings.NewReader(body))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"import": {
+			"mode": "fail", "created": 2, "updated": 0, "skipped": 0, "failed": 3,
+			"errors": [
+				{"line": 3, "message": "badly-formed JSON"},
+				{"line": 4, "id": 3, "errors": {"tags": "must contain at least 1 tag"}},
+				{"line": 5, "message": "record contains unknown key \"colour\""}
+			]
+		}}`, respBody)
+
+		csvBody := "id,title,date,body,tags\n" +
+			"10,Valid,2016-09-22,\"Multi\nline\",a;b\n" +
+			"eleven,Bad ID,2016-09-22,Body,a\n" +
+			"12,Short row\n" +
+			"13,Bad date,22/09/2016,Body,a\n"
+
+		statusCode, _, respBody = ts.post(t, "/v1/admin/import", "text/csv", strings.NewReader(csvBody))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"import": {
+			"mode": "fail", "created": 1, "updated": 0, "skipped": 0, "failed": 3,
+			"errors": [
+				{"line": 4, "message": "record contains incorrect CSV type for field \"id\""},
+				{"line": 5, "message": "badly-formed CSV (wrong number of fields)"},
+				{"line": 6, "message": "invalid date format"}
+			]
+		}}`, respBody)
+
+		article, err := app.daos.Articles.Get(10)
+		require.NoError(t, err)
+		assert.Equal(t, "Multi\nline", article.Body)
+		assert.Equal(t, []string{"a", "b"}, article.Tags)
+
+		statusCode, _, respBody = ts.post(t, "/v1/admin/import", "text/csv", strings.NewReader("id,colour\n1,red\n"))
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		assert.JSONEq(t, `{"error": "body contains unknown column \"colour\""}`, respBody)
+
+		statusCode, _, _ = ts.post(t, "/v1/admin/import?on_conflict=replace", "application/x-ndjson", strings.NewReader(""))
+		assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+
+		statusCode, _, respBody = ts.post(t, "/v1/admin/import", "application/json", strings.NewReader("[]"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, statusCode)
+		assert.Contains(t, respBody, "application/x-ndjson, text/csv")
+
+		long := `{"title": "` + strings.Repeat("a", maxRequestBytes) + `"}`
+		statusCode, _, respBody = ts.post(t, "/v1/admin/import?on_conflict=skip", "application/x-ndjson", strings.NewReader(body+"\n"+long))
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		assert.Contains(t, respBody, `"error":"line 7: must not be longer than 1048576 bytes"`)
+	})
+}
+
+func TestSyncDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "chips.md"), []byte("---\nid: 1\ntitle: Chips\ndate: 2016-09-22\ntags: [food]\n---\nCrunchy <script>x</script>\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dips.md"), []byte("---\nid: 2\ntitle: Dips\n---\nCreamy\n"), 0o600))
+
+	app := newTestApplication(t)
+	app.config.sync.dir = dir
+	app.config.sync.interval = 10 * time.Millisecond
+
+	t.Run("DryRun", func(t *testing.T) {
+		app.config.sync.dryRun = true
+		defer func() { app.config.sync.dryRun = false }()
+
+		var out bytes.Buffer
+		require.NoError(t, app.runSync(&out))
+		assert.Equal(t, `+ 1 "Chips" (chips.md)
+! dips.md: date must be provided and valid; tags must be provided
+would create 1, update 0, delete 0; 1 files with problems
+`, out.String())
+		assert.Empty(t, app.daos.Articles.GetAll())
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		app.watchSyncDir()
+
+		require.Eventually(t, func() bool {
+			statusCode, _, _ := ts.get(t, "/v1/articles/1")
+			return statusCode == http.StatusOK
+		}, time.Second, 10*time.Millisecond)
+
+		// Bodies are sanitized as they are on create.
+		article, err := app.daos.Articles.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "Crunchy ", article.Body)
+	})
+}
+
+func TestImportNearDuplicates(t *testing.T) {
+	// Imports and syncs apply the near-duplicate policy to the articles they
+	// create, as creates do.
+	bird := "A new species of bird has been found in the Pacific by a team of scientists."
+	rocket := "Space agencies have launched a new rocket to Mars carrying a rover and a small helicopter."
+
+	newApp := func(t *testing.T) *application {
+		app := newTestApplication(t)
+		app.config.dedupe.policy = dedupePolicyReject
+		require.NoError(t, app.daos.Articles.Insert(&data.Article{
+			ID: 1, Title: "Bird found", Date: data.ArticleDate(time.Date(2016, 9, 22, 0, 0, 0, 0, time.UTC)), Body: bird, Tags: []string{"science"},
+		}))
+		return app
+	}
+
+	t.Run("Feed", func(t *testing.T) {
+		app := newApp(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		rss := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Legacy</title>
+	<item><title>Bird found</title><pubDate>Wed, 21 Sep 2016 09:30:00 GMT</pubDate><description>` + bird + `</description><category>science</category></item>
+	<item><title>Rocket launched</title><pubDate>Fri, 23 Sep 2016 09:30:00 GMT</pubDate><description>` + rocket + `</description><category>science</category></item>
+	<item><title>Rocket launched</title><pubDate>Sat, 24 Sep 2016 09:30:00 GMT</pubDate><description>` + rocket + `!</description><category>science</category></item>
+</channel></rss>`
+
+		statusCode, _, body := ts.post(t, "/v1/admin/import/feed", "application/rss+xml", strings.NewReader(rss))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"import": {
+			"format": "rss", "created": 1, "skipped": 2, "invalid": 0,
+			"items": [
+				{"item": 1, "title": "Bird found", "id": 1, "status": "skipped", "reason": "near-duplicate of article 1"},
+				{"item": 2, "title": "Rocket launched", "id": 2, "status": "created"},
+				{"item": 3, "title": "Rocket launched", "id": 2, "status": "skipped", "reason": "near-duplicate of article 2"}
+			]
+		}}`, body)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		app := newApp(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+		ts.enableAdmin(app)
+
+		records := fmt.Sprintf(`{"id": 2, "title": "Bird found", "date": "2016-09-22", "body": %q, "tags": ["science"]}`, bird) + "\n" +
+			fmt.Sprintf(`{"id": 3, "title": "Rocket launched", "date": "2016-09-23", "body": %q, "tags": ["science"]}`, rocket) + "\n" +
+			fmt.Sprintf(`{"id": 4, "title": "Rocket launched", "date": "2016-09-24", "body": %q, "tags": ["science"]}`, rocket+"!") + "\n"
+
+		statusCode, _, body := ts.post(t, "/v1/admin/import", "application/x-ndjson", strings.NewReader(records))
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"import": {
+			"mode": "fail", "created": 1, "updated": 0, "skipped": 0, "failed": 2,
+			"errors": [
+				{"line": 1, "id": 2, "message": "article is a near-duplicate of existing article 1"},
+				{"line": 3, "id": 4, "message": "article is a near-duplicate of existing article 3"}
+			]
+		}}`, body)
+	})
+
+	t.Run("Sync", func(t *testing.T) {
+		app := newApp(t)
+		app.config.sync.dir = t.TempDir()
+
+		files := map[string]string{
+			"a.md": "---\nid: 2\ntitle: Bird found\ndate: 2016-09-22\ntags: [science]\n---\n" + bird + "\n",
+			"b.md": "---\nid: 3\ntitle: Rocket launched\ndate: 2016-09-23\ntags: [science]\n---\n" + rocket + "\n",
+			"c.md": "---\nid: 4\ntitle: Rocket launched\ndate: 2016-09-24\ntags: [science]\n---\n" + rocket + "!\n",
+		}
+		for name, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(app.config.sync.dir, name), []byte(content), 0o600))
+		}
+
+		var out bytes.Buffer
+		require.NoError(t, app.runSync(&out))
+		assert.Equal(t, `+ 3 "Rocket launched" (b.md)
+! a.md: article is a near-duplicate of existing article 1
+! c.md: article is a near-duplicate of existing article 3
+created 1, updated 0, deleted 0; 2 files with problems
+`, out.String())
+
+		// Articles already stored are updated whatever they resemble.
+		require.NoError(t, os.WriteFile(filepath.Join(app.config.sync.dir, "a.md"), []byte(strings.Replace(files["a.md"], "id: 2", "id: 1", 1)), 0o600))
+		require.NoError(t, os.Remove(filepath.Join(app.config.sync.dir, "c.md")))
+
+		out.Reset()
+		require.NoError(t, app.runSync(&out))
+		assert.Equal(t, "created 0, updated 0, deleted 0; 0 files with problems\n", out.String())
+	})
+}
+
+func TestStaticSite(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.baseURL = "https://example.com/archive"
+	app.config.site.dir = t.TempDir()
+	app.config.site.pageSize = 2
+
+	articles := []data.Article{
+		{ID: 1, Title: "Chips", Date: data.ArticleDate(time.Date(2016, 9, 22, 0, 0, 0, 0, time.UTC)), Body: "Salty *chips*.", Tags: []string{"food", "snacks"}},
+		{ID: 2, Title: "Dips", Date: data.ArticleDate(time.Date(2016, 9, 22, 0, 0, 0, 0, time.UTC)), Body: "Creamy dips.", Tags: []string{"food"}},
+		{ID: 3, Title: "Running", Date: data.ArticleDate(time.Date(2016, 9, 23, 0, 0, 0, 0, time.UTC)), Body: "Running <b>fast</b>.", Tags: []string{"health & fitness"}},
+	}
+	for i := range articles {
+		require.NoError(t, app.daos.Articles.Insert(&articles[i]))
+	}
+
+	readPage := func(t *testing.T, path string) string {
+		t.Helper()
+		page, err := os.ReadFile(filepath.Join(app.config.site.dir, filepath.FromSlash(path)))
+		require.NoError(t, err)
+		return string(page)
+	}
+
+	t.Run("Build", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, app.buildSite(&out))
+		assert.Equal(t, "24 written, 0 unchanged, 0 removed\n", out.String())
+
+		index := readPage(t, "index.html")
+		assert.Contains(t, index, `<a href="./articles/3/">Running</a>`)
+		assert.Contains(t, index, `<a rel="next" href="./page/2/">Older</a>`)
+		assert.NotContains(t, index, "Chips")
+		assert.Contains(t, readPage(t, "page/2/index.html"), `<a href="../../articles/1/">Chips</a>`)
+
+		article := readPage(t, "articles/1/index.html")
+		assert.Contains(t, article, "<p>Salty <em>chips</em>.</p>")
+		assert.Contains(t, article, `<a class="tag" href="../../tags/snacks/">snacks</a>`)
+		assert.Contains(t, readPage(t, "articles/3/index.html"), `<a class="tag" href="../../tags/health-fitness/">health &amp; fitness</a>`)
+
+		tagDate := readPage(t, "tags/food/20160922/index.html")
+		assert.Contains(t, tagDate, "<dt>Tags</dt><dd>2</dd>")
+		assert.Contains(t, tagDate, `<a href="../../../tags/snacks/">snacks</a>`)
+
+		assert.Contains(t, readPage(t, "tags/index.html"), `<a href="../tags/food/">food</a> (2)`)
+		assert.Contains(t, readPage(t, "tags/food/feed.atom"), "<id>https://example.com/archive/articles/2/</id>")
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, app.buildSite(&out))
+		assert.Equal(t, "0 written, 24 unchanged, 0 removed\n", out.String())
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		article := articles[1]
+		article.Title = "Salsa"
+		require.NoError(t, app.daos.Articles.Update(&article))
+
+		// Only the article's page and the pages and feeds listing it.
+		var out bytes.Buffer
+		require.NoError(t, app.buildSite(&out))
+		assert.Equal(t, "10 written, 14 unchanged, 0 removed\n", out.String())
+		assert.Contains(t, readPage(t, "index.html"), "Salsa")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, app.daos.Articles.Delete(3))
+
+		var out bytes.Buffer
+		require.NoError(t, app.buildSite(&out))
+		assert.Contains(t, out.String(), "removed")
+		assert.NoDirExists(t, filepath.Join(app.config.site.dir, "articles", "3"))
+		assert.NoDirExists(t, filepath.Join(app.config.site.dir, "tags", "health-fitness"))
+		assert.NotContains(t, readPage(t, "index.html"), "Running")
+	})
+
+	t.Run("NoBaseURL", func(t *testing.T) {
+		app.config.baseURL = ""
+		defer func() { app.config.baseURL = "https://example.com/archive" }()
+
+		assert.Error(t, app.buildSite(io.Discard))
+	})
+}
+
+func TestAdminAuthentication(t *testing.T) {
+	endpoints := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/v1/admin/export"},
+		{http.MethodPost, "/v1/admin/import"},
+		{http.MethodPost, "/v1/admin/import/feed"},
+	}
+
+	request := func(t *testing.T, ts *testServer, method, path string, header http.Header) (int, http.Header, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(""))
+		require.NoError(t, err)
+		req.Header = header
+		req.Header.Set("Content-Type", "application/x-ndjson")
+
+		rs, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer rs.Body.Close()
+
+		body, err := io.ReadAll(rs.Body)
+		require.NoError(t, err)
+		return rs.StatusCode, rs.Header, string(bytes.TrimSpace(body))
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		app := newTestApplication(t)
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		for _, endpoint := range endpoints {
+			statusCode, _, body := request(t, ts, endpoint.method, endpoint.path, http.Header{"Authorization": {"Bearer "}})
+			assert.Equal(t, http.StatusForbidden, statusCode, endpoint.path)
+			assert.JSONEq(t, `{"error": "the admin endpoints are disabled, set an admin token to enable them"}`, body)
+		}
+	})
+
+	t.Run("Token", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.admin.token = "s3cret"
+		app.config.validateRequests = true
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		tests := []struct {
+			name           string
+			authorization  string
+			expectedStatus int
+		}{
+			{name: "Missing", expectedStatus: http.StatusUnauthorized},
+			{name: "Wrong", authorization: "Bearer guess", expectedStatus: http.StatusUnauthorized},
+			{name: "Wrong Scheme", authorization: "Basic s3cret", expectedStatus: http.StatusUnauthorized},
+			{name: "Prefix", authorization: "Bearer s3cre", expectedStatus: http.StatusUnauthorized},
+			{name: "Valid", authorization: "Bearer s3cret", expectedStatus: http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				header := http.Header{}
+				if tt.authorization != "" {
+					header.Set("Authorization", tt.authorization)
+				}
+
+				statusCode, respHeader, body := request(t, ts, http.MethodGet, "/v1/admin/export", header)
+				assert.Equal(t, tt.expectedStatus, statusCode)
+				if tt.expectedStatus == http.StatusUnauthorized {
+					assert.Equal(t, "Bearer", respHeader.Get("WWW-Authenticate"))
+					assert.JSONEq(t, `{"error": "invalid or missing admin token"}`, body)
+				}
+			})
+		}
+
+		// The token is checked before the request is validated.
+		statusCode, _, _ := request(t, ts, http.MethodPost, "/v1/admin/import?on_conflict=replace", http.Header{})
+		assert.Equal(t, http.StatusUnauthorized, statusCode)
+		statusCode, _, _ = request(t, ts, http.MethodPost, "/v1/admin/import?on_conflict=replace", http.Header{"Authorization": {"Bearer s3cret"}})
+		assert.Equal(t, http.StatusUnprocessableEntity, statusCode)
+	})
+
+	t.Run("OpenAPI", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.routes()
+		doc, err := app.apiDocument()
+		require.NoError(t, err)
+
+		for _, endpoint := range endpoints {
+			op := doc.Operation(endpoint.method, endpoint.path)
+			require.NotNil(t, op, endpoint.path)
+			assert.Equal(t, []map[string][]string{{"adminToken": {}}}, op.Security)
+			assert.Contains(t, op.Responses, "401")
+			assert.Contains(t, op.Responses, "403")
+		}
+		assert.Nil(t, doc.Operation(http.MethodGet, "/v1/articles").Security)
+	})
+}
+
+func TestCreateArticleDuplicateID(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	article := map[string]any{
+		"id":    1,
+		"title": "chips",
+		"date":  "2016-09-22",
+		"body":  "potato chips are tasty",
+		"tags":  []string{"food"},
+	}
+
+	statusCode, _, _ := ts.postJSON(t, "/v1/articles", article)
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	article["title"] = "different chips"
+	article["body"] = "an entirely different article about salt"
+	statusCode, _, body := ts.postJSON(t, "/v1/articles", article)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.JSONEq(t, `{"error": "article already exists"}`, body)
+
+	stored, err := app.daos.Articles.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "chips", stored.Title)
+}
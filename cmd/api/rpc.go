@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/jsonrpc"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// Error codes of the RPC methods, in the range JSON-RPC leaves to servers.
+// They match the HTTP status the REST API would respond with.
+const (
+	rpcCodeNotFound = -32004
+	rpcCodeConflict = -32009
+)
+
+// rpcHandler carries out a JSON-RPC 2.0 call, or batch of calls. Calls are
+// answered with 200 OK, whether they succeed or not, except that a request
+// made only of notifications gets 204 No Content.
+func (app *application) rpcHandler(w http.ResponseWriter, r *http.Request) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			app.unsupportedMediaTypeResponse(w, r, []string{"application/json"})
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, app.contextGetBodyLimit(r))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		app.badRequestResponse(w, r, bodyReadError(err))
+		return
+	}
+
+	resp := app.rpc.Handle(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, resp, nil, app.prettyJSON(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rpcValidationError reports parameters that failed validation, keyed by
+// parameter as in a 422 response.
+func rpcValidationError(errs map[string]string) error {
+	return jsonrpc.NewError(jsonrpc.CodeInvalidParams, errs)
+}
+
+// rpcNotFoundError reports that the resource a method asked for doesn't exist.
+func rpcNotFoundError() error {
+	return &jsonrpc.Error{Code: rpcCodeNotFound, Message: "the requested resource could not be found"}
+}
+
+// newRPCServer builds the JSON-RPC server served at /rpc. Its methods validate
+// their parameters and use the store as the equivalent REST handlers do.
+func (app *application) newRPCServer() *jsonrpc.Server {
+	server := jsonrpc.NewServer()
+	server.MaxBatch = app.config.batch.maxItems
+	server.ErrorLog = func(method string, err error) {
+		app.logger.Error(err.Error(), "rpc_method", method)
+	}
+
+	server.Register("articles.create", jsonrpc.Method{
+		Params:  []string{"id", "title", "date", "body", "tags", "include"},
+		Handler: app.rpcCreateArticle,
+	})
+	server.Register("articles.get", jsonrpc.Method{
+		Params:  []string{"id", "include", "body_format"},
+		Handler: app.rpcGetArticle,
+	})
+	server.Register("articles.getMany", jsonrpc.Method{
+		Params:  []string{"ids", "include"},
+		Handler: app.rpcGetArticles,
+	})
+	server.Register("articles.list", jsonrpc.Method{
+		Handler: app.rpcListArticles,
+	})
+	server.Register("tags.summary", jsonrpc.Method{
+		Params:  []string{"tag", "date"},
+		Handler: app.rpcSummarizeTag,
+	})
+	server.Register("tags.list", jsonrpc.Method{
+		Handler: app.rpcListTags,
+	})
+
+	return server
+}
+
+// rpcCreateArticle creates an article as POST /v1/articles does, returning the
+// same object as its response.
+func (app *application) rpcCreateArticle(ctx context.Context, params json.RawMessage) (any, error) {
+	var input struct {
+		articleRecord
+		Include []string `json:"include"`
+	}
+
+	err := jsonrpc.DecodeParams(params, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+	if validateIncludes(v, input.Include); !v.Valid() {
+		return nil, rpcValidationError(v.Errors)
+	}
+
+	article := &data.Article{
+		ID:    input.ID,
+		Title: input.Title,
+		Date:  input.Date,
+		Body:  input.Body,
+		Tags:  input.Tags,
+	}
+
+	prepared, err := app.createArticle(v, article)
+	switch {
+	case !v.Valid():
+		return nil, rpcValidationError(v.Errors)
+	case errors.Is(err, data.ErrNearDuplicate):
+		return nil, &jsonrpc.Error{
+			Code:    rpcCodeConflict,
+			Message: err.Error(),
+			Data: map[string]any{
+				"existing_id": prepared.duplicates[0].ID,
+				"similarity":  prepared.duplicates[0].Similarity,
+			},
+		}
+	case errors.Is(err, data.ErrDuplicateID):
+		return nil, &jsonrpc.Error{Code: rpcCodeConflict, Message: "article already exists"}
+	case err != nil:
+		return nil, err
+	}
+
+	applyIncludes(article, input.Include)
+
+	return prepared.envelope(article), nil
+}
+
+// rpcGetArticle returns an article as GET /v1/articles/:id does.
+func (app *application) rpcGetArticle(ctx context.Context, params json.RawMessage) (any, error) {
+	input := struct {
+		ID         int64    `json:"id"`
+		Include    []string `json:"include"`
+		BodyFormat string   `json:"body_format"`
+	}{BodyFormat: "raw"}
+
+	err := jsonrpc.DecodeParams(params, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+	v.Check(input.ID > 0, "id", "must be a positive integer")
+	validateIncludes(v, input.Include)
+	validateBodyFormat(v, input.BodyFormat)
+
+	if !v.Valid() {
+		return nil, rpcValidationError(v.Errors)
+	}
+
+	article, err := app.daos.Articles.Get(input.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return nil, rpcNotFoundError()
+		default:
+			return nil, err
+		}
+	}
+
+	applyIncludes(article, input.Include)
+	app.applyBodyFormat(article, input.BodyFormat)
+
+	return article, nil
+}
+
+// rpcGetArticles returns the articles with the given IDs as
+// POST /v1/articles/batch-get does.
+func (app *application) rpcGetArticles(ctx context.Context, params json.RawMessage) (any, error) {
+	var input struct {
+		IDs     []int64  `json:"ids"`
+		Include []string `json:"include"`
+	}
+
+	err := jsonrpc.DecodeParams(params, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := uniqueIDs(input.IDs)
+
+	v := validator.New()
+	for _, id := range ids {
+		if id < 1 {
+			v.AddError("ids", "must only contain positive integers")
+		}
+	}
+	v.Check(len(ids) > 0, "ids", "must contain at least 1 ID")
+	v.Check(len(ids) <= app.config.batch.maxItems, "ids", fmt.Sprintf("must not contain more than %d IDs", app.config.batch.maxItems))
+	validateIncludes(v, input.Include)
+
+	if !v.Valid() {
+		return nil, rpcValidationError(v.Errors)
+	}
+
+	articles, missing := app.daos.Articles.GetMany(ids)
+	for i := range articles {
+		applyIncludes(&articles[i], input.Include)
+	}
+
+	return envelope{"articles": articles, "missing_ids": missing}, nil
+}
+
+// rpcListArticles returns a page of articles as GET /v1/articles does.
+func (app *application) rpcListArticles(ctx context.Context, params json.RawMessage) (any, error) {
+	input := struct {
+		Language       string   `json:"language"`
+		MinWordCount   int      `json:"min_word_count"`
+		MaxWordCount   int      `json:"max_word_count"`
+		MaxReadingTime int      `json:"max_reading_time"`
+		MinReadingEase *float64 `json:"min_reading_ease"`
+		MaxReadingEase *float64 `json:"max_reading_ease"`
+		Page           int      `json:"page"`
+		PageSize       int      `json:"page_size"`
+		Include        []string `json:"include"`
+	}{Page: 1, PageSize: 20}
+
+	err := jsonrpc.DecodeParams(params, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := data.ArticleFilter{
+		Language:       input.Language,
+		MinWordCount:   input.MinWordCount,
+		MaxWordCount:   input.MaxWordCount,
+		MaxReadingTime: input.MaxReadingTime,
+		MinReadingEase: input.MinReadingEase,
+		MaxReadingEase: input.MaxReadingEase,
+	}
+	filters := data.Filters{Page: input.Page, PageSize: input.PageSize}
+
+	v := validator.New()
+	data.ValidateArticleFilter(v, filter)
+	data.ValidateFilters(v, filters)
+	validateIncludes(v, input.Include)
+
+	if !v.Valid() {
+		return nil, rpcValidationError(v.Errors)
+	}
+
+	articles, metadata := app.daos.Articles.List(filter, filters)
+	for i := range articles {
+		applyIncludes(&articles[i], input.Include)
+	}
+
+	return envelope{"articles": articles, "metadata": metadata}, nil
+}
+
+// rpcSummarizeTag summarises the articles with a tag on a date as
+// GET /v1/tags/:tagName/:date does.
+func (app *application) rpcSummarizeTag(ctx context.Context, params json.RawMessage) (any, error) {
+	var input struct {
+		Tag  string           `json:"tag"`
+		Date data.ArticleDate `json:"date"`
+	}
+
+	err := jsonrpc.DecodeParams(params, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+	v.Check(input.Tag != "", "tag", "must be provided")
+	v.Check(!time.Time(input.Date).IsZero(), "date", "must be provided and valid")
+
+	if !v.Valid() {
+		return nil, rpcValidationError(v.Errors)
+	}
+
+	tagSummary, err := app.summarizeTag(input.Tag, input.Date)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return nil, rpcNotFoundError()
+		default:
+			return nil, err
+		}
+	}
+
+	return tagSummary, nil
+}
+
+// rpcListTags lists every tag in the store, in alphabetical order, with the
+// number of articles that have it.
+func (app *application) rpcListTags(ctx context.Context, params json.RawMessage) (any, error) {
+	err := jsonrpc.DecodeParams(params, &struct{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	return app.daos.Articles.TagCounts(), nil
+}
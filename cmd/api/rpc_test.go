@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPC(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	// call posts a JSON-RPC request and returns the response status code and
+	// body.
+	call := func(t *testing.T, request string) (int, string) {
+		code, _, body := ts.post(t, "/rpc", "application/json", strings.NewReader(request))
+		return code, body
+	}
+
+	tests := []struct {
+		name         string
+		request      string
+		expectedBody string
+	}{
+		{
+			name:         "NotFound",
+			request:      `{"jsonrpc": "2.0", "method": "articles.get", "params": {"id": 999}, "id": 1}`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32004, "message": "the requested resource could not be found"}, "id": 1}`,
+		},
+		{
+			name:         "InvalidParams",
+			request:      `{"jsonrpc": "2.0", "method": "articles.get", "params": {"id": 0, "body_format": "pdf"}, "id": 1}`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": {"id": "must be a positive integer", "body_format": "must be one of raw, html or text"}}, "id": 1}`,
+		},
+		{
+			name:         "UnknownParam",
+			request:      `{"jsonrpc": "2.0", "method": "tags.list", "params": {"tag": "science"}, "id": 1}`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": "params contain unknown key \"tag\""}, "id": 1}`,
+		},
+		{
+			name:         "TooManyParams",
+			request:      `{"jsonrpc": "2.0", "method": "tags.summary", "params": ["science", "2016-09-22", 1], "id": 1}`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": "params must not contain more than 2 values"}, "id": 1}`,
+		},
+		{
+			name:         "MethodNotFound",
+			request:      `{"jsonrpc": "2.0", "method": "articles.delete", "params": [1], "id": 1}`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found", "data": "method \"articles.delete\" does not exist"}, "id": 1}`,
+		},
+		{
+			name:         "WrongVersion",
+			request:      `{"jsonrpc": "1.0", "method": "tags.list", "id": 1}`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "jsonrpc must be \"2.0\""}, "id": 1}`,
+		},
+		{
+			name:         "InvalidID",
+			request:      `{"jsonrpc": "2.0", "method": "tags.list", "id": {}}`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "id must be a string, number or null"}, "id": null}`,
+		},
+		{
+			name:         "ParseError",
+			request:      `{"jsonrpc": "2.0", "method": "tags.list", "id": 1`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error", "data": "body contains badly-formed JSON"}, "id": null}`,
+		},
+		{
+			name:         "EmptyBatch",
+			request:      `[]`,
+			expectedBody: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "batch must contain at least 1 call"}, "id": null}`,
+		},
+		{
+			name:    "Batch",
+			request: `[{"jsonrpc": "2.0", "method": "articles.get", "params": [6], "id": 1}, {"jsonrpc": "2.0", "method": "articles.get", "params": [999]}, 1, {"jsonrpc": "2.0", "method": "articles.get", "params": [999], "id": 2}]`,
+			expectedBody: `[
+				{"jsonrpc": "2.0", "result": {"id": 6, "title": "A New Beginning", "date": "2021-01-02", "body": "This is the second article in the system.", "tags": ["welcome", "second"]}, "id": 1},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "request must be an object"}, "id": null},
+				{"jsonrpc": "2.0", "error": {"code": -32004, "message": "the requested resource could not be found"}, "id": 2}
+			]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := call(t, tt.request)
+			assert.Equal(t, http.StatusOK, code)
+			assert.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+
+	t.Run("MatchesREST", func(t *testing.T) {
+		// result returns the result of a call, after checking that it succeeded.
+		result := func(t *testing.T, request string) json.RawMessage {
+			code, body := call(t, request)
+			require.Equal(t, http.StatusOK, code)
+
+			var response struct {
+				Result json.RawMessage `json:"result"`
+				Error  any             `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(body), &response), body)
+			require.Nil(t, response.Error)
+			return response.Result
+		}
+
+		_, _, body := ts.get(t, "/v1/articles/1?body_format=text&include=metrics")
+		expected := `{"article": ` + string(result(t, `{"jsonrpc": "2.0", "method": "articles.get", "params": {"id": 1, "body_format": "text", "include": ["metrics"]}, "id": 1}`)) + `}`
+		assert.JSONEq(t, expected, body)
+
+		// Parameters may be passed by position.
+		_, _, body = ts.get(t, "/v1/articles/5")
+		expected = `{"article": ` + string(result(t, `{"jsonrpc": "2.0", "method": "articles.get", "params": [5], "id": "5"}`)) + `}`
+		assert.JSONEq(t, expected, body)
+
+		_, _, body = ts.get(t, "/v1/tags/science/20160922")
+		expected = `{"tag_summary": ` + string(result(t, `{"jsonrpc": "2.0", "method": "tags.summary", "params": ["science", "2016-09-22"], "id": 1}`)) + `}`
+		compareJSONBodies(t, expected, body)
+
+		_, _, body = ts.get(t, "/v1/articles?page=2&page_size=2&max_word_count=10")
+		expected = string(result(t, `{"jsonrpc": "2.0", "method": "articles.list", "params": {"page": 2, "page_size": 2, "max_word_count": 10}, "id": 1}`))
+		assert.JSONEq(t, expected, body)
+
+		_, _, body = ts.get(t, "/v1/articles?ids=2,999,2")
+		expected = string(result(t, `{"jsonrpc": "2.0", "method": "articles.getMany", "params": [[2, 999, 2]], "id": 1}`))
+		assert.JSONEq(t, expected, body)
+
+		var tags []data.TagCount
+		require.NoError(t, json.Unmarshal(result(t, `{"jsonrpc": "2.0", "method": "tags.list", "id": 1}`), &tags))
+		assert.Contains(t, tags, data.TagCount{Tag: "science", Count: 6})
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		code, body := call(t, `{"jsonrpc": "2.0", "method": "articles.create", "params": {"id": 100, "title": "Carrots", "date": "2016-09-23", "body": "Carrots are <b>crunchy</b><script>x</script>.", "tags": ["food"], "include": ["metrics"]}, "id": 1}`)
+		assert.Equal(t, http.StatusOK, code)
+
+		var response struct {
+			Result map[string]any `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(body), &response), body)
+		article := response.Result["article"].(map[string]any)
+		assert.Equal(t, "Carrots are <b>crunchy</b>.", article["body"])
+		assert.Contains(t, article, "metrics")
+		assert.Contains(t, response.Result, "sanitized")
+
+		// The article is stored as if it had been created through REST.
+		code, _, body = ts.get(t, "/v1/articles/100")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, body, `"title":"Carrots"`)
+
+		code, body = call(t, `{"jsonrpc": "2.0", "method": "articles.create", "params": {"id": 100, "title": "Carrots", "date": "2016-09-23", "body": "Carrots.", "tags": ["food"]}, "id": 2}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"jsonrpc": "2.0", "error": {"code": -32009, "message": "article already exists"}, "id": 2}`, body)
+
+		code, body = call(t, `{"jsonrpc": "2.0", "method": "articles.create", "params": {"id": 101, "title": "", "date": "2016-09-23", "body": "Carrots.", "tags": []}, "id": 3}`)
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": {"title": "must be provided", "tags": "must contain at least 1 tag"}}, "id": 3}`, body)
+	})
+
+	t.Run("Notifications", func(t *testing.T) {
+		code, body := call(t, `[{"jsonrpc": "2.0", "method": "articles.create", "params": {"id": 102, "title": "Beans", "date": "2016-09-23", "body": "Beans.", "tags": ["food"]}}, {"jsonrpc": "2.0", "method": "articles.delete"}]`)
+		assert.Equal(t, http.StatusNoContent, code)
+		assert.Empty(t, body)
+
+		code, _, _ = ts.get(t, "/v1/articles/102")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("MalformedRequests", func(t *testing.T) {
+		code, _, body := ts.post(t, "/rpc", "text/plain", strings.NewReader(`{}`))
+		assert.Equal(t, http.StatusUnsupportedMediaType, code, body)
+
+		code, _, body = ts.get(t, "/rpc")
+		assert.Equal(t, http.StatusMethodNotAllowed, code, body)
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// sanitizeBody removes the HTML the configured policy doesn't allow from an
+// article's body and returns the changes made. Under the reject policy a body
+// that would lose content is left alone and an error is added to v instead.
+func (app *application) sanitizeBody(v *validator.Validator, article *data.Article) []markup.Change {
+	body, changes := markup.Sanitize(article.Body, app.htmlPolicy())
+
+	if app.config.html.policy == htmlPolicyReject {
+		var removed []string
+		for _, c := range changes {
+			if c.Removal() {
+				removed = append(removed, describeChange(c))
+			}
+		}
+		if len(removed) > 0 {
+			v.AddError("body", "must not contain "+strings.Join(removed, ", "))
+			return nil
+		}
+	}
+
+	article.Body = body
+	return changes
+}
+
+// htmlPolicy returns the configured policy for HTML in article bodies, which
+// bodies are sanitized and rendered with.
+func (app *application) htmlPolicy() markup.Policy {
+	return markup.Policy{
+		Elements:      app.config.html.elements,
+		InternalHosts: app.config.html.internalHosts,
+	}
+}
+
+// describeChange names the HTML a removal change is about.
+func describeChange(c markup.Change) string {
+	switch c.Action {
+	case markup.RemovedComment:
+		return "HTML comments"
+	case markup.RemovedAttribute:
+		return fmt.Sprintf("the %s attribute on <%s>", c.Attribute, c.Element)
+	}
+	return fmt.Sprintf("<%s> elements", c.Element)
+}
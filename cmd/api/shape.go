@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/codec"
+)
+
+// unshapedKeys are the envelope keys that don't hold resources, which the
+// fields and expand query parameters leave alone.
+var unshapedKeys = []string{"metadata", "error"}
+
+// expander resolves a list of IDs in a response to the objects they identify.
+type expander func(r *http.Request, ids []int64) (any, error)
+
+// expanders returns the expanders for the members of a response that can be
+// expanded, keyed by the members' names as given to the expand query
+// parameter.
+func (app *application) expanders() map[string]expander {
+	return map[string]expander{
+		"articles": app.expandArticles,
+	}
+}
+
+// expandArticles returns the articles with the IDs, leaving out any that are
+// no longer stored. Like other article responses, they only have metrics if
+// the request includes them.
+func (app *application) expandArticles(r *http.Request, ids []int64) (any, error) {
+	include := app.readCSV(r.URL.Query(), "include", nil)
+
+	articles, _ := app.daos.Articles.GetMany(ids)
+	for i := range articles {
+		applyIncludes(&articles[i], include)
+	}
+
+	return codec.Normalize(articles)
+}
+
+// shapeResponse applies the fields and expand query parameters to a response,
+// so that every endpoint supports them. expand lists members holding IDs to
+// replace with the objects they identify, such as "articles" in a tag
+// summary. fields then keeps only the listed members of each resource in the
+// response, with dotted paths selecting the members of nested objects, e.g.
+// "articles.title". Names that don't match anything are ignored.
+func (app *application) shapeResponse(r *http.Request, data envelope) (envelope, error) {
+	qs := r.URL.Query()
+	fields := app.readCSV(qs, "fields", nil)
+	expand := app.readCSV(qs, "expand", nil)
+
+	if len(fields) == 0 && len(expand) == 0 {
+		return data, nil
+	}
+
+	expanders := app.expanders()
+
+	shaped := make(envelope, len(data))
+	for key, value := range data {
+		if slices.Contains(unshapedKeys, key) {
+			shaped[key] = value
+			continue
+		}
+
+		// The envelope's own members can be expanded too.
+		tree, err := codec.Normalize(envelope{key: value})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range expand {
+			name = strings.TrimSpace(name)
+			if expander, ok := expanders[name]; ok {
+				tree, err = expandMembers(r, tree, name, expander)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		value, _ = tree.(codec.Object).Get(key)
+		if len(fields) > 0 {
+			value = codec.Project(value, fields)
+		}
+		shaped[key] = value
+	}
+
+	return shaped, nil
+}
+
+// expandMembers replaces the lists of IDs held by members called name,
+// anywhere in a normalised tree, with what expand resolves them to.
+func expandMembers(r *http.Request, v any, name string, expand expander) (any, error) {
+	switch value := v.(type) {
+	case codec.Object:
+		obj := make(codec.Object, len(value))
+		for i, m := range value {
+			var err error
+			if ids, ok := idList(m.Value); ok && m.Key == name {
+				m.Value, err = expand(r, ids)
+			} else {
+				m.Value, err = expandMembers(r, m.Value, name, expand)
+			}
+			if err != nil {
+				return nil, err
+			}
+			obj[i] = m
+		}
+		return obj, nil
+	case []any:
+		list := make([]any, len(value))
+		for i, item := range value {
+			var err error
+			list[i], err = expandMembers(r, item, name, expand)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+
+	return v, nil
+}
+
+// idList returns the IDs in a normalised list of integers.
+func idList(v any) ([]int64, bool) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	ids := make([]int64, len(list))
+	for i, item := range list {
+		id, ok := item.(int64)
+		if !ok {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseShaping(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.postMockArticles(t)
+
+	tests := []struct {
+		name         string
+		url          string
+		expectedBody string
+	}{
+		{
+			name:         "Fields",
+			url:          "/v1/articles/1?fields=id,title,date",
+			expectedBody: `{"article": {"id": 1, "title": "latest science shows that potato chips are better for you than sugar", "date": "2016-09-22"}}`,
+		},
+		{
+			name:         "NestedFields",
+			url:          "/v1/articles/1?include=metrics&fields=id,metrics.word_count",
+			expectedBody: `{"article": {"id": 1, "metrics": {"word_count": 10}}}`,
+		},
+		{
+			name:         "UnknownFields",
+			url:          "/v1/articles/1?fields=id,colour",
+			expectedBody: `{"article": {"id": 1}}`,
+		},
+		{
+			name:         "ListKeepsMetadata",
+			url:          "/v1/articles?page_size=2&fields=id",
+			expectedBody: `{"articles": [{"id": 1}, {"id": 2}], "metadata": {"current_page": 1, "page_size": 2, "first_page": 1, "last_page": 14, "total_records": 27}}`,
+		},
+		{
+			name:         "TagSummary",
+			url:          "/v1/tags/science/20160922?fields=tag,articles",
+			expectedBody: `{"tag_summary": {"tag": "science", "articles": [18, 3, 2, 1]}}`,
+		},
+		{
+			name: "Expand",
+			url:  "/v1/tags/science/20160922?expand=articles&fields=tag,articles.id,articles.title",
+			expectedBody: `{"tag_summary": {"tag": "science", "articles": [
+				{"id": 18, "title": "new species of bird found"},
+				{"id": 3, "title": "new species of bird found"},
+				{"id": 2, "title": "breakthrough in sleep science"},
+				{"id": 1, "title": "latest science shows that potato chips are better for you than sugar"}
+			]}}`,
+		},
+		{
+			name:         "ExpandUnknown",
+			url:          "/v1/tags/science/20160922?expand=tags&fields=articles",
+			expectedBody: `{"tag_summary": {"articles": [18, 3, 2, 1]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, _, body := ts.get(t, tt.url)
+			require.Equal(t, http.StatusOK, statusCode, body)
+			assert.JSONEq(t, tt.expectedBody, body)
+		})
+	}
+
+	t.Run("ExpandedArticlesKeepIncludes", func(t *testing.T) {
+		statusCode, _, body := ts.get(t, "/v1/tags/science/20160922?expand=articles&fields=articles.metrics")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"tag_summary": {"articles": [{}, {}, {}, {}]}}`, body)
+
+		statusCode, _, body = ts.get(t, "/v1/tags/science/20160922?expand=articles&include=metrics&fields=articles.metrics.word_count")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.JSONEq(t, `{"tag_summary": {"articles": [{"metrics": {"word_count": 11}}, {"metrics": {"word_count": 11}}, {"metrics": {"word_count": 12}}, {"metrics": {"word_count": 10}}]}}`, body)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		statusCode, _, body := ts.get(t, "/v1/articles?page_size=2&fields=id,title&format=csv")
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, "id,title\n1,latest science shows that potato chips are better for you than sugar\n2,breakthrough in sleep science", body)
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		header := make(http.Header)
+		header.Set("Accept", "application/xml")
+
+		statusCode, _, body := ts.getWithHeader(t, "/v1/articles/1?fields=id,tags", header)
+		require.Equal(t, http.StatusOK, statusCode)
+		assert.Contains(t, body, "<id>1</id>")
+		assert.Contains(t, body, "<tag>fitness</tag>")
+		assert.NotContains(t, body, "<title>")
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/des-ant/2024-article-api/internal/site"
+)
+
+// buildSite renders the store into a static site in the configured directory,
+// writing what the build did to w. Pages whose articles haven't changed since
+// the last build are left alone.
+func (app *application) buildSite(w io.Writer) error {
+	if app.config.site.dir == "" {
+		return errors.New("site: no output directory given")
+	}
+	if app.config.baseURL == "" {
+		return errors.New("site: -base-url is needed for links in the site's feeds")
+	}
+
+	stats, err := site.Build(app.config.site.dir, app.daos.Articles, site.Options{
+		Title:     app.config.feed.title,
+		BaseURL:   app.config.baseURL,
+		PageSize:  app.config.site.pageSize,
+		FeedItems: app.config.feed.items,
+		HTML:      app.htmlPolicy(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%d written, %d unchanged, %d removed\n", stats.Written, stats.Unchanged, stats.Removed)
+	return err
+}
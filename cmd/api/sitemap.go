@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/sitemap"
+)
+
+// generateSitemap starts a background goroutine that keeps the sitemap in step
+// with the stored articles, so that requests never have to build it. It runs
+// for the life of the process: the sitemap is only held in memory, so there is
+// nothing to finish on shutdown.
+func (app *application) generateSitemap() {
+	watcher := app.daos.Articles.Watch()
+
+	go func() {
+		for {
+			app.updateSitemap(watcher)
+
+			// updateSitemap only returns after a panic. Wait a moment before
+			// rebuilding, so that a panic on every rebuild doesn't spin.
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+// updateSitemap builds the sitemap from every stored article, then applies
+// the articles that change to it one at a time, without going back through
+// the rest. It only returns if it panics, after logging the panic.
+func (app *application) updateSitemap(watcher *data.Watcher) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.Error(fmt.Sprintf("sitemap generation failed, rebuilding: %v", err))
+		}
+	}()
+
+	// Anything that changes while the sitemap is built is applied again
+	// afterwards, which leaves it as it was.
+	watcher.Changed()
+	app.sitemap.Set(articleURLs(app.daos.Articles.GetAll()))
+
+	for range watcher.C {
+		articles, missing := app.daos.Articles.GetMany(watcher.Changed())
+
+		remove := make([]string, len(missing))
+		for i, id := range missing {
+			remove[i] = articleLoc(id)
+		}
+		app.sitemap.Apply(articleURLs(articles), remove)
+	}
+}
+
+// articleURLs returns the sitemap URLs of the given articles.
+func articleURLs(articles []data.Article) []sitemap.URL {
+	urls := make([]sitemap.URL, len(articles))
+	for i, article := range articles {
+		urls[i] = sitemap.URL{
+			Loc:     articleLoc(article.ID),
+			LastMod: article.Date.ToTime(),
+		}
+	}
+	return urls
+}
+
+// articleLoc returns the sitemap path of the article with the given ID.
+func articleLoc(id int64) string {
+	return fmt.Sprintf("%s/articles/%d", basePathV1, id)
+}
+
+// sitemapHandler serves the sitemap. While every URL fits in one sitemap file
+// that file is served; beyond that a sitemap index listing the files is.
+func (app *application) sitemapHandler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	baseURL := app.baseURL(r)
+
+	if app.sitemap.Len() > 1 {
+		err := app.sitemap.WriteIndex(&buf, func(i int) string {
+			return fmt.Sprintf("%s%s/sitemaps/%d.xml", baseURL, basePathV1, i+1)
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		_, err := app.sitemap.WriteFile(&buf, 0, baseURL)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	app.serveSitemap(w, r, buf.Bytes())
+}
+
+// sitemapFileHandler serves one of the sitemap files listed in the sitemap
+// index, numbered from 1.
+func (app *application) sitemapFileHandler(w http.ResponseWriter, r *http.Request) {
+	name := httprouter.ParamsFromContext(r.Context()).ByName("file")
+
+	n, err := strconv.Atoi(strings.TrimSuffix(name, ".xml"))
+	if err != nil || n < 1 || !strings.HasSuffix(name, ".xml") {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var buf bytes.Buffer
+	ok, err := app.sitemap.WriteFile(&buf, n-1, app.baseURL(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.serveSitemap(w, r, buf.Bytes())
+}
+
+// serveSitemap writes a sitemap document, answering conditional requests
+// based on when the sitemap last changed.
+func (app *application) serveSitemap(w http.ResponseWriter, r *http.Request, doc []byte) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	http.ServeContent(w, r, "", app.sitemap.Modified(), bytes.NewReader(doc))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/text"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// minAutofillTagScore is the lowest posterior probability a suggestion needs
+// before it is added to an article automatically.
+const minAutofillTagScore = 0.05
+
+// suggestTagsHandler returns tags ranked by how well they fit the submitted
+// title and body, based on the articles already in the store.
+func (app *application) suggestTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title string   `json:"title"`
+		Body  string   `json:"body"`
+		Tags  []string `json:"tags"`
+		Limit *int     `json:"limit"`
+	}
+
+	err := app.readBody(w, r, &input)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnsupportedMediaType):
+			app.unsupportedMediaTypeResponse(w, r, supportedRequestMediaTypes)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	limit := 5
+	if input.Limit != nil {
+		limit = *input.Limit
+	}
+
+	v := validator.New()
+
+	v.Check(input.Title != "" || input.Body != "", "body", "title or body must be provided")
+	v.Check(limit >= 1 && limit <= data.MaxArticleTags, "limit", fmt.Sprintf("must be between 1 and %d", data.MaxArticleTags))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions := app.daos.Articles.SuggestTags(input.Title, input.Body, input.Tags, limit)
+	if suggestions == nil {
+		suggestions = []text.TagSuggestion{}
+	}
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// suggestMissingTags returns suggested tags for the article until it would have
+// target tags, never taking it past the maximum allowed by ValidateArticle.
+// Low-confidence suggestions are dropped rather than used as filler.
+func (app *application) suggestMissingTags(article *data.Article, target int) []string {
+	target = min(target, data.MaxArticleTags)
+
+	missing := target - len(article.Tags)
+	if missing < 1 {
+		return nil
+	}
+
+	var tags []string
+	for _, suggestion := range app.daos.Articles.SuggestTags(article.Title, article.Body, article.Tags, missing) {
+		if suggestion.Score >= minAutofillTagScore {
+			tags = append(tags, suggestion.Tag)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// maxSummarySentences caps how many sentences a client may ask for.
+const maxSummarySentences = 20
+
+// validateSummarySentences checks the number of sentences a summary is asked
+// for.
+func validateSummarySentences(v *validator.Validator, sentences int) {
+	v.Check(sentences >= 1 && sentences <= maxSummarySentences, "sentences", fmt.Sprintf("must be between 1 and %d", maxSummarySentences))
+}
+
+// showArticleSummaryHandler returns an extractive summary of an article body.
+// The number of sentences defaults to 3 and can be set with "sentences".
+func (app *application) showArticleSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	sentences := app.readInt(r.URL.Query(), "sentences", 3, v)
+	validateSummarySentences(v, sentences)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	article, err := app.daos.Articles.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	summary := data.SummarizeArticle(article, sentences)
+
+	err = app.writeResponse(w, r, http.StatusOK, envelope{"summary": summary}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/dirsync"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// newSyncer returns a Syncer for the configured directory, which validates and
+// sanitizes articles as they would be on create, and applies the near-duplicate
+// policy to the articles it creates. Articles already stored are updated from
+// their files whatever they resemble.
+func (app *application) newSyncer() *dirsync.Syncer {
+	return dirsync.New(app.config.sync.dir, app.daos.Articles, dirsync.Options{
+		Delete: app.config.sync.delete,
+		Prepare: func(v *validator.Validator, article *data.Article) {
+			app.sanitizeBody(v, article)
+			if data.ValidateArticle(v, article); !v.Valid() || app.config.dedupe.policy != dedupePolicyReject || app.articleExists(article.ID) {
+				return
+			}
+
+			// Checked here too so that dry runs report the articles the
+			// policy would refuse.
+			duplicates := app.daos.Articles.FindNearDuplicates(article, app.config.dedupe.threshold)
+			if len(duplicates) > 0 {
+				v.AddError("article", fmt.Sprintf("is a near-duplicate of existing article %d", duplicates[0].ID))
+			}
+		},
+		Insert: func(article *data.Article) ([]data.NearDuplicate, error) {
+			return app.insertArticles(article)
+		},
+	})
+}
+
+// runSync syncs the store with the configured directory once, writing the
+// changes made, or in a dry run the changes that would be, to w as a diff.
+func (app *application) runSync(w io.Writer) error {
+	if app.config.sync.dir == "" {
+		return errors.New("sync: no directory given")
+	}
+
+	result, err := app.newSyncer().Sync(app.config.sync.dryRun)
+	if err != nil {
+		return err
+	}
+
+	return writeSyncDiff(w, result, app.config.sync.dryRun)
+}
+
+// watchSyncDir starts a background goroutine that syncs the store with the
+// configured directory whenever its files change, logging what each sync did
+// and rendering the static site again, if there is one, after changes.
+// Like the sitemap, it runs for the life of the process.
+func (app *application) watchSyncDir() {
+	syncer := app.newSyncer()
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("directory sync stopped: %v", err))
+			}
+		}()
+
+		syncer.Watch(context.Background(), app.config.sync.interval, func(result dirsync.Result, err error) {
+			if err != nil {
+				app.logger.Error(err.Error(), "dir", app.config.sync.dir)
+				return
+			}
+			for _, change := range result.Changes {
+				app.logger.Info("synced article", "action", change.Action, "id", change.ID, "file", change.File)
+			}
+			for _, problem := range result.Problems {
+				app.logger.Warn("cannot sync article", "file", problem.File, "errors", problem.Errors)
+			}
+
+			if len(result.Changes) > 0 && app.config.site.dir != "" {
+				err := app.buildSite(io.Discard)
+				if err != nil {
+					app.logger.Error(err.Error(), "dir", app.config.site.dir)
+				}
+			}
+		})
+	}()
+}
+
+// writeSyncDiff writes a line for each change in a sync result, marked "+" for
+// articles created, "~" for those updated and "-" for those deleted, then a
+// line for each file that couldn't be synced, marked "!", and a summary.
+func writeSyncDiff(w io.Writer, result dirsync.Result, dryRun bool) error {
+	counts := make(map[dirsync.Action]int)
+
+	var sb strings.Builder
+	for _, change := range result.Changes {
+		counts[change.Action]++
+
+		switch change.Action {
+		case dirsync.Create:
+			fmt.Fprintf(&sb, "+ %d %q (%s)\n", change.ID, change.Title, change.File)
+		case dirsync.Update:
+			fmt.Fprintf(&sb, "~ %d %q (%s): %s\n", change.ID, change.Title, change.File, strings.Join(change.Fields, ", "))
+		case dirsync.Delete:
+			fmt.Fprintf(&sb, "- %d %q\n", change.ID, change.Title)
+		}
+	}
+
+	for _, problem := range result.Problems {
+		fmt.Fprintf(&sb, "! %s: %s\n", problem.File, formatErrors(problem.Errors))
+	}
+
+	verbs := "created %d, updated %d, deleted %d"
+	if dryRun {
+		verbs = "would create %d, update %d, delete %d"
+	}
+	fmt.Fprintf(&sb, verbs+"; %d files with problems\n", counts[dirsync.Create], counts[dirsync.Update], counts[dirsync.Delete], len(result.Problems))
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// formatErrors joins validation errors into one line, ordered by key.
+func formatErrors(errs map[string]string) string {
+	parts := make([]string, 0, len(errs))
+	for key, message := range errs {
+		parts = append(parts, key+" "+message)
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, "; ")
+}
@@ -14,6 +14,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/data/mocks"
+	"github.com/des-ant/2024-article-api/internal/markup"
+	"github.com/des-ant/2024-article-api/internal/sitemap"
 )
 
 // newTestApplication creates a new instance of the application struct with mocked dependencies.
@@ -22,12 +25,28 @@ func newTestApplication(t *testing.T) *application {
 		port: 4000,
 		env:  "test",
 	}
-
-	return &application{
-		config: cfg,
-		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
-		daos:   data.NewDAOs(),
+	cfg.dedupe.policy = dedupePolicyWarn
+	cfg.dedupe.threshold = 0.9
+	cfg.html.policy = htmlPolicyStrip
+	cfg.html.elements = markup.DefaultElements
+	cfg.feed.title = "Articles"
+	cfg.feed.items = 20
+	cfg.site.pageSize = 20
+	cfg.batch.maxItems = 100
+	cfg.batch.maxBytes = 10 << 20
+	cfg.graphql.maxDepth = 10
+	cfg.graphql.maxComplexity = 1000
+
+	app := &application{
+		config:  cfg,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		daos:    data.NewDAOs(),
+		sitemap: sitemap.New(sitemap.MaxURLs),
 	}
+	app.schema = app.newGraphQLSchema()
+	app.rpc = app.newRPCServer()
+
+	return app
 }
 
 // testServer wraps httptest.Server to provide helper methods for testing.
@@ -42,6 +61,30 @@ func newTestServer(t *testing.T, h http.Handler) *testServer {
 	return &testServer{ts}
 }
 
+// testAdminToken is the admin token enableAdmin sets.
+const testAdminToken = "test-admin-token"
+
+// enableAdmin enables the admin endpoints of app, and has the server's client
+// send the admin token with every request.
+func (ts *testServer) enableAdmin(app *application) {
+	app.config.admin.token = testAdminToken
+
+	client := ts.Client()
+	client.Transport = bearerTransport{token: testAdminToken, next: client.Transport}
+}
+
+// bearerTransport adds a bearer token to every request it sends.
+type bearerTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(r)
+}
+
 // get performs a GET request to the server and returns the response status code, headers, and body.
 func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, string) {
 	rs, err := ts.Client().Get(ts.URL + urlPath)
@@ -59,6 +102,48 @@ func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, strin
 	return rs.StatusCode, rs.Header, string(body)
 }
 
+// getWithHeader performs a GET request with the provided headers and returns the response
+// status code, headers, and body.
+func (ts *testServer) getWithHeader(t *testing.T, urlPath string, header http.Header) (int, http.Header, string) {
+	req, err := http.NewRequest(http.MethodGet, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header
+
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = bytes.TrimSpace(body)
+
+	return rs.StatusCode, rs.Header, string(body)
+}
+
+// post performs a POST request to the server with the provided content type and body, and
+// returns the response status code, headers, and body.
+func (ts *testServer) post(t *testing.T, urlPath, contentType string, reqBody io.Reader) (int, http.Header, string) {
+	rs, err := ts.Client().Post(ts.URL+urlPath, contentType, reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = bytes.TrimSpace(body)
+
+	return rs.StatusCode, rs.Header, string(body)
+}
+
 // postJSON performs a POST request to the server with JSON data and returns the response status code, headers, and body.
 func (ts *testServer) postJSON(t *testing.T, urlPath string, data interface{}) (int, http.Header, string) {
 	jsonData, err := json.Marshal(data)
@@ -81,6 +166,23 @@ func (ts *testServer) postJSON(t *testing.T, urlPath string, data interface{}) (
 	return rs.StatusCode, rs.Header, string(body)
 }
 
+// postMockArticles creates every mock article through the API.
+func (ts *testServer) postMockArticles(t *testing.T) {
+	for _, article := range mocks.InitMockArticles() {
+		articleMap := map[string]interface{}{
+			"id":    article.ID,
+			"title": article.Title,
+			"date":  article.Date.String(),
+			"body":  article.Body,
+			"tags":  article.Tags,
+		}
+		statusCode, _, _ := ts.postJSON(t, "/v1/articles", articleMap)
+		if statusCode != http.StatusCreated {
+			t.Fatalf("expected status %d; got %d", http.StatusCreated, statusCode)
+		}
+	}
+}
+
 // sortArticlesAndTags sorts the "articles" and "related_tags" slices in the tag_summary map.
 // JSON marshalling does not guarantee the order of slices, so we need to sort them to compare them in tests.
 func sortArticlesAndTags(bodyMap map[string]interface{}) {
@@ -0,0 +1,149 @@
+package codec
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotTabular is returned by EncodeCSV when the value is not a list of
+// objects or scalars.
+var ErrNotTabular = errors.New("codec: value cannot be represented as CSV")
+
+// CSVListSeparator joins lists of scalars inside a single CSV cell.
+const CSVListSeparator = ";"
+
+// EncodeCSV writes a list to w as CSV with a header row. The columns are the
+// keys of the listed objects in order of first appearance. Lists of scalars
+// are joined with semicolons and nested objects are written as JSON. A list of
+// scalars produces a single "value" column.
+func EncodeCSV(w io.Writer, v any) error {
+	tree, err := Normalize(v)
+	if err != nil {
+		return err
+	}
+
+	list, ok := tree.([]any)
+	if !ok && tree != nil {
+		return ErrNotTabular
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	for _, element := range list {
+		switch item := element.(type) {
+		case Object:
+			for _, m := range item {
+				if !seen[m.Key] {
+					seen[m.Key] = true
+					columns = append(columns, m.Key)
+				}
+			}
+		case []any:
+			return ErrNotTabular
+		default:
+			if !seen["value"] {
+				seen["value"] = true
+				columns = append(columns, "value")
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+
+	err = cw.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	for _, element := range list {
+		obj, ok := element.(Object)
+		if !ok {
+			obj = Object{{Key: "value", Value: element}}
+		}
+
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			value, _ := obj.Get(column)
+			record[i], err = csvCell(value)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell formats a normalised value for a single CSV cell.
+func csvCell(v any) (string, error) {
+	switch value := v.(type) {
+	case Object:
+		return toJSON(value)
+	case []any:
+		cells := make([]string, len(value))
+		for i, element := range value {
+			switch element.(type) {
+			case Object, []any:
+				return toJSON(value)
+			}
+			cells[i] = FormatScalar(element)
+		}
+		return strings.Join(cells, CSVListSeparator), nil
+	}
+	return FormatScalar(v), nil
+}
+
+// toJSON encodes a normalised value as compact JSON, keeping member order.
+func toJSON(v any) (string, error) {
+	var sb strings.Builder
+	err := appendJSON(&sb, v)
+	return sb.String(), err
+}
+
+func appendJSON(sb *strings.Builder, v any) error {
+	switch value := v.(type) {
+	case Object:
+		sb.WriteByte('{')
+		for i, m := range value {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			key, _ := json.Marshal(m.Key)
+			sb.Write(key)
+			sb.WriteByte(':')
+			err := appendJSON(sb, m.Value)
+			if err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	case []any:
+		sb.WriteByte('[')
+		for i, element := range value {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			err := appendJSON(sb, element)
+			if err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	default:
+		js, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		sb.Write(js)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package codec
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Field describes a struct field as encoding/json sees it.
+type Field struct {
+	Name      string
+	Index     []int
+	OmitEmpty bool
+	Quoted    bool
+	Type      reflect.Type
+}
+
+var fieldCache sync.Map // map[reflect.Type][]Field
+
+// Fields returns the fields of struct type t that encoding/json would encode,
+// in encoding order. Embedded structs without a JSON name are flattened into
+// their parent, and fields tagged "-" are skipped.
+func Fields(t reflect.Type) []Field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]Field)
+	}
+
+	fields := typeFields(t, nil, make(map[reflect.Type]bool))
+
+	// Shallower fields hide promoted fields with the same name.
+	depth := make(map[string]int)
+	for _, f := range fields {
+		if d, ok := depth[f.Name]; !ok || len(f.Index) < d {
+			depth[f.Name] = len(f.Index)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var visible []Field
+	for _, f := range fields {
+		if len(f.Index) == depth[f.Name] && !seen[f.Name] {
+			seen[f.Name] = true
+			visible = append(visible, f)
+		}
+	}
+
+	fieldCache.Store(t, visible)
+	return visible
+}
+
+func typeFields(t reflect.Type, index []int, visited map[reflect.Type]bool) []Field {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	var fields []Field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int{}, index...), i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, typeFields(ft, fieldIndex, visited)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, Field{
+			Name:      name,
+			Index:     fieldIndex,
+			OmitEmpty: hasOption(opts, "omitempty"),
+			Quoted:    hasOption(opts, "string"),
+			Type:      sf.Type,
+		})
+	}
+
+	return fields
+}
+
+// fieldByIndex walks v along index, returning false if it passes through a
+// nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
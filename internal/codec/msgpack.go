@@ -0,0 +1,339 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxMsgpackDepth limits how deeply nested a MessagePack document may be, to
+// protect the recursive decoder from malicious input.
+const maxMsgpackDepth = 100
+
+// ErrMsgpackSyntax is wrapped by every error DecodeMsgpack returns for invalid
+// input.
+var ErrMsgpackSyntax = errors.New("invalid MessagePack")
+
+// EncodeMsgpack writes v to w in the MessagePack binary format. Integers use
+// the smallest encoding that holds them, so values round-trip to the same
+// numbers JSON would carry.
+func EncodeMsgpack(w io.Writer, v any) error {
+	tree, err := Normalize(v)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	err = encodeMsgpack(bw, tree)
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func encodeMsgpack(w *bufio.Writer, v any) error {
+	switch value := v.(type) {
+	case nil:
+		return w.WriteByte(0xc0)
+	case bool:
+		if value {
+			return w.WriteByte(0xc3)
+		}
+		return w.WriteByte(0xc2)
+	case int64:
+		return writeMsgpackInt(w, value)
+	case uint64:
+		if value <= math.MaxInt64 {
+			return writeMsgpackInt(w, int64(value))
+		}
+		return writeMsgpackHeader(w, 0xcf, 8, value)
+	case float64:
+		return writeMsgpackHeader(w, 0xcb, 8, math.Float64bits(value))
+	case string:
+		n := uint64(len(value))
+		var err error
+		switch {
+		case n < 32:
+			err = w.WriteByte(0xa0 | byte(n))
+		case n <= math.MaxUint8:
+			err = writeMsgpackHeader(w, 0xd9, 1, n)
+		case n <= math.MaxUint16:
+			err = writeMsgpackHeader(w, 0xda, 2, n)
+		default:
+			err = writeMsgpackHeader(w, 0xdb, 4, n)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = w.WriteString(value)
+		return err
+	case []any:
+		err := writeMsgpackLength(w, 0x90, 0xdc, 0xdd, len(value))
+		if err != nil {
+			return err
+		}
+		for _, element := range value {
+			err = encodeMsgpack(w, element)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case Object:
+		err := writeMsgpackLength(w, 0x80, 0xde, 0xdf, len(value))
+		if err != nil {
+			return err
+		}
+		for _, m := range value {
+			err = encodeMsgpack(w, m.Key)
+			if err != nil {
+				return err
+			}
+			err = encodeMsgpack(w, m.Value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("codec: cannot encode %T as MessagePack", v)
+}
+
+func writeMsgpackInt(w *bufio.Writer, i int64) error {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return w.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		return w.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return writeMsgpackHeader(w, 0xd0, 1, uint64(uint8(int8(i))))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return writeMsgpackHeader(w, 0xd1, 2, uint64(uint16(int16(i))))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return writeMsgpackHeader(w, 0xd2, 4, uint64(uint32(int32(i))))
+	}
+	return writeMsgpackHeader(w, 0xd3, 8, uint64(i))
+}
+
+// writeMsgpackLength writes the header of an array or map, choosing between the
+// fixed, 16-bit and 32-bit forms.
+func writeMsgpackLength(w *bufio.Writer, fixed, code16, code32 byte, n int) error {
+	switch {
+	case n < 16:
+		return w.WriteByte(fixed | byte(n))
+	case n <= math.MaxUint16:
+		return writeMsgpackHeader(w, code16, 2, uint64(n))
+	}
+	return writeMsgpackHeader(w, code32, 4, uint64(n))
+}
+
+// writeMsgpackHeader writes a type code followed by a big-endian value of the
+// given width in bytes.
+func writeMsgpackHeader(w *bufio.Writer, code byte, width int, value uint64) error {
+	var buf [9]byte
+	buf[0] = code
+	binary.BigEndian.PutUint64(buf[1:], value<<(8*(8-width)))
+
+	_, err := w.Write(buf[:1+width])
+	return err
+}
+
+// DecodeMsgpack decodes a single MessagePack document. Maps become
+// map[string]any, arrays []any, integers int64 (or uint64 when too large),
+// floats float64, strings string and binary data []byte. Map keys must be
+// strings, extension types are not supported, and trailing data is an error.
+func DecodeMsgpack(data []byte) (any, error) {
+	d := msgpackDecoder{data: data}
+
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%w: unexpected data after top-level value at byte %d", ErrMsgpackSyntax, d.pos)
+	}
+
+	return v, nil
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at byte %d", ErrMsgpackSyntax, fmt.Sprintf(format, args...), d.pos)
+}
+
+// read consumes the next n bytes.
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, d.errorf("unexpected end of data")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readUint consumes a big-endian unsigned integer of the given width.
+func (d *msgpackDecoder) readUint(width int) (uint64, error) {
+	b, err := d.read(width)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v, nil
+}
+
+func (d *msgpackDecoder) decode(depth int) (any, error) {
+	if depth > maxMsgpackDepth {
+		return nil, d.errorf("exceeded maximum nesting depth of %d", maxMsgpackDepth)
+	}
+
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return d.decodeMap(uint64(code&0x0f), depth)
+	case code&0xf0 == 0x90:
+		return d.decodeArray(uint64(code&0x0f), depth)
+	case code&0xe0 == 0xa0:
+		return d.decodeString(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		bin, err := d.read(int(min(n, math.MaxInt32)))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), bin...), nil
+	case 0xca:
+		bits, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := d.readUint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce:
+		v, err := d.readUint(1 << (code - 0xcc))
+		return int64(v), err
+	case 0xcf:
+		v, err := d.readUint(8)
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := d.readUint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.readUint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.readUint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.readUint(8)
+		return int64(v), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(min(n, math.MaxInt32)))
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n, depth)
+	}
+
+	return nil, d.errorf("unsupported type code 0x%02x", code)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (any, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) decodeArray(n uint64, depth int) (any, error) {
+	// Every element takes at least one byte, which bounds the allocation.
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, d.errorf("array length %d exceeds remaining data", n)
+	}
+
+	list := make([]any, n)
+	for i := range list {
+		v, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n uint64, depth int) (any, error) {
+	// Every key and value takes at least one byte each.
+	if n > uint64(len(d.data)-d.pos)/2 {
+		return nil, d.errorf("map length %d exceeds remaining data", n)
+	}
+
+	m := make(map[string]any, n)
+	for range n {
+		k, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, d.errorf("map keys must be strings, got %T", k)
+		}
+
+		v, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
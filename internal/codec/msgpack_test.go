@@ -0,0 +1,168 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// msgpackAsJSON encodes v as MessagePack, decodes it again and returns the
+// result as JSON.
+func msgpackAsJSON(t *testing.T, v any) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeMsgpack(&buf, v))
+
+	decoded, err := DecodeMsgpack(buf.Bytes())
+	require.NoError(t, err)
+
+	js, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	return string(js)
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	article := &data.Article{
+		ID:    1,
+		Title: "potato chips & sugar",
+		Date:  data.ArticleDate(time.Date(2016, 9, 22, 0, 0, 0, 0, time.UTC)),
+		Body:  "some text about potato chips",
+		Tags:  []string{"health", "science"},
+		Metrics: &data.ArticleMetrics{
+			WordCount:          5,
+			ReadingTimeMinutes: 1,
+			ReadingEase:        -47.99,
+			Language:           "en",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{name: "Article", value: article},
+		{name: "Tag Summary", value: &data.TagSummary{
+			Tag:         "health",
+			Count:       3,
+			Articles:    []int64{1, 7, 300},
+			RelatedTags: []string{"science"},
+			TopKeywords: []string{},
+		}},
+		{name: "Envelope", value: map[string]any{
+			"articles": []*data.Article{article, article},
+			"metadata": map[string]int{"current_page": 1, "total_records": 70000},
+		}},
+		{name: "Error", value: map[string]any{"error": map[string]string{"id": "must be provided"}}},
+		{name: "Integers", value: []int64{0, 127, 128, -32, -33, 255, -128, 65536, -32769, math.MaxInt64, math.MinInt64}},
+		{name: "Large Unsigned", value: uint64(math.MaxUint64)},
+		{name: "Strings", value: []string{"", "x", string(make([]byte, 31)), string(make([]byte, 256)), string(make([]byte, 70000))}},
+		{name: "Large Array", value: make([]bool, 70000)},
+		{name: "Null", value: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := json.Marshal(tt.value)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, string(expected), msgpackAsJSON(t, tt.value))
+		})
+	}
+}
+
+func TestDecodeMsgpack(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected any
+		wantErr  string
+	}{
+		{name: "Float32", input: []byte{0xca, 0x3f, 0xc0, 0x00, 0x00}, expected: 1.5},
+		{name: "Binary", input: []byte{0xc4, 0x02, 'h', 'i'}, expected: []byte("hi")},
+		{name: "Uint16", input: []byte{0xcd, 0x01, 0x00}, expected: int64(256)},
+		{name: "Truncated", input: []byte{0xda, 0x00}, wantErr: "invalid MessagePack: unexpected end of data at byte 1"},
+		{name: "Trailing Data", input: []byte{0xc0, 0xc0}, wantErr: "invalid MessagePack: unexpected data after top-level value at byte 1"},
+		{name: "Non-String Key", input: []byte{0x81, 0x01, 0x02}, wantErr: "invalid MessagePack: map keys must be strings, got int64 at byte 2"},
+		{name: "Extension", input: []byte{0xd4, 0x01, 0x00}, wantErr: "invalid MessagePack: unsupported type code 0xd4 at byte 1"},
+		{name: "Oversized Array", input: []byte{0xdd, 0xff, 0xff, 0xff, 0xff}, wantErr: "invalid MessagePack: array length 4294967295 exceeds remaining data at byte 5"},
+		{name: "Too Deep", input: bytes.Repeat([]byte{0x91}, maxMsgpackDepth+2), wantErr: "invalid MessagePack: exceeded maximum nesting depth of 100 at byte 101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := DecodeMsgpack(tt.input)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, ErrMsgpackSyntax)
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+// FuzzMsgpackArticle checks that any article survives a MessagePack round trip
+// with the same JSON representation.
+func FuzzMsgpackArticle(f *testing.F) {
+	f.Add(int64(1), "potato chips & sugar", int64(1474502400), "some text about potato chips", "health", 0.5)
+	f.Add(int64(-7), "", int64(0), "", "", math.Inf(1))
+
+	f.Fuzz(func(t *testing.T, id int64, title string, unix int64, body, tag string, ease float64) {
+		if math.IsInf(ease, 0) || math.IsNaN(ease) {
+			t.Skip("JSON cannot represent non-finite numbers")
+		}
+
+		article := &data.Article{
+			ID:      id,
+			Title:   title,
+			Date:    data.ArticleDate(time.Unix(unix%1e10, 0).UTC()),
+			Body:    body,
+			Tags:    []string{tag},
+			Metrics: &data.ArticleMetrics{ReadingEase: ease},
+		}
+
+		expected, err := json.Marshal(article)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, string(expected), msgpackAsJSON(t, article))
+	})
+}
+
+// FuzzDecodeMsgpack checks that the decoder never panics, and that anything it
+// accepts re-encodes to the same value.
+func FuzzDecodeMsgpack(f *testing.F) {
+	f.Add([]byte{0x81, 0xa2, 'i', 'd', 0x01})
+	f.Add([]byte{0x92, 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0xc3})
+	f.Add([]byte{0xdf, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		v, err := DecodeMsgpack(input)
+		if err != nil {
+			return
+		}
+
+		expected, err := json.Marshal(v)
+		if err != nil {
+			// JSON cannot represent every MessagePack value, such as NaN.
+			return
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, EncodeMsgpack(&buf, v))
+
+		again, err := DecodeMsgpack(buf.Bytes())
+		require.NoError(t, err)
+
+		actual, err := json.Marshal(again)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(actual))
+	})
+}
@@ -0,0 +1,266 @@
+// Package codec converts response values into formats other than JSON.
+//
+// Every encoder works from the same intermediate tree produced by Normalize,
+// which follows the encoding/json rules for field names, omitted fields and
+// custom marshalers. This keeps each format a faithful rendering of the JSON
+// representation clients already rely on.
+package codec
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Object is an ordered set of key/value pairs, the normalised form of structs
+// and maps.
+type Object []Member
+
+// Member is a single key/value pair in an Object.
+type Member struct {
+	Key   string
+	Value any
+}
+
+// Get returns the value stored under key, and whether it was found.
+func (o Object) Get(key string) (any, bool) {
+	for _, m := range o {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON encodes the object as a JSON object with its members in order,
+// so that normalised trees can be sent as JSON too.
+func (o Object) MarshalJSON() ([]byte, error) {
+	js, err := toJSON(o)
+	return []byte(js), err
+}
+
+// Normalize converts v into a tree made only of nil, bool, int64, uint64,
+// float64, string, []any and Object values.
+// Trees that are already normalised are returned as they are.
+//
+// Struct fields are named and omitted exactly as encoding/json would name and
+// omit them. Values implementing encoding.TextMarshaler become strings, and
+// other json.Marshaler implementations are normalised from their JSON output.
+// Map keys are sorted, as they are by encoding/json.
+func Normalize(v any) (any, error) {
+	return normalize(reflect.ValueOf(v))
+}
+
+var (
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	objectType        = reflect.TypeFor[Object]()
+)
+
+func normalize(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if v.Type() == objectType {
+		return v.Interface(), nil
+	}
+
+	// Custom marshalers take priority, matching encoding/json.
+	if v.Kind() != reflect.Pointer && v.CanAddr() && reflect.PointerTo(v.Type()).Implements(jsonMarshalerType) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Implements(textMarshalerType) {
+			text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			return string(text), nil
+		}
+		return normalizeJSON(v.Interface().(json.Marshaler))
+	}
+	if v.Type().Implements(textMarshalerType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return nil, nil
+		}
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return normalize(v.Elem())
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+		}
+		return normalizeList(v)
+	case reflect.Array:
+		return normalizeList(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return normalizeMap(v)
+	case reflect.Struct:
+		return normalizeStruct(v)
+	}
+
+	return nil, fmt.Errorf("codec: unsupported type %s", v.Type())
+}
+
+func normalizeList(v reflect.Value) (any, error) {
+	list := make([]any, v.Len())
+	for i := range list {
+		item, err := normalize(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		list[i] = item
+	}
+	return list, nil
+}
+
+func normalizeMap(v reflect.Value) (any, error) {
+	if v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("codec: unsupported map key type %s", v.Type().Key())
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	obj := make(Object, 0, len(keys))
+	for _, key := range keys {
+		value, err := normalize(v.MapIndex(key))
+		if err != nil {
+			return nil, err
+		}
+		obj = append(obj, Member{Key: key.String(), Value: value})
+	}
+	return obj, nil
+}
+
+func normalizeStruct(v reflect.Value) (any, error) {
+	obj := Object{}
+	for _, field := range Fields(v.Type()) {
+		fv, ok := fieldByIndex(v, field.Index)
+		if !ok || (field.OmitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+
+		value, err := normalize(fv)
+		if err != nil {
+			return nil, err
+		}
+		if field.Quoted {
+			value = fmt.Sprint(value)
+		}
+		obj = append(obj, Member{Key: field.Name, Value: value})
+	}
+	return obj, nil
+}
+
+// normalizeJSON normalises a value from its own JSON encoding, preserving the
+// order of object keys.
+func normalizeJSON(m json.Marshaler) (any, error) {
+	js, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(js)))
+	dec.UseNumber()
+	return decodeOrdered(dec)
+}
+
+// decodeOrdered reads the next JSON value from dec into a normalised tree.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := Object{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, Member{Key: keyTok.(string), Value: value})
+			}
+			_, err = dec.Token()
+			return obj, err
+		case '[':
+			list := []any{}
+			for dec.More() {
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			_, err = dec.Token()
+			return list, err
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	}
+
+	return tok, nil
+}
+
+// isEmptyValue reports whether v is empty according to the omitempty rules of
+// encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
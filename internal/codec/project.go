@@ -0,0 +1,65 @@
+package codec
+
+import "strings"
+
+// fieldSet is a tree of the members a projection keeps. A member mapped to nil
+// is kept whole.
+type fieldSet map[string]fieldSet
+
+// Project returns a normalised tree keeping only the listed members of its
+// objects, in their original order. A field names a member, or with a dotted
+// path such as "metrics.word_count" a member of a member. Lists are projected
+// item by item, and scalars are returned as they are.
+func Project(v any, fields []string) any {
+	return project(v, newFieldSet(fields))
+}
+
+func newFieldSet(fields []string) fieldSet {
+	set := fieldSet{}
+	for _, field := range fields {
+		node := set
+		parts := strings.Split(strings.TrimSpace(field), ".")
+		for i, part := range parts {
+			child, exists := node[part]
+			if i == len(parts)-1 {
+				// A whole member includes any of its members listed too.
+				node[part] = nil
+				break
+			}
+			if exists && child == nil {
+				break
+			}
+			if !exists {
+				child = fieldSet{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return set
+}
+
+func project(v any, fields fieldSet) any {
+	if fields == nil {
+		return v
+	}
+
+	switch value := v.(type) {
+	case Object:
+		obj := Object{}
+		for _, m := range value {
+			if sub, ok := fields[m.Key]; ok {
+				obj = append(obj, Member{Key: m.Key, Value: project(m.Value, sub)})
+			}
+		}
+		return obj
+	case []any:
+		list := make([]any, len(value))
+		for i, item := range value {
+			list[i] = project(item, fields)
+		}
+		return list
+	}
+
+	return v
+}
@@ -0,0 +1,132 @@
+package codec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EncodeXML writes v to w as an XML document with the given root element.
+//
+// Object members become child elements named after their keys. Lists become a
+// wrapper element whose items are named after the singular form of the
+// wrapper, so "tags" holds "tag" elements. Null values become empty elements.
+func EncodeXML(w io.Writer, root string, v any) error {
+	tree, err := Normalize(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+
+	err = encodeXMLElement(enc, root, tree)
+	if err != nil {
+		return err
+	}
+
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+
+	// End with a newline to make it easier to view in terminal applications.
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func encodeXMLElement(enc *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case nil:
+	case Object:
+		for _, m := range value {
+			err = encodeXMLElement(enc, m.Key, m.Value)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		item := singular(name)
+		for _, element := range value {
+			err = encodeXMLElement(enc, item, element)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		err = enc.EncodeToken(xml.CharData(FormatScalar(value)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// FormatScalar formats a normalised scalar value as text.
+func FormatScalar(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case uint64:
+		return strconv.FormatUint(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// xmlName turns an arbitrary key into a valid XML element name by replacing
+// disallowed characters with underscores.
+func xmlName(key string) string {
+	var sb strings.Builder
+	for i, r := range key {
+		valid := unicode.IsLetter(r) || r == '_' ||
+			(i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'))
+		if valid {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+
+	name := sb.String()
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		name = "_" + name
+	}
+	return name
+}
+
+// singular derives an item element name from a list element name.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ses"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return "item"
+}
@@ -0,0 +1,56 @@
+package codec
+
+import (
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeYAML writes v to w as a YAML document, keeping object members in the
+// same order as their JSON encoding.
+func EncodeYAML(w io.Writer, v any) error {
+	tree, err := Normalize(v)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	err = enc.Encode(yamlNode(tree))
+	if err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func yamlNode(v any) *yaml.Node {
+	switch value := v.(type) {
+	case Object:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, m := range value {
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: m.Key},
+				yamlNode(m.Value),
+			)
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, element := range value {
+			node.Content = append(node.Content, yamlNode(element))
+		}
+		return node
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: FormatScalar(value)}
+	case int64, uint64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: FormatScalar(value)}
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: FormatScalar(value)}
+	}
+
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: FormatScalar(v)}
+}
@@ -38,6 +38,26 @@ func (ad *ArticleDate) UnmarshalJSON(jsonValue []byte) error {
 	return nil
 }
 
+// MarshalText implements the encoding.TextMarshaler interface, which is used by
+// encodings other than JSON such as XML, YAML and CSV.
+// It will encode the time as text in the format "2006-01-02".
+func (ad ArticleDate) MarshalText() ([]byte, error) {
+	return []byte(time.Time(ad).Format("2006-01-02")), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It expects the time to be text in the format "2006-01-02".
+func (ad *ArticleDate) UnmarshalText(text []byte) error {
+	parsedTime, err := time.Parse("2006-01-02", string(text))
+	if err != nil {
+		return ErrInvalidArticleDateFormat
+	}
+
+	*ad = ArticleDate(parsedTime)
+
+	return nil
+}
+
 // ToTime converts ArticleDate back to time.Time.
 func (ad ArticleDate) ToTime() time.Time {
 	return time.Time(ad)
@@ -2,9 +2,14 @@ package data
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/des-ant/2024-article-api/internal/text"
 	"github.com/des-ant/2024-article-api/internal/validator"
 )
 
@@ -15,6 +20,26 @@ type Article struct {
 	Date  ArticleDate `json:"date"`
 	Body  string      `json:"body"`
 	Tags  []string    `json:"tags"`
+
+	// Summary is an extractive summary of the body. It is only kept when the
+	// store has been configured to do so with StoreSummaries.
+	Summary string `json:"summary,omitempty"`
+
+	// Metrics are computed when the article is stored. Handlers only send
+	// them to clients that ask for them.
+	Metrics *ArticleMetrics `json:"metrics,omitempty"`
+
+	// Fingerprint is a SimHash of the title and body, computed on insert and
+	// used to detect near-duplicate articles. It is never sent to clients.
+	Fingerprint uint64 `json:"-"`
+
+	// Version counts the times the article has been stored: it is 1 once
+	// inserted and goes up with every update. It is never sent to clients.
+	Version int64 `json:"-"`
+
+	// Modified is when the article was last inserted or updated. It is never
+	// sent to clients.
+	Modified time.Time `json:"-"`
 }
 
 // TagSummary represents a summary of tags for a given article.
@@ -23,15 +48,19 @@ type TagSummary struct {
 	Count       int      `json:"count"`
 	Articles    []int64  `json:"articles"`
 	RelatedTags []string `json:"related_tags"`
+	TopKeywords []string `json:"top_keywords"`
 }
 
+// MaxArticleTitleBytes is the maximum length of an article title, in bytes.
+const MaxArticleTitleBytes = 500
+
 // ValidateArticle validates the provided Article struct and adds an error message
 // to the validator instance if any of the validation rules fail.
 func ValidateArticle(v *validator.Validator, article *Article) {
 	v.Check(article.ID > 0, "id", "must be a positive integer")
 
 	v.Check(article.Title != "", "title", "must be provided")
-	v.Check(len(article.Title) <= 500, "title", "must not be more than 500 bytes long")
+	v.Check(len(article.Title) <= MaxArticleTitleBytes, "title", fmt.Sprintf("must not be more than %d bytes long", MaxArticleTitleBytes))
 
 	v.Check(!time.Time(article.Date).IsZero(), "date", "must be provided and valid")
 
@@ -39,14 +68,21 @@ func ValidateArticle(v *validator.Validator, article *Article) {
 
 	v.Check(article.Tags != nil, "tags", "must be provided")
 	v.Check(len(article.Tags) >= 1, "tags", "must contain at least 1 tag")
-	v.Check(len(article.Tags) <= 10, "tags", "must not contain more than 10 tags")
+	v.Check(len(article.Tags) <= MaxArticleTags, "tags", fmt.Sprintf("must not contain more than %d tags", MaxArticleTags))
 	v.Check(validator.Unique(article.Tags), "tags", "must not contain duplicate values")
 }
 
 // ArticleDAO represents the data access object for articles.
 type ArticleDAO struct {
-	articles map[int64]Article
-	mutex    sync.RWMutex
+	articles         map[int64]Article
+	summarySentences int
+	// modified is when the store last changed.
+	modified time.Time
+	watchers []*Watcher
+	// classifier is the tag classifier trained on the stored articles. It is
+	// trained when first needed and thrown away whenever the store changes.
+	classifier *text.TagClassifier
+	mutex      sync.RWMutex
 }
 
 // NewArticleDAO creates a new instance of ArticleDAO.
@@ -56,20 +92,199 @@ func NewArticleDAO() *ArticleDAO {
 	}
 }
 
-// Insert adds a new article to the store.
+// Insert adds a new article to the store. It returns ErrDuplicateID if an
+// article with the same ID is already stored.
 func (dao *ArticleDAO) Insert(article *Article) error {
+	return dao.InsertAll([]*Article{article})
+}
+
+// InsertAll adds new articles to the store all at once: if any of them has
+// the ID of a stored article, or of another in the list, none are added and
+// it returns ErrDuplicateID.
+func (dao *ArticleDAO) InsertAll(articles []*Article) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	err := dao.checkIDs(articles)
+	if err != nil {
+		return err
+	}
+
+	dao.store(articles)
+	return nil
+}
+
+// InsertDistinct is like InsertAll, but also adds none of the articles if any
+// of them is a near-duplicate, with a similarity of at least threshold, of a
+// stored article or of one before it in the list. It then returns the articles
+// that one resembles, most similar first as FindNearDuplicates would, along
+// with ErrNearDuplicate. The store is checked under the same lock the articles
+// are added with, so of two similar articles inserted at once only the first
+// gets in.
+func (dao *ArticleDAO) InsertDistinct(articles []*Article, threshold float64) ([]NearDuplicate, error) {
 	dao.mutex.Lock()
 	defer dao.mutex.Unlock()
 
-	if _, exists := dao.articles[article.ID]; exists {
-		return errors.New("duplicate key, article with ID already exists")
+	err := dao.checkIDs(articles)
+	if err != nil {
+		return nil, err
 	}
 
+	fingerprints := make([]uint64, len(articles))
+	for i, article := range articles {
+		fingerprints[i] = Fingerprint(article)
+
+		duplicates := dao.findNearDuplicates(article, threshold)
+		for j, earlier := range articles[:i] {
+			if similarity := text.Similarity(fingerprints[i], fingerprints[j]); similarity >= threshold {
+				duplicates = append(duplicates, NearDuplicate{
+					ID:         earlier.ID,
+					Title:      earlier.Title,
+					Similarity: similarity,
+				})
+			}
+		}
+
+		if len(duplicates) > 0 {
+			sortNearDuplicates(duplicates)
+			return duplicates, ErrNearDuplicate
+		}
+	}
+
+	dao.store(articles)
+	return nil, nil
+}
+
+// checkIDs returns ErrDuplicateID if any of the articles has the ID of a
+// stored article, or of another in the list. It must be called with the mutex
+// held.
+func (dao *ArticleDAO) checkIDs(articles []*Article) error {
+	ids := make(map[int64]bool, len(articles))
+	for _, article := range articles {
+		if _, exists := dao.articles[article.ID]; exists || ids[article.ID] {
+			return ErrDuplicateID
+		}
+		ids[article.ID] = true
+	}
+
+	return nil
+}
+
+// store adds articles whose IDs have been checked to the store. It must be
+// called with the mutex held.
+func (dao *ArticleDAO) store(articles []*Article) {
+	now := time.Now()
+	ids := make([]int64, len(articles))
+	for i, article := range articles {
+		dao.derive(article)
+		article.Version = 1
+		article.Modified = now
+		dao.articles[article.ID] = *article
+		ids[i] = article.ID
+	}
+	dao.changed(ids...)
+}
+
+// Update replaces a stored article with the same ID.
+func (dao *ArticleDAO) Update(article *Article) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	stored, exists := dao.articles[article.ID]
+	if !exists {
+		return ErrRecordNotFound
+	}
+
+	dao.derive(article)
+	article.Version = stored.Version + 1
+	article.Modified = time.Now()
 	dao.articles[article.ID] = *article
+	dao.changed(article.ID)
+
+	return nil
+}
+
+// Delete removes an article from the store.
+func (dao *ArticleDAO) Delete(id int64) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	if _, exists := dao.articles[id]; !exists {
+		return ErrRecordNotFound
+	}
+
+	delete(dao.articles, id)
+	dao.changed(id)
 
 	return nil
 }
 
+// Watcher is told when articles are inserted, updated or deleted.
+type Watcher struct {
+	// C receives a value after articles change. Notifications are
+	// coalesced, so a watcher should call Changed to find out what changed
+	// rather than count them.
+	C <-chan struct{}
+
+	notify  chan struct{}
+	pending map[int64]bool
+	mutex   sync.Mutex
+}
+
+// Changed returns the IDs of the articles inserted, updated or deleted since
+// the last call, in ascending order. Read them from the store to find out
+// which are still there.
+func (w *Watcher) Changed() []int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	ids := slices.Sorted(maps.Keys(w.pending))
+	clear(w.pending)
+	return ids
+}
+
+// Watch returns a Watcher of the store's articles.
+func (dao *ArticleDAO) Watch() *Watcher {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	notify := make(chan struct{}, 1)
+	w := &Watcher{C: notify, notify: notify, pending: make(map[int64]bool)}
+	dao.watchers = append(dao.watchers, w)
+	return w
+}
+
+// changed records that the articles with the given IDs have just been written,
+// throwing away what was worked out from the old articles and notifying
+// watchers. It must be called with the mutex held.
+func (dao *ArticleDAO) changed(ids ...int64) {
+	dao.modified = time.Now()
+	dao.classifier = nil
+
+	for _, w := range dao.watchers {
+		w.mutex.Lock()
+		for _, id := range ids {
+			w.pending[id] = true
+		}
+		w.mutex.Unlock()
+
+		select {
+		case w.notify <- struct{}{}:
+		default:
+			// The watcher already has a notification pending.
+		}
+	}
+}
+
+// LastModified returns when the store last changed, or the zero time if it
+// has never been written to.
+func (dao *ArticleDAO) LastModified() time.Time {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	return dao.modified
+}
+
 // Get retrieves an article by ID.
 func (dao *ArticleDAO) Get(id int64) (*Article, error) {
 	if id < 1 {
@@ -87,6 +302,129 @@ func (dao *ArticleDAO) Get(id int64) (*Article, error) {
 	return &article, nil
 }
 
+// GetMany retrieves the articles with the given IDs in one read of the store.
+// Found articles are returned in the order their IDs were given, along with
+// the IDs that were not found.
+func (dao *ArticleDAO) GetMany(ids []int64) ([]Article, []int64) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	found := make([]Article, 0, len(ids))
+	missing := []int64{}
+	for _, id := range ids {
+		article, exists := dao.articles[id]
+		if !exists {
+			missing = append(missing, id)
+			continue
+		}
+		found = append(found, article)
+	}
+
+	return found, missing
+}
+
+// GetAll retrieves every article in the store, ordered by ID.
+func (dao *ArticleDAO) GetAll() []Article {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	result := make([]Article, 0, len(dao.articles))
+	for _, article := range dao.articles {
+		result = append(result, article)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+// Recent retrieves up to n of the newest articles, ordered by date and then ID,
+// newest first. If tag is not empty, only articles with that tag are included.
+func (dao *ArticleDAO) Recent(tag string, n int) []Article {
+	var result []Article
+	for _, article := range dao.GetAll() {
+		if tag == "" || slices.Contains(article.Tags, tag) {
+			result = append(result, article)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		di, dj := result[i].Date.ToTime(), result[j].Date.ToTime()
+		if !di.Equal(dj) {
+			return di.After(dj)
+		}
+		return result[i].ID > result[j].ID
+	})
+
+	return result[:min(n, len(result))]
+}
+
+// GetByTagBetween retrieves the articles with a tag dated from from to to
+// inclusive, ordered by date and then ID, oldest first. A zero from or to
+// leaves that end of the range open.
+func (dao *ArticleDAO) GetByTagBetween(tag string, from, to time.Time) []Article {
+	var result []Article
+	for _, article := range dao.GetAll() {
+		date := article.Date.ToTime()
+		if !slices.Contains(article.Tags, tag) || (!from.IsZero() && date.Before(from)) || (!to.IsZero() && date.After(to)) {
+			continue
+		}
+		result = append(result, article)
+	}
+
+	// GetAll orders by ID, so a stable sort by date leaves IDs in order.
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.ToTime().Before(result[j].Date.ToTime())
+	})
+
+	return result
+}
+
+// List retrieves the articles matching the filter, ordered by ID, and returns
+// the requested page of them along with pagination metadata.
+func (dao *ArticleDAO) List(filter ArticleFilter, filters Filters) ([]Article, Metadata) {
+	var matches []Article
+	for _, article := range dao.GetAll() {
+		if filter.Matches(&article) {
+			matches = append(matches, article)
+		}
+	}
+
+	metadata := calculateMetadata(len(matches), filters.Page, filters.PageSize)
+
+	start := min(filters.offset(), len(matches))
+	end := min(start+filters.limit(), len(matches))
+
+	return matches[start:end], metadata
+}
+
+// ListAfter retrieves up to n of the articles matching the filter whose IDs
+// are greater than after, ordered by ID. It also returns the number of
+// articles matching the filter, and whether more follow those returned.
+func (dao *ArticleDAO) ListAfter(filter ArticleFilter, after int64, n int) ([]Article, int, bool) {
+	var result []Article
+	total, more := 0, false
+	for _, article := range dao.GetAll() {
+		if !filter.Matches(&article) {
+			continue
+		}
+		total++
+
+		if article.ID <= after {
+			continue
+		}
+		if len(result) == n {
+			more = true
+			continue
+		}
+		result = append(result, article)
+	}
+
+	return result, total, more
+}
+
 // GetArticlesByTagAndDate retrieves articles by tag and date.
 func (dao *ArticleDAO) GetArticlesByTagAndDate(tag string, date ArticleDate) ([]Article, error) {
 	dao.mutex.RLock()
@@ -6,6 +6,8 @@ import (
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
+	ErrDuplicateID    = errors.New("duplicate key, article with ID already exists")
+	ErrNearDuplicate  = errors.New("article is a near-duplicate of an existing article")
 )
 
 // DAOs represents a collection of data access objects.
@@ -0,0 +1,72 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/text"
+)
+
+// ArticleMetrics holds statistics derived from an article's content.
+type ArticleMetrics struct {
+	WordCount          int     `json:"word_count"`
+	ReadingTimeMinutes int     `json:"reading_time_minutes"`
+	ReadingEase        float64 `json:"flesch_reading_ease"`
+	Language           string  `json:"language"`
+}
+
+// ComputeMetrics calculates the content metrics for an article. Word count,
+// reading time and readability describe the body; the language is detected
+// from the title and body together.
+func ComputeMetrics(article *Article) *ArticleMetrics {
+	wordCount := len(text.Words(article.Body))
+
+	return &ArticleMetrics{
+		WordCount:          wordCount,
+		ReadingTimeMinutes: text.ReadingTime(wordCount),
+		ReadingEase:        text.FleschReadingEase(article.Body),
+		Language:           text.DetectLanguage(article.Title + "\n" + article.Body),
+	}
+}
+
+// ArticleSummary is an extractive summary of an article body.
+type ArticleSummary struct {
+	ID        int64    `json:"id"`
+	Sentences []string `json:"sentences"`
+	Text      string   `json:"text"`
+}
+
+// SummarizeArticle picks up to the given number of key sentences from the
+// article body using TextRank.
+func SummarizeArticle(article *Article, sentences int) ArticleSummary {
+	summary := text.Summarize(article.Body, sentences)
+	if summary == nil {
+		summary = []string{}
+	}
+
+	return ArticleSummary{
+		ID:        article.ID,
+		Sentences: summary,
+		Text:      strings.Join(summary, " "),
+	}
+}
+
+// StoreSummaries makes the store keep a summary of the given number of
+// sentences on every article it saves. Zero turns stored summaries off.
+func (dao *ArticleDAO) StoreSummaries(sentences int) {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	dao.summarySentences = sentences
+}
+
+// derive computes the fields that the store keeps alongside each article. It
+// must be called with the mutex held.
+func (dao *ArticleDAO) derive(article *Article) {
+	article.Fingerprint = Fingerprint(article)
+	article.Metrics = ComputeMetrics(article)
+
+	article.Summary = ""
+	if dao.summarySentences > 0 {
+		article.Summary = SummarizeArticle(article, dao.summarySentences).Text
+	}
+}
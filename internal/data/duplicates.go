@@ -0,0 +1,211 @@
+package data
+
+import (
+	"math"
+	"sort"
+
+	"github.com/des-ant/2024-article-api/internal/text"
+)
+
+// NearDuplicate describes a stored article that closely resembles another one.
+type NearDuplicate struct {
+	ID         int64   `json:"id"`
+	Title      string  `json:"title"`
+	Similarity float64 `json:"similarity"`
+}
+
+// DuplicateCluster is a group of stored articles that are near-duplicates of
+// each other. Similarity is the lowest pairwise score that joined the cluster.
+type DuplicateCluster struct {
+	Articles   []int64 `json:"articles"`
+	Similarity float64 `json:"similarity"`
+}
+
+// Fingerprint computes the SimHash fingerprint of an article's title and body.
+func Fingerprint(article *Article) uint64 {
+	return text.SimHash(article.Title + "\n" + article.Body)
+}
+
+// FindNearDuplicates returns the stored articles whose similarity to the
+// provided article is at least threshold, most similar first. The article
+// itself is skipped if it is already in the store.
+func (dao *ArticleDAO) FindNearDuplicates(article *Article, threshold float64) []NearDuplicate {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	return dao.findNearDuplicates(article, threshold)
+}
+
+// findNearDuplicates does the work of FindNearDuplicates. It must be called
+// with the mutex held.
+func (dao *ArticleDAO) findNearDuplicates(article *Article, threshold float64) []NearDuplicate {
+	fingerprint := Fingerprint(article)
+
+	var result []NearDuplicate
+	for id, stored := range dao.articles {
+		if id == article.ID {
+			continue
+		}
+
+		similarity := text.Similarity(fingerprint, stored.Fingerprint)
+		if similarity >= threshold {
+			result = append(result, NearDuplicate{
+				ID:         id,
+				Title:      stored.Title,
+				Similarity: similarity,
+			})
+		}
+	}
+
+	sortNearDuplicates(result)
+	return result
+}
+
+// sortNearDuplicates orders near-duplicates most similar first, and then by
+// ID.
+func sortNearDuplicates(duplicates []NearDuplicate) {
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].Similarity != duplicates[j].Similarity {
+			return duplicates[i].Similarity > duplicates[j].Similarity
+		}
+		return duplicates[i].ID < duplicates[j].ID
+	})
+}
+
+// GetDuplicateClusters groups every stored article with its near-duplicates.
+// Articles are linked when their similarity is at least threshold, and linked
+// articles are merged transitively into clusters. Articles without any
+// near-duplicates are not reported.
+func (dao *ArticleDAO) GetDuplicateClusters(threshold float64) []DuplicateCluster {
+	articles := dao.GetAll()
+
+	fingerprints := make([]uint64, len(articles))
+	for i, article := range articles {
+		fingerprints[i] = article.Fingerprint
+	}
+
+	// Union-find over article indexes, tracking the weakest link per root.
+	parent := make([]int, len(articles))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	weakest := make(map[int]float64)
+	similarPairs(fingerprints, threshold, func(i, j int, similarity float64) {
+		ri, rj := find(i), find(j)
+		lowest := similarity
+		if w, ok := weakest[ri]; ok && w < lowest {
+			lowest = w
+		}
+		if w, ok := weakest[rj]; ok && w < lowest {
+			lowest = w
+		}
+
+		parent[rj] = ri
+		delete(weakest, rj)
+		weakest[ri] = lowest
+	})
+
+	groups := make(map[int][]int64)
+	for i, article := range articles {
+		root := find(i)
+		groups[root] = append(groups[root], article.ID)
+	}
+
+	var clusters []DuplicateCluster
+	for root, ids := range groups {
+		if len(ids) < 2 {
+			continue
+		}
+		clusters = append(clusters, DuplicateCluster{
+			Articles:   ids,
+			Similarity: weakest[root],
+		})
+	}
+
+	// Articles are visited in ID order, so each cluster is already sorted and
+	// clusters can be ordered by their lowest ID.
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Articles[0] < clusters[j].Articles[0]
+	})
+
+	return clusters
+}
+
+// similarPairs calls fn once for every pair of fingerprints, by index with
+// i < j, whose similarity is at least threshold.
+//
+// Rather than compare every pair, the fingerprints are indexed by blocks of
+// their bits: if two fingerprints differ in at most k bits and are split into
+// k+1 blocks, at least one block must be the same in both, so only
+// fingerprints sharing a block need comparing. At low thresholds the blocks
+// get too narrow to narrow anything down, and every pair is compared.
+func similarPairs(fingerprints []uint64, threshold float64, fn func(i, j int, similarity float64)) {
+	// The most bits two fingerprints can differ in and still be similar. The
+	// small margin keeps rounding from leaving out the boundary case.
+	maxBits := int(math.Floor(64*(1-threshold) + 1e-9))
+	blocks := min(maxBits+1, 64)
+
+	if width := 64 / blocks; width < 32 && blocks >= 1<<width {
+		for i := range fingerprints {
+			for j := i + 1; j < len(fingerprints); j++ {
+				if similarity := text.Similarity(fingerprints[i], fingerprints[j]); similarity >= threshold {
+					fn(i, j, similarity)
+				}
+			}
+		}
+		return
+	}
+
+	// Spread the 64 bits across the blocks as evenly as possible.
+	masks := make([]uint64, blocks)
+	start := 0
+	for b := range masks {
+		width := 64 / blocks
+		if b < 64%blocks {
+			width++
+		}
+		masks[b] = (1<<width - 1) << start
+		start += width
+	}
+
+	for b, mask := range masks {
+		buckets := make(map[uint64][]int)
+		for i, fingerprint := range fingerprints {
+			buckets[fingerprint&mask] = append(buckets[fingerprint&mask], i)
+		}
+
+		for _, bucket := range buckets {
+			for x, i := range bucket {
+				for _, j := range bucket[x+1:] {
+					// Each pair is only reported for the first block it
+					// shares.
+					if sharesBlock(fingerprints[i], fingerprints[j], masks[:b]) {
+						continue
+					}
+					if similarity := text.Similarity(fingerprints[i], fingerprints[j]); similarity >= threshold {
+						fn(i, j, similarity)
+					}
+				}
+			}
+		}
+	}
+}
+
+// sharesBlock reports whether a and b are the same in any of the blocks.
+func sharesBlock(a, b uint64, masks []uint64) bool {
+	for _, mask := range masks {
+		if (a^b)&mask == 0 {
+			return true
+		}
+	}
+	return false
+}
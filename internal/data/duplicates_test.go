@@ -0,0 +1,124 @@
+package data
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/des-ant/2024-article-api/internal/text"
+)
+
+func TestSimilarPairs(t *testing.T) {
+	// Random fingerprints, each followed by a few copies with some bits
+	// flipped, so that there are pairs at every similarity near the top.
+	rng := rand.New(rand.NewPCG(1, 2))
+	var fingerprints []uint64
+	for range 50 {
+		fingerprint := rng.Uint64()
+		fingerprints = append(fingerprints, fingerprint)
+		for flips := range 8 {
+			for range flips {
+				fingerprint ^= 1 << rng.IntN(64)
+			}
+			fingerprints = append(fingerprints, fingerprint)
+		}
+	}
+
+	type pair struct{ i, j int }
+
+	tests := []struct {
+		name      string
+		threshold float64
+	}{
+		{name: "Identical", threshold: 1},
+		{name: "Default", threshold: 0.9},
+		{name: "Boundary", threshold: 1 - 6.0/64},
+		{name: "Loose", threshold: 0.75},
+		{name: "Every Pair", threshold: 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := make(map[pair]float64)
+			for i := range fingerprints {
+				for j := i + 1; j < len(fingerprints); j++ {
+					if similarity := text.Similarity(fingerprints[i], fingerprints[j]); similarity >= tt.threshold {
+						expected[pair{i, j}] = similarity
+					}
+				}
+			}
+
+			got := make(map[pair]float64)
+			similarPairs(fingerprints, tt.threshold, func(i, j int, similarity float64) {
+				if _, ok := got[pair{i, j}]; ok || i >= j {
+					t.Errorf("pair (%d, %d) reported out of order or twice", i, j)
+				}
+				got[pair{i, j}] = similarity
+			})
+
+			assert.NotEmpty(t, expected)
+			assert.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestInsertDistinct(t *testing.T) {
+	dao := NewArticleDAO()
+	stored := &Article{ID: 1, Title: "Potato chips", Body: "Potato chips are tasty and crunchy."}
+	assert.NoError(t, dao.Insert(stored))
+
+	tests := []struct {
+		name        string
+		articles    []*Article
+		expectedErr error
+		duplicates  []int64
+	}{
+		{
+			name:        "Duplicate ID",
+			articles:    []*Article{{ID: 1, Title: "Other", Body: "Something else entirely."}},
+			expectedErr: ErrDuplicateID,
+		},
+		{
+			name:        "Near Duplicate",
+			articles:    []*Article{{ID: 2, Title: "Potato chips", Body: "Potato chips are tasty and crunchy."}},
+			expectedErr: ErrNearDuplicate,
+			duplicates:  []int64{1},
+		},
+		{
+			name: "Near Duplicate In Batch",
+			articles: []*Article{
+				{ID: 4, Title: "Bird found", Body: "A new species of bird has been found in the Pacific."},
+				{ID: 5, Title: "Bird found", Body: "A new species of bird has been found in the Pacific!"},
+			},
+			expectedErr: ErrNearDuplicate,
+			duplicates:  []int64{4},
+		},
+		{
+			name:     "Distinct",
+			articles: []*Article{{ID: 3, Title: "Rockets", Body: "Space agencies launch rockets to Mars."}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duplicates, err := dao.InsertDistinct(tt.articles, 0.9)
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			var ids []int64
+			for _, duplicate := range duplicates {
+				ids = append(ids, duplicate.ID)
+			}
+			assert.Equal(t, tt.duplicates, ids)
+
+			for _, article := range tt.articles {
+				_, err = dao.Get(article.ID)
+				if tt.expectedErr == nil {
+					assert.NoError(t, err)
+				} else if article.ID != stored.ID {
+					assert.ErrorIs(t, err, ErrRecordNotFound)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/text"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// Filters holds the pagination parameters for listings.
+type Filters struct {
+	Page     int
+	PageSize int
+}
+
+// ValidateFilters checks that the pagination parameters are within range.
+func ValidateFilters(v *validator.Validator, f Filters) {
+	v.Check(f.Page > 0, "page", "must be greater than zero")
+	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+}
+
+// limit returns the number of records on a page.
+func (f Filters) limit() int {
+	return f.PageSize
+}
+
+// offset returns the number of records before the current page.
+func (f Filters) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+// Metadata holds the pagination details returned alongside a listing.
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records,omitempty"`
+}
+
+// calculateMetadata works out the pagination metadata for a listing of
+// totalRecords records. An empty listing has empty metadata.
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
+
+// ArticleFilter narrows down a listing of articles using their content
+// metrics. Zero values and nil pointers match every article.
+type ArticleFilter struct {
+	Language       string
+	MinWordCount   int
+	MaxWordCount   int
+	MaxReadingTime int
+	MinReadingEase *float64
+	MaxReadingEase *float64
+}
+
+// ValidateArticleFilter checks that the filter values are sensible.
+func ValidateArticleFilter(v *validator.Validator, f ArticleFilter) {
+	v.Check(f.MinWordCount >= 0, "min_word_count", "must not be negative")
+	v.Check(f.MaxWordCount >= 0, "max_word_count", "must not be negative")
+	v.Check(f.MaxReadingTime >= 0, "max_reading_time", "must not be negative")
+
+	languages := text.Languages()
+	v.Check(f.Language == "" || validator.PermittedValue(f.Language, languages...), "language",
+		fmt.Sprintf("must be one of %s or %s", strings.Join(languages[:len(languages)-1], ", "), languages[len(languages)-1]))
+}
+
+// Matches reports whether the article satisfies every condition in the filter.
+func (f ArticleFilter) Matches(article *Article) bool {
+	metrics := article.Metrics
+	if metrics == nil {
+		metrics = ComputeMetrics(article)
+	}
+
+	switch {
+	case f.Language != "" && metrics.Language != f.Language:
+		return false
+	case f.MinWordCount > 0 && metrics.WordCount < f.MinWordCount:
+		return false
+	case f.MaxWordCount > 0 && metrics.WordCount > f.MaxWordCount:
+		return false
+	case f.MaxReadingTime > 0 && metrics.ReadingTimeMinutes > f.MaxReadingTime:
+		return false
+	case f.MinReadingEase != nil && metrics.ReadingEase < *f.MinReadingEase:
+		return false
+	case f.MaxReadingEase != nil && metrics.ReadingEase > *f.MaxReadingEase:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"github.com/des-ant/2024-article-api/internal/text"
+)
+
+// ArticleKeywords holds the keyphrases extracted from an article body and the
+// named entity candidates found in its title and body.
+type ArticleKeywords struct {
+	ID       int64          `json:"id"`
+	Keywords []text.Keyword `json:"keywords"`
+	Entities []string       `json:"entities"`
+}
+
+// ExtractKeywords returns up to limit keyphrases and every entity candidate
+// for the article.
+func ExtractKeywords(article *Article, limit int) ArticleKeywords {
+	keywords := text.Keywords(article.Body, limit)
+
+	// Treat the title as its own sentence so it does not run into the body.
+	entities := text.Entities(article.Title + ".\n\n" + article.Body)
+
+	return ArticleKeywords{
+		ID:       article.ID,
+		Keywords: keywords,
+		Entities: entities,
+	}
+}
+
+// TopKeywords aggregates the keyphrases of several articles by summing their
+// scores and returns the limit highest scoring phrases.
+func TopKeywords(articles []Article, limit int) []string {
+	scores := make(map[string]float64)
+	for _, article := range articles {
+		for _, keyword := range text.Keywords(article.Body, limit) {
+			scores[keyword.Phrase] += keyword.Score
+		}
+	}
+
+	keywords := make([]text.Keyword, 0, len(scores))
+	for phrase, score := range scores {
+		keywords = append(keywords, text.Keyword{Phrase: phrase, Score: score})
+	}
+	text.SortKeywords(keywords)
+
+	top := make([]string, 0, limit)
+	for i := 0; i < len(keywords) && i < limit; i++ {
+		top = append(top, keywords[i].Phrase)
+	}
+
+	return top
+}
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"sort"
+
+	"github.com/des-ant/2024-article-api/internal/text"
+)
+
+// MaxArticleTags is the maximum number of tags an article may have.
+const MaxArticleTags = 10
+
+// SuggestTags ranks tags for the given title and body using a naive Bayes
+// classifier trained on the articles currently in the store. Tags listed in
+// exclude are never suggested, and at most limit suggestions are returned.
+func (dao *ArticleDAO) SuggestTags(title, body string, exclude []string, limit int) []text.TagSuggestion {
+	return dao.tagClassifier().Suggest(title+"\n"+body, limit, exclude)
+}
+
+// tagClassifier returns the classifier trained on the stored articles,
+// training it first if the store has changed since it was last trained.
+func (dao *ArticleDAO) tagClassifier() *text.TagClassifier {
+	dao.mutex.RLock()
+	classifier := dao.classifier
+	dao.mutex.RUnlock()
+
+	if classifier != nil {
+		return classifier
+	}
+
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	// Another request may have trained it while the lock was released.
+	if dao.classifier == nil {
+		dao.classifier = text.NewTagClassifier()
+		for _, article := range dao.articles {
+			dao.classifier.Train(article.Title+"\n"+article.Body, article.Tags)
+		}
+	}
+
+	return dao.classifier
+}
+
+// TagCount is the number of stored articles with a tag.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TagCounts returns every tag in the store with the number of articles that
+// have it, ordered by tag.
+func (dao *ArticleDAO) TagCounts() []TagCount {
+	counts := make(map[string]int)
+
+	dao.mutex.RLock()
+	for _, article := range dao.articles {
+		for _, tag := range article.Tags {
+			counts[tag]++
+		}
+	}
+	dao.mutex.RUnlock()
+
+	result := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, TagCount{Tag: tag, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+
+	return result
+}
@@ -0,0 +1,344 @@
+// Package dirsync keeps the article store in step with a directory of
+// Markdown files with YAML front matter, such as a writers' git repository.
+//
+// Each sync reads every file, works out how the store differs from it, and
+// unless it is a dry run applies the difference. A Syncer can also poll the
+// directory, syncing whenever a file is added, changed or removed.
+package dirsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/validator"
+)
+
+// Action says how a sync changes the store.
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+// Change is a change a sync makes, or in a dry run would make, to the store.
+type Change struct {
+	Action Action `json:"action"`
+	ID     int64  `json:"id"`
+	Title  string `json:"title"`
+	// File is the path of the article's file relative to the directory. It is
+	// empty for deletions.
+	File string `json:"file,omitempty"`
+	// Fields lists the fields an update changes.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Problem describes a file that couldn't be synced. Its article, if stored,
+// is left alone.
+type Problem struct {
+	File   string            `json:"file"`
+	Errors map[string]string `json:"errors"`
+}
+
+// Result reports what a sync did.
+type Result struct {
+	Changes  []Change  `json:"changes"`
+	Problems []Problem `json:"problems"`
+}
+
+// Options configure a Syncer.
+type Options struct {
+	// Delete removes stored articles that have no file, making the directory
+	// the only source of articles. A sync that finds a file it can't parse
+	// deletes nothing, as the file could be that of any article.
+	Delete bool
+	// Prepare validates an article read from a file, and may normalise it,
+	// as the API would when creating it. It defaults to data.ValidateArticle.
+	Prepare func(v *validator.Validator, article *data.Article)
+	// Insert stores an article created from a file. It defaults to the
+	// store's Insert. An article it refuses with data.ErrNearDuplicate,
+	// returning the articles it resembles, is reported as a problem with its
+	// file rather than stopping the sync.
+	Insert func(article *data.Article) ([]data.NearDuplicate, error)
+}
+
+// Syncer syncs the store with a directory.
+type Syncer struct {
+	dir      string
+	articles *data.ArticleDAO
+	options  Options
+
+	// mutex stops syncs from overlapping.
+	mutex sync.Mutex
+}
+
+// New returns a Syncer keeping articles in step with the files in dir.
+func New(dir string, articles *data.ArticleDAO, options Options) *Syncer {
+	if options.Prepare == nil {
+		options.Prepare = data.ValidateArticle
+	}
+	if options.Insert == nil {
+		options.Insert = func(article *data.Article) ([]data.NearDuplicate, error) {
+			return nil, articles.Insert(article)
+		}
+	}
+	return &Syncer{dir: dir, articles: articles, options: options}
+}
+
+// articleFile is an article read from a file.
+type articleFile struct {
+	name    string
+	article data.Article
+}
+
+// Sync reads the directory and applies the changes needed to bring the store
+// in line with it. In a dry run the changes are only reported.
+func (s *Syncer) Sync(dryRun bool) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result := Result{Changes: []Change{}, Problems: []Problem{}}
+
+	files, keep, unparsed, err := s.readArticles(&result)
+	if err != nil {
+		return result, err
+	}
+
+	for _, file := range files {
+		article := file.article
+		keep[article.ID] = true
+
+		stored, err := s.articles.Get(article.ID)
+		if err != nil {
+			if !dryRun {
+				duplicates, err := s.options.Insert(&article)
+				if errors.Is(err, data.ErrNearDuplicate) {
+					result.Problems = append(result.Problems, Problem{File: file.name, Errors: map[string]string{
+						"article": fmt.Sprintf("is a near-duplicate of existing article %d", duplicates[0].ID),
+					}})
+					continue
+				}
+				if err != nil {
+					return result, fmt.Errorf("dirsync: storing %s: %w", file.name, err)
+				}
+			}
+			result.Changes = append(result.Changes, Change{Action: Create, ID: article.ID, Title: article.Title, File: file.name})
+			continue
+		}
+
+		fields := changedFields(*stored, article)
+		if len(fields) == 0 {
+			continue
+		}
+
+		result.Changes = append(result.Changes, Change{Action: Update, ID: article.ID, Title: article.Title, File: file.name, Fields: fields})
+		if !dryRun {
+			err = s.articles.Update(&article)
+			if err != nil {
+				return result, fmt.Errorf("dirsync: storing %s: %w", file.name, err)
+			}
+		}
+	}
+
+	if !s.options.Delete || unparsed {
+		return result, nil
+	}
+
+	for _, stored := range s.articles.GetAll() {
+		if keep[stored.ID] {
+			continue
+		}
+		result.Changes = append(result.Changes, Change{Action: Delete, ID: stored.ID, Title: stored.Title})
+		if !dryRun {
+			// The article may already have been deleted by a request, which
+			// is no reason to stop.
+			_ = s.articles.Delete(stored.ID)
+		}
+	}
+
+	return result, nil
+}
+
+// readArticles reads and validates every article file in the directory,
+// adding the files it can't use to result's problems. It also returns the IDs
+// of articles whose files have problems, which deletion must leave alone, and
+// whether any file couldn't be parsed, leaving its article's ID unknown.
+func (s *Syncer) readArticles(result *Result) ([]articleFile, map[int64]bool, bool, error) {
+	names, err := s.listFiles()
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var files []articleFile
+	keep := make(map[int64]bool)
+	usedBy := make(map[int64]string)
+	unparsed := false
+
+	for _, name := range names {
+		src, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("dirsync: %w", err)
+		}
+
+		problem := func(errors map[string]string) {
+			result.Problems = append(result.Problems, Problem{File: name, Errors: errors})
+		}
+
+		article, err := ParseArticle(src)
+		if err != nil {
+			problem(map[string]string{"file": err.Error()})
+			unparsed = true
+			continue
+		}
+
+		if article.ID > 0 {
+			if other, ok := usedBy[article.ID]; ok {
+				problem(map[string]string{"id": fmt.Sprintf("is also used by %s", other)})
+				continue
+			}
+			usedBy[article.ID] = name
+		}
+
+		v := validator.New()
+		if s.options.Prepare(v, &article); !v.Valid() {
+			problem(v.Errors)
+			keep[article.ID] = true
+			continue
+		}
+
+		files = append(files, articleFile{name: name, article: article})
+	}
+
+	return files, keep, unparsed, nil
+}
+
+// listFiles returns the paths, relative to the directory, of the article files
+// in it and its subdirectories, in lexical order. Hidden directories such as
+// .git are skipped.
+func (s *Syncer) listFiles() ([]string, error) {
+	var names []string
+
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != s.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(d.Name()))
+		if ext != ".md" && ext != ".markdown" {
+			return nil
+		}
+
+		name, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dirsync: %w", err)
+	}
+
+	return names, nil
+}
+
+// changedFields lists the fields that differ between a stored article and
+// the one read from its file.
+func changedFields(stored, article data.Article) []string {
+	var fields []string
+	if stored.Title != article.Title {
+		fields = append(fields, "title")
+	}
+	if !stored.Date.ToTime().Equal(article.Date.ToTime()) {
+		fields = append(fields, "date")
+	}
+	if stored.Body != article.Body {
+		fields = append(fields, "body")
+	}
+	if !slices.Equal(stored.Tags, article.Tags) {
+		fields = append(fields, "tags")
+	}
+	return fields
+}
+
+// fileStamp identifies a version of a file for change detection.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stamp returns the stamps of the article files in the directory, keyed by
+// their paths.
+func (s *Syncer) stamp() (map[string]fileStamp, error) {
+	names, err := s.listFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	stamps := make(map[string]fileStamp, len(names))
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("dirsync: %w", err)
+		}
+		stamps[name] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	return stamps, nil
+}
+
+// Watch polls the directory every interval until ctx is done, syncing it
+// straight away and then whenever an article file is added, removed, or
+// changes its size or modification time. A change is only synced once the
+// directory has stayed the same for a poll, so that files still being
+// written aren't read half-finished. It calls report with the result of each
+// sync, and with any error reading the directory. A sync that fails is
+// retried at the next poll.
+func (s *Syncer) Watch(ctx context.Context, interval time.Duration, report func(Result, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// synced holds the stamps at the last successful sync, and previous
+	// those found by the last poll.
+	var synced, previous map[string]fileStamp
+	for {
+		stamps, err := s.stamp()
+		switch {
+		case err != nil:
+			report(Result{}, err)
+		case synced == nil || !maps.Equal(stamps, synced) && maps.Equal(stamps, previous):
+			result, err := s.Sync(false)
+			report(result, err)
+			if err == nil {
+				synced = stamps
+			}
+		}
+		if err == nil {
+			previous = stamps
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
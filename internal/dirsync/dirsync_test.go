@@ -0,0 +1,278 @@
+package dirsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+)
+
+func TestParseArticle(t *testing.T) {
+	date := data.ArticleDate(time.Date(2016, 9, 22, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name        string
+		src         string
+		expected    data.Article
+		expectedErr string
+	}{
+		{
+			name: "Valid",
+			src:  "---\nid: 1\ntitle: Potatoes\ndate: 2016-09-22\ntags: [health, food]\nauthor: ignored\n---\n\nPotatoes are *great*.\n",
+			expected: data.Article{
+				ID: 1, Title: "Potatoes", Date: date, Body: "Potatoes are *great*.", Tags: []string{"health", "food"},
+			},
+		},
+		{
+			name: "WindowsLineEndingsAndByteOrderMark",
+			src:  "\ufeff---\r\nid: 2\r\ntitle: Chips\r\ntags:\r\n  - food\r\n...\r\nCrunchy\r\n--- not front matter\r\n",
+			expected: data.Article{
+				ID: 2, Title: "Chips", Body: "Crunchy\n--- not front matter", Tags: []string{"food"},
+			},
+		},
+		{
+			name:     "EmptyFrontMatterAndBody",
+			src:      "---\n---",
+			expected: data.Article{},
+		},
+		{
+			name:        "NoFrontMatter",
+			src:         "# Potatoes\n",
+			expectedErr: "must start with front matter between --- lines",
+		},
+		{
+			name:        "UnterminatedFrontMatter",
+			src:         "---\ntitle: Potatoes\n",
+			expectedErr: "front matter must end with a --- line",
+		},
+		{
+			name:        "BadYAML",
+			src:         "---\ntags: [health\n---\n",
+			expectedErr: "badly-formed front matter",
+		},
+		{
+			name:        "BadDate",
+			src:         "---\ndate: 22/09/2016\n---\n",
+			expectedErr: "date must be in the YYYY-MM-DD format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article, err := ParseArticle([]byte(tt.src))
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, article)
+		})
+	}
+}
+
+// writeFile writes an article file into dir, creating its parent directories.
+func writeFile(t *testing.T, dir, name, src string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, dir, "a.md", "---\nid: 1\ntitle: First\ndate: 2016-09-22\ntags: [health]\n---\nFirst body\n")
+	writeFile(t, dir, "posts/b.markdown", "---\nid: 2\ntitle: Second\ndate: 2016-09-23\ntags: [food]\n---\nSecond body\n")
+	writeFile(t, dir, "posts/untagged.md", "---\nid: 3\ntitle: Untagged\ndate: 2016-09-24\n---\nBody\n")
+	writeFile(t, dir, "posts/broken.md", "no front matter")
+	writeFile(t, dir, "notes.txt", "not an article")
+	writeFile(t, dir, ".git/c.md", "---\nid: 4\n---\n")
+
+	dao := data.NewArticleDAO()
+	syncer := New(dir, dao, Options{Delete: true})
+
+	t.Run("DryRun", func(t *testing.T) {
+		result, err := syncer.Sync(true)
+		require.NoError(t, err)
+		assert.Equal(t, []Change{
+			{Action: Create, ID: 1, Title: "First", File: "a.md"},
+			{Action: Create, ID: 2, Title: "Second", File: "posts/b.markdown"},
+		}, result.Changes)
+		require.Len(t, result.Problems, 2)
+		assert.Equal(t, Problem{File: "posts/broken.md", Errors: map[string]string{"file": "must start with front matter between --- lines"}}, result.Problems[0])
+		assert.Equal(t, "posts/untagged.md", result.Problems[1].File)
+		assert.Equal(t, "must be provided", result.Problems[1].Errors["tags"])
+		assert.Empty(t, dao.GetAll())
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		result, err := syncer.Sync(false)
+		require.NoError(t, err)
+		assert.Len(t, result.Changes, 2)
+		assert.Len(t, result.Problems, 2)
+
+		article, err := dao.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "Second body", article.Body)
+		assert.NotNil(t, article.Metrics)
+
+		result, err = syncer.Sync(false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		writeFile(t, dir, "a.md", "---\nid: 1\ntitle: First, revised\ndate: 2016-09-22\ntags: [health, news]\n---\nFirst body\n")
+
+		result, err := syncer.Sync(false)
+		require.NoError(t, err)
+		assert.Equal(t, []Change{
+			{Action: Update, ID: 1, Title: "First, revised", File: "a.md", Fields: []string{"title", "tags"}},
+		}, result.Changes)
+
+		article, err := dao.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "First, revised", article.Title)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		// Articles stored by other means go too, unless their file only has
+		// a problem.
+		require.NoError(t, dao.Insert(&data.Article{ID: 3, Title: "Untagged", Body: "Body", Tags: []string{"a"}}))
+		require.NoError(t, dao.Insert(&data.Article{ID: 9, Title: "Elsewhere", Body: "Body", Tags: []string{"a"}}))
+		require.NoError(t, os.Remove(filepath.Join(dir, "posts/b.markdown")))
+
+		// A file that can't be parsed could be that of any article, so
+		// nothing is deleted while there is one.
+		result, err := syncer.Sync(false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes)
+		assert.Len(t, dao.GetAll(), 4)
+
+		require.NoError(t, os.Remove(filepath.Join(dir, "posts/broken.md")))
+
+		result, err = syncer.Sync(true)
+		require.NoError(t, err)
+		assert.Equal(t, []Change{
+			{Action: Delete, ID: 2, Title: "Second"},
+			{Action: Delete, ID: 9, Title: "Elsewhere"},
+		}, result.Changes)
+		assert.Len(t, dao.GetAll(), 4)
+
+		_, err = syncer.Sync(false)
+		require.NoError(t, err)
+		assert.Len(t, dao.GetAll(), 2)
+
+		// Without the option nothing is deleted.
+		require.NoError(t, dao.Insert(&data.Article{ID: 9, Title: "Elsewhere", Body: "Body", Tags: []string{"a"}}))
+		result, err = New(dir, dao, Options{}).Sync(false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes)
+	})
+
+	t.Run("BrokenFrontMatter", func(t *testing.T) {
+		// A typo in the front matter of a stored article's file doesn't get
+		// the article deleted.
+		writeFile(t, dir, "a.md", "---\nid: 1\ntitle: First, revised\ntags: [health\n---\nFirst body\n")
+
+		result, err := syncer.Sync(false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes)
+		require.Len(t, result.Problems, 2)
+		assert.Equal(t, "a.md", result.Problems[0].File)
+
+		article, err := dao.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "First, revised", article.Title)
+
+		writeFile(t, dir, "a.md", "---\nid: 1\ntitle: First, revised\ndate: 2016-09-22\ntags: [health, news]\n---\nFirst body\n")
+	})
+
+	t.Run("DuplicateID", func(t *testing.T) {
+		writeFile(t, dir, "z.md", "---\nid: 1\ntitle: Clash\ndate: 2016-09-22\ntags: [x]\n---\nBody\n")
+		defer os.Remove(filepath.Join(dir, "z.md"))
+
+		result, err := New(dir, dao, Options{}).Sync(false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes)
+		assert.Contains(t, result.Problems, Problem{File: "z.md", Errors: map[string]string{"id": "is also used by a.md"}})
+	})
+
+	t.Run("NearDuplicate", func(t *testing.T) {
+		writeFile(t, dir, "z.md", "---\nid: 5\ntitle: First, revised\ndate: 2016-09-22\ntags: [health]\n---\nFirst body\n")
+		defer os.Remove(filepath.Join(dir, "z.md"))
+
+		// Articles the Insert option refuses as near-duplicates are reported
+		// as problems, and the rest of the sync goes ahead.
+		syncer := New(dir, dao, Options{Insert: func(article *data.Article) ([]data.NearDuplicate, error) {
+			return dao.InsertDistinct([]*data.Article{article}, 0.9)
+		}})
+		result, err := syncer.Sync(false)
+		require.NoError(t, err)
+		assert.Empty(t, result.Changes)
+		assert.Contains(t, result.Problems, Problem{File: "z.md", Errors: map[string]string{"article": "is a near-duplicate of existing article 1"}})
+
+		_, err = dao.Get(5)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("MissingDirectory", func(t *testing.T) {
+		_, err := New(filepath.Join(dir, "missing"), dao, Options{}).Sync(false)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.md", "---\nid: 1\ntitle: First\ndate: 2016-09-22\ntags: [health]\n---\nFirst body\n")
+
+	dao := data.NewArticleDAO()
+	syncer := New(dir, dao, Options{})
+
+	results := make(chan Result, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		syncer.Watch(ctx, 10*time.Millisecond, func(result Result, err error) {
+			assert.NoError(t, err)
+			results <- result
+		})
+	}()
+
+	// The directory is synced straight away.
+	result := <-results
+	require.Len(t, result.Changes, 1)
+	assert.Equal(t, Create, result.Changes[0].Action)
+
+	// Polls that find nothing changed don't sync.
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, results)
+
+	writeFile(t, dir, "a.md", "---\nid: 1\ntitle: First\ndate: 2016-09-22\ntags: [health]\n---\nFirst body, edited\n")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a.md"), later, later))
+
+	select {
+	case result = <-results:
+		require.Len(t, result.Changes, 1)
+		assert.Equal(t, []string{"body"}, result.Changes[0].Fields)
+	case <-time.After(time.Second):
+		t.Fatal("change was not synced")
+	}
+
+	cancel()
+	<-done
+
+	article, err := dao.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, "First body, edited", article.Body)
+}
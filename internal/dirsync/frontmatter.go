@@ -0,0 +1,81 @@
+package dirsync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+)
+
+// frontMatter holds the fields read from an article file's front matter.
+// Other keys, which writers' tools often add, are ignored.
+type frontMatter struct {
+	ID    int64    `yaml:"id"`
+	Title string   `yaml:"title"`
+	Date  string   `yaml:"date"`
+	Tags  []string `yaml:"tags"`
+}
+
+// ParseArticle reads an article from a Markdown file that starts with YAML
+// front matter between "---" lines, such as:
+//
+//	---
+//	id: 1
+//	title: Potatoes
+//	date: 2016-09-22
+//	tags: [health, food]
+//	---
+//	Potatoes are *great*.
+//
+// The rest of the file is the body. The article isn't validated, except that
+// a date that is given must be in the YYYY-MM-DD format.
+func ParseArticle(src []byte) (data.Article, error) {
+	text := strings.TrimPrefix(string(src), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	lines := strings.SplitAfter(text, "\n")
+	if strings.TrimSuffix(lines[0], "\n") != "---" {
+		return data.Article{}, errors.New("must start with front matter between --- lines")
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if line := strings.TrimSuffix(lines[i], "\n"); line == "---" || line == "..." {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return data.Article{}, errors.New("front matter must end with a --- line")
+	}
+
+	header := strings.Join(lines[1:end], "")
+	body := strings.Join(lines[end+1:], "")
+
+	var fm frontMatter
+	err := yaml.Unmarshal([]byte(header), &fm)
+	if err != nil {
+		return data.Article{}, fmt.Errorf("badly-formed front matter (%s)", strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	article := data.Article{
+		ID:    fm.ID,
+		Title: fm.Title,
+		Body:  strings.TrimSpace(body),
+		Tags:  fm.Tags,
+	}
+
+	if fm.Date != "" {
+		date, err := time.Parse(time.DateOnly, fm.Date)
+		if err != nil {
+			return data.Article{}, errors.New("date must be in the YYYY-MM-DD format")
+		}
+		article.Date = data.ArticleDate(date)
+	}
+
+	return article, nil
+}
@@ -0,0 +1,219 @@
+// Package epub writes EPUB 3 e-books: a zip container holding an OPF package
+// document, a navigation document and an XHTML document for each chapter.
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MediaType is the media type of EPUB files.
+const MediaType = "application/epub+zip"
+
+// ErrNoChapters is returned by Write for a book without chapters, which EPUB
+// doesn't allow.
+var ErrNoChapters = errors.New("epub: a book needs at least one chapter")
+
+// Book is an e-book to write.
+type Book struct {
+	// Identifier uniquely identifies the book, such as the URL it was made
+	// from.
+	Identifier string
+	Title      string
+	// Language is a BCP 47 language tag, "en" if empty.
+	Language string
+	Creator  string
+	// Modified is when the book's content last changed.
+	Modified time.Time
+	Chapters []Chapter
+}
+
+// Chapter is a chapter of a book, listed in its table of contents.
+type Chapter struct {
+	Title string
+	// ContentXHTML is the chapter's body as an XHTML fragment, which must be
+	// well-formed XML. It goes in a <section> after the chapter's heading.
+	ContentXHTML string
+}
+
+// Paths of the files in the container. The package document and everything it
+// lists go in the EPUB directory.
+const (
+	containerPath = "META-INF/container.xml"
+	packagePath   = "EPUB/package.opf"
+	navPath       = "nav.xhtml"
+)
+
+// chapterPath returns the path of the nth chapter relative to the package
+// document, counting from 1.
+func chapterPath(n int) string {
+	return fmt.Sprintf("chapter-%d.xhtml", n)
+}
+
+// Write writes book to w as an EPUB file.
+func Write(w io.Writer, book Book) error {
+	if len(book.Chapters) == 0 {
+		return ErrNoChapters
+	}
+	if book.Language == "" {
+		book.Language = "en"
+	}
+
+	zw := zip.NewWriter(w)
+
+	// The mimetype file must come first, uncompressed and without extra
+	// fields, so that tools can identify the file from its first bytes.
+	// Writing it raw keeps the zip package from adding a data descriptor.
+	mimetype := []byte(MediaType)
+	f, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(mimetype)
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(zw, containerPath, containerXML())
+	if err != nil {
+		return err
+	}
+	err = writeFile(zw, packagePath, packageXML(book))
+	if err != nil {
+		return err
+	}
+	err = writeFile(zw, "EPUB/"+navPath, navXHTML(book))
+	if err != nil {
+		return err
+	}
+	for i, chapter := range book.Chapters {
+		err = writeFile(zw, "EPUB/"+chapterPath(i+1), chapterXHTML(book.Language, chapter))
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeFile adds a compressed file to the container.
+func writeFile(zw *zip.Writer, name string, content []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+func containerXML() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="` + packagePath + `" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`)
+	return buf.Bytes()
+}
+
+// packageXML returns the package document, which holds the book's metadata
+// and lists its files, and the order of its chapters in the spine.
+func packageXML(book Book) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<package version="3.0" unique-identifier="book-id" xml:lang="%s" xmlns="http://www.idpf.org/2007/opf">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="book-id">%s</dc:identifier>
+<dc:title>%s</dc:title>
+<dc:language>%s</dc:language>
+`, escape(book.Language), escape(book.Identifier), escape(book.Title), escape(book.Language))
+	if book.Creator != "" {
+		fmt.Fprintf(&buf, "<dc:creator>%s</dc:creator>\n", escape(book.Creator))
+	}
+	fmt.Fprintf(&buf, `<meta property="dcterms:modified">%s</meta>
+</metadata>
+<manifest>
+<item id="nav" href="%s" media-type="application/xhtml+xml" properties="nav"/>
+`, book.Modified.UTC().Format("2006-01-02T15:04:05Z"), navPath)
+	for i := range book.Chapters {
+		fmt.Fprintf(&buf, "<item id=\"chapter-%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, chapterPath(i+1))
+	}
+	buf.WriteString("</manifest>\n<spine>\n")
+	for i := range book.Chapters {
+		fmt.Fprintf(&buf, "<itemref idref=\"chapter-%d\"/>\n", i+1)
+	}
+	buf.WriteString("</spine>\n</package>\n")
+	return buf.Bytes()
+}
+
+// navXHTML returns the navigation document, whose table of contents links to
+// each chapter.
+func navXHTML(book Book) []byte {
+	var body strings.Builder
+	fmt.Fprintf(&body, "<nav epub:type=\"toc\" id=\"toc\">\n<h1>%s</h1>\n<ol>\n", escape(book.Title))
+	for i, chapter := range book.Chapters {
+		fmt.Fprintf(&body, "<li><a href=\"%s\">%s</a></li>\n", chapterPath(i+1), escape(chapter.Title))
+	}
+	body.WriteString("</ol>\n</nav>\n")
+	return xhtml(book.Language, book.Title, body.String())
+}
+
+func chapterXHTML(language string, chapter Chapter) []byte {
+	body := fmt.Sprintf("<section>\n<h1>%s</h1>\n%s</section>\n", escape(chapter.Title), validXML(chapter.ContentXHTML))
+	return xhtml(language, chapter.Title, body)
+}
+
+// xhtml wraps body in an XHTML document.
+func xhtml(language, title, body string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, `<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%s" lang="%s">
+<head>
+<meta charset="utf-8"/>
+<title>%s</title>
+</head>
+<body>
+%s</body>
+</html>
+`, escape(language), escape(language), escape(title), body)
+	return buf.Bytes()
+}
+
+// escape escapes s for use in XML text and attribute values, replacing the
+// characters XML doesn't allow.
+func escape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(validXML(s)))
+	return sb.String()
+}
+
+// validXML replaces the characters XML doesn't allow, such as most control
+// characters, with U+FFFD.
+func validXML(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20 || (r >= 0xD800 && r <= 0xDFFF) || r == 0xFFFE || r == 0xFFFF:
+			return utf8.RuneError
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,157 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// opf holds the parts of a package document the tests check.
+type opf struct {
+	Version    string `xml:"version,attr"`
+	UniqueID   string `xml:"unique-identifier,attr"`
+	Identifier struct {
+		ID    string `xml:"id,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"metadata>identifier"`
+	Title    string `xml:"metadata>title"`
+	Language string `xml:"metadata>language"`
+	Meta     []struct {
+		Property string `xml:"property,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"metadata>meta"`
+	Items []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func TestWrite(t *testing.T) {
+	book := Book{
+		Identifier: "https://example.com/v1/tags/food/export.epub?from=2016-01-01",
+		Title:      "Articles: food & drink",
+		Creator:    "Articles",
+		Modified:   time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("AEST", 10*60*60)),
+		Chapters: []Chapter{
+			{Title: "Chips <3", ContentXHTML: "<p>Salty <em>chips</em>.</p>\n"},
+			{Title: "Dips", ContentXHTML: "<p>Creamy\x00 dips.</p>\n"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, book))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = content
+
+		// Every file other than the mimetype is XML, which must be
+		// well-formed.
+		if f.Name != "mimetype" {
+			dec := xml.NewDecoder(bytes.NewReader(content))
+			for {
+				_, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err, f.Name)
+			}
+		}
+	}
+
+	t.Run("Mimetype", func(t *testing.T) {
+		first := zr.File[0]
+		assert.Equal(t, "mimetype", first.Name)
+		assert.Equal(t, zip.Store, first.Method)
+		assert.Empty(t, first.Extra)
+		assert.Equal(t, MediaType, string(files["mimetype"]))
+
+		// Readers identify EPUB files from the start of the local header.
+		assert.Equal(t, "mimetype"+MediaType, string(buf.Bytes()[30:30+len("mimetype"+MediaType)]))
+	})
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	require.NoError(t, xml.Unmarshal(files["META-INF/container.xml"], &container))
+	require.Len(t, container.Rootfiles, 1)
+	assert.Equal(t, "application/oebps-package+xml", container.Rootfiles[0].MediaType)
+
+	packagePath := container.Rootfiles[0].FullPath
+	var pkg opf
+	require.NoError(t, xml.Unmarshal(files[packagePath], &pkg))
+
+	t.Run("Metadata", func(t *testing.T) {
+		assert.Equal(t, "3.0", pkg.Version)
+		assert.Equal(t, pkg.UniqueID, pkg.Identifier.ID)
+		assert.Equal(t, book.Identifier, pkg.Identifier.Value)
+		assert.Equal(t, book.Title, pkg.Title)
+		assert.Equal(t, "en", pkg.Language)
+		require.Len(t, pkg.Meta, 1)
+		assert.Equal(t, "dcterms:modified", pkg.Meta[0].Property)
+		assert.Equal(t, "2024-05-05T21:08:09Z", pkg.Meta[0].Value)
+	})
+
+	t.Run("Manifest", func(t *testing.T) {
+		hrefs := make(map[string]string)
+		var nav string
+		for _, item := range pkg.Items {
+			name := path.Join(path.Dir(packagePath), item.Href)
+			assert.Contains(t, files, name, "manifest item %s", item.ID)
+			assert.Equal(t, "application/xhtml+xml", item.MediaType)
+			hrefs[item.ID] = item.Href
+			if item.Properties == "nav" {
+				nav = name
+			}
+		}
+
+		// The spine lists the chapters in order.
+		var spine []string
+		for _, itemref := range pkg.Spine {
+			spine = append(spine, hrefs[itemref.IDRef])
+		}
+		assert.Equal(t, []string{"chapter-1.xhtml", "chapter-2.xhtml"}, spine)
+
+		require.NotEmpty(t, nav)
+		assert.Contains(t, string(files[nav]), `<nav epub:type="toc" id="toc">`)
+		assert.Contains(t, string(files[nav]), `<li><a href="chapter-1.xhtml">Chips &lt;3</a></li>`)
+	})
+
+	t.Run("Chapters", func(t *testing.T) {
+		dir := path.Dir(packagePath)
+		first := string(files[path.Join(dir, "chapter-1.xhtml")])
+		assert.Contains(t, first, "<title>Chips &lt;3</title>")
+		assert.Contains(t, first, "<h1>Chips &lt;3</h1>\n<p>Salty <em>chips</em>.</p>")
+
+		// Characters XML doesn't allow are replaced.
+		assert.Contains(t, string(files[path.Join(dir, "chapter-2.xhtml")]), "<p>Creamy\ufffd dips.</p>")
+	})
+}
+
+func TestWriteNoChapters(t *testing.T) {
+	err := Write(io.Discard, Book{Title: "Empty"})
+	assert.ErrorIs(t, err, ErrNoChapters)
+}
@@ -0,0 +1,38 @@
+// Package feed renders lists of articles as syndication feeds.
+//
+// Handlers describe a feed once with the format-independent Feed type, and
+// each writer renders it in its own format.
+package feed
+
+import (
+	"time"
+)
+
+// Feed is a list of items published at a URL.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the URL of the resource the feed lists, and SelfLink the URL
+	// of the feed itself.
+	Link     string
+	SelfLink string
+	// Updated is when the feed last changed in a way readers care about.
+	Updated time.Time
+	// Author is credited with every item.
+	Author string
+	Items  []Item
+}
+
+// Item is an entry in a feed.
+type Item struct {
+	// Link is the permanent URL of the item, which also identifies it.
+	Link       string
+	Title      string
+	Published  time.Time
+	Updated    time.Time
+	Categories []string
+	// Summary is an optional plain text summary of the item.
+	Summary string
+	// ContentHTML is the full content of the item as HTML.
+	ContentHTML string
+}
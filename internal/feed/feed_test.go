@@ -0,0 +1,128 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFeed returns a feed with the given items, described the same way in
+// every test.
+func testFeed(items ...Item) Feed {
+	return Feed{
+		Title:       "Chips & Dips",
+		Description: "News about snacks",
+		Link:        "https://example.com/v1/articles",
+		SelfLink:    "https://example.com/v1/feeds/atom",
+		Updated:     time.Date(2016, 9, 22, 10, 0, 0, 0, time.FixedZone("AEST", 10*60*60)),
+		Author:      "Snack Desk",
+		Items:       items,
+	}
+}
+
+var testItem = Item{
+	Link:        "https://example.com/v1/articles/1",
+	Title:       "Chips <are> better",
+	Published:   time.Date(2016, 9, 22, 0, 0, 0, 0, time.UTC),
+	Updated:     time.Date(2016, 9, 23, 0, 0, 0, 0, time.UTC),
+	Categories:  []string{"health", "science"},
+	Summary:     "Chips are better than sugar.",
+	ContentHTML: "<p>Chips are <em>better</em> than sugar.</p>",
+}
+
+func TestWriteAtom(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []Item
+	}{
+		{name: "Items", items: []Item{testItem}},
+		{name: "Empty", items: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WriteAtom(&buf, testFeed(tt.items...)))
+			assert.True(t, strings.HasPrefix(buf.String(), xml.Header))
+
+			var got atomFeed
+			require.NoError(t, xml.Unmarshal(buf.Bytes(), &got))
+			assert.Equal(t, "https://example.com/v1/feeds/atom", got.ID)
+			assert.Equal(t, "Chips & Dips", got.Title)
+			assert.Equal(t, "2016-09-22T00:00:00Z", got.Updated)
+			assert.Equal(t, "Snack Desk", got.Author.Name)
+			assert.Equal(t, []atomLink{
+				{Rel: "self", Type: AtomMediaType, Href: "https://example.com/v1/feeds/atom"},
+				{Rel: "alternate", Href: "https://example.com/v1/articles"},
+			}, got.Links)
+			require.Len(t, got.Entries, len(tt.items))
+
+			for _, entry := range got.Entries {
+				assert.Equal(t, atomEntry{
+					ID:         "https://example.com/v1/articles/1",
+					Title:      "Chips <are> better",
+					Link:       atomLink{Rel: "alternate", Href: "https://example.com/v1/articles/1"},
+					Published:  "2016-09-22T00:00:00Z",
+					Updated:    "2016-09-23T00:00:00Z",
+					Categories: []atomCategory{{Term: "health"}, {Term: "science"}},
+					Summary:    "Chips are better than sugar.",
+					Content:    atomContent{Type: "html", Body: "<p>Chips are <em>better</em> than sugar.</p>"},
+				}, entry)
+			}
+		})
+	}
+}
+
+func TestWriteRSS(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []Item
+	}{
+		{name: "Items", items: []Item{testItem}},
+		{name: "Empty", items: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WriteRSS(&buf, testFeed(tt.items...)))
+
+			var got struct {
+				Version string `xml:"version,attr"`
+				Channel struct {
+					Title         string    `xml:"title"`
+					Description   string    `xml:"description"`
+					LastBuildDate string    `xml:"lastBuildDate"`
+					Items         []rssItem `xml:"item"`
+				} `xml:"channel"`
+			}
+			require.NoError(t, xml.Unmarshal(buf.Bytes(), &got))
+			assert.Equal(t, "2.0", got.Version)
+			assert.Equal(t, "Chips & Dips", got.Channel.Title)
+			assert.Equal(t, "News about snacks", got.Channel.Description)
+			assert.Equal(t, "Thu, 22 Sep 2016 00:00:00 +0000", got.Channel.LastBuildDate)
+
+			// The channel's link and atom:link share a local name, so they are
+			// checked in the document itself.
+			assert.Contains(t, buf.String(), "<link>https://example.com/v1/articles</link>")
+			assert.Contains(t, buf.String(), `<atom:link href="https://example.com/v1/feeds/atom" rel="self" type="application/rss+xml"></atom:link>`)
+			require.Len(t, got.Channel.Items, len(tt.items))
+
+			for _, item := range got.Channel.Items {
+				assert.Equal(t, rssItem{
+					Title:       "Chips <are> better",
+					Link:        "https://example.com/v1/articles/1",
+					GUID:        rssGUID{IsPermaLink: true, Value: "https://example.com/v1/articles/1"},
+					PubDate:     "Thu, 22 Sep 2016 00:00:00 +0000",
+					Categories:  []string{"health", "science"},
+					Description: "<p>Chips are <em>better</em> than sugar.</p>",
+				}, item)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJSONFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		feed     Feed
+		expected string
+	}{
+		{
+			name: "Items",
+			feed: testFeed(testItem),
+			expected: `{
+				"version": "https://jsonfeed.org/version/1.1",
+				"title": "Chips & Dips",
+				"home_page_url": "https://example.com/v1/articles",
+				"feed_url": "https://example.com/v1/feeds/atom",
+				"description": "News about snacks",
+				"authors": [{"name": "Snack Desk"}],
+				"items": [{
+					"id": "https://example.com/v1/articles/1",
+					"url": "https://example.com/v1/articles/1",
+					"title": "Chips <are> better",
+					"content_html": "<p>Chips are <em>better</em> than sugar.</p>",
+					"summary": "Chips are better than sugar.",
+					"date_published": "2016-09-22T00:00:00Z",
+					"date_modified": "2016-09-23T00:00:00Z",
+					"tags": ["health", "science"]
+				}]
+			}`,
+		},
+		{
+			name: "Empty Without Author",
+			feed: Feed{Title: "Empty", Link: "https://example.com/", SelfLink: "https://example.com/feed.json"},
+			expected: `{
+				"version": "https://jsonfeed.org/version/1.1",
+				"title": "Empty",
+				"home_page_url": "https://example.com/",
+				"feed_url": "https://example.com/feed.json",
+				"items": []
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WriteJSONFeed(&buf, tt.feed))
+			assert.True(t, json.Valid(buf.Bytes()))
+			assert.JSONEq(t, tt.expected, buf.String())
+		})
+	}
+}
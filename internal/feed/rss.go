@@ -0,0 +1,82 @@
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// RSSMediaType is the media type of RSS feeds.
+const RSSMediaType = "application/rss+xml"
+
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomXMLNS string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	LastBuildDate string      `xml:"lastBuildDate"`
+	AtomLink      rssAtomLink `xml:"atom:link"`
+	Items         []rssItem   `xml:"item"`
+}
+
+// rssAtomLink is the atom:link element recommended for giving an RSS feed's own
+// URL.
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// WriteRSS writes f to w as an RSS 2.0 feed. RSS has no separate summary, so
+// items are described by their HTML content.
+func WriteRSS(w io.Writer, f Feed) error {
+	rf := rssFeed{
+		Version:   "2.0",
+		AtomXMLNS: "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			LastBuildDate: rssTime(f.Updated),
+			AtomLink:      rssAtomLink{Href: f.SelfLink, Rel: "self", Type: RSSMediaType},
+		},
+	}
+
+	for _, item := range f.Items {
+		rf.Channel.Items = append(rf.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{IsPermaLink: true, Value: item.Link},
+			PubDate:     rssTime(item.Published),
+			Categories:  item.Categories,
+			Description: item.ContentHTML,
+		})
+	}
+
+	return writeXML(w, rf)
+}
+
+// rssTime formats a time as an RFC 822 date, as RSS requires.
+func rssTime(t time.Time) string {
+	return t.UTC().Format(time.RFC1123Z)
+}
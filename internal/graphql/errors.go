@@ -0,0 +1,81 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes set in the "code" extension of request errors, which stop a
+// request before it is executed.
+const (
+	CodeParseFailed      = "GRAPHQL_PARSE_FAILED"
+	CodeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	CodeBadUserInput     = "BAD_USER_INPUT"
+	CodeQueryTooDeep     = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex  = "QUERY_TOO_COMPLEX"
+)
+
+// Location is a position in a GraphQL document. Lines and columns count from 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error as described by the "Errors" section of the spec.
+// Path is only set for errors raised while resolving a field, and lists the
+// response keys and list indexes leading to it.
+//
+// Resolvers may return an *Error to add extensions to the reported error; its
+// locations and path are filled in by the executor.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if len(e.Locations) > 0 {
+		return fmt.Sprintf("graphql: %s (%d:%d)", e.Message, e.Locations[0].Line, e.Locations[0].Column)
+	}
+	return "graphql: " + e.Message
+}
+
+// NewError returns an error with the given message and, if code isn't empty,
+// a "code" extension.
+func NewError(code, message string) *Error {
+	e := &Error{Message: message}
+	if code != "" {
+		e.Extensions = map[string]any{"code": code}
+	}
+	return e
+}
+
+func syntaxError(loc Location, message string) *Error {
+	e := NewError(CodeParseFailed, "Syntax Error: "+message)
+	e.Locations = []Location{loc}
+	return e
+}
+
+func validationError(message string, locs ...Location) *Error {
+	e := NewError(CodeValidationFailed, message)
+	e.Locations = locs
+	return e
+}
+
+// fieldError converts an error returned while resolving a field into a
+// GraphQL error located at the field.
+func fieldError(err error, loc Location, path []any) *Error {
+	e := &Error{Message: err.Error()}
+
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		e.Message = gqlErr.Message
+		e.Extensions = gqlErr.Extensions
+	}
+
+	e.Locations = []Location{loc}
+	e.Path = path
+	return e
+}
@@ -0,0 +1,377 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// Response is the result of executing a request. Data is only sent once
+// execution has started, even if it ends up null; requests that fail to
+// parse or validate only have errors.
+type Response struct {
+	Data     any
+	Errors   []*Error
+	executed bool
+}
+
+// MarshalJSON encodes the response as described by the "Response" section of
+// the spec, errors first.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	if len(r.Errors) > 0 {
+		errs, err := json.Marshal(r.Errors)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`"errors":`)
+		buf.Write(errs)
+	}
+
+	if r.executed {
+		data, err := json.Marshal(r.Data)
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Errors) > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"data":`)
+		buf.Write(data)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// requestError returns a response holding errors that stopped a request from
+// being executed.
+func requestError(errs ...*Error) *Response {
+	return &Response{Errors: errs}
+}
+
+// Execute parses, validates and executes a request against the schema.
+// Field errors don't stop execution: the field is set to null and the error
+// reported alongside the data.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return requestError(err.(*Error))
+	}
+
+	if errs := validate(s, doc); len(errs) > 0 {
+		return requestError(errs...)
+	}
+
+	op, gqlErr := selectOperation(doc, req.OperationName)
+	if gqlErr != nil {
+		return requestError(gqlErr)
+	}
+
+	variables, errs := coerceVariables(op, s.types(), req.Variables)
+	if len(errs) > 0 {
+		return requestError(errs...)
+	}
+
+	e := &executor{
+		schema:    s,
+		fragments: make(map[string]*fragment),
+		variables: variables,
+	}
+	for _, frag := range doc.fragments {
+		e.fragments[frag.name] = frag
+	}
+
+	if gqlErr := e.checkLimits(op); gqlErr != nil {
+		return requestError(gqlErr)
+	}
+
+	resp := &Response{executed: true}
+	data, failed := e.executeSelections(ctx, s.Query, nil, op.selections, nil)
+	if !failed {
+		resp.Data = data
+	}
+	resp.Errors = e.errors
+
+	return resp
+}
+
+// selectOperation picks the operation a request asks for by name. The name
+// may be left out of documents with a single operation.
+func selectOperation(doc *document, name string) (*operation, *Error) {
+	if name == "" {
+		if len(doc.operations) != 1 {
+			return nil, NewError(CodeBadUserInput, "Must provide operation name if query contains multiple operations.")
+		}
+		return doc.operations[0], nil
+	}
+
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, NewError(CodeBadUserInput, fmt.Sprintf("Unknown operation named %q.", name))
+}
+
+// executor executes one operation.
+type executor struct {
+	schema    *Schema
+	fragments map[string]*fragment
+	variables map[string]any
+	errors    []*Error
+}
+
+// fieldGroup holds the fields selected under one response key, which are
+// executed together.
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collectFields groups the fields selected on t by response key, in the
+// order they were first selected, following fragments and skipping
+// selections excluded by @skip or @include.
+func (e *executor) collectFields(t *Object, selections []selection) []*fieldGroup {
+	var groups []*fieldGroup
+	index := make(map[string]*fieldGroup)
+	visited := make(map[string]bool)
+
+	var collect func(selections []selection)
+	collect = func(selections []selection) {
+		for _, sel := range selections {
+			switch sel := sel.(type) {
+			case *field:
+				if !e.included(sel.directives) {
+					continue
+				}
+				key := sel.responseKey()
+				group, ok := index[key]
+				if !ok {
+					group = &fieldGroup{key: key}
+					index[key] = group
+					groups = append(groups, group)
+				}
+				group.fields = append(group.fields, sel)
+
+			case *inlineFragment:
+				if !e.included(sel.directives) || (sel.typeCondition != "" && sel.typeCondition != t.Name) {
+					continue
+				}
+				collect(sel.selections)
+
+			case *fragmentSpread:
+				if visited[sel.name] || !e.included(sel.directives) {
+					continue
+				}
+				visited[sel.name] = true
+
+				frag, ok := e.fragments[sel.name]
+				if !ok || frag.typeCondition != t.Name {
+					continue
+				}
+				collect(frag.selections)
+			}
+		}
+	}
+	collect(selections)
+
+	return groups
+}
+
+// included evaluates the @skip and @include directives of a selection.
+func (e *executor) included(ds []*directive) bool {
+	for _, d := range ds {
+		args, err := coerceArguments(directives[d.name], d.arguments, e.variables)
+		if err != nil {
+			continue
+		}
+		cond, _ := args["if"].(bool)
+		if (d.name == "skip" && cond) || (d.name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// subselections merges the selections of a group of fields.
+func subselections(fields []*field) []selection {
+	var selections []selection
+	for _, f := range fields {
+		selections = append(selections, f.selections...)
+	}
+	return selections
+}
+
+// executeSelections executes the selections made on an object of type t,
+// whose value is source. It reports whether the object is null because a
+// non-null field failed.
+func (e *executor) executeSelections(ctx context.Context, t *Object, source any, selections []selection, path []any) (*orderedMap, bool) {
+	result := &orderedMap{}
+
+	for _, group := range e.collectFields(t, selections) {
+		def := lookupField(t, group.fields[0].name)
+		fieldPath := append(slices.Clip(path), group.key)
+
+		v, failed := e.executeField(ctx, t, source, def, group, fieldPath)
+		if failed {
+			if _, nonNull := def.Type.(*NonNull); nonNull {
+				return nil, true
+			}
+			v = nil
+		}
+		result.set(group.key, v)
+	}
+
+	return result, false
+}
+
+// executeField resolves a field and completes its value. It reports whether
+// the value is null because of an error that has been recorded.
+func (e *executor) executeField(ctx context.Context, t *Object, source any, def *Field, group *fieldGroup, path []any) (any, bool) {
+	f := group.fields[0]
+
+	if f.name == "__typename" {
+		return t.Name, false
+	}
+
+	args, err := coerceArguments(def.Args, f.arguments, e.variables)
+	if err != nil {
+		e.errors = append(e.errors, fieldError(err, f.loc, path))
+		return nil, true
+	}
+
+	var v any
+	if def.Resolve != nil {
+		v, err = def.Resolve(ResolveParams{Context: ctx, Source: source, Args: args})
+	} else if m, ok := source.(map[string]any); ok {
+		v = m[f.name]
+	}
+	if err != nil {
+		e.errors = append(e.errors, fieldError(err, f.loc, path))
+		return nil, true
+	}
+
+	return e.completeValue(ctx, fmt.Sprintf("%s.%s", t.Name, f.name), def.Type, group.fields, v, path)
+}
+
+// completeValue converts a resolved value into its result for the type t. It
+// reports whether the value is null because of an error that has been
+// recorded, which makes the nearest nullable field or list item null.
+func (e *executor) completeValue(ctx context.Context, name string, t Type, fields []*field, v any, path []any) (any, bool) {
+	fail := func(format string, args ...any) (any, bool) {
+		e.errors = append(e.errors, fieldError(fmt.Errorf(format, args...), fields[0].loc, path))
+		return nil, true
+	}
+
+	if nn, ok := t.(*NonNull); ok {
+		result, failed := e.completeValue(ctx, name, nn.OfType, fields, v, path)
+		if failed {
+			return nil, true
+		}
+		if result == nil {
+			return fail("Cannot return null for non-nullable field %s.", name)
+		}
+		return result, false
+	}
+
+	if isNull(v) {
+		return nil, false
+	}
+
+	switch t := t.(type) {
+	case *List:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fail("Expected Iterable, but did not find one for field %s.", name)
+		}
+
+		_, itemNonNull := t.OfType.(*NonNull)
+		items := make([]any, rv.Len())
+		for i := range items {
+			item, failed := e.completeValue(ctx, name, t.OfType, fields, rv.Index(i).Interface(), append(slices.Clip(path), i))
+			if failed && itemNonNull {
+				return nil, true
+			}
+			items[i] = item
+		}
+		return items, false
+
+	case *Object:
+		result, failed := e.executeSelections(ctx, t, v, subselections(fields), path)
+		if failed {
+			return nil, true
+		}
+		return result, false
+
+	case *Enum:
+		s, ok := v.(string)
+		if !ok || !slices.Contains(t.Values, s) {
+			return fail("Enum %q cannot represent value: %v", t.Name, v)
+		}
+		return s, false
+
+	case *Scalar:
+		result, err := t.Serialize(v)
+		if err != nil {
+			return fail("%s", err)
+		}
+		return result, false
+	}
+
+	return fail("Cannot complete value of unexpected type %q.", t)
+}
+
+// orderedMap is an object in the result, whose keys are sent in the order
+// they were selected.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, v any) {
+	if m.values == nil {
+		m.values = make(map[string]any)
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = v
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
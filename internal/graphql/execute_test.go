@@ -0,0 +1,351 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSchema returns a small schema of articles for tests, along with the
+// articles it serves.
+func testSchema() *Schema {
+	articles := []map[string]any{
+		{"id": "1", "title": "Potato chips", "tags": []string{"health", "food"}, "status": "PUBLISHED"},
+		{"id": "2", "title": "Rockets", "tags": []string{"science"}, "status": "DRAFT"},
+		{"id": "3", "title": "Mars", "tags": []string{"science"}, "status": "PUBLISHED"},
+	}
+
+	status := &Enum{Name: "Status", Values: []string{"DRAFT", "PUBLISHED"}}
+
+	article := &Object{Name: "Article"}
+	article.Fields = map[string]*Field{
+		"id":     {Type: NewNonNull(ID)},
+		"title":  {Type: NewNonNull(String)},
+		"tags":   {Type: NewNonNull(NewList(NewNonNull(String)))},
+		"status": {Type: status},
+		"related": {
+			Type: NewList(NewNonNull(article)),
+			Args: map[string]*Argument{"first": {Type: Int, Default: 2}},
+			Resolve: func(p ResolveParams) (any, error) {
+				return articles[:p.Args["first"].(int)], nil
+			},
+			Complexity: func(args map[string]any, childComplexity int) int {
+				first, _ := args["first"].(int)
+				return 1 + first*childComplexity
+			},
+		},
+		"broken": {
+			Type: NewNonNull(String),
+			Resolve: func(p ResolveParams) (any, error) {
+				return nil, nil
+			},
+		},
+	}
+
+	filter := &InputObject{
+		Name: "ArticleFilter",
+		Fields: map[string]*InputField{
+			"tag":    {Type: String},
+			"status": {Type: status, Default: "PUBLISHED"},
+			"ids":    {Type: NewList(NewNonNull(ID))},
+		},
+	}
+
+	query := &Object{
+		Name: "Query",
+		Fields: map[string]*Field{
+			"article": {
+				Type: article,
+				Args: map[string]*Argument{"id": {Type: NewNonNull(ID)}},
+				Resolve: func(p ResolveParams) (any, error) {
+					for _, a := range articles {
+						if a["id"] == p.Args["id"] {
+							return a, nil
+						}
+					}
+					return nil, nil
+				},
+			},
+			"articles": {
+				Type: NewNonNull(NewList(NewNonNull(article))),
+				Args: map[string]*Argument{
+					"filter": {Type: filter},
+					"first":  {Type: Int, Default: 10},
+				},
+				Resolve: func(p ResolveParams) (any, error) {
+					f, _ := p.Args["filter"].(map[string]any)
+					var result []map[string]any
+					for _, a := range articles {
+						if tag, ok := f["tag"]; ok && !slices.Contains(a["tags"].([]string), tag.(string)) {
+							continue
+						}
+						if s, ok := f["status"]; ok && a["status"] != s {
+							continue
+						}
+						result = append(result, a)
+					}
+					return result[:min(len(result), p.Args["first"].(int))], nil
+				},
+				Complexity: func(args map[string]any, childComplexity int) int {
+					first, _ := args["first"].(int)
+					return 1 + first*childComplexity
+				},
+			},
+			"fail": {
+				Type: String,
+				Resolve: func(p ResolveParams) (any, error) {
+					return nil, NewError("NOT_FOUND", "nothing here")
+				},
+			},
+			"echo": {
+				Type: String,
+				Args: map[string]*Argument{"value": {Type: String}},
+				Resolve: func(p ResolveParams) (any, error) {
+					return p.Args["value"], nil
+				},
+			},
+		},
+	}
+
+	return &Schema{Query: query}
+}
+
+// execute runs a query against the test schema and returns its response as
+// JSON.
+func execute(t *testing.T, schema *Schema, req Request) string {
+	t.Helper()
+
+	js, err := json.Marshal(schema.Execute(context.Background(), req))
+	require.NoError(t, err)
+	return string(js)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		variables map[string]any
+		expected  string
+	}{
+		{
+			name:     "Fields In Order",
+			query:    `{ article(id: 1) { title id __typename } }`,
+			expected: `{"data":{"article":{"title":"Potato chips","id":"1","__typename":"Article"}}}`,
+		},
+		{
+			name:     "Aliases",
+			query:    `{ a: article(id: "1") { title } b: article(id: 3) { title } }`,
+			expected: `{"data":{"a":{"title":"Potato chips"},"b":{"title":"Mars"}}}`,
+		},
+		{
+			name:     "Missing Object",
+			query:    `{ article(id: 9) { title } }`,
+			expected: `{"data":{"article":null}}`,
+		},
+		{
+			name:     "Input Object Default",
+			query:    `{ articles(filter: {tag: "science"}) { id } }`,
+			expected: `{"data":{"articles":[{"id":"3"}]}}`,
+		},
+		{
+			name:      "Variables",
+			query:     `query ($tag: String, $first: Int = 1) { articles(filter: {tag: $tag, status: DRAFT}, first: $first) { title status } }`,
+			variables: map[string]any{"tag": "science"},
+			expected:  `{"data":{"articles":[{"title":"Rockets","status":"DRAFT"}]}}`,
+		},
+		{
+			name:     "Merged Fields",
+			query:    `{ article(id: 1) { id } article(id: 1) { title ...F } } fragment F on Article { id tags }`,
+			expected: `{"data":{"article":{"id":"1","title":"Potato chips","tags":["health","food"]}}}`,
+		},
+		{
+			name:      "Skip And Include",
+			query:     `query ($yes: Boolean!) { article(id: 1) { id @skip(if: $yes) title @include(if: $yes) ... @skip(if: true) { tags } } }`,
+			variables: map[string]any{"yes": true},
+			expected:  `{"data":{"article":{"title":"Potato chips"}}}`,
+		},
+		{
+			name:     "Resolver Error",
+			query:    `{ echo(value: "hi") fail }`,
+			expected: `{"errors":[{"message":"nothing here","locations":[{"line":1,"column":21}],"path":["fail"],"extensions":{"code":"NOT_FOUND"}}],"data":{"echo":"hi","fail":null}}`,
+		},
+		{
+			name:     "Null In Non-Null Field",
+			query:    `{ article(id: 1) { id broken } }`,
+			expected: `{"errors":[{"message":"Cannot return null for non-nullable field Article.broken.","locations":[{"line":1,"column":23}],"path":["article","broken"]}],"data":{"article":null}}`,
+		},
+		{
+			name:     "Null Propagates Through Non-Null List",
+			query:    `{ articles(first: 1) { broken } }`,
+			expected: `{"errors":[{"message":"Cannot return null for non-nullable field Article.broken.","locations":[{"line":1,"column":24}],"path":["articles",0,"broken"]}],"data":null}`,
+		},
+		{
+			name:      "Operation Name",
+			query:     `query A { echo(value: "a") } query B { echo(value: "b") }`,
+			operation: "B",
+			expected:  `{"data":{"echo":"b"}}`,
+		},
+		{
+			name:     "Operation Name Required",
+			query:    `query A { echo } query B { echo }`,
+			expected: `{"errors":[{"message":"Must provide operation name if query contains multiple operations.","extensions":{"code":"BAD_USER_INPUT"}}]}`,
+		},
+		{
+			name:      "Unknown Operation",
+			query:     `query A { echo }`,
+			operation: "C",
+			expected:  `{"errors":[{"message":"Unknown operation named \"C\".","extensions":{"code":"BAD_USER_INPUT"}}]}`,
+		},
+		{
+			name:     "Syntax Error",
+			query:    `{ echo(`,
+			expected: `{"errors":[{"message":"Syntax Error: Expected Name, found <EOF>.","locations":[{"line":1,"column":8}],"extensions":{"code":"GRAPHQL_PARSE_FAILED"}}]}`,
+		},
+	}
+
+	schema := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.expected, execute(t, schema, Request{Query: tt.query, OperationName: tt.operation, Variables: tt.variables}))
+		})
+	}
+}
+
+func TestCoerceVariables(t *testing.T) {
+	query := `query ($id: ID!, $first: Int, $filter: ArticleFilter, $value: String = "x") {
+		article(id: $id) { id }
+		articles(first: $first, filter: $filter) { id tags }
+		echo(value: $value)
+	}`
+
+	tests := []struct {
+		name      string
+		variables map[string]any
+		expected  string
+	}{
+		{name: "Missing Required", variables: map[string]any{}, expected: `Variable "$id" of required type "ID!" was not provided.`},
+		{name: "Null Required", variables: map[string]any{"id": nil}, expected: `Variable "$id" of non-null type "ID!" must not be null.`},
+		{name: "Fractional Int", variables: map[string]any{"id": 1, "first": 1.5}, expected: `Variable "$first" got invalid value 1.5; Int cannot represent non-integer value: 1.5`},
+		{name: "Int Out Of Range", variables: map[string]any{"id": 1, "first": 1 << 40}, expected: `Variable "$first" got invalid value 1099511627776; Int cannot represent non 32-bit signed integer value: 1099511627776`},
+		{name: "String As Int", variables: map[string]any{"id": 1, "first": "1"}, expected: `Variable "$first" got invalid value "1"; Int cannot represent non-integer value: "1"`},
+		{name: "Boolean As ID", variables: map[string]any{"id": true}, expected: `Variable "$id" got invalid value true; ID cannot represent value: true`},
+		{name: "Filter Not Object", variables: map[string]any{"id": 1, "filter": "x"}, expected: `Variable "$filter" got invalid value "x"; Expected type "ArticleFilter" to be an object.`},
+		{name: "Unknown Filter Field", variables: map[string]any{"id": 1, "filter": map[string]any{"author": "x"}}, expected: `Variable "$filter" got invalid value {"author":"x"}; Field "author" is not defined by type "ArticleFilter".`},
+		{name: "Bad Enum", variables: map[string]any{"id": 1, "filter": map[string]any{"status": "GONE"}}, expected: `Variable "$filter" got invalid value {"status":"GONE"}; at "status": Value "GONE" does not exist in "Status" enum.`},
+		{name: "Null List Item", variables: map[string]any{"id": 1, "filter": map[string]any{"ids": []any{"1", nil}}}, expected: `Variable "$filter" got invalid value {"ids":["1",null]}; at "ids": at index 1: Expected non-nullable type "ID!" not to be null.`},
+	}
+
+	schema := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := schema.Execute(context.Background(), Request{Query: query, Variables: tt.variables})
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tt.expected, resp.Errors[0].Message)
+			assert.Equal(t, CodeBadUserInput, resp.Errors[0].Extensions["code"])
+			assert.Nil(t, resp.Data)
+		})
+	}
+}
+
+func TestExecuteLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxDepth      int
+		maxComplexity int
+		query         string
+		code          string
+		expected      string
+	}{
+		{
+			name:     "Depth Within Limit",
+			maxDepth: 3,
+			query:    `{ article(id: 1) { related { id } } }`,
+		},
+		{
+			name:     "Too Deep",
+			maxDepth: 3,
+			query:    `{ article(id: 1) { related { related { id } } } }`,
+			code:     CodeQueryTooDeep,
+			expected: "Query depth exceeds the maximum of 3.",
+		},
+		{
+			name:     "Too Deep Through Fragments",
+			maxDepth: 2,
+			query:    `{ article(id: 1) { ...R } } fragment R on Article { related { id } }`,
+			code:     CodeQueryTooDeep,
+			expected: "Query depth exceeds the maximum of 2.",
+		},
+		{
+			name:     "Skipped Selections Don't Count",
+			maxDepth: 2,
+			query:    `{ article(id: 1) { related @skip(if: true) { id } } }`,
+		},
+		{
+			name:          "Complexity Within Limit",
+			maxComplexity: 7,
+			query:         `{ articles(first: 3) { id } }`,
+		},
+		{
+			name:          "Too Complex",
+			maxComplexity: 12,
+			query:         `{ articles(first: 3) { related(first: 3) { id } } }`,
+			code:          CodeQueryTooComplex,
+			expected:      "Query complexity 13 exceeds the maximum of 12.",
+		},
+		{
+			name:          "Too Many Fields",
+			maxComplexity: 3,
+			query:         `{ a: echo b: echo c: echo d: echo }`,
+			code:          CodeQueryTooComplex,
+			expected:      "Query selects more than the maximum of 3 fields.",
+		},
+		{
+			name:     "Conflicting Fields",
+			query:    `{ article(id: 1) { x: id x: title } }`,
+			code:     CodeValidationFailed,
+			expected: `Fields "x" conflict because "id" and "title" are different fields. Use different aliases on the fields to fetch both if this was intentional.`,
+		},
+		{
+			name:     "Conflicting Arguments",
+			query:    `{ article(id: 1) { id } article(id: 2) { id } }`,
+			code:     CodeValidationFailed,
+			expected: `Fields "article" conflict because they have differing arguments. Use different aliases on the fields to fetch both if this was intentional.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := testSchema()
+			schema.MaxDepth = tt.maxDepth
+			schema.MaxComplexity = tt.maxComplexity
+
+			resp := schema.Execute(context.Background(), Request{Query: tt.query})
+			if tt.code == "" {
+				assert.Empty(t, resp.Errors)
+				assert.NotNil(t, resp.Data)
+				return
+			}
+
+			require.Len(t, resp.Errors, 1)
+			assert.Equal(t, tt.expected, resp.Errors[0].Message)
+			assert.Equal(t, tt.code, resp.Errors[0].Extensions["code"])
+			assert.Nil(t, resp.Data)
+		})
+	}
+}
+
+func TestFieldError(t *testing.T) {
+	plain := fieldError(errors.New("boom"), Location{1, 2}, []any{"a", 0})
+	assert.Equal(t, &Error{Message: "boom", Locations: []Location{{1, 2}}, Path: []any{"a", 0}}, plain)
+
+	wrapped := fieldError(errors.Join(NewError("CODE", "inner")), Location{3, 4}, nil)
+	assert.Equal(t, "inner", wrapped.Message)
+	assert.Equal(t, map[string]any{"code": "CODE"}, wrapped.Extensions)
+	assert.Equal(t, "graphql: inner (3:4)", wrapped.Error())
+}
@@ -0,0 +1,373 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// token is a lexical token of a GraphQL document. The value of a string token
+// is the string it denotes, with escapes resolved.
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+// describe names the token for syntax errors.
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "<EOF>"
+	case tokenName:
+		return fmt.Sprintf("Name %q", t.value)
+	case tokenInt:
+		return fmt.Sprintf("Int %q", t.value)
+	case tokenFloat:
+		return fmt.Sprintf("Float %q", t.value)
+	case tokenString:
+		return fmt.Sprintf("String %q", t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+// lexer splits a GraphQL document into tokens, skipping whitespace, commas
+// and comments.
+type lexer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1}
+}
+
+// location returns the line and column of the byte offset pos, which must be
+// on the current line. Columns count characters from 1.
+func (l *lexer) location(pos int) Location {
+	return Location{Line: l.line, Column: utf8.RuneCountInString(l.src[l.lineStart:pos]) + 1}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...any) *Error {
+	return syntaxError(l.location(pos), fmt.Sprintf(format, args...))
+}
+
+// newline records a line terminator ending just before pos.
+func (l *lexer) newline(pos int) {
+	l.line++
+	l.lineStart = pos
+}
+
+// skipIgnored moves past whitespace, line terminators, commas, comments and
+// byte order marks.
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case ' ', '\t', ',':
+			l.pos++
+		case '\n':
+			l.pos++
+			l.newline(l.pos)
+		case '\r':
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.newline(l.pos)
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			if strings.HasPrefix(l.src[l.pos:], "\uFEFF") {
+				l.pos += len("\uFEFF")
+				continue
+			}
+			return
+		}
+	}
+}
+
+// next returns the next token in the document.
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+
+	start := l.pos
+	if start >= len(l.src) {
+		return token{kind: tokenEOF, loc: l.location(start)}, nil
+	}
+
+	c := l.src[start]
+	switch {
+	case strings.IndexByte("!$&():=@[]{|}", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, value: string(c), loc: l.location(start)}, nil
+
+	case c == '.':
+		if strings.HasPrefix(l.src[start:], "...") {
+			l.pos += 3
+			return token{kind: tokenPunctuator, value: "...", loc: l.location(start)}, nil
+		}
+		return token{}, l.errorf(start, "Unexpected \".\".")
+
+	case isNameStart(c):
+		for l.pos < len(l.src) && isNameContinue(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], loc: l.location(start)}, nil
+
+	case c == '-' || isDigit(c):
+		return l.number()
+
+	case c == '"':
+		if strings.HasPrefix(l.src[start:], `"""`) {
+			return l.blockString()
+		}
+		return l.string()
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[start:])
+	return token{}, l.errorf(start, "Unexpected character %q.", r)
+}
+
+// number lexes an IntValue or a FloatValue.
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokenInt
+
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+
+	switch {
+	case l.pos < len(l.src) && l.src[l.pos] == '0':
+		l.pos++
+		if l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			return token{}, l.errorf(l.pos, "Invalid number, unexpected digit after 0.")
+		}
+	case l.pos < len(l.src) && isDigit(l.src[l.pos]):
+		l.digits()
+	default:
+		return token{}, l.errorf(l.pos, "Invalid number, expected digit.")
+	}
+
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if l.pos >= len(l.src) || !isDigit(l.src[l.pos]) {
+			return token{}, l.errorf(l.pos, "Invalid number, expected digit.")
+		}
+		l.digits()
+	}
+
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if l.pos >= len(l.src) || !isDigit(l.src[l.pos]) {
+			return token{}, l.errorf(l.pos, "Invalid number, expected digit.")
+		}
+		l.digits()
+	}
+
+	// A number must not run straight into a name or another dot.
+	if l.pos < len(l.src) && (l.src[l.pos] == '.' || isNameStart(l.src[l.pos])) {
+		return token{}, l.errorf(l.pos, "Invalid number, expected digit.")
+	}
+
+	return token{kind: kind, value: l.src[start:l.pos], loc: l.location(start)}, nil
+}
+
+func (l *lexer) digits() {
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+}
+
+// string lexes a quoted StringValue, resolving its escape sequences.
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: sb.String(), loc: l.location(start)}, nil
+
+		case c == '\n' || c == '\r':
+			return token{}, l.errorf(l.pos, "Unterminated string.")
+
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(l.pos, "Unterminated string.")
+			}
+			switch e := l.src[l.pos+1]; e {
+			case '"', '\\', '/':
+				sb.WriteByte(e)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				r, n, ok := l.unicodeEscape(l.pos)
+				if !ok {
+					return token{}, l.errorf(l.pos, "Invalid Unicode escape sequence.")
+				}
+				sb.WriteRune(r)
+				l.pos += n
+				continue
+			default:
+				return token{}, l.errorf(l.pos, "Invalid character escape sequence: \"\\%c\".", e)
+			}
+			l.pos += 2
+
+		case c < 0x20 && c != '\t':
+			return token{}, l.errorf(l.pos, "Invalid character within String: %q.", rune(c))
+
+		default:
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			sb.WriteRune(r)
+			l.pos += size
+		}
+	}
+
+	return token{}, l.errorf(l.pos, "Unterminated string.")
+}
+
+// unicodeEscape decodes the \uXXXX escape at pos, combining a surrogate pair
+// written as two escapes. It returns the rune and the length of the escape.
+func (l *lexer) unicodeEscape(pos int) (rune, int, bool) {
+	hex := func(pos int) (rune, bool) {
+		if pos+6 > len(l.src) || l.src[pos:pos+2] != `\u` {
+			return 0, false
+		}
+		v, err := strconv.ParseUint(l.src[pos+2:pos+6], 16, 16)
+		return rune(v), err == nil
+	}
+
+	r, ok := hex(pos)
+	if !ok {
+		return 0, 0, false
+	}
+	if !utf8.ValidRune(r) {
+		// A leading surrogate must be followed by a trailing one.
+		if r < 0xD800 || r > 0xDBFF {
+			return 0, 0, false
+		}
+		low, ok := hex(pos + 6)
+		if !ok || low < 0xDC00 || low > 0xDFFF {
+			return 0, 0, false
+		}
+		return (r-0xD800)<<10 + (low - 0xDC00) + 0x10000, 12, true
+	}
+	return r, 6, true
+}
+
+// blockString lexes a """triple-quoted""" StringValue. Its only escape is
+// \""", and its lines are dedented by their common indentation.
+func (l *lexer) blockString() (token, error) {
+	start := l.pos
+	startLoc := l.location(start)
+	l.pos += 3
+
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			return token{kind: tokenString, value: blockStringValue(sb.String()), loc: startLoc}, nil
+
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			sb.WriteString(`"""`)
+			l.pos += 4
+
+		case l.src[l.pos] == '\n':
+			sb.WriteByte('\n')
+			l.pos++
+			l.newline(l.pos)
+
+		case l.src[l.pos] == '\r':
+			sb.WriteByte('\n')
+			l.pos++
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+			l.newline(l.pos)
+
+		default:
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			sb.WriteRune(r)
+			l.pos += size
+		}
+	}
+
+	return token{}, l.errorf(l.pos, "Unterminated string.")
+}
+
+// blockStringValue removes the common indentation of a block string's lines,
+// other than the first, and its leading and trailing blank lines.
+func blockStringValue(raw string) string {
+	lines := strings.Split(raw, "\n")
+
+	common := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (common < 0 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			lines[i] = lines[i][min(common, len(lines[i])):]
+		}
+	}
+
+	blank := func(line string) bool {
+		return strings.Trim(line, " \t") == ""
+	}
+	for len(lines) > 0 && blank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && blank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isNameContinue(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
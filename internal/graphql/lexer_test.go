@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lex returns every token in src up to the end of the document.
+func lex(src string) ([]token, error) {
+	l := newLexer(src)
+
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return tokens, err
+		}
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []token
+	}{
+		{
+			name: "Punctuators",
+			src:  "{ ... $x: [Int!] @skip }",
+			expected: []token{
+				{kind: tokenPunctuator, value: "{", loc: Location{1, 1}},
+				{kind: tokenPunctuator, value: "...", loc: Location{1, 3}},
+				{kind: tokenPunctuator, value: "$", loc: Location{1, 7}},
+				{kind: tokenName, value: "x", loc: Location{1, 8}},
+				{kind: tokenPunctuator, value: ":", loc: Location{1, 9}},
+				{kind: tokenPunctuator, value: "[", loc: Location{1, 11}},
+				{kind: tokenName, value: "Int", loc: Location{1, 12}},
+				{kind: tokenPunctuator, value: "!", loc: Location{1, 15}},
+				{kind: tokenPunctuator, value: "]", loc: Location{1, 16}},
+				{kind: tokenPunctuator, value: "@", loc: Location{1, 18}},
+				{kind: tokenName, value: "skip", loc: Location{1, 19}},
+				{kind: tokenPunctuator, value: "}", loc: Location{1, 24}},
+			},
+		},
+		{
+			name: "Ignored",
+			src:  "\uFEFFa,b # comment, c\r\n\td\re",
+			expected: []token{
+				{kind: tokenName, value: "a", loc: Location{1, 2}},
+				{kind: tokenName, value: "b", loc: Location{1, 4}},
+				{kind: tokenName, value: "d", loc: Location{2, 2}},
+				{kind: tokenName, value: "e", loc: Location{3, 1}},
+			},
+		},
+		{
+			name: "Numbers",
+			src:  "0 -12 3.5 -0.25 1e10 2E-3 6.02e+23",
+			expected: []token{
+				{kind: tokenInt, value: "0", loc: Location{1, 1}},
+				{kind: tokenInt, value: "-12", loc: Location{1, 3}},
+				{kind: tokenFloat, value: "3.5", loc: Location{1, 7}},
+				{kind: tokenFloat, value: "-0.25", loc: Location{1, 11}},
+				{kind: tokenFloat, value: "1e10", loc: Location{1, 17}},
+				{kind: tokenFloat, value: "2E-3", loc: Location{1, 22}},
+				{kind: tokenFloat, value: "6.02e+23", loc: Location{1, 27}},
+			},
+		},
+		{
+			name: "String Escapes",
+			src:  `"a\"b\\c\/d\n\té😀"`,
+			expected: []token{
+				{kind: tokenString, value: "a\"b\\c/d\n\té😀", loc: Location{1, 1}},
+			},
+		},
+		{
+			name: "Columns Count Characters",
+			src:  `"héllo" x`,
+			expected: []token{
+				{kind: tokenString, value: "héllo", loc: Location{1, 1}},
+				{kind: tokenName, value: "x", loc: Location{1, 9}},
+			},
+		},
+		{
+			name: "Block String",
+			src:  "\"\"\"\n    Potato\n      chips \\\"\"\" \"\n\n  \"\"\" x",
+			expected: []token{
+				{kind: tokenString, value: "Potato\n  chips \"\"\" \"", loc: Location{1, 1}},
+				{kind: tokenName, value: "x", loc: Location{5, 7}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lex(tt.src)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, tokens)
+		})
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+		loc      Location
+	}{
+		{name: "Unexpected Character", src: "a ?", expected: `Unexpected character '?'.`, loc: Location{1, 3}},
+		{name: "Unexpected Unicode Character", src: "é", expected: `Unexpected character 'é'.`, loc: Location{1, 1}},
+		{name: "Lone Dot", src: "a.b", expected: `Unexpected ".".`, loc: Location{1, 2}},
+		{name: "Two Dots", src: "..", expected: `Unexpected ".".`, loc: Location{1, 1}},
+		{name: "Leading Zero", src: "007", expected: "Invalid number, unexpected digit after 0.", loc: Location{1, 2}},
+		{name: "Lone Minus", src: "-a", expected: "Invalid number, expected digit.", loc: Location{1, 2}},
+		{name: "Missing Fraction", src: "1.", expected: "Invalid number, expected digit.", loc: Location{1, 3}},
+		{name: "Missing Exponent", src: "1e+", expected: "Invalid number, expected digit.", loc: Location{1, 4}},
+		{name: "Number Then Name", src: "123abc", expected: "Invalid number, expected digit.", loc: Location{1, 4}},
+		{name: "Number Then Dot", src: "1.5.3", expected: "Invalid number, expected digit.", loc: Location{1, 4}},
+		{name: "Unterminated String", src: `"abc`, expected: "Unterminated string.", loc: Location{1, 5}},
+		{name: "Newline In String", src: "\"ab\ncd\"", expected: "Unterminated string.", loc: Location{1, 4}},
+		{name: "Trailing Backslash", src: `"ab\`, expected: "Unterminated string.", loc: Location{1, 4}},
+		{name: "Bad Escape", src: `"a\x"`, expected: `Invalid character escape sequence: "\x".`, loc: Location{1, 3}},
+		{name: "Short Unicode Escape", src: `"\u12"`, expected: "Invalid Unicode escape sequence.", loc: Location{1, 2}},
+		{name: "Lone Surrogate", src: `"\uD83D"`, expected: "Invalid Unicode escape sequence.", loc: Location{1, 2}},
+		{name: "Reversed Surrogates", src: `"\uDE00\uD83D"`, expected: "Invalid Unicode escape sequence.", loc: Location{1, 2}},
+		{name: "Control Character", src: "\"a\x01\"", expected: `Invalid character within String: '\x01'.`, loc: Location{1, 3}},
+		{name: "Unterminated Block String", src: "\"\"\"abc\ndef", expected: "Unterminated string.", loc: Location{2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := lex(tt.src)
+
+			var gqlErr *Error
+			require.ErrorAs(t, err, &gqlErr)
+			assert.Equal(t, "Syntax Error: "+tt.expected, gqlErr.Message)
+			assert.Equal(t, []Location{tt.loc}, gqlErr.Locations)
+			assert.Equal(t, map[string]any{"code": CodeParseFailed}, gqlErr.Extensions)
+		})
+	}
+}
+
+func TestBlockStringValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{name: "Single Line", raw: "  Potato chips  ", expected: "  Potato chips  "},
+		{name: "Common Indent", raw: "\n    one\n      two\n    three\n", expected: "one\n  two\nthree"},
+		{name: "First Line Kept", raw: "  first\n    second", expected: "  first\nsecond"},
+		{name: "Blank Lines Ignored For Indent", raw: "\n    one\n\n  \n    two", expected: "one\n\n\ntwo"},
+		{name: "Tabs", raw: "\n\tone\n\t\ttwo", expected: "one\n\ttwo"},
+		{name: "Only Blank", raw: "\n  \n\t\n", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, blockStringValue(tt.raw))
+		})
+	}
+}
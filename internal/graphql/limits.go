@@ -0,0 +1,126 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCost caps the cost worked out for any field, so that complexity
+// functions multiplying large costs together can't overflow.
+const maxCost = 1 << 31
+
+// checkLimits works out the depth and complexity of an operation, after
+// fragments and @skip and @include have been applied, and returns an error if
+// either is over the schema's limits. It also rejects fields that share a
+// response key but can't be merged, since only one of them could be sent.
+func (e *executor) checkLimits(op *operation) *Error {
+	m := &measurer{executor: e, loc: op.loc}
+
+	complexity, err := m.measure(e.schema.Query, op.selections, 1)
+	if err != nil {
+		return err
+	}
+
+	if e.schema.MaxComplexity > 0 && complexity > e.schema.MaxComplexity {
+		return m.tooComplex(fmt.Sprintf("Query complexity %d exceeds the maximum of %d.", complexity, e.schema.MaxComplexity))
+	}
+
+	return nil
+}
+
+// measurer works out the depth and complexity of an operation. It gives up as
+// soon as the operation is too deep, or has more fields than its complexity
+// allows, so that documents reusing fragments can't make it explode.
+type measurer struct {
+	*executor
+	loc Location
+	// fields counts the fields measured so far.
+	fields int
+}
+
+func (m *measurer) tooComplex(message string) *Error {
+	err := NewError(CodeQueryTooComplex, message)
+	err.Locations = []Location{m.loc}
+	return err
+}
+
+// measure returns the complexity of the selections made on t, which are at
+// the given depth.
+func (m *measurer) measure(t *Object, selections []selection, depth int) (int, *Error) {
+	if m.schema.MaxDepth > 0 && depth > m.schema.MaxDepth {
+		err := NewError(CodeQueryTooDeep, fmt.Sprintf("Query depth exceeds the maximum of %d.", m.schema.MaxDepth))
+		err.Locations = []Location{m.loc}
+		return 0, err
+	}
+
+	complexity := 0
+
+	for _, group := range m.collectFields(t, selections) {
+		f := group.fields[0]
+		for _, other := range group.fields[1:] {
+			if reason := conflict(f, other); reason != "" {
+				return 0, validationError(fmt.Sprintf("Fields %q conflict because %s. Use different aliases on the fields to fetch both if this was intentional.", group.key, reason), f.loc, other.loc)
+			}
+		}
+
+		// Every field costs at least 1 unless its complexity function says
+		// otherwise, so an operation with more fields than the limit is too
+		// complex whatever their costs.
+		m.fields++
+		if m.schema.MaxComplexity > 0 && m.fields > m.schema.MaxComplexity {
+			return 0, m.tooComplex(fmt.Sprintf("Query selects more than the maximum of %d fields.", m.schema.MaxComplexity))
+		}
+
+		def := lookupField(t, f.name)
+
+		childComplexity := 0
+		if obj, ok := namedType(def.Type).(*Object); ok {
+			var err *Error
+			childComplexity, err = m.measure(obj, subselections(group.fields), depth+1)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		cost := 1 + childComplexity
+		if def.Complexity != nil {
+			// Invalid arguments are reported when the field is executed, so
+			// they can be left to the complexity function's defaults here.
+			args, _ := coerceArguments(def.Args, f.arguments, m.variables)
+			if args == nil {
+				args = map[string]any{}
+			}
+			cost = def.Complexity(args, childComplexity)
+		}
+
+		complexity = min(complexity+min(cost, maxCost), maxCost)
+	}
+
+	return complexity, nil
+}
+
+// conflict returns why two fields selected under the same response key can't
+// be merged, or "" if they can.
+func conflict(a, b *field) string {
+	if a.name != b.name {
+		return fmt.Sprintf("%q and %q are different fields", a.name, b.name)
+	}
+	if printArguments(a.arguments) != printArguments(b.arguments) {
+		return "they have differing arguments"
+	}
+	return ""
+}
+
+// printArguments formats arguments in a canonical form for comparison.
+func printArguments(args []*argument) string {
+	printed := make(map[string]string, len(args))
+	for _, arg := range args {
+		printed[arg.name] = printValue(arg.value)
+	}
+
+	var sb strings.Builder
+	for _, name := range sortedKeys(printed) {
+		fmt.Fprintf(&sb, "%s:%s,", name, printed[name])
+	}
+	return sb.String()
+}
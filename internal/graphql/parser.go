@@ -0,0 +1,542 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// maxNesting bounds how deeply selection sets and input values may nest in a
+// document, so that hostile documents can't exhaust the parser's stack. It is
+// far above any sensible query depth limit.
+const maxNesting = 128
+
+// parser builds the syntax tree of an executable document by recursive
+// descent, one token of lookahead at a time.
+type parser struct {
+	lexer   *lexer
+	tok     token
+	nesting int
+}
+
+// parse parses an executable document. Type system definitions and
+// extensions are rejected, since requests can't use them.
+func parse(src string) (*document, error) {
+	p := &parser{lexer: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{}
+	for {
+		switch {
+		case p.tok.kind == tokenEOF:
+			if len(doc.operations) == 0 && len(doc.fragments) == 0 {
+				return nil, p.unexpected()
+			}
+			return doc, nil
+
+		case p.peek("{"):
+			op := &operation{kind: "query", loc: p.tok.loc}
+			var err error
+			op.selections, err = p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+
+		case p.peekName("fragment"):
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments = append(doc.fragments, frag)
+
+		default:
+			return nil, p.unexpected()
+		}
+	}
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// peek reports whether the current token is the given punctuator.
+func (p *parser) peek(punctuator string) bool {
+	return p.tok.kind == tokenPunctuator && p.tok.value == punctuator
+}
+
+// peekName reports whether the current token is the given name.
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokenName && p.tok.value == name
+}
+
+func (p *parser) unexpected() *Error {
+	return syntaxError(p.tok.loc, fmt.Sprintf("Unexpected %s.", p.tok.describe()))
+}
+
+// skip consumes the given punctuator if it is next, reporting whether it was.
+func (p *parser) skip(punctuator string) (bool, error) {
+	if !p.peek(punctuator) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+// expect consumes the given punctuator, which must be next.
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return syntaxError(p.tok.loc, fmt.Sprintf("Expected %q, found %s.", punctuator, p.tok.describe()))
+	}
+	return p.advance()
+}
+
+// expectName consumes a name and returns it.
+func (p *parser) expectName() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", syntaxError(p.tok.loc, fmt.Sprintf("Expected Name, found %s.", p.tok.describe()))
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// expectKeyword consumes the given name, which must be next.
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.peekName(keyword) {
+		return syntaxError(p.tok.loc, fmt.Sprintf("Expected %q, found %s.", keyword, p.tok.describe()))
+	}
+	return p.advance()
+}
+
+// nest records entering a nested selection set or input value. The returned
+// function records leaving it.
+func (p *parser) nest() (func(), error) {
+	if p.nesting >= maxNesting {
+		return nil, syntaxError(p.tok.loc, "Document is nested too deeply.")
+	}
+	p.nesting++
+	return func() { p.nesting-- }, nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.tok.value, loc: p.tok.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek("(") {
+		op.variables, err = p.parseVariableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	op.directives, err = p.parseDirectives(false)
+	if err != nil {
+		return nil, err
+	}
+
+	op.selections, err = p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinitions() ([]*variableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var defs []*variableDefinition
+	for {
+		done, err := p.skip(")")
+		if err != nil {
+			return nil, err
+		}
+		if done && len(defs) > 0 {
+			return defs, nil
+		}
+		if done {
+			return nil, syntaxError(p.tok.loc, "Expected a variable definition.")
+		}
+
+		def := &variableDefinition{loc: p.tok.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		if def.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if def.typ, err = p.parseTypeRef(); err != nil {
+			return nil, err
+		}
+
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if def.defaultValue, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+
+		// Directives on variable definitions are allowed by the grammar, but
+		// none apply to them here.
+		if _, err := p.parseDirectives(true); err != nil {
+			return nil, err
+		}
+
+		defs = append(defs, def)
+	}
+}
+
+func (p *parser) parseTypeRef() (*typeRef, error) {
+	t := &typeRef{loc: p.tok.loc}
+
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		leave, err := p.nest()
+		if err != nil {
+			return nil, err
+		}
+		t.elem, err = p.parseTypeRef()
+		leave()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		if t.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	ok, err := p.skip("!")
+	if err != nil {
+		return nil, err
+	}
+	t.nonNull = ok
+
+	return t, nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	leave, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	var selections []selection
+	for {
+		done, err := p.skip("}")
+		if err != nil {
+			return nil, err
+		}
+		if done && len(selections) > 0 {
+			return selections, nil
+		}
+		if done {
+			return nil, syntaxError(p.tok.loc, "Expected a selection.")
+		}
+
+		var sel selection
+		if p.peek("...") {
+			sel, err = p.parseFragmentSelection()
+		} else {
+			sel, err = p.parseField()
+		}
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+}
+
+func (p *parser) parseField() (*field, error) {
+	f := &field{loc: p.tok.loc}
+
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+		if name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	f.name = name
+
+	if f.arguments, err = p.parseArguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.parseDirectives(false); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// parseFragmentSelection parses a fragment spread or an inline fragment,
+// starting at the "..." that begins both.
+func (p *parser) parseFragmentSelection() (selection, error) {
+	loc := p.tok.loc
+	if err := p.expect("..."); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		spread := &fragmentSpread{name: p.tok.value, loc: loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		spread.directives, err = p.parseDirectives(false)
+		if err != nil {
+			return nil, err
+		}
+		return spread, nil
+	}
+
+	inline := &inlineFragment{loc: loc}
+	var err error
+	if p.peekName("on") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if inline.typeCondition, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if inline.directives, err = p.parseDirectives(false); err != nil {
+		return nil, err
+	}
+	if inline.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+
+	return inline, nil
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	frag := &fragment{loc: p.tok.loc}
+	if err := p.expectKeyword("fragment"); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	if frag.name, err = p.expectName(); err != nil {
+		return nil, err
+	}
+	if err = p.expectKeyword("on"); err != nil {
+		return nil, err
+	}
+	if frag.typeCondition, err = p.expectName(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.parseDirectives(false); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+
+	return frag, nil
+}
+
+// parseArguments parses an optional argument list. Constant lists, such as
+// those in default values, can't refer to variables.
+func (p *parser) parseArguments(constant bool) ([]*argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+
+	var args []*argument
+	for {
+		done, err := p.skip(")")
+		if err != nil {
+			return nil, err
+		}
+		if done && len(args) > 0 {
+			return args, nil
+		}
+		if done {
+			return nil, syntaxError(p.tok.loc, "Expected an argument.")
+		}
+
+		arg := &argument{loc: p.tok.loc}
+		if arg.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.value, err = p.parseValue(constant); err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+}
+
+func (p *parser) parseDirectives(constant bool) ([]*directive, error) {
+	var directives []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if d.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+		if d.arguments, err = p.parseArguments(constant); err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// parseValue parses an input value. Constant values can't refer to variables.
+func (p *parser) parseValue(constant bool) (*value, error) {
+	tok := p.tok
+	v := &value{raw: tok.value, loc: tok.loc}
+
+	switch tok.kind {
+	case tokenInt:
+		v.kind = intValue
+	case tokenFloat:
+		v.kind = floatValue
+	case tokenString:
+		v.kind = stringValue
+	case tokenName:
+		switch tok.value {
+		case "true", "false":
+			v.kind = booleanValue
+		case "null":
+			v.kind = nullValue
+		default:
+			v.kind = enumValue
+		}
+	case tokenPunctuator:
+		switch {
+		case tok.value == "$" && !constant:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return &value{kind: variableValue, raw: name, loc: tok.loc}, nil
+		case tok.value == "[":
+			return p.parseList(constant)
+		case tok.value == "{":
+			return p.parseObject(constant)
+		}
+		return nil, p.unexpected()
+	default:
+		return nil, p.unexpected()
+	}
+
+	return v, p.advance()
+}
+
+func (p *parser) parseList(constant bool) (*value, error) {
+	v := &value{kind: listValue, list: []*value{}, loc: p.tok.loc}
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+
+	leave, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	for {
+		if done, err := p.skip("]"); err != nil || done {
+			return v, err
+		}
+		item, err := p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+		v.list = append(v.list, item)
+	}
+}
+
+func (p *parser) parseObject(constant bool) (*value, error) {
+	v := &value{kind: objectValue, loc: p.tok.loc}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	leave, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	for {
+		if done, err := p.skip("}"); err != nil || done {
+			return v, err
+		}
+
+		f := &objectField{loc: p.tok.loc}
+		if f.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if f.value, err = p.parseValue(constant); err != nil {
+			return nil, err
+		}
+		v.fields = append(v.fields, f)
+	}
+}
@@ -0,0 +1,141 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		query Articles($first: Int = 5, $tags: [String!]! @deprecated) @live {
+			list: articles(first: $first, filter: {tags: $tags, status: PUBLISHED, title: "x", score: -1.5, draft: null}) {
+				...Fields
+				... on Article @include(if: true) { id }
+				... { title }
+			}
+		}
+		fragment Fields on Article { id }
+		{ shorthand }
+	`)
+	require.NoError(t, err)
+	require.Len(t, doc.operations, 2)
+	require.Len(t, doc.fragments, 1)
+
+	op := doc.operations[0]
+	assert.Equal(t, "query", op.kind)
+	assert.Equal(t, "Articles", op.name)
+	assert.Equal(t, Location{2, 3}, op.loc)
+	require.Len(t, op.variables, 2)
+	assert.Equal(t, "Int", op.variables[0].typ.String())
+	assert.Equal(t, "5", op.variables[0].defaultValue.raw)
+	assert.Equal(t, "[String!]!", op.variables[1].typ.String())
+	require.Len(t, op.directives, 1)
+	assert.Equal(t, "live", op.directives[0].name)
+
+	require.Len(t, op.selections, 1)
+	list := op.selections[0].(*field)
+	assert.Equal(t, "list", list.alias)
+	assert.Equal(t, "articles", list.name)
+	assert.Equal(t, "list", list.responseKey())
+	require.Len(t, list.arguments, 2)
+	assert.Equal(t, "$first", printValue(list.arguments[0].value))
+	assert.Equal(t, `{tags: $tags, status: PUBLISHED, title: "x", score: -1.5, draft: null}`, printValue(list.arguments[1].value))
+
+	require.Len(t, list.selections, 3)
+	assert.Equal(t, "Fields", list.selections[0].(*fragmentSpread).name)
+	inline := list.selections[1].(*inlineFragment)
+	assert.Equal(t, "Article", inline.typeCondition)
+	assert.Equal(t, "include", inline.directives[0].name)
+	assert.Equal(t, "", list.selections[2].(*inlineFragment).typeCondition)
+
+	assert.Equal(t, "Fields", doc.fragments[0].name)
+	assert.Equal(t, "Article", doc.fragments[0].typeCondition)
+
+	shorthand := doc.operations[1]
+	assert.Equal(t, "query", shorthand.kind)
+	assert.Equal(t, "", shorthand.name)
+	assert.Equal(t, "shorthand", shorthand.selections[0].(*field).responseKey())
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{name: "Empty", src: "", expected: "Unexpected <EOF>."},
+		{name: "Only Comments", src: "# nothing here", expected: "Unexpected <EOF>."},
+		{name: "Type Definition", src: "type Query { id: ID }", expected: `Unexpected Name "type".`},
+		{name: "Unclosed Selection Set", src: "{ id", expected: "Expected Name, found <EOF>."},
+		{name: "Empty Selection Set", src: "{ }", expected: "Expected a selection."},
+		{name: "Empty Arguments", src: "{ article() { id } }", expected: "Expected an argument."},
+		{name: "Empty Variables", src: "query () { id }", expected: "Expected a variable definition."},
+		{name: "Missing Argument Value", src: "{ article(id:) { id } }", expected: `Unexpected ")".`},
+		{name: "Missing Colon", src: "{ article(id 1) { id } }", expected: `Expected ":", found Int "1".`},
+		{name: "Variable In Constant", src: "query ($a: Int = $b) { id }", expected: `Unexpected "$".`},
+		{name: "Variable In Directive Default", src: "query ($a: Int @x(if: $b)) { id }", expected: `Unexpected "$".`},
+		{name: "Fragment Named On", src: "fragment on on Article { id }", expected: `Unexpected Name "on".`},
+		{name: "Fragment Without Condition", src: "fragment Fields { id }", expected: `Expected "on", found "{".`},
+		{name: "Unclosed List Type", src: "query ($a: [Int) { id }", expected: `Expected "]", found ")".`},
+		{name: "Unclosed List Value", src: "{ articles(ids: [1, 2) { id } }", expected: `Unexpected ")".`},
+		{name: "Object Value Without Name", src: `{ articles(filter: {"tag": 1}) { id } }`, expected: `Expected Name, found String "tag".`},
+		{name: "Lexer Error", src: "{ id ? }", expected: `Unexpected character '?'.`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.src)
+
+			var gqlErr *Error
+			require.ErrorAs(t, err, &gqlErr)
+			assert.Equal(t, "Syntax Error: "+tt.expected, gqlErr.Message)
+		})
+	}
+}
+
+func TestParseNesting(t *testing.T) {
+	tests := []struct {
+		name string
+		src  func(depth int) string
+	}{
+		{
+			name: "Selection Sets",
+			src: func(depth int) string {
+				return strings.Repeat("{ a ", depth) + strings.Repeat("}", depth)
+			},
+		},
+		{
+			name: "List Values",
+			src: func(depth int) string {
+				return "{ a(b: " + strings.Repeat("[", depth) + strings.Repeat("]", depth) + ") }"
+			},
+		},
+		{
+			name: "Object Values",
+			src: func(depth int) string {
+				return "{ a(b: " + strings.Repeat("{c: ", depth) + "1" + strings.Repeat("}", depth) + ") }"
+			},
+		},
+		{
+			name: "List Types",
+			src: func(depth int) string {
+				return "query ($a: " + strings.Repeat("[", depth) + "Int" + strings.Repeat("]", depth) + ") { a }"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.src(maxNesting - 1))
+			assert.NoError(t, err)
+
+			_, err = parse(tt.src(100000))
+			var gqlErr *Error
+			require.ErrorAs(t, err, &gqlErr)
+			assert.Equal(t, "Syntax Error: Document is nested too deeply.", gqlErr.Message)
+		})
+	}
+}
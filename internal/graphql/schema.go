@@ -0,0 +1,390 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Schema describes the types a GraphQL service exposes. Only queries are
+// supported, so Query is its only root type.
+//
+// MaxDepth and MaxComplexity limit the operations the schema will execute;
+// zero disables a limit. Depth counts nested selection sets, top-level fields
+// being at depth 1. Complexity is the sum of the costs of the selected fields,
+// as worked out by their Complexity functions.
+type Schema struct {
+	Query         *Object
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// Type is a GraphQL type: an *Object, *Scalar, *Enum, *InputObject, *List or
+// *NonNull.
+type Type interface {
+	// String returns the type as written in a document, such as "[String!]".
+	String() string
+}
+
+// Object is an object type, whose fields are selected by queries.
+type Object struct {
+	Name        string
+	Description string
+	Fields      map[string]*Field
+}
+
+// Field is a field of an object type.
+type Field struct {
+	Type        Type
+	Description string
+	Args        map[string]*Argument
+	// Resolve returns the field's value. If nil, the value is looked up in a
+	// source of type map[string]any under the field's name.
+	Resolve ResolveFunc
+	// Complexity returns the cost of selecting the field given its arguments
+	// and the cost of its own selections. If nil, the cost is 1 plus that of
+	// its selections, which suits fields that aren't lists. List fields should
+	// multiply the cost of their selections by the most items they return.
+	Complexity ComplexityFunc
+}
+
+// ResolveFunc resolves the value of a field.
+type ResolveFunc func(p ResolveParams) (any, error)
+
+// ResolveParams holds what a resolver is given: the value of the object the
+// field belongs to, and its arguments coerced to their types. Arguments that
+// weren't given and have no default are missing from Args.
+type ResolveParams struct {
+	Context context.Context
+	Source  any
+	Args    map[string]any
+}
+
+// ComplexityFunc returns the cost of selecting a field.
+type ComplexityFunc func(args map[string]any, childComplexity int) int
+
+// Argument is an argument of a field. Default is used when the argument isn't
+// given; a nil Default leaves it out of the resolver's arguments.
+type Argument struct {
+	Type        Type
+	Description string
+	Default     any
+}
+
+// InputObject is an input object type, whose fields are coerced to a
+// map[string]any. Fields that weren't given and have no default are missing
+// from the map.
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      map[string]*InputField
+}
+
+// InputField is a field of an input object type.
+type InputField struct {
+	Type        Type
+	Description string
+	Default     any
+}
+
+// Scalar is a leaf type.
+type Scalar struct {
+	Name        string
+	Description string
+	// Serialize converts a resolved value into a value encoding/json writes
+	// as the scalar.
+	Serialize func(v any) (any, error)
+	// ParseValue coerces an input value. It is given values decoded from the
+	// JSON variables, or the Go form of a literal: int64 for integers,
+	// float64 for floats, string, or bool.
+	ParseValue func(v any) (any, error)
+}
+
+// Enum is an enum type. Its values are coerced to, and resolved as, strings.
+type Enum struct {
+	Name        string
+	Description string
+	Values      []string
+}
+
+// List is a list of another type.
+type List struct {
+	OfType Type
+}
+
+// NonNull is a type whose values can't be null.
+type NonNull struct {
+	OfType Type
+}
+
+// NewList returns a list of t.
+func NewList(t Type) *List {
+	return &List{OfType: t}
+}
+
+// NewNonNull returns the non-null version of t.
+func NewNonNull(t Type) *NonNull {
+	return &NonNull{OfType: t}
+}
+
+func (t *Object) String() string      { return t.Name }
+func (t *InputObject) String() string { return t.Name }
+func (t *Scalar) String() string      { return t.Name }
+func (t *Enum) String() string        { return t.Name }
+func (t *List) String() string        { return "[" + t.OfType.String() + "]" }
+func (t *NonNull) String() string     { return t.OfType.String() + "!" }
+
+// namedType strips the list and non-null wrappers from t.
+func namedType(t Type) Type {
+	for {
+		switch w := t.(type) {
+		case *List:
+			t = w.OfType
+		case *NonNull:
+			t = w.OfType
+		default:
+			return t
+		}
+	}
+}
+
+// isLeaf reports whether t is a scalar or enum, which have no selections.
+func isLeaf(t Type) bool {
+	switch namedType(t).(type) {
+	case *Scalar, *Enum:
+		return true
+	}
+	return false
+}
+
+// isInputType reports whether values of t can be given as input.
+func isInputType(t Type) bool {
+	switch namedType(t).(type) {
+	case *Scalar, *Enum, *InputObject:
+		return true
+	}
+	return false
+}
+
+// types returns every named type reachable from the schema, keyed by name.
+func (s *Schema) types() map[string]Type {
+	types := map[string]Type{
+		Int.Name:     Int,
+		Float.Name:   Float,
+		String.Name:  String,
+		Boolean.Name: Boolean,
+		ID.Name:      ID,
+	}
+
+	var visit func(t Type)
+	visit = func(t Type) {
+		t = namedType(t)
+		name := t.String()
+		if _, seen := types[name]; seen {
+			return
+		}
+		types[name] = t
+
+		switch t := t.(type) {
+		case *Object:
+			for _, f := range t.Fields {
+				visit(f.Type)
+				for _, arg := range f.Args {
+					visit(arg.Type)
+				}
+			}
+		case *InputObject:
+			for _, f := range t.Fields {
+				visit(f.Type)
+			}
+		}
+	}
+	visit(s.Query)
+
+	return types
+}
+
+// typenameField is the __typename meta-field every object type has.
+var typenameField = &Field{Type: NewNonNull(String)}
+
+// lookupField returns the field of t with the given name, including the
+// __typename meta-field.
+func lookupField(t *Object, name string) *Field {
+	if name == "__typename" {
+		return typenameField
+	}
+	return t.Fields[name]
+}
+
+// The built-in scalars.
+var (
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "A signed 32-bit integer.",
+		Serialize: func(v any) (any, error) {
+			return coerceInt(v, true)
+		},
+		ParseValue: func(v any) (any, error) {
+			return coerceInt(v, false)
+		},
+	}
+
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "A double-precision floating-point number.",
+		Serialize: func(v any) (any, error) {
+			return coerceFloat(v)
+		},
+		ParseValue: func(v any) (any, error) {
+			return coerceFloat(v)
+		},
+	}
+
+	String = &Scalar{
+		Name:        "String",
+		Description: "A UTF-8 string.",
+		Serialize: func(v any) (any, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case fmt.Stringer:
+				return v.String(), nil
+			}
+			return nil, fmt.Errorf("String cannot represent value: %v", v)
+		},
+		ParseValue: func(v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("String cannot represent a non-string value: %s", describeValue(v))
+			}
+			return s, nil
+		},
+	}
+
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "true or false.",
+		Serialize: func(v any) (any, error) {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("Boolean cannot represent value: %v", v)
+			}
+			return b, nil
+		},
+		ParseValue: func(v any) (any, error) {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("Boolean cannot represent a non-boolean value: %s", describeValue(v))
+			}
+			return b, nil
+		},
+	}
+
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "A unique identifier, sent as a string.",
+		Serialize: func(v any) (any, error) {
+			return coerceID(v)
+		},
+		ParseValue: func(v any) (any, error) {
+			return coerceID(v)
+		},
+	}
+)
+
+// coerceInt converts v to an int within the 32-bit range GraphQL allows.
+// Output values may be any integer type; input values must already be
+// integers, though JSON numbers decoded as float64 are accepted when whole.
+func coerceInt(v any, output bool) (int, error) {
+	var n float64
+	switch v := v.(type) {
+	case int:
+		n = float64(v)
+	case int32:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case float64:
+		n = v
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("Int cannot represent non-integer value: %s", v)
+		}
+		n = float64(i)
+	default:
+		if output {
+			return 0, fmt.Errorf("Int cannot represent value: %v", v)
+		}
+		return 0, fmt.Errorf("Int cannot represent non-integer value: %s", describeValue(v))
+	}
+
+	if n != math.Trunc(n) {
+		return 0, fmt.Errorf("Int cannot represent non-integer value: %s", strconv.FormatFloat(n, 'f', -1, 64))
+	}
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		return 0, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %s", strconv.FormatFloat(n, 'f', -1, 64))
+	}
+	return int(n), nil
+}
+
+func coerceFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		f, err := v.Float64()
+		if err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("Float cannot represent non numeric value: %s", describeValue(v))
+}
+
+// coerceID converts a string or integer to an ID string.
+func coerceID(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return strconv.FormatInt(int64(v), 10), nil
+		}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return v.String(), nil
+		}
+	}
+	return "", fmt.Errorf("ID cannot represent value: %s", describeValue(v))
+}
+
+// parseValue coerces an input value to one of the enum's values.
+func (t *Enum) parseValue(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok || !slices.Contains(t.Values, s) {
+		return "", fmt.Errorf("Value %s does not exist in %q enum.", describeValue(v), t.Name)
+	}
+	return s, nil
+}
+
+// describeValue formats an input value for error messages, as JSON.
+func describeValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,489 @@
+package graphql
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// directives are the directives the executor understands, with their
+// arguments.
+var directives = map[string]map[string]*Argument{
+	"include": {"if": {Type: NewNonNull(Boolean)}},
+	"skip":    {"if": {Type: NewNonNull(Boolean)}},
+}
+
+// validator checks a document against the schema before it is executed,
+// following the validation rules of the spec that apply to queries.
+type validator struct {
+	schema    *Schema
+	types     map[string]Type
+	doc       *document
+	fragments map[string]*fragment
+	// spread records the fragments spread by any operation.
+	spread map[string]bool
+	errors []*Error
+	// reported dedupes errors found more than once, such as those in a
+	// fragment spread by several operations.
+	reported map[string]bool
+}
+
+// variableUsage is a variable referred to by an input value, along with the
+// type expected where it is used.
+type variableUsage struct {
+	name string
+	typ  Type
+	// hasDefault is set if the argument or input field the variable is used
+	// for has a default value, which lets a nullable variable fill a non-null
+	// position.
+	hasDefault bool
+	loc        Location
+}
+
+// walkState tracks what an operation uses while its selections, and those of
+// the fragments it spreads, are walked.
+type walkState struct {
+	usages []variableUsage
+	// visited holds the fragments already walked for the operation, and stack
+	// those being walked, to detect spreads of a fragment within itself.
+	visited map[string]bool
+	stack   []string
+}
+
+// validate returns the errors in doc, or nil if it is valid.
+func validate(schema *Schema, doc *document) []*Error {
+	v := &validator{
+		schema:    schema,
+		types:     schema.types(),
+		doc:       doc,
+		fragments: make(map[string]*fragment),
+		spread:    make(map[string]bool),
+		reported:  make(map[string]bool),
+	}
+
+	v.validateOperations()
+	v.validateFragments()
+
+	for _, op := range doc.operations {
+		if op.kind == "query" {
+			v.validateOperation(op)
+		}
+	}
+
+	for _, frag := range doc.fragments {
+		if !v.spread[frag.name] {
+			v.errorf([]Location{frag.loc}, "Fragment %q is never used.", frag.name)
+		}
+	}
+
+	return v.errors
+}
+
+func (v *validator) errorf(locs []Location, format string, args ...any) {
+	e := validationError(fmt.Sprintf(format, args...), locs...)
+
+	key := fmt.Sprint(e.Message, e.Locations)
+	if v.reported[key] {
+		return
+	}
+	v.reported[key] = true
+
+	v.errors = append(v.errors, e)
+}
+
+// validateOperations checks the operations' names and kinds.
+func (v *validator) validateOperations() {
+	names := make(map[string]Location)
+	for _, op := range v.doc.operations {
+		if op.name == "" && len(v.doc.operations) > 1 {
+			v.errorf([]Location{op.loc}, "This anonymous operation must be the only defined operation.")
+		}
+		if op.name != "" {
+			if loc, ok := names[op.name]; ok {
+				v.errorf([]Location{loc, op.loc}, "There can be only one operation named %q.", op.name)
+			}
+			names[op.name] = op.loc
+		}
+		if op.kind != "query" {
+			v.errorf([]Location{op.loc}, "Schema is not configured to execute %s operation.", op.kind)
+		}
+	}
+}
+
+// validateFragments checks the fragments' names and type conditions.
+func (v *validator) validateFragments() {
+	for _, frag := range v.doc.fragments {
+		if other, ok := v.fragments[frag.name]; ok {
+			v.errorf([]Location{other.loc, frag.loc}, "There can be only one fragment named %q.", frag.name)
+			continue
+		}
+		v.fragments[frag.name] = frag
+
+		v.objectType(frag.typeCondition, frag.loc)
+		for _, d := range frag.directives {
+			v.errorf([]Location{d.loc}, "Directive \"@%s\" may not be used on FRAGMENT_DEFINITION.", d.name)
+		}
+	}
+}
+
+// objectType returns the object type named by a type condition, reporting an
+// error if there is none.
+func (v *validator) objectType(name string, loc Location) *Object {
+	t, ok := v.types[name]
+	if !ok {
+		v.errorf([]Location{loc}, "Unknown type %q.", name)
+		return nil
+	}
+
+	obj, ok := t.(*Object)
+	if !ok {
+		v.errorf([]Location{loc}, "Fragment cannot condition on non composite type %q.", name)
+		return nil
+	}
+	return obj
+}
+
+// validateOperation checks a query's variables and selections.
+func (v *validator) validateOperation(op *operation) {
+	defs := make(map[string]*variableDefinition)
+	// invalid holds the variables whose types have been reported, so that
+	// their uses aren't reported as well.
+	invalid := make(map[string]bool)
+	for _, def := range op.variables {
+		if _, ok := defs[def.name]; ok {
+			v.errorf([]Location{def.loc}, "There can be only one variable named \"$%s\".", def.name)
+			continue
+		}
+		defs[def.name] = def
+
+		t, err := resolveTypeRef(def.typ, v.types)
+		if err != nil {
+			v.errorf([]Location{def.typ.loc}, "%s", err)
+			invalid[def.name] = true
+			continue
+		}
+		if !isInputType(t) {
+			v.errorf([]Location{def.typ.loc}, "Variable \"$%s\" cannot be non-input type %q.", def.name, def.typ)
+			invalid[def.name] = true
+			continue
+		}
+		if def.defaultValue != nil {
+			st := &walkState{}
+			v.checkValue(def.defaultValue, t, false, st)
+		}
+	}
+
+	for _, d := range op.directives {
+		v.errorf([]Location{d.loc}, "Directive \"@%s\" may not be used on QUERY.", d.name)
+	}
+
+	st := &walkState{visited: make(map[string]bool)}
+	v.walk(v.schema.Query, op.selections, st)
+
+	operation := ""
+	if op.name != "" {
+		operation = fmt.Sprintf(" by operation %q", op.name)
+	}
+
+	used := make(map[string]bool)
+	for _, u := range st.usages {
+		used[u.name] = true
+
+		def, ok := defs[u.name]
+		if !ok {
+			v.errorf([]Location{u.loc, op.loc}, "Variable \"$%s\" is not defined%s.", u.name, operation)
+			continue
+		}
+		if !invalid[u.name] && !variableAllowed(def, u) {
+			v.errorf([]Location{def.loc, u.loc}, "Variable \"$%s\" of type %q used in position expecting type %q.", u.name, def.typ, u.typ)
+		}
+	}
+
+	if op.name != "" {
+		operation = fmt.Sprintf(" in operation %q", op.name)
+	}
+	for _, def := range op.variables {
+		if !used[def.name] {
+			v.errorf([]Location{def.loc}, "Variable \"$%s\" is never used%s.", def.name, operation)
+		}
+	}
+}
+
+// walk checks selections made on the object type t.
+func (v *validator) walk(t *Object, selections []selection, st *walkState) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			v.checkDirectives(sel.directives, st)
+
+			def := lookupField(t, sel.name)
+			if def == nil {
+				v.errorf([]Location{sel.loc}, "Cannot query field %q on type %q.", sel.name, t.Name)
+				continue
+			}
+
+			v.checkArguments(fmt.Sprintf("Field %q", sel.name), fmt.Sprintf("field \"%s.%s\"", t.Name, sel.name), def.Args, sel.arguments, sel.loc, st)
+
+			obj, isObject := namedType(def.Type).(*Object)
+			switch {
+			case !isObject && len(sel.selections) > 0:
+				v.errorf([]Location{sel.loc}, "Field %q must not have a selection since type %q has no subfields.", sel.name, def.Type)
+			case isObject && len(sel.selections) == 0:
+				v.errorf([]Location{sel.loc}, "Field %q of type %q must have a selection of subfields. Did you mean \"%s { ... }\"?", sel.name, def.Type, sel.name)
+			case isObject:
+				v.walk(obj, sel.selections, st)
+			}
+
+		case *inlineFragment:
+			v.checkDirectives(sel.directives, st)
+
+			target := t
+			if sel.typeCondition != "" {
+				target = v.objectType(sel.typeCondition, sel.loc)
+				if target == nil {
+					continue
+				}
+				if target != t {
+					v.errorf([]Location{sel.loc}, "Fragment cannot be spread here as objects of type %q can never be of type %q.", t.Name, target.Name)
+					continue
+				}
+			}
+			v.walk(target, sel.selections, st)
+
+		case *fragmentSpread:
+			v.checkDirectives(sel.directives, st)
+
+			frag, ok := v.fragments[sel.name]
+			if !ok {
+				v.errorf([]Location{sel.loc}, "Unknown fragment %q.", sel.name)
+				continue
+			}
+			v.spread[sel.name] = true
+
+			if slices.Contains(st.stack, sel.name) {
+				v.errorf([]Location{sel.loc}, "Cannot spread fragment %q within itself.", sel.name)
+				continue
+			}
+
+			target, ok := v.types[frag.typeCondition].(*Object)
+			if !ok {
+				// The fragment's type condition has already been reported.
+				continue
+			}
+			if target != t {
+				v.errorf([]Location{sel.loc}, "Fragment %q cannot be spread here as objects of type %q can never be of type %q.", sel.name, t.Name, target.Name)
+				continue
+			}
+
+			// A fragment is only walked once per operation, however often it
+			// is spread, so that documents can't make validation explode.
+			if st.visited[sel.name] {
+				continue
+			}
+			st.visited[sel.name] = true
+
+			st.stack = append(st.stack, sel.name)
+			v.walk(target, frag.selections, st)
+			st.stack = st.stack[:len(st.stack)-1]
+		}
+	}
+}
+
+func (v *validator) checkDirectives(ds []*directive, st *walkState) {
+	seen := make(map[string]bool)
+	for _, d := range ds {
+		args, ok := directives[d.name]
+		if !ok {
+			v.errorf([]Location{d.loc}, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		if seen[d.name] {
+			v.errorf([]Location{d.loc}, "The directive \"@%s\" can only be used once at this location.", d.name)
+		}
+		seen[d.name] = true
+
+		v.checkArguments(fmt.Sprintf("Directive \"@%s\"", d.name), fmt.Sprintf("directive \"@%s\"", d.name), args, d.arguments, d.loc, st)
+	}
+}
+
+// checkArguments checks the arguments given to a field or directive. owner
+// names it in errors about missing arguments, such as "Field \"article\"",
+// and qualified in errors about unknown ones, such as "field \"Query.article\"".
+func (v *validator) checkArguments(owner, qualified string, defs map[string]*Argument, args []*argument, loc Location, st *walkState) {
+	given := make(map[string]bool)
+	for _, arg := range args {
+		if given[arg.name] {
+			v.errorf([]Location{arg.loc}, "There can be only one argument named %q.", arg.name)
+			continue
+		}
+		given[arg.name] = true
+
+		def, ok := defs[arg.name]
+		if !ok {
+			v.errorf([]Location{arg.loc}, "Unknown argument %q on %s.", arg.name, qualified)
+			continue
+		}
+		v.checkValue(arg.value, def.Type, def.Default != nil, st)
+	}
+
+	for _, argName := range sortedKeys(defs) {
+		def := defs[argName]
+		if _, nonNull := def.Type.(*NonNull); nonNull && def.Default == nil && !given[argName] {
+			v.errorf([]Location{loc}, "%s argument %q of type %q is required, but it was not provided.", owner, argName, def.Type)
+		}
+	}
+}
+
+// checkValue checks that a literal input value can be coerced to t, and
+// records the variables it uses.
+func (v *validator) checkValue(val *value, t Type, hasDefault bool, st *walkState) {
+	if val.kind == variableValue {
+		st.usages = append(st.usages, variableUsage{name: val.raw, typ: t, hasDefault: hasDefault, loc: val.loc})
+		return
+	}
+
+	if nn, ok := t.(*NonNull); ok {
+		if val.kind == nullValue {
+			v.errorf([]Location{val.loc}, "Expected value of type %q, found null.", t)
+			return
+		}
+		t = nn.OfType
+	}
+
+	if val.kind == nullValue {
+		return
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if val.kind != listValue {
+			v.checkValue(val, t.OfType, false, st)
+			return
+		}
+		for _, item := range val.list {
+			v.checkValue(item, t.OfType, false, st)
+		}
+
+	case *InputObject:
+		if val.kind != objectValue {
+			v.errorf([]Location{val.loc}, "Expected value of type %q, found %s.", t, printValue(val))
+			return
+		}
+
+		given := make(map[string]bool)
+		for _, f := range val.fields {
+			if given[f.name] {
+				v.errorf([]Location{f.loc}, "There can be only one input field named %q.", f.name)
+				continue
+			}
+			given[f.name] = true
+
+			def, ok := t.Fields[f.name]
+			if !ok {
+				v.errorf([]Location{f.loc}, "Field %q is not defined by type %q.", f.name, t.Name)
+				continue
+			}
+			v.checkValue(f.value, def.Type, def.Default != nil, st)
+		}
+
+		for _, name := range sortedKeys(t.Fields) {
+			def := t.Fields[name]
+			if _, nonNull := def.Type.(*NonNull); nonNull && def.Default == nil && !given[name] {
+				v.errorf([]Location{val.loc}, "Field \"%s.%s\" of required type %q was not provided.", t.Name, name, def.Type)
+			}
+		}
+
+	default:
+		// Leaf values can't contain variables, so coercing them checks them.
+		_, err := coerceLiteral(val, t, nil)
+		if err != nil {
+			v.errorf([]Location{val.loc}, "Expected value of type %q, found %s; %s", t, printValue(val), err)
+		}
+	}
+}
+
+// variableAllowed reports whether a variable's type suits the position it is
+// used in. A nullable variable may fill a non-null position if either has a
+// default value.
+func variableAllowed(def *variableDefinition, u variableUsage) bool {
+	if nn, ok := u.typ.(*NonNull); ok && !def.typ.nonNull {
+		hasDefault := def.defaultValue != nil && def.defaultValue.kind != nullValue
+		if !hasDefault && !u.hasDefault {
+			return false
+		}
+		return isSubType(def.typ, nn.OfType)
+	}
+	return isSubType(def.typ, u.typ)
+}
+
+// isSubType reports whether values of the variable type ref are always valid
+// for t.
+func isSubType(ref *typeRef, t Type) bool {
+	if nn, ok := t.(*NonNull); ok {
+		return ref.nonNull && isSubType(&typeRef{name: ref.name, elem: ref.elem}, nn.OfType)
+	}
+	if ref.nonNull {
+		return isSubType(&typeRef{name: ref.name, elem: ref.elem}, t)
+	}
+	if list, ok := t.(*List); ok {
+		return ref.elem != nil && isSubType(ref.elem, list.OfType)
+	}
+	return ref.elem == nil && ref.name == t.String()
+}
+
+// resolveTypeRef returns the type a variable definition refers to.
+func resolveTypeRef(ref *typeRef, types map[string]Type) (Type, error) {
+	var t Type
+	if ref.elem != nil {
+		elem, err := resolveTypeRef(ref.elem, types)
+		if err != nil {
+			return nil, err
+		}
+		t = NewList(elem)
+	} else {
+		named, ok := types[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown type %q.", ref.name)
+		}
+		t = named
+	}
+
+	if ref.nonNull {
+		t = NewNonNull(t)
+	}
+	return t, nil
+}
+
+// printValue formats a literal as it would be written in a document.
+func printValue(val *value) string {
+	switch val.kind {
+	case variableValue:
+		return "$" + val.raw
+	case stringValue:
+		return describeValue(val.raw)
+	case listValue:
+		items := make([]string, len(val.list))
+		for i, item := range val.list {
+			items[i] = printValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case objectValue:
+		fields := make([]string, len(val.fields))
+		for i, f := range val.fields {
+			fields[i] = f.name + ": " + printValue(f.value)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case nullValue:
+		return "null"
+	}
+	return val.raw
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
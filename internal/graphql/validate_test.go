@@ -0,0 +1,274 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:  "Valid",
+			query: `query A($id: ID!, $f: ArticleFilter) { article(id: $id) { ...F ... on Article { tags } } articles(filter: $f) { id } } fragment F on Article { title }`,
+		},
+		{
+			name:     "Anonymous Operation Not Alone",
+			query:    `{ echo } query A { echo }`,
+			expected: []string{"This anonymous operation must be the only defined operation."},
+		},
+		{
+			name:     "Duplicate Operation",
+			query:    `query A { echo } query A { echo }`,
+			expected: []string{`There can be only one operation named "A".`},
+		},
+		{
+			name:     "Mutation",
+			query:    `mutation { echo }`,
+			expected: []string{"Schema is not configured to execute mutation operation."},
+		},
+		{
+			name:     "Unknown Field",
+			query:    `{ author }`,
+			expected: []string{`Cannot query field "author" on type "Query".`},
+		},
+		{
+			name:     "Selection On Leaf",
+			query:    `{ echo { length } }`,
+			expected: []string{`Field "echo" must not have a selection since type "String" has no subfields.`},
+		},
+		{
+			name:     "Missing Selection",
+			query:    `{ article(id: 1) }`,
+			expected: []string{`Field "article" of type "Article" must have a selection of subfields. Did you mean "article { ... }"?`},
+		},
+		{
+			name:     "Missing Argument",
+			query:    `{ article { id } }`,
+			expected: []string{`Field "article" argument "id" of type "ID!" is required, but it was not provided.`},
+		},
+		{
+			name:     "Unknown Argument",
+			query:    `{ echo(text: "x") }`,
+			expected: []string{`Unknown argument "text" on field "Query.echo".`},
+		},
+		{
+			name:     "Duplicate Argument",
+			query:    `{ echo(value: "a", value: "b") }`,
+			expected: []string{`There can be only one argument named "value".`},
+		},
+		{
+			name:     "Null For Non-Null Argument",
+			query:    `{ article(id: null) { id } }`,
+			expected: []string{`Expected value of type "ID!", found null.`},
+		},
+		{
+			name:     "Wrong Literal Type",
+			query:    `{ articles(first: "ten") { id } }`,
+			expected: []string{`Expected value of type "Int", found "ten"; Int cannot represent non-integer value: "ten"`},
+		},
+		{
+			name:     "Int Out Of Range",
+			query:    `{ articles(first: 3000000000) { id } }`,
+			expected: []string{`Expected value of type "Int", found 3000000000; Int cannot represent non 32-bit signed integer value: 3000000000`},
+		},
+		{
+			name:     "Unknown Enum Value",
+			query:    `{ articles(filter: {status: GONE}) { id } }`,
+			expected: []string{`Expected value of type "Status", found GONE; Value "GONE" does not exist in "Status" enum.`},
+		},
+		{
+			name:     "String For Enum",
+			query:    `{ articles(filter: {status: "DRAFT"}) { id } }`,
+			expected: []string{`Expected value of type "Status", found "DRAFT"; Enum "Status" cannot represent non-enum value: "DRAFT".`},
+		},
+		{
+			name:     "Input Object Errors",
+			query:    `{ articles(filter: {tag: "a", tag: "b", author: "c", ids: [1, null]}) { id } }`,
+			expected: []string{`There can be only one input field named "tag".`, `Field "author" is not defined by type "ArticleFilter".`, `Expected value of type "ID!", found null.`},
+		},
+		{
+			name:     "Scalar For Input Object",
+			query:    `{ articles(filter: "science") { id } }`,
+			expected: []string{`Expected value of type "ArticleFilter", found "science".`},
+		},
+		{
+			name:  "Single Value For List",
+			query: `{ articles(filter: {ids: 1}) { id } }`,
+		},
+		{
+			name:     "Unknown Directive",
+			query:    `{ echo @cached }`,
+			expected: []string{`Unknown directive "@cached".`},
+		},
+		{
+			name:     "Repeated Directive",
+			query:    `{ echo @skip(if: true) @skip(if: false) }`,
+			expected: []string{`The directive "@skip" can only be used once at this location.`},
+		},
+		{
+			name:     "Directive Missing Argument",
+			query:    `{ echo @include }`,
+			expected: []string{`Directive "@include" argument "if" of type "Boolean!" is required, but it was not provided.`},
+		},
+		{
+			name:     "Directive On Operation",
+			query:    `query @skip(if: true) { echo }`,
+			expected: []string{`Directive "@skip" may not be used on QUERY.`},
+		},
+		{
+			name:     "Directive On Fragment",
+			query:    `{ article(id: 1) { ...F } } fragment F on Article @skip(if: true) { id }`,
+			expected: []string{`Directive "@skip" may not be used on FRAGMENT_DEFINITION.`},
+		},
+		{
+			name:     "Unknown Fragment",
+			query:    `{ article(id: 1) { ...F } }`,
+			expected: []string{`Unknown fragment "F".`},
+		},
+		{
+			name:     "Unused Fragment",
+			query:    `{ echo } fragment F on Article { id }`,
+			expected: []string{`Fragment "F" is never used.`},
+		},
+		{
+			name:     "Duplicate Fragment",
+			query:    `{ article(id: 1) { ...F } } fragment F on Article { id } fragment F on Article { title }`,
+			expected: []string{`There can be only one fragment named "F".`},
+		},
+		{
+			name:     "Fragment On Unknown Type",
+			query:    `{ article(id: 1) { ...F } } fragment F on Author { id }`,
+			expected: []string{`Unknown type "Author".`},
+		},
+		{
+			name:     "Fragment On Scalar",
+			query:    `{ article(id: 1) { ...F } } fragment F on String { id }`,
+			expected: []string{`Fragment cannot condition on non composite type "String".`},
+		},
+		{
+			name:     "Fragment On Wrong Type",
+			query:    `{ ...F } fragment F on Article { id }`,
+			expected: []string{`Fragment "F" cannot be spread here as objects of type "Query" can never be of type "Article".`},
+		},
+		{
+			name:     "Inline Fragment On Wrong Type",
+			query:    `{ ... on Article { id } }`,
+			expected: []string{`Fragment cannot be spread here as objects of type "Query" can never be of type "Article".`},
+		},
+		{
+			name:     "Fragment Cycle",
+			query:    `{ article(id: 1) { ...A } } fragment A on Article { ...B } fragment B on Article { related { ...A } }`,
+			expected: []string{`Cannot spread fragment "A" within itself.`},
+		},
+		{
+			name:     "Undefined Variable",
+			query:    `query A { echo(value: $v) }`,
+			expected: []string{`Variable "$v" is not defined by operation "A".`},
+		},
+		{
+			name:     "Unused Variable",
+			query:    `query A($v: String) { echo }`,
+			expected: []string{`Variable "$v" is never used in operation "A".`},
+		},
+		{
+			name:     "Duplicate Variable",
+			query:    `query ($v: String, $v: String) { echo(value: $v) }`,
+			expected: []string{`There can be only one variable named "$v".`},
+		},
+		{
+			name:     "Unknown Variable Type",
+			query:    `query ($v: Text) { echo(value: $v) }`,
+			expected: []string{`Unknown type "Text".`},
+		},
+		{
+			name:     "Output Variable Type",
+			query:    `query ($v: Article) { echo(value: $v) }`,
+			expected: []string{`Variable "$v" cannot be non-input type "Article".`},
+		},
+		{
+			name:     "Invalid Variable Default",
+			query:    `query ($v: String = 1) { echo(value: $v) }`,
+			expected: []string{`Expected value of type "String", found 1; String cannot represent a non-string value: 1`},
+		},
+		{
+			name:     "Nullable Variable In Non-Null Position",
+			query:    `query ($id: ID) { article(id: $id) { id } }`,
+			expected: []string{`Variable "$id" of type "ID" used in position expecting type "ID!".`},
+		},
+		{
+			name:  "Nullable Variable With Default In Non-Null Position",
+			query: `query ($id: ID = 1) { article(id: $id) { id } }`,
+		},
+		{
+			name:  "Nullable Variable In Position With Default",
+			query: `query ($s: Status) { articles(filter: {status: $s}) { id } }`,
+		},
+		{
+			name:     "Wrong Variable Type",
+			query:    `query ($n: Int) { echo(value: $n) }`,
+			expected: []string{`Variable "$n" of type "Int" used in position expecting type "String".`},
+		},
+		{
+			name:     "List Variable In Scalar Position",
+			query:    `query ($ids: [ID]) { articles(filter: {tag: $ids}) { id } }`,
+			expected: []string{`Variable "$ids" of type "[ID]" used in position expecting type "String".`},
+		},
+		{
+			name:  "Non-Null List Variable",
+			query: `query ($ids: [ID!]!) { articles(filter: {ids: $ids}) { id } }`,
+		},
+		{
+			name:     "Errors In A Fragment Spread Twice Are Reported Once",
+			query:    `{ a: article(id: 1) { ...F } b: article(id: 2) { ...F } } fragment F on Article { author }`,
+			expected: []string{`Cannot query field "author" on type "Article".`},
+		},
+	}
+
+	schema := testSchema()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parse(tt.query)
+			require.NoError(t, err)
+
+			var messages []string
+			for _, err := range validate(schema, doc) {
+				assert.Equal(t, CodeValidationFailed, err.Extensions["code"])
+				assert.NotEmpty(t, err.Locations)
+				messages = append(messages, err.Message)
+			}
+			assert.Equal(t, tt.expected, messages)
+		})
+	}
+}
+
+func TestValidateRepeatedFragments(t *testing.T) {
+	// Each fragment spreads the next twice, so walking every spread would
+	// take 2^n steps.
+	var sb strings.Builder
+	sb.WriteString("{ article(id: 1) { ...F0 } }\n")
+	for i := range 64 {
+		sb.WriteString("fragment F" + strconv.Itoa(i) + " on Article { id ...F" + strconv.Itoa(i+1) + " ...F" + strconv.Itoa(i+1) + " }\n")
+	}
+	sb.WriteString("fragment F64 on Article { title }\n")
+
+	doc, err := parse(sb.String())
+	require.NoError(t, err)
+	assert.Empty(t, validate(testSchema(), doc))
+}
+
+func TestVariableLocations(t *testing.T) {
+	doc, err := parse("query A($v: Int)\n{ echo(value: $v) }")
+	require.NoError(t, err)
+
+	errs := validate(testSchema(), doc)
+	require.Len(t, errs, 1)
+	assert.Equal(t, []Location{{1, 9}, {2, 15}}, errs[0].Locations)
+}
@@ -0,0 +1,303 @@
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// coerceVariables coerces the variable values sent with a request to the
+// types the operation defines for them. Variables that weren't sent and have
+// no default are left out.
+func coerceVariables(op *operation, types map[string]Type, values map[string]any) (map[string]any, []*Error) {
+	coerced := make(map[string]any)
+	var errs []*Error
+
+	for _, def := range op.variables {
+		t, err := resolveTypeRef(def.typ, types)
+		if err != nil {
+			// Validation has already reported unknown types.
+			continue
+		}
+
+		fail := func(format string, args ...any) {
+			e := NewError(CodeBadUserInput, fmt.Sprintf(format, args...))
+			e.Locations = []Location{def.loc}
+			errs = append(errs, e)
+		}
+
+		v, given := values[def.name]
+		switch {
+		case !given && def.defaultValue != nil:
+			v, err := coerceLiteral(def.defaultValue, t, nil)
+			if err != nil {
+				fail("Variable \"$%s\" has invalid default value: %s", def.name, err)
+				continue
+			}
+			coerced[def.name] = v
+
+		case !given && def.typ.nonNull:
+			fail("Variable \"$%s\" of required type %q was not provided.", def.name, def.typ)
+
+		case !given:
+
+		case v == nil && def.typ.nonNull:
+			fail("Variable \"$%s\" of non-null type %q must not be null.", def.name, def.typ)
+
+		default:
+			v, err := coerceInput(v, t)
+			if err != nil {
+				fail("Variable \"$%s\" got invalid value %s; %s", def.name, describeValue(values[def.name]), err)
+				continue
+			}
+			coerced[def.name] = v
+		}
+	}
+
+	return coerced, errs
+}
+
+// coerceInput coerces a value decoded from JSON to t.
+func coerceInput(v any, t Type) (any, error) {
+	if nn, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("Expected non-nullable type %q not to be null.", t)
+		}
+		t = nn.OfType
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		items, ok := v.([]any)
+		if !ok {
+			// A single value is coerced to a list of one.
+			item, err := coerceInput(v, t.OfType)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+
+		list := make([]any, len(items))
+		for i, item := range items {
+			item, err := coerceInput(item, t.OfType)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+			list[i] = item
+		}
+		return list, nil
+
+	case *InputObject:
+		fields, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("Expected type %q to be an object.", t.Name)
+		}
+		for name := range fields {
+			if _, ok := t.Fields[name]; !ok {
+				return nil, fmt.Errorf("Field %q is not defined by type %q.", name, t.Name)
+			}
+		}
+
+		object := make(map[string]any)
+		for _, name := range sortedKeys(t.Fields) {
+			def := t.Fields[name]
+			fv, given := fields[name]
+			if !given {
+				if def.Default != nil {
+					object[name] = def.Default
+				} else if _, nonNull := def.Type.(*NonNull); nonNull {
+					return nil, fmt.Errorf("Field %q of required type %q was not provided.", name, def.Type)
+				}
+				continue
+			}
+
+			fv, err := coerceInput(fv, def.Type)
+			if err != nil {
+				return nil, fmt.Errorf("at %q: %w", name, err)
+			}
+			object[name] = fv
+		}
+		return object, nil
+
+	case *Enum:
+		return t.parseValue(v)
+
+	case *Scalar:
+		return t.ParseValue(v)
+	}
+
+	return nil, fmt.Errorf("Type %q is not an input type.", t)
+}
+
+// coerceLiteral coerces a literal from a document to t, looking up the values
+// of the variables it refers to in variables. Variables without a value are
+// coerced to null.
+func coerceLiteral(val *value, t Type, variables map[string]any) (any, error) {
+	if val.kind == variableValue {
+		v := variables[val.raw]
+		if _, nonNull := t.(*NonNull); nonNull && v == nil {
+			return nil, fmt.Errorf("Expected non-nullable type %q not to be null.", t)
+		}
+		return v, nil
+	}
+
+	if nn, ok := t.(*NonNull); ok {
+		if val.kind == nullValue {
+			return nil, fmt.Errorf("Expected non-nullable type %q not to be null.", t)
+		}
+		t = nn.OfType
+	}
+	if val.kind == nullValue {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if val.kind != listValue {
+			item, err := coerceLiteral(val, t.OfType, variables)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+
+		list := make([]any, len(val.list))
+		for i, item := range val.list {
+			item, err := coerceLiteral(item, t.OfType, variables)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+
+	case *InputObject:
+		if val.kind != objectValue {
+			return nil, fmt.Errorf("Expected type %q to be an object.", t.Name)
+		}
+
+		given := make(map[string]*value)
+		for _, f := range val.fields {
+			if _, ok := t.Fields[f.name]; !ok {
+				return nil, fmt.Errorf("Field %q is not defined by type %q.", f.name, t.Name)
+			}
+			given[f.name] = f.value
+		}
+
+		object := make(map[string]any)
+		for _, name := range sortedKeys(t.Fields) {
+			def := t.Fields[name]
+			fv, ok := given[name]
+			// A field set to a variable without a value counts as not given.
+			if ok && fv.kind == variableValue {
+				_, ok = variables[fv.raw]
+			}
+			if !ok {
+				if def.Default != nil {
+					object[name] = def.Default
+				} else if _, nonNull := def.Type.(*NonNull); nonNull {
+					return nil, fmt.Errorf("Field %q of required type %q was not provided.", name, def.Type)
+				}
+				continue
+			}
+
+			v, err := coerceLiteral(fv, def.Type, variables)
+			if err != nil {
+				return nil, err
+			}
+			object[name] = v
+		}
+		return object, nil
+
+	case *Enum:
+		if val.kind != enumValue {
+			return nil, fmt.Errorf("Enum %q cannot represent non-enum value: %s.", t.Name, printValue(val))
+		}
+		return t.parseValue(val.raw)
+
+	case *Scalar:
+		var v any
+		switch val.kind {
+		case intValue:
+			n, err := strconv.ParseInt(val.raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s cannot represent value: %s", t.Name, val.raw)
+			}
+			v = n
+		case floatValue:
+			// Float literals never stand for integers, even when whole.
+			if t == Int || t == ID {
+				return nil, fmt.Errorf("%s cannot represent non-integer value: %s", t.Name, val.raw)
+			}
+			f, err := strconv.ParseFloat(val.raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s cannot represent value: %s", t.Name, val.raw)
+			}
+			v = f
+		case stringValue:
+			v = val.raw
+		case booleanValue:
+			v = val.raw == "true"
+		default:
+			return nil, fmt.Errorf("%s cannot represent value: %s", t.Name, printValue(val))
+		}
+		return t.ParseValue(v)
+	}
+
+	return nil, fmt.Errorf("Type %q is not an input type.", t)
+}
+
+// coerceArguments coerces the arguments given to a field or directive.
+// Arguments that weren't given, or were set to a variable without a value,
+// take their default or are left out.
+func coerceArguments(defs map[string]*Argument, args []*argument, variables map[string]any) (map[string]any, error) {
+	given := make(map[string]*value, len(args))
+	for _, arg := range args {
+		given[arg.name] = arg.value
+	}
+
+	coerced := make(map[string]any, len(defs))
+	for _, name := range sortedKeys(defs) {
+		def := defs[name]
+
+		val, ok := given[name]
+		if ok && val.kind == variableValue {
+			_, ok = variables[val.raw]
+		}
+		if !ok {
+			if def.Default != nil {
+				coerced[name] = def.Default
+			} else if _, nonNull := def.Type.(*NonNull); nonNull {
+				return nil, fmt.Errorf("Argument %q of required type %q was not provided.", name, def.Type)
+			}
+			continue
+		}
+
+		v, err := coerceLiteral(val, def.Type, variables)
+		if err != nil {
+			return nil, fmt.Errorf("Argument %q has invalid value %s: %s", name, printValue(val), err)
+		}
+		coerced[name] = v
+	}
+
+	return coerced, nil
+}
+
+// isNull reports whether a resolved value stands for null: nil, or a nil
+// pointer, map or interface. Nil slices are empty lists rather than null.
+func isNull(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+// Package importer reads articles from RSS, Atom and WordPress export (WXR)
+// files, as used to migrate archives from other publishing systems.
+//
+// A Decoder reads items one at a time, so large archives are never held in
+// memory, and maps each one onto a data.Article. It doesn't validate the
+// articles it produces: that is left to the caller, which knows what is
+// already stored.
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+)
+
+// Format identifies the kind of file being imported.
+type Format string
+
+const (
+	FormatRSS  Format = "rss"
+	FormatAtom Format = "atom"
+	FormatWXR  Format = "wxr"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// ErrUnknownFormat is returned when a file isn't an RSS, Atom or WXR document.
+var ErrUnknownFormat = errors.New("not an RSS, Atom or WordPress export file")
+
+// Item is an entry read from an import file.
+type Item struct {
+	// Index is the position of the item in the file, counting from 1.
+	Index int
+	// Article holds the item's title, date, body and tags. Its ID is only set
+	// for WordPress posts, whose post IDs are kept.
+	Article data.Article
+	// Skip explains why the item shouldn't be imported, such as it being a
+	// draft, or is empty if it should be.
+	Skip string
+}
+
+// Decoder reads items from an import file.
+type Decoder struct {
+	d      *xml.Decoder
+	format Format
+	index  int
+}
+
+// NewDecoder returns a Decoder reading from r, which must hold UTF-8 XML.
+// HTML entities such as &nbsp;, which often slip into exported content, are
+// accepted.
+func NewDecoder(r io.Reader) *Decoder {
+	d := xml.NewDecoder(r)
+	d.Entity = xml.HTMLEntity
+	return &Decoder{d: d}
+}
+
+// Format returns the format of the file, once it is known. WordPress exports
+// are RSS files, so they are only told apart once their first item or the
+// WXR version is read.
+func (dec *Decoder) Format() Format {
+	return dec.format
+}
+
+// Next returns the next item in the file. It returns io.EOF once there are no
+// more, and ErrUnknownFormat if the file isn't one it can read.
+func (dec *Decoder) Next() (Item, error) {
+	for {
+		tok, err := dec.d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				if dec.format == "" {
+					return Item{}, ErrUnknownFormat
+				}
+				return Item{}, io.EOF
+			}
+			return Item{}, syntaxError(err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if dec.format == "" {
+			switch {
+			case start.Name.Local == "rss":
+				dec.format = FormatRSS
+			case start.Name.Local == "feed" && start.Name.Space == atomNamespace:
+				dec.format = FormatAtom
+			default:
+				return Item{}, ErrUnknownFormat
+			}
+			continue
+		}
+
+		switch {
+		case start.Name.Local == "wxr_version":
+			dec.format = FormatWXR
+
+		case start.Name.Local == "item" && dec.format != FormatAtom:
+			var item rssItem
+			err := dec.d.DecodeElement(&item, &start)
+			if err != nil {
+				return Item{}, syntaxError(err)
+			}
+			if item.PostType != "" {
+				dec.format = FormatWXR
+			}
+			dec.index++
+			return item.toItem(dec.index, dec.format), nil
+
+		case start.Name.Local == "entry" && dec.format == FormatAtom:
+			var entry atomEntry
+			err := dec.d.DecodeElement(&entry, &start)
+			if err != nil {
+				return Item{}, syntaxError(err)
+			}
+			dec.index++
+			return entry.toItem(dec.index), nil
+		}
+	}
+}
+
+// syntaxError describes an error reading the XML of an import file.
+func syntaxError(err error) error {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("badly-formed XML on line %d: %s", syntaxErr.Line, syntaxErr.Msg)
+	}
+	return err
+}
+
+// rssItem is an RSS item, including the fields WordPress adds to them.
+type rssItem struct {
+	Title       string        `xml:"title"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description"`
+	Content     string        `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories  []rssCategory `xml:"category"`
+
+	// WordPress fields.
+	PostID      string `xml:"post_id"`
+	PostDate    string `xml:"post_date"`
+	PostDateGMT string `xml:"post_date_gmt"`
+	PostType    string `xml:"post_type"`
+	Status      string `xml:"status"`
+}
+
+type rssCategory struct {
+	Domain string `xml:"domain,attr"`
+	Name   string `xml:",chardata"`
+}
+
+func (item rssItem) toItem(index int, format Format) Item {
+	result := Item{
+		Index: index,
+		Article: data.Article{
+			Title: strings.TrimSpace(item.Title),
+			Body:  strings.TrimSpace(item.Content),
+		},
+	}
+
+	if result.Article.Body == "" {
+		result.Article.Body = strings.TrimSpace(item.Description)
+	}
+
+	var tags []string
+	for _, category := range item.Categories {
+		tags = append(tags, category.Name)
+	}
+	result.Article.Tags = cleanTags(tags)
+
+	date := parseRSSDate(item.PubDate)
+
+	if format == FormatWXR {
+		if id, err := strconv.ParseInt(strings.TrimSpace(item.PostID), 10, 64); err == nil && id > 0 {
+			result.Article.ID = id
+		}
+
+		// WordPress leaves the dates of unpublished posts zeroed, as
+		// "0000-00-00 00:00:00", which doesn't parse.
+		for _, value := range []string{item.PostDateGMT, item.PostDate} {
+			if t, err := time.Parse(time.DateTime, strings.TrimSpace(value)); err == nil {
+				date = t
+				break
+			}
+		}
+
+		switch {
+		case item.PostType != "" && item.PostType != "post":
+			result.Skip = fmt.Sprintf("not a post (type %s)", item.PostType)
+		case item.Status != "" && item.Status != "publish":
+			result.Skip = fmt.Sprintf("not published (status %s)", item.Status)
+		}
+	}
+
+	result.Article.Date = articleDate(date)
+
+	return result
+}
+
+// rssDateLayouts are the date formats found in RSS feeds. RSS requires RFC 822
+// dates, but feeds in the wild vary in whether they include the day of the
+// week, the seconds and a numeric zone.
+var rssDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+}
+
+// parseRSSDate parses an RSS date, returning the zero time if it can't.
+func parseRSSDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range rssDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// atomEntry is an Atom entry.
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Content    atomText       `xml:"content"`
+	Summary    atomText       `xml:"summary"`
+	Categories []atomCategory `xml:"category"`
+}
+
+// atomText is an Atom text construct, which holds text, escaped HTML, or
+// XHTML wrapped in a div.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the text or markup held by t.
+func (t atomText) String() string {
+	if t.Type != "xhtml" {
+		return strings.TrimSpace(t.Text)
+	}
+
+	inner := strings.TrimSpace(t.Inner)
+	if start := strings.Index(inner, ">"); start >= 0 && strings.HasPrefix(inner, "<div") {
+		if end := strings.LastIndex(inner, "</"); end > start {
+			inner = inner[start+1 : end]
+		}
+	}
+	return strings.TrimSpace(inner)
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+func (entry atomEntry) toItem(index int) Item {
+	result := Item{
+		Index: index,
+		Article: data.Article{
+			Title: strings.TrimSpace(entry.Title),
+			Body:  entry.Content.String(),
+		},
+	}
+
+	if result.Article.Body == "" {
+		result.Article.Body = entry.Summary.String()
+	}
+
+	var tags []string
+	for _, category := range entry.Categories {
+		tags = append(tags, category.Term)
+	}
+	result.Article.Tags = cleanTags(tags)
+
+	published := entry.Published
+	if published == "" {
+		published = entry.Updated
+	}
+	date, _ := time.Parse(time.RFC3339, strings.TrimSpace(published))
+	result.Article.Date = articleDate(date)
+
+	return result
+}
+
+// articleDate returns the calendar date of t, in t's own time zone, so that an
+// item keeps the date it was published on where it was written. The zero time
+// stays zero, so that validation reports the date as missing.
+func articleDate(t time.Time) data.ArticleDate {
+	if t.IsZero() {
+		return data.ArticleDate{}
+	}
+	return data.ArticleDate(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+}
+
+// cleanTags trims tags and drops empty and repeated ones, which WordPress
+// produces when a post has a category and a tag of the same name.
+func cleanTags(tags []string) []string {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
@@ -0,0 +1,200 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+)
+
+// readAll reads every item from src, returning the items read before any
+// error along with the format the decoder settled on.
+func readAll(src string) ([]Item, Format, error) {
+	dec := NewDecoder(strings.NewReader(src))
+
+	var items []Item
+	for {
+		item, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			return items, dec.Format(), nil
+		}
+		if err != nil {
+			return items, dec.Format(), err
+		}
+		items = append(items, item)
+	}
+}
+
+func date(year int, month time.Month, day int) data.ArticleDate {
+	return data.ArticleDate(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name           string
+		src            string
+		expectedFormat Format
+		expectedItems  []Item
+	}{
+		{
+			name: "RSS",
+			src: `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Ignored</title>
+    <item>
+      <title> Chips&nbsp;news </title>
+      <pubDate>Thu, 22 Sep 2016 10:00:00 +1000</pubDate>
+      <description>Summary only</description>
+      <content:encoded><![CDATA[<p>Full <b>body</b></p>]]></content:encoded>
+      <category>health</category>
+      <category> health </category>
+      <category>science</category>
+    </item>
+    <item>
+      <title>Short date</title>
+      <pubDate>22 Sep 2016 10:00:00 GMT</pubDate>
+      <description>Only a description</description>
+    </item>
+    <item>
+      <title>Bad date</title>
+      <pubDate>yesterday</pubDate>
+      <description>Body</description>
+    </item>
+  </channel>
+</rss>`,
+			expectedFormat: FormatRSS,
+			expectedItems: []Item{
+				{Index: 1, Article: data.Article{Title: "Chips\u00a0news", Date: date(2016, 9, 22), Body: "<p>Full <b>body</b></p>", Tags: []string{"health", "science"}}},
+				{Index: 2, Article: data.Article{Title: "Short date", Date: date(2016, 9, 22), Body: "Only a description", Tags: []string{}}},
+				{Index: 3, Article: data.Article{Title: "Bad date", Body: "Body", Tags: []string{}}},
+			},
+		},
+		{
+			name: "Atom",
+			src: `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Ignored</title>
+  <entry>
+    <title>XHTML content</title>
+    <published>2016-09-22T23:00:00-05:00</published>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Hello <em>there</em></p></div></content>
+    <category term="health" label="Health"/>
+  </entry>
+  <entry>
+    <title>Summary fallback</title>
+    <updated>2016-09-21T08:00:00Z</updated>
+    <summary type="html">&lt;p&gt;Escaped&lt;/p&gt;</summary>
+  </entry>
+</feed>`,
+			expectedFormat: FormatAtom,
+			expectedItems: []Item{
+				{Index: 1, Article: data.Article{Title: "XHTML content", Date: date(2016, 9, 22), Body: "<p>Hello <em>there</em></p>", Tags: []string{"health"}}},
+				{Index: 2, Article: data.Article{Title: "Summary fallback", Date: date(2016, 9, 21), Body: "<p>Escaped</p>", Tags: []string{}}},
+			},
+		},
+		{
+			name: "WordPress",
+			src: `<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:wp="http://wordpress.org/export/1.2/">
+  <channel>
+    <wp:wxr_version>1.2</wp:wxr_version>
+    <item>
+      <title>Published post</title>
+      <pubDate>Thu, 01 Jan 2015 00:00:00 +0000</pubDate>
+      <content:encoded>Post body</content:encoded>
+      <category domain="category">news</category>
+      <category domain="post_tag">news</category>
+      <wp:post_id>42</wp:post_id>
+      <wp:post_date>2016-09-22 23:30:00</wp:post_date>
+      <wp:post_date_gmt>2016-09-22 13:30:00</wp:post_date_gmt>
+      <wp:post_type>post</wp:post_type>
+      <wp:status>publish</wp:status>
+    </item>
+    <item>
+      <title>Draft</title>
+      <content:encoded>Not ready</content:encoded>
+      <wp:post_id>43</wp:post_id>
+      <wp:post_date>0000-00-00 00:00:00</wp:post_date>
+      <wp:post_date_gmt>0000-00-00 00:00:00</wp:post_date_gmt>
+      <wp:post_type>post</wp:post_type>
+      <wp:status>draft</wp:status>
+    </item>
+    <item>
+      <title>Logo</title>
+      <wp:post_id>44</wp:post_id>
+      <wp:post_type>attachment</wp:post_type>
+      <wp:status>inherit</wp:status>
+    </item>
+  </channel>
+</rss>`,
+			expectedFormat: FormatWXR,
+			expectedItems: []Item{
+				{Index: 1, Article: data.Article{ID: 42, Title: "Published post", Date: date(2016, 9, 22), Body: "Post body", Tags: []string{"news"}}},
+				{Index: 2, Article: data.Article{ID: 43, Title: "Draft", Body: "Not ready", Tags: []string{}}, Skip: "not published (status draft)"},
+				{Index: 3, Article: data.Article{ID: 44, Title: "Logo", Tags: []string{}}, Skip: "not a post (type attachment)"},
+			},
+		},
+		{
+			name:           "RSS Without Items",
+			src:            `<rss version="2.0"><channel><title>Empty</title></channel></rss>`,
+			expectedFormat: FormatRSS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, format, err := readAll(tt.src)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedFormat, format)
+			assert.Equal(t, tt.expectedItems, items)
+		})
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		src           string
+		expectedItems int
+		expectedErr   error
+		expectedMsg   string
+	}{
+		{name: "Empty", src: "", expectedErr: ErrUnknownFormat},
+		{name: "Not XML", src: "just some text", expectedErr: ErrUnknownFormat},
+		{name: "HTML", src: "<html><body>Hi</body></html>", expectedErr: ErrUnknownFormat},
+		{name: "Feed Outside Atom Namespace", src: "<feed><entry><title>x</title></entry></feed>", expectedErr: ErrUnknownFormat},
+		{
+			name:          "Truncated",
+			src:           "<rss>\n<channel>\n<item><title>One</title></item>\n<item><title>Two",
+			expectedItems: 1,
+			expectedMsg:   "badly-formed XML on line 4: unexpected EOF",
+		},
+		{
+			name:        "Mismatched Tags",
+			src:         "<rss><channel><item><title>One</item></channel></rss>",
+			expectedMsg: "badly-formed XML on line 1: element <title> closed by </item>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, _, err := readAll(tt.src)
+			require.Error(t, err)
+			assert.Len(t, items, tt.expectedItems)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+			if tt.expectedMsg != "" {
+				assert.EqualError(t, err, tt.expectedMsg)
+			}
+		})
+	}
+}
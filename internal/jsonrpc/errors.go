@@ -0,0 +1,40 @@
+package jsonrpc
+
+// The error codes defined by the JSON-RPC 2.0 specification. Codes from
+// -32000 to -32099 are left for servers to define their own errors.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// messages holds the message the specification gives each of its error codes.
+var messages = map[int]string{
+	CodeParseError:     "Parse error",
+	CodeInvalidRequest: "Invalid Request",
+	CodeMethodNotFound: "Method not found",
+	CodeInvalidParams:  "Invalid params",
+	CodeInternalError:  "Internal error",
+}
+
+// Error is the error object of a response. Data holds further details of the
+// error, such as the parameters that failed validation.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Error implements the error interface, so that methods can return an *Error
+// to decide the error object sent to the client.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError returns an error with one of the codes defined by the
+// specification, and its message.
+func NewError(code int, data any) *Error {
+	return &Error{Code: code, Message: messages[code], Data: data}
+}
@@ -0,0 +1,297 @@
+// Package jsonrpc implements the server side of JSON-RPC 2.0, as described by
+// https://www.jsonrpc.org/specification, independently of the transport.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Version is the version of the protocol, which requests must name.
+const Version = "2.0"
+
+// HandlerFunc carries out a call to a method. Params always holds a JSON
+// object, with parameters passed by position already named; DecodeParams
+// decodes it. Returning an *Error sends that error to the client; any other
+// error is reported as an internal error.
+type HandlerFunc func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Method is a method that can be called.
+type Method struct {
+	// Params names the method's parameters in order, so that they can be
+	// passed by position as well as by name.
+	Params  []string
+	Handler HandlerFunc
+}
+
+// Server dispatches calls to the methods registered with it.
+type Server struct {
+	methods map[string]Method
+	// MaxBatch is the most calls a batch may hold, or 0 for no limit.
+	MaxBatch int
+	// ErrorLog, if set, is called with errors returned by methods that aren't
+	// *Error, since clients only learn that there was an internal error.
+	ErrorLog func(method string, err error)
+}
+
+// NewServer returns a server without any methods.
+func NewServer() *Server {
+	return &Server{methods: make(map[string]Method)}
+}
+
+// Register makes a method available under the given name.
+func (s *Server) Register(name string, m Method) {
+	s.methods[name] = m
+}
+
+// Response is the response to a call. ID is the ID of the call, or nil for
+// null when the call was too malformed to have one.
+type Response struct {
+	ID     json.RawMessage
+	Result any
+	Error  *Error
+}
+
+// MarshalJSON encodes the response with exactly one of a result or an error,
+// as the specification requires, even when the result is null.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Error   *Error          `json:"error"`
+			ID      json.RawMessage `json:"id"`
+		}{Version, r.Error, id})
+	}
+
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  any             `json:"result"`
+		ID      json.RawMessage `json:"id"`
+	}{Version, r.Result, id})
+}
+
+// Handle carries out the call, or batch of calls, in body. It returns what
+// to send back: a *Response, a []*Response in the order the calls were made,
+// or nil when every call was a notification, which gets no response. Calls in
+// a batch are carried out one after another.
+func (s *Server) Handle(ctx context.Context, body []byte) any {
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		return &Response{Error: NewError(CodeParseError, "body contains badly-formed JSON")}
+	}
+
+	if body[0] != '[' {
+		resp := s.call(ctx, body)
+		if resp == nil {
+			return nil
+		}
+		return resp
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return &Response{Error: NewError(CodeParseError, err.Error())}
+	}
+
+	if len(batch) == 0 {
+		return &Response{Error: NewError(CodeInvalidRequest, "batch must contain at least 1 call")}
+	}
+	if s.MaxBatch > 0 && len(batch) > s.MaxBatch {
+		return &Response{Error: NewError(CodeInvalidRequest, fmt.Sprintf("batch must not contain more than %d calls", s.MaxBatch))}
+	}
+
+	var responses []*Response
+	for _, raw := range batch {
+		if resp := s.call(ctx, raw); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// request is a call as sent by the client. A call without an ID is a
+// notification.
+type request struct {
+	id      json.RawMessage
+	method  string
+	params  json.RawMessage
+	notify  bool
+	invalid string
+}
+
+// parseRequest reads a request object, recording what is wrong with it in
+// req.invalid. The ID is kept as long as it is valid, so that errors can be
+// matched up with the call.
+func parseRequest(raw json.RawMessage) *request {
+	req := &request{}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil || members == nil {
+		req.invalid = "request must be an object"
+		return req
+	}
+
+	id, ok := members["id"]
+	switch {
+	case !ok:
+		req.notify = true
+	case isJSON(id, 'n') || isJSON(id, '"') || isNumber(id):
+		req.id = id
+	default:
+		req.invalid = "id must be a string, number or null"
+		return req
+	}
+
+	var version string
+	if err := json.Unmarshal(members["jsonrpc"], &version); err != nil || version != Version {
+		req.invalid = fmt.Sprintf("jsonrpc must be %q", Version)
+		return req
+	}
+
+	if err := json.Unmarshal(members["method"], &req.method); err != nil || req.method == "" {
+		req.invalid = "method must be a non-empty string"
+		return req
+	}
+
+	if params, ok := members["params"]; ok {
+		if !isJSON(params, '{') && !isJSON(params, '[') {
+			req.invalid = "params must be an object or an array"
+			return req
+		}
+		req.params = params
+	}
+
+	for name := range members {
+		switch name {
+		case "jsonrpc", "method", "params", "id":
+		default:
+			req.invalid = fmt.Sprintf("request contains unknown member %q", name)
+			return req
+		}
+	}
+
+	return req
+}
+
+// isJSON reports whether a JSON value starts with the given character, which
+// tells its type.
+func isJSON(raw json.RawMessage, c byte) bool {
+	return len(raw) > 0 && raw[0] == c
+}
+
+// isNumber reports whether a JSON value is a number.
+func isNumber(raw json.RawMessage) bool {
+	return len(raw) > 0 && (raw[0] == '-' || (raw[0] >= '0' && raw[0] <= '9'))
+}
+
+// call carries out one call, returning nil if it was a notification.
+func (s *Server) call(ctx context.Context, raw json.RawMessage) *Response {
+	req := parseRequest(raw)
+	if req.invalid != "" {
+		// Invalid requests aren't notifications, even without an ID.
+		return &Response{ID: req.id, Error: NewError(CodeInvalidRequest, req.invalid)}
+	}
+
+	result, err := s.dispatch(ctx, req)
+
+	var rpcErr *Error
+	if err != nil && !errors.As(err, &rpcErr) {
+		if s.ErrorLog != nil {
+			s.ErrorLog(req.method, err)
+		}
+		rpcErr = NewError(CodeInternalError, nil)
+	}
+
+	if req.notify {
+		return nil
+	}
+	if rpcErr != nil {
+		return &Response{ID: req.id, Error: rpcErr}
+	}
+	return &Response{ID: req.id, Result: result}
+}
+
+// dispatch calls the method a request names with its parameters.
+func (s *Server) dispatch(ctx context.Context, req *request) (any, error) {
+	m, ok := s.methods[req.method]
+	if !ok {
+		return nil, NewError(CodeMethodNotFound, fmt.Sprintf("method %q does not exist", req.method))
+	}
+
+	params, err := nameParams(m.Params, req.params)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Handler(ctx, params)
+}
+
+// nameParams returns the parameters of a call as a JSON object, naming those
+// passed by position.
+func nameParams(names []string, params json.RawMessage) (json.RawMessage, error) {
+	if params == nil {
+		return json.RawMessage("{}"), nil
+	}
+	if !isJSON(params, '[') {
+		return params, nil
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(params, &values); err != nil {
+		return nil, NewError(CodeInvalidParams, err.Error())
+	}
+	if len(values) > len(names) {
+		return nil, NewError(CodeInvalidParams, fmt.Sprintf("params must not contain more than %d values", len(names)))
+	}
+
+	object := make(map[string]json.RawMessage, len(values))
+	for i, v := range values {
+		object[names[i]] = v
+	}
+	return json.Marshal(object)
+}
+
+// DecodeParams decodes the parameters of a call into dst, which is usually a
+// pointer to a struct. Unknown parameters and values of the wrong type are
+// reported as invalid params.
+func DecodeParams(params json.RawMessage, dst any) error {
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	var unmarshalTypeError *json.UnmarshalTypeError
+	var invalidUnmarshalError *json.InvalidUnmarshalError
+
+	switch {
+	case errors.As(err, &unmarshalTypeError) && unmarshalTypeError.Field != "":
+		return NewError(CodeInvalidParams, fmt.Sprintf("params contain incorrect JSON type for %q", unmarshalTypeError.Field))
+	case errors.As(err, &unmarshalTypeError):
+		return NewError(CodeInvalidParams, "params contain incorrect JSON type")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return NewError(CodeInvalidParams, "params contain unknown key "+strings.TrimPrefix(err.Error(), "json: unknown field "))
+	case errors.Is(err, io.EOF):
+		return NewError(CodeInvalidParams, "params must not be empty")
+	case errors.As(err, &invalidUnmarshalError):
+		panic(err)
+	default:
+		return NewError(CodeInvalidParams, err.Error())
+	}
+}
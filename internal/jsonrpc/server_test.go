@@ -0,0 +1,325 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer returns a server with methods that echo their parameters, fail
+// with an *Error and fail with a plain error.
+func testServer() *Server {
+	s := NewServer()
+	s.MaxBatch = 3
+
+	s.Register("subtract", Method{
+		Params: []string{"minuend", "subtrahend"},
+		Handler: func(ctx context.Context, params json.RawMessage) (any, error) {
+			var input struct {
+				Minuend    int `json:"minuend"`
+				Subtrahend int `json:"subtrahend"`
+			}
+			if err := DecodeParams(params, &input); err != nil {
+				return nil, err
+			}
+			return input.Minuend - input.Subtrahend, nil
+		},
+	})
+	s.Register("nothing", Method{
+		Handler: func(ctx context.Context, params json.RawMessage) (any, error) {
+			return nil, nil
+		},
+	})
+	s.Register("missing", Method{
+		Handler: func(ctx context.Context, params json.RawMessage) (any, error) {
+			return nil, &Error{Code: -32004, Message: "article not found"}
+		},
+	})
+	s.Register("broken", Method{
+		Handler: func(ctx context.Context, params json.RawMessage) (any, error) {
+			return nil, errors.New("database is down")
+		},
+	})
+
+	return s
+}
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "Named Params",
+			body:     `{"jsonrpc": "2.0", "method": "subtract", "params": {"subtrahend": 23, "minuend": 42}, "id": 3}`,
+			expected: `{"jsonrpc": "2.0", "result": 19, "id": 3}`,
+		},
+		{
+			name:     "Positional Params",
+			body:     `{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "a"}`,
+			expected: `{"jsonrpc": "2.0", "result": 19, "id": "a"}`,
+		},
+		{
+			name:     "Fewer Positional Params",
+			body:     `{"jsonrpc": "2.0", "method": "subtract", "params": [42], "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "result": 42, "id": 1}`,
+		},
+		{
+			name:     "Null Result",
+			body:     `{"jsonrpc": "2.0", "method": "nothing", "id": null}`,
+			expected: `{"jsonrpc": "2.0", "result": null, "id": null}`,
+		},
+		{
+			name:     "Large ID Kept",
+			body:     `{"jsonrpc": "2.0", "method": "nothing", "id": 12345678901234567890}`,
+			expected: `{"jsonrpc": "2.0", "result": null, "id": 12345678901234567890}`,
+		},
+		{
+			name: "Notification",
+			body: `{"jsonrpc": "2.0", "method": "subtract", "params": [1, 2]}`,
+		},
+		{
+			name: "Failed Notification",
+			body: `{"jsonrpc": "2.0", "method": "broken"}`,
+		},
+		{
+			name:     "Parse Error",
+			body:     `{"jsonrpc": "2.0", "method": "foobar, "params": "bar", "baz]`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error", "data": "body contains badly-formed JSON"}, "id": null}`,
+		},
+		{
+			name:     "Empty Body",
+			body:     ` `,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error", "data": "body contains badly-formed JSON"}, "id": null}`,
+		},
+		{
+			name:     "Not An Object",
+			body:     `"subtract"`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "request must be an object"}, "id": null}`,
+		},
+		{
+			name:     "Null Request",
+			body:     `null`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "request must be an object"}, "id": null}`,
+		},
+		{
+			name:     "Wrong Version",
+			body:     `{"jsonrpc": "1.0", "method": "nothing", "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "jsonrpc must be \"2.0\""}, "id": 1}`,
+		},
+		{
+			name:     "Missing Version Without ID",
+			body:     `{"method": "nothing"}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "jsonrpc must be \"2.0\""}, "id": null}`,
+		},
+		{
+			name:     "Object ID",
+			body:     `{"jsonrpc": "2.0", "method": "nothing", "id": {"a": 1}}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "id must be a string, number or null"}, "id": null}`,
+		},
+		{
+			name:     "Boolean ID",
+			body:     `{"jsonrpc": "2.0", "method": "nothing", "id": true}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "id must be a string, number or null"}, "id": null}`,
+		},
+		{
+			name:     "Method Not A String",
+			body:     `{"jsonrpc": "2.0", "method": 1, "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "method must be a non-empty string"}, "id": 1}`,
+		},
+		{
+			name:     "Empty Method",
+			body:     `{"jsonrpc": "2.0", "method": "", "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "method must be a non-empty string"}, "id": 1}`,
+		},
+		{
+			name:     "Scalar Params",
+			body:     `{"jsonrpc": "2.0", "method": "nothing", "params": "bar", "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "params must be an object or an array"}, "id": 1}`,
+		},
+		{
+			name:     "Unknown Member",
+			body:     `{"jsonrpc": "2.0", "method": "nothing", "id": 1, "extra": true}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "request contains unknown member \"extra\""}, "id": 1}`,
+		},
+		{
+			name:     "Method Not Found",
+			body:     `{"jsonrpc": "2.0", "method": "foobar", "id": "1"}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found", "data": "method \"foobar\" does not exist"}, "id": "1"}`,
+		},
+		{
+			name:     "Too Many Positional Params",
+			body:     `{"jsonrpc": "2.0", "method": "subtract", "params": [1, 2, 3], "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": "params must not contain more than 2 values"}, "id": 1}`,
+		},
+		{
+			name:     "Unknown Param",
+			body:     `{"jsonrpc": "2.0", "method": "subtract", "params": {"divisor": 2}, "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": "params contain unknown key \"divisor\""}, "id": 1}`,
+		},
+		{
+			name:     "Param Of Wrong Type",
+			body:     `{"jsonrpc": "2.0", "method": "subtract", "params": ["42", 23], "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": "params contain incorrect JSON type for \"minuend\""}, "id": 1}`,
+		},
+		{
+			name:     "Method Error",
+			body:     `{"jsonrpc": "2.0", "method": "missing", "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32004, "message": "article not found"}, "id": 1}`,
+		},
+		{
+			name:     "Internal Error",
+			body:     `{"jsonrpc": "2.0", "method": "broken", "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32603, "message": "Internal error"}, "id": 1}`,
+		},
+		{
+			name: "Batch",
+			body: `[
+				{"jsonrpc": "2.0", "method": "subtract", "params": [42, 23], "id": "1"},
+				{"jsonrpc": "2.0", "method": "nothing"},
+				{"foo": "boo"}
+			]`,
+			expected: `[
+				{"jsonrpc": "2.0", "result": 19, "id": "1"},
+				{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "jsonrpc must be \"2.0\""}, "id": null}
+			]`,
+		},
+		{
+			name:     "Batch Of Scalars",
+			body:     `[1, 2]`,
+			expected: `[{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "request must be an object"}, "id": null}, {"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "request must be an object"}, "id": null}]`,
+		},
+		{
+			name: "Batch Of Notifications",
+			body: `[{"jsonrpc": "2.0", "method": "nothing"}, {"jsonrpc": "2.0", "method": "broken"}]`,
+		},
+		{
+			name:     "Empty Batch",
+			body:     `[]`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "batch must contain at least 1 call"}, "id": null}`,
+		},
+		{
+			name:     "Batch Too Large",
+			body:     `[{}, {}, {}, {}]`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request", "data": "batch must not contain more than 3 calls"}, "id": null}`,
+		},
+	}
+
+	s := testServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.Handle(context.Background(), []byte(tt.body))
+			if tt.expected == "" {
+				assert.Nil(t, resp)
+				return
+			}
+
+			js, err := json.Marshal(resp)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(js))
+		})
+	}
+}
+
+func TestErrorLog(t *testing.T) {
+	s := testServer()
+
+	var logged []string
+	s.ErrorLog = func(method string, err error) {
+		logged = append(logged, method+": "+err.Error())
+	}
+
+	s.Handle(context.Background(), []byte(`[
+		{"jsonrpc": "2.0", "method": "broken", "id": 1},
+		{"jsonrpc": "2.0", "method": "broken"},
+		{"jsonrpc": "2.0", "method": "missing", "id": 2}
+	]`))
+
+	assert.Equal(t, []string{"broken: database is down", "broken: database is down"}, logged)
+}
+
+func TestNameParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   string
+		expected string
+		err      string
+	}{
+		{name: "None", params: "", expected: `{}`},
+		{name: "Object", params: `{"b": 2}`, expected: `{"b": 2}`},
+		{name: "Array", params: `[1, "x"]`, expected: `{"a": 1, "b": "x"}`},
+		{name: "Empty Array", params: `[]`, expected: `{}`},
+		{name: "Too Many", params: `[1, 2, 3]`, err: "params must not contain more than 2 values"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params json.RawMessage
+			if tt.params != "" {
+				params = json.RawMessage(tt.params)
+			}
+
+			named, err := nameParams([]string{"a", "b"}, params)
+			if tt.err != "" {
+				var rpcErr *Error
+				require.ErrorAs(t, err, &rpcErr)
+				assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+				assert.Equal(t, tt.err, rpcErr.Data)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(named))
+		})
+	}
+}
+
+func TestDecodeParams(t *testing.T) {
+	type input struct {
+		ID   int64    `json:"id"`
+		Tags []string `json:"tags"`
+	}
+
+	tests := []struct {
+		name     string
+		params   string
+		expected input
+		err      string
+	}{
+		{name: "Valid", params: `{"id": 1, "tags": ["a"]}`, expected: input{ID: 1, Tags: []string{"a"}}},
+		{name: "Empty Object", params: `{}`},
+		{name: "Empty", params: ``, err: "params must not be empty"},
+		{name: "Wrong Type", params: `{"id": "1"}`, err: `params contain incorrect JSON type for "id"`},
+		{name: "Wrong Type In List", params: `{"tags": [1]}`, err: `params contain incorrect JSON type for "tags.0"`},
+		{name: "Not An Object", params: `[1]`, err: "params contain incorrect JSON type"},
+		{name: "Unknown Key", params: `{"title": "x"}`, err: `params contain unknown key "title"`},
+		{name: "Badly Formed", params: `{"id": }`, err: "invalid character '}' looking for beginning of value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst input
+			err := DecodeParams(json.RawMessage(tt.params), &dst)
+			if tt.err != "" {
+				var rpcErr *Error
+				require.ErrorAs(t, err, &rpcErr)
+				assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+				assert.Equal(t, "Invalid params", rpcErr.Message)
+				assert.Equal(t, tt.err, rpcErr.Data)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, dst)
+		})
+	}
+
+	assert.Panics(t, func() {
+		_ = DecodeParams(json.RawMessage(`{}`), input{})
+	})
+}
@@ -0,0 +1,53 @@
+// Package markup parses the Markdown subset used in article bodies and renders
+// it as sanitized HTML or plain text.
+//
+// The supported syntax is ATX headings, paragraphs, emphasis and strong
+// emphasis, inline code and fenced code blocks, links and autolinks, block
+// quotes, ordered and unordered lists, inline HTML tags and HTML entities.
+// Anything else is treated as text.
+package markup
+
+// Kind identifies the type of a Node.
+type Kind int
+
+const (
+	// Block nodes.
+	Document Kind = iota
+	Heading
+	Paragraph
+	List
+	ListItem
+	CodeBlock
+	Blockquote
+
+	// Inline nodes.
+	Text
+	Emphasis
+	Strong
+	Code
+	Link
+	SoftBreak
+	HTML
+)
+
+// Node is a node of the syntax tree produced by Parse.
+type Node struct {
+	Kind     Kind
+	Children []*Node
+
+	// Literal holds the content of Text, Code and CodeBlock nodes, and the
+	// tag of an HTML node.
+	Literal string
+	// Level is the level of a Heading, from 1 to 6.
+	Level int
+	// Ordered, Start and Tight describe a List. Items of a tight list were
+	// not separated by blank lines, so their paragraphs render without <p>.
+	Ordered bool
+	Start   int
+	Tight   bool
+	// Info is the language given after the opening fence of a CodeBlock.
+	Info string
+	// Destination and Title describe a Link.
+	Destination string
+	Title       string
+}
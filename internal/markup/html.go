@@ -0,0 +1,260 @@
+package markup
+
+import (
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// allowedSchemes lists the URL schemes links may use. Links with any other
+// scheme, such as javascript:, are rendered as plain text. Relative URLs are
+// always allowed.
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// inlineElements are the elements inline HTML tags are kept for, if the
+// policy allows them. Other elements, such as blocks, can't be nested in the
+// elements produced for markup, so their tags are left out.
+var inlineElements = []string{
+	"a", "abbr", "b", "br", "cite", "code", "del", "dfn", "em", "i", "ins",
+	"kbd", "mark", "q", "s", "samp", "small", "span", "strong", "sub", "sup",
+	"u", "var", "wbr",
+}
+
+// voidElements have no content or end tag.
+var voidElements = []string{"br", "wbr"}
+
+// RenderHTML renders a parsed document as HTML. All text is escaped, so the
+// output only contains the elements produced for markup: h1-h6, p, ul, ol, li,
+// pre, code, blockquote, em, strong and a, and the inline HTML tags policy
+// allows. Links that fail the URL scheme allowlist lose their anchor but keep
+// their text, and links to external hosts are given rel="noopener nofollow".
+//
+// Inline HTML tags are cleaned as Sanitize would, and closed within the
+// markup they were opened in, so that the output is well-formed. Tags of
+// elements the policy doesn't allow are escaped, and tags of allowed elements
+// that aren't inline are left out.
+func RenderHTML(doc *Node, policy Policy) string {
+	r := htmlRenderer{policy: policy}
+	r.render(doc, false)
+	return r.sb.String()
+}
+
+// htmlRenderer holds the state of a call to RenderHTML.
+type htmlRenderer struct {
+	sb     strings.Builder
+	policy Policy
+	// open lists the inline HTML elements left open, and base the number of
+	// them opened outside the inlines being rendered.
+	open []string
+	base int
+	// inLink is set while rendering the text of a link, which can't contain
+	// another.
+	inLink bool
+}
+
+// render writes node. tight is set for the children of tight list items,
+// whose paragraphs are rendered without <p> tags.
+func (r *htmlRenderer) render(node *Node, tight bool) {
+	sb := &r.sb
+
+	switch node.Kind {
+	case Document:
+		r.renderChildren(node)
+	case Heading:
+		tag := "h" + strconv.Itoa(node.Level)
+		sb.WriteString("<" + tag + ">")
+		r.renderChildren(node)
+		sb.WriteString("</" + tag + ">\n")
+	case Paragraph:
+		if tight {
+			r.renderChildren(node)
+			return
+		}
+		sb.WriteString("<p>")
+		r.renderChildren(node)
+		sb.WriteString("</p>\n")
+	case List:
+		tag := "ul"
+		if node.Ordered {
+			tag = "ol"
+		}
+		sb.WriteString("<" + tag)
+		if node.Ordered && node.Start != 1 {
+			sb.WriteString(` start="` + strconv.Itoa(node.Start) + `"`)
+		}
+		sb.WriteString(">\n")
+		for _, item := range node.Children {
+			r.render(item, node.Tight)
+		}
+		sb.WriteString("</" + tag + ">\n")
+	case ListItem:
+		sb.WriteString("<li>")
+		// Blocks start on a new line, except for the paragraphs of tight
+		// items, which are written inline.
+		midLine := true
+		for _, child := range node.Children {
+			inline := tight && child.Kind == Paragraph
+			if midLine && !inline {
+				sb.WriteByte('\n')
+			}
+			r.render(child, tight)
+			midLine = inline
+		}
+		sb.WriteString("</li>\n")
+	case CodeBlock:
+		sb.WriteString("<pre><code")
+		if isLanguageName(node.Info) {
+			sb.WriteString(` class="language-` + node.Info + `"`)
+		}
+		sb.WriteString(">")
+		sb.WriteString(html.EscapeString(node.Literal))
+		sb.WriteString("</code></pre>\n")
+	case Blockquote:
+		sb.WriteString("<blockquote>\n")
+		r.renderChildren(node)
+		sb.WriteString("</blockquote>\n")
+	case Text:
+		sb.WriteString(html.EscapeString(node.Literal))
+	case Emphasis:
+		sb.WriteString("<em>")
+		r.renderChildren(node)
+		sb.WriteString("</em>")
+	case Strong:
+		sb.WriteString("<strong>")
+		r.renderChildren(node)
+		sb.WriteString("</strong>")
+	case Code:
+		sb.WriteString("<code>" + html.EscapeString(node.Literal) + "</code>")
+	case Link:
+		if !SafeURL(node.Destination) || r.inAnchor() {
+			r.renderChildren(node)
+			return
+		}
+		sb.WriteString(`<a href="` + html.EscapeString(node.Destination) + `"`)
+		if node.Title != "" {
+			sb.WriteString(` title="` + html.EscapeString(node.Title) + `"`)
+		}
+		if r.policy.isExternal(node.Destination) {
+			sb.WriteString(` rel="` + externalLinkRel + `"`)
+		}
+		sb.WriteString(">")
+		r.inLink = true
+		r.renderChildren(node)
+		r.inLink = false
+		sb.WriteString("</a>")
+	case SoftBreak:
+		sb.WriteByte('\n')
+	case HTML:
+		r.renderTag(node.Literal)
+	}
+}
+
+// renderChildren writes the children of node, then closes the inline HTML
+// elements opened among them.
+func (r *htmlRenderer) renderChildren(node *Node) {
+	base := r.base
+	r.base = len(r.open)
+
+	for _, child := range node.Children {
+		r.render(child, false)
+	}
+
+	r.closeTo(r.base)
+	r.base = base
+}
+
+// renderTag writes an inline HTML tag, if it is allowed and its element can
+// be opened or closed where it is.
+func (r *htmlRenderer) renderTag(raw string) {
+	tok, _ := readHTMLTag(raw)
+
+	switch {
+	case !slices.Contains(r.policy.Elements, tok.name):
+		r.sb.WriteString(html.EscapeString(raw))
+		return
+	case !slices.Contains(inlineElements, tok.name):
+		return
+	}
+
+	if tok.typ == endTagToken {
+		i := slices.Index(r.open[r.base:], tok.name)
+		if i >= 0 {
+			r.closeTo(r.base + i)
+		}
+		return
+	}
+
+	void := slices.Contains(voidElements, tok.name)
+	if tok.name == "a" && r.inAnchor() {
+		return
+	}
+
+	// A self-closing tag of an element that isn't void is read by browsers
+	// as a start tag, so it is written as one.
+	tok.typ = startTagToken
+	if void {
+		tok.typ = selfClosingTagToken
+	}
+
+	s := sanitizer{policy: r.policy}
+	s.writeStartTag(tok)
+	r.sb.Write(s.out)
+
+	if !void {
+		r.open = append(r.open, tok.name)
+	}
+}
+
+// inAnchor reports whether what is being rendered is inside a link, from
+// markup or an HTML tag.
+func (r *htmlRenderer) inAnchor() bool {
+	return r.inLink || slices.Contains(r.open, "a")
+}
+
+// closeTo closes the open inline HTML elements after the first n.
+func (r *htmlRenderer) closeTo(n int) {
+	for i := len(r.open) - 1; i >= n; i-- {
+		r.sb.WriteString("</" + r.open[i] + ">")
+	}
+	r.open = r.open[:n]
+}
+
+// SafeURL reports whether a link destination is relative or uses one of the
+// allowed URL schemes. Control characters and whitespace are ignored when
+// finding the scheme, as browsers do.
+func SafeURL(rawURL string) bool {
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, rawURL)
+
+	// A colon only starts a scheme if it comes before any path, query or
+	// fragment.
+	i := strings.IndexAny(cleaned, ":/?#")
+	if i < 0 || cleaned[i] != ':' {
+		return true
+	}
+
+	return allowedSchemes[strings.ToLower(cleaned[:i])]
+}
+
+// isLanguageName reports whether a code block's info string is safe to use as
+// a class name.
+func isLanguageName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("+-_#.", c)) {
+			return false
+		}
+	}
+	return true
+}
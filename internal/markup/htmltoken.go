@@ -0,0 +1,500 @@
+package markup
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// htmlTokenType identifies the type of an htmlToken.
+type htmlTokenType int
+
+const (
+	textToken htmlTokenType = iota
+	startTagToken
+	endTagToken
+	selfClosingTagToken
+	commentToken
+	// codeToken is Markdown code, whose content is shown as text.
+	codeToken
+)
+
+// htmlToken is a piece of an HTML document. raw holds the token's source text.
+type htmlToken struct {
+	typ   htmlTokenType
+	raw   string
+	name  string
+	attrs []htmlAttribute
+}
+
+// htmlAttribute is an attribute of a start tag. Its value is still escaped.
+type htmlAttribute struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// rawTextElements hold text that is not parsed for tags, up to their end tag.
+var rawTextElements = []string{"script", "style", "textarea", "title", "xmp", "iframe", "noembed", "noframes", "noscript"}
+
+// tokenizeHTML splits s into HTML tokens, following the way browsers find tags
+// closely enough that anything a browser would treat as markup is returned as
+// a tag or comment. A "<" that cannot start a tag is returned as text.
+func tokenizeHTML(s string) []htmlToken {
+	return tokenizeProse(s, nil)
+}
+
+// tokenizeProse splits s into HTML tokens as tokenizeHTML does, except that
+// Markdown code spans starting within one of the spans ranges are returned
+// whole as code tokens. A code span can't run past the end of its range.
+func tokenizeProse(s string, spans [][2]int) []htmlToken {
+	var tokens []htmlToken
+	textStart := 0
+	// noCloser maps the length of a backtick run found to have no closing run
+	// to the end of its range, as no later run of that length in the range
+	// can have one either.
+	noCloser := make(map[int]int)
+
+	flushText := func(end int) {
+		if end > textStart {
+			tokens = append(tokens, htmlToken{typ: textToken, raw: s[textStart:end]})
+		}
+	}
+
+	for i := 0; i < len(s); {
+		if s[i] == '`' || s[i] == '\\' {
+			if end, ok := spanEnd(spans, i); ok {
+				switch {
+				case s[i] == '\\' && i+1 < end && (s[i+1] == '`' || s[i+1] == '\\'):
+					// An escaped backtick can't open a code span.
+					i += 2
+					continue
+				case s[i] == '`':
+					run, n := backtickRun(s[i:end]), 0
+					if noCloser[run] != end {
+						n = codeSpanLength(s[i:end], run)
+					}
+					if n == 0 {
+						// A run with no closing run is text.
+						noCloser[run] = end
+						i += run
+						continue
+					}
+
+					flushText(i)
+					if code := s[i : i+n]; strings.Contains(code, "|") {
+						// Tables split code spans into cells at a "|", so the
+						// span is tokenized as HTML for renderers that do.
+						tokens = append(tokens, tokenizeHTML(code)...)
+					} else {
+						tokens = append(tokens, htmlToken{typ: codeToken, raw: code})
+					}
+					i += n
+					textStart = i
+					continue
+				}
+			}
+		}
+
+		if s[i] != '<' {
+			i++
+			continue
+		}
+
+		tok, n := readHTMLTag(s[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+
+		flushText(i)
+		tokens = append(tokens, tok)
+		i += n
+		textStart = i
+
+		// The content of raw text elements runs to the matching end tag. If
+		// there isn't one the content is tokenized as usual, so that the
+		// element's removal can't leave markup behind.
+		if tok.typ == startTagToken && slices.Contains(rawTextElements, tok.name) {
+			end := findEndTag(s[i:], tok.name)
+			if i+end == len(s) {
+				continue
+			}
+			flushText(i + end)
+			i += end
+			textStart = i
+		}
+	}
+	flushText(len(s))
+
+	return tokens
+}
+
+// tokenizeMarkdown splits Markdown source into HTML tokens as tokenizeHTML
+// does, except that fenced code blocks and code spans are returned whole as
+// code tokens, as Markdown shows their content as text.
+//
+// Code is only looked for where every Markdown renderer would find it. Lines
+// that might belong to an HTML block, which renderers that allow HTML pass
+// through as it is, are always tokenized as HTML, and so is anything a code
+// span could only reach by crossing into another block.
+func tokenizeMarkdown(src string) []htmlToken {
+	var tokens []htmlToken
+
+	// spans holds the ranges of the prose since proseStart in which code
+	// spans may be found.
+	var spans [][2]int
+	proseStart := 0
+
+	flushProse := func(end int) {
+		for i := range spans {
+			spans[i][0] -= proseStart
+			spans[i][1] -= proseStart
+		}
+		tokens = append(tokens, tokenizeProse(src[proseStart:end], spans)...)
+		spans = nil
+	}
+
+	lines := strings.SplitAfter(src, "\n")
+
+	// htmlEnd is the text that ends the HTML block being read, or "" if it
+	// ends at a blank line.
+	inHTML, htmlEnd := false, ""
+	// join reports whether a code span may continue from the previous line.
+	join := false
+	prevDepth := 0
+
+	for i, offset := 0, 0; i < len(lines); i++ {
+		start := offset
+		offset += len(lines[i])
+		line := readMarkdownLine(lines[i])
+		trimmed := strings.TrimSpace(line.content)
+
+		newRange := !join || line.item || line.depth != prevDepth
+		join = false
+		prevDepth = line.depth
+
+		switch {
+		case inHTML:
+			if htmlEnd == "" && trimmed == "" || htmlEnd != "" && strings.Contains(strings.ToLower(line.content), htmlEnd) {
+				inHTML = false
+			}
+		case trimmed == "" || isBreak(trimmed):
+		case indentation(line.content) < 4 && trimmed[0] == '<':
+			htmlEnd = htmlBlockEnd(trimmed)
+			inHTML = htmlEnd == "" || !strings.Contains(strings.ToLower(trimmed[1:]), htmlEnd)
+		case indentation(line.content) < 4 && isFence(line.content):
+			n := fencedCodeLines(lines[i:])
+			end := start
+			for _, l := range lines[i : i+n] {
+				end += len(l)
+			}
+			flushProse(start)
+			tokens = append(tokens, htmlToken{typ: codeToken, raw: src[start:end]})
+			proseStart = end
+			offset = end
+			i += n - 1
+		default:
+			_, _, heading := parseHeading(line.content)
+			if newRange || heading {
+				spans = append(spans, [2]int{start, offset})
+			} else {
+				spans[len(spans)-1][1] = offset
+			}
+			// A heading is a single line, so no code span continues from it.
+			join = !heading
+		}
+	}
+	flushProse(len(src))
+
+	return tokens
+}
+
+// markdownLine is a line of Markdown with its block quote and list markers
+// removed.
+type markdownLine struct {
+	content string
+	// depth is the number of block quote markers.
+	depth int
+	// column is the column content starts at after the block quote markers.
+	column int
+	// item reports whether the line starts a list item.
+	item bool
+}
+
+func readMarkdownLine(s string) markdownLine {
+	var line markdownLine
+	line.content, line.depth = stripQuoteMarkers(s)
+	for {
+		m, ok := parseListMarker(line.content)
+		if !ok {
+			return line
+		}
+		line.content = m.content
+		line.column += m.width
+		line.item = true
+	}
+}
+
+// stripQuoteMarkers removes the block quote markers from the start of a line,
+// returning the rest of the line and the number of markers.
+func stripQuoteMarkers(line string) (string, int) {
+	depth := 0
+	for indentation(line) < 4 {
+		rest, ok := strings.CutPrefix(strings.TrimLeft(line, " \t"), ">")
+		if !ok {
+			break
+		}
+		line = strings.TrimPrefix(rest, " ")
+		depth++
+	}
+	return line, depth
+}
+
+// isFence reports whether a line opens a fenced code block.
+func isFence(line string) bool {
+	_, _, ok := openingFence(line)
+	return ok
+}
+
+// fencedCodeLines returns the number of lines in the fenced code block opened
+// by lines[0]. The block ends after its closing fence, or before the first line
+// that isn't inside the quote or list item the block is in, so a block without
+// a closing fence may be shorter than the parser would make it, but never
+// longer.
+func fencedCodeLines(lines []string) int {
+	open := readMarkdownLine(lines[0])
+	fence, _, _ := openingFence(open.content)
+	column := open.column + indentation(open.content)
+
+	for i := 1; i < len(lines); i++ {
+		content, depth := stripQuoteMarkers(lines[i])
+		trimmed := strings.TrimSpace(content)
+		if depth != open.depth || trimmed != "" && indentation(content) < column {
+			return i
+		}
+		if indentation(content) < column+4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			return i + 1
+		}
+	}
+	return len(lines)
+}
+
+// isBreak reports whether a trimmed line is a thematic break or the underline
+// of a heading, such as "---" or "===".
+func isBreak(trimmed string) bool {
+	return strings.Trim(trimmed, "-=*_ \t") == ""
+}
+
+// htmlBlockEnd returns the text that ends an HTML block starting with the
+// trimmed line, or "" if the block ends at a blank line. Blocks of script,
+// preformatted text, comments and the like may contain blank lines.
+func htmlBlockEnd(trimmed string) string {
+	lower := strings.ToLower(trimmed)
+	for _, name := range []string{"script", "pre", "style", "textarea"} {
+		if after, ok := strings.CutPrefix(lower, "<"+name); ok && (after == "" || isHTMLSpace(after[0]) || after[0] == '>') {
+			return "</" + name + ">"
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(lower, "<!--"):
+		return "-->"
+	case strings.HasPrefix(lower, "<?"):
+		return "?>"
+	case strings.HasPrefix(lower, "<![cdata["):
+		return "]]>"
+	case strings.HasPrefix(lower, "<!"):
+		return ">"
+	}
+	return ""
+}
+
+// spanEnd returns the end of the range in spans, which are in order, that
+// holds position i.
+func spanEnd(spans [][2]int, i int) (int, bool) {
+	j, found := slices.BinarySearchFunc(spans, i, func(span [2]int, i int) int {
+		return cmp.Compare(span[0], i)
+	})
+	if !found {
+		j--
+	}
+	if j < 0 || i >= spans[j][1] {
+		return 0, false
+	}
+	return spans[j][1], true
+}
+
+// codeSpanLength returns the length of the code span opened by the run of
+// backticks of length run at the start of s, which is closed by the next run
+// of the same length, or 0 if there isn't one.
+func codeSpanLength(s string, run int) int {
+	for i := run; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		m := backtickRun(s[i:])
+		if m == run {
+			return i + m
+		}
+		i += m
+	}
+	return 0
+}
+
+// backtickRun returns the number of backticks at the start of s.
+func backtickRun(s string) int {
+	n := 0
+	for n < len(s) && s[n] == '`' {
+		n++
+	}
+	return n
+}
+
+// readHTMLTag reads the tag or comment at the start of s, returning the number
+// of bytes it takes up, or 0 if s doesn't start with one.
+func readHTMLTag(s string) (htmlToken, int) {
+	switch {
+	case strings.HasPrefix(s, "<!--"):
+		end := strings.Index(s[4:], "-->")
+		if end < 0 {
+			return htmlToken{typ: commentToken, raw: s}, len(s)
+		}
+		return htmlToken{typ: commentToken, raw: s[:4+end+3]}, 4 + end + 3
+	case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+		// Doctypes, CDATA sections and processing instructions are treated
+		// as comments, as browsers do in HTML content.
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return htmlToken{typ: commentToken, raw: s}, len(s)
+		}
+		return htmlToken{typ: commentToken, raw: s[:end+1]}, end + 1
+	case strings.HasPrefix(s, "</"):
+		if len(s) < 3 || !isASCIILetter(s[2]) {
+			return htmlToken{}, 0
+		}
+		// Like start tags, an unfinished end tag runs to the end of the input.
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			end = len(s) - 1
+		}
+		name, _ := readTagName(s[2 : end+1])
+		return htmlToken{typ: endTagToken, raw: s[:end+1], name: name}, end + 1
+	case len(s) > 1 && isASCIILetter(s[1]):
+		return readStartTag(s)
+	}
+
+	return htmlToken{}, 0
+}
+
+// readStartTag reads a start tag and its attributes.
+func readStartTag(s string) (htmlToken, int) {
+	name, i := readTagName(s[1:])
+	i++
+
+	tok := htmlToken{typ: startTagToken, name: name}
+	for i < len(s) {
+		switch c := s[i]; {
+		case isHTMLSpace(c):
+			i++
+		case c == '>':
+			tok.raw = s[:i+1]
+			return tok, i + 1
+		case c == '/':
+			i++
+			if i < len(s) && s[i] == '>' {
+				tok.typ = selfClosingTagToken
+				tok.raw = s[:i+1]
+				return tok, i + 1
+			}
+		default:
+			var attr htmlAttribute
+			attr, i = readAttribute(s, i)
+			tok.attrs = append(tok.attrs, attr)
+		}
+	}
+
+	// A tag still open at the end of the input would be completed by
+	// whatever markup follows it when served, so it is treated as a tag.
+	tok.raw = s
+	return tok, len(s)
+}
+
+// readTagName reads a tag name, which runs until whitespace, "/" or ">".
+func readTagName(s string) (string, int) {
+	i := 0
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' {
+		i++
+	}
+	return strings.ToLower(s[:i]), i
+}
+
+// readAttribute reads the attribute starting at s[i].
+func readAttribute(s string, i int) (htmlAttribute, int) {
+	var attr htmlAttribute
+
+	start := i
+	// The first character is part of the name even if it is "=".
+	i++
+	for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '/' && s[i] != '>' && s[i] != '=' {
+		i++
+	}
+	attr.name = strings.ToLower(s[start:i])
+
+	j := i
+	for j < len(s) && isHTMLSpace(s[j]) {
+		j++
+	}
+	if j >= len(s) || s[j] != '=' {
+		return attr, i
+	}
+	j++
+	for j < len(s) && isHTMLSpace(s[j]) {
+		j++
+	}
+
+	attr.hasValue = true
+	if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+		end := strings.IndexByte(s[j+1:], s[j])
+		if end < 0 {
+			attr.value = s[j+1:]
+			return attr, len(s)
+		}
+		attr.value = s[j+1 : j+1+end]
+		return attr, j + 1 + end + 1
+	}
+
+	start = j
+	for j < len(s) && !isHTMLSpace(s[j]) && s[j] != '>' {
+		j++
+	}
+	attr.value = s[start:j]
+	return attr, j
+}
+
+// findEndTag returns the position of the end tag for the named element in s,
+// or len(s) if there isn't one.
+func findEndTag(s, name string) int {
+	for i := 0; ; i += 2 {
+		j := strings.Index(s[i:], "</")
+		if j < 0 {
+			return len(s)
+		}
+		i += j
+
+		after := i + 2 + len(name)
+		if after <= len(s) && strings.EqualFold(s[i+2:after], name) &&
+			(after == len(s) || isHTMLSpace(s[after]) || s[after] == '/' || s[after] == '>') {
+			return i
+		}
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
@@ -0,0 +1,451 @@
+package markup
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// inlineParser turns the text of a paragraph or heading into inline nodes.
+type inlineParser struct {
+	src   string
+	pos   int
+	nodes []*Node
+	text  strings.Builder
+	// inLink stops links being nested inside link text.
+	inLink bool
+	// depth is the number of inline elements the text is nested in.
+	depth int
+
+	// Once a search for a closing delimiter fails, every later search for the
+	// same delimiter fails too. Remembering the failures keeps parsing linear
+	// for text full of unmatched delimiters.
+	noCloser     [2][4]bool
+	noCodeCloser map[int]bool
+	// brackets maps the position of each "[" to that of its closing "]", or
+	// -1 if it has none.
+	brackets map[int]int
+}
+
+// parseInlines parses s into inline nodes.
+func parseInlines(s string, inLink bool, depth int) []*Node {
+	p := &inlineParser{src: s, inLink: inLink, depth: depth}
+	p.parse()
+	return p.nodes
+}
+
+func (p *inlineParser) parse() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src) && isASCIIPunct(p.src[p.pos+1]):
+			p.text.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == '`' && p.codeSpan():
+		case (c == '*' || c == '_') && p.emphasis():
+		case c == '[' && !p.inLink && p.link():
+		case c == '<' && !p.inLink && p.autolink():
+		case c == '<' && p.htmlTag():
+		case c == '&' && p.entity():
+		case c == '\n':
+			p.add(&Node{Kind: SoftBreak})
+			p.pos++
+		default:
+			p.text.WriteByte(c)
+			p.pos++
+		}
+	}
+	p.flushText()
+}
+
+// add appends a node after any pending text.
+func (p *inlineParser) add(node *Node) {
+	p.flushText()
+	p.nodes = append(p.nodes, node)
+}
+
+func (p *inlineParser) flushText() {
+	if p.text.Len() > 0 {
+		p.nodes = append(p.nodes, &Node{Kind: Text, Literal: p.text.String()})
+		p.text.Reset()
+	}
+}
+
+// runLength returns the length of the run of c starting at i.
+func (p *inlineParser) runLength(i int, c byte) int {
+	n := 0
+	for i+n < len(p.src) && p.src[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// codeSpan parses a code span opened by a run of backticks and closed by a run
+// of the same length. An unmatched run is kept as text.
+func (p *inlineParser) codeSpan() bool {
+	n := p.runLength(p.pos, '`')
+	start := p.pos + n
+
+	for i := start; i < len(p.src) && !p.noCodeCloser[n]; {
+		if p.src[i] != '`' {
+			i++
+			continue
+		}
+		m := p.runLength(i, '`')
+		if m != n {
+			i += m
+			continue
+		}
+
+		content := strings.ReplaceAll(p.src[start:i], "\n", " ")
+		if len(content) > 2 && content[0] == ' ' && content[len(content)-1] == ' ' && strings.TrimSpace(content) != "" {
+			content = content[1 : len(content)-1]
+		}
+		p.add(&Node{Kind: Code, Literal: content})
+		p.pos = i + n
+		return true
+	}
+
+	if p.noCodeCloser == nil {
+		p.noCodeCloser = make(map[int]bool)
+	}
+	p.noCodeCloser[n] = true
+
+	p.text.WriteString(p.src[p.pos:start])
+	p.pos = start
+	return true
+}
+
+// emphasis parses emphasis (one delimiter), strong emphasis (two) or both
+// (three), delimited by "*" or "_". An unmatched run is kept as text.
+func (p *inlineParser) emphasis() bool {
+	c := p.src[p.pos]
+	n := p.runLength(p.pos, c)
+	start := p.pos + n
+
+	if n <= 3 && p.depth < maxNesting && p.canOpen(p.pos, n) {
+		if end, ok := p.findCloser(start, c, n); ok {
+			children := parseInlines(p.src[start:end], p.inLink, p.depth+1)
+
+			var node *Node
+			switch n {
+			case 1:
+				node = &Node{Kind: Emphasis, Children: children}
+			case 2:
+				node = &Node{Kind: Strong, Children: children}
+			case 3:
+				node = &Node{Kind: Strong, Children: []*Node{{Kind: Emphasis, Children: children}}}
+			}
+			p.add(node)
+			p.pos = end + n
+			return true
+		}
+	}
+
+	p.text.WriteString(p.src[p.pos:start])
+	p.pos = start
+	return true
+}
+
+// canOpen reports whether the delimiter run of length n at i can open
+// emphasis. It must be followed by a non-space character, and an underscore
+// may not start inside a word.
+func (p *inlineParser) canOpen(i, n int) bool {
+	next, _ := utf8.DecodeRuneInString(p.src[i+n:])
+	if i+n >= len(p.src) || unicode.IsSpace(next) {
+		return false
+	}
+
+	if p.src[i] == '_' && i > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(p.src[:i])
+		return !isWordRune(prev)
+	}
+	return true
+}
+
+// findCloser finds a run of exactly n delimiters c after start that can close
+// emphasis, skipping over code spans. It returns the start of the run.
+func (p *inlineParser) findCloser(start int, c byte, n int) (int, bool) {
+	failed := &p.noCloser[strings.IndexByte("*_", c)][n]
+	if *failed {
+		return 0, false
+	}
+
+	for i := start; i < len(p.src); {
+		switch p.src[i] {
+		case '\\':
+			i += 2
+			continue
+		case '`':
+			// Delimiters inside a code span don't count.
+			m := p.runLength(i, '`')
+			if end := strings.Index(p.src[i+m:], p.src[i:i+m]); end >= 0 {
+				i += m + end + m
+				continue
+			}
+			i += m
+			continue
+		case c:
+		default:
+			i++
+			continue
+		}
+
+		m := p.runLength(i, c)
+		if m == n && i > start && p.canClose(i, n) {
+			return i, true
+		}
+		i += m
+	}
+
+	*failed = true
+	return 0, false
+}
+
+// canClose reports whether the delimiter run of length n at i can close
+// emphasis. It must follow a non-space character, and an underscore may not
+// end inside a word.
+func (p *inlineParser) canClose(i, n int) bool {
+	prev, _ := utf8.DecodeLastRuneInString(p.src[:i])
+	if unicode.IsSpace(prev) {
+		return false
+	}
+
+	if p.src[i] == '_' && i+n < len(p.src) {
+		next, _ := utf8.DecodeRuneInString(p.src[i+n:])
+		return !isWordRune(next)
+	}
+	return true
+}
+
+// link parses an inline link: [text](destination "optional title").
+func (p *inlineParser) link() bool {
+	end := p.closingBracket(p.pos)
+	if end < 0 || end+1 >= len(p.src) || p.src[end+1] != '(' {
+		return false
+	}
+
+	destination, title, next, ok := parseLinkTarget(p.src, end+2)
+	if !ok {
+		return false
+	}
+
+	p.add(&Node{
+		Kind:        Link,
+		Destination: destination,
+		Title:       title,
+		Children:    parseInlines(p.src[p.pos+1:end], true, p.depth+1),
+	})
+	p.pos = next
+	return true
+}
+
+// closingBracket returns the position of the "]" matching the "[" at i, or -1.
+// Brackets in link text must be balanced.
+func (p *inlineParser) closingBracket(i int) int {
+	if p.brackets == nil {
+		p.brackets = make(map[int]int)
+
+		var open []int
+		for j := 0; j < len(p.src); j++ {
+			switch p.src[j] {
+			case '\\':
+				j++
+			case '[':
+				open = append(open, j)
+				p.brackets[j] = -1
+			case ']':
+				if len(open) > 0 {
+					p.brackets[open[len(open)-1]] = j
+					open = open[:len(open)-1]
+				}
+			}
+		}
+	}
+
+	return p.brackets[i]
+}
+
+// maxLinkTarget is the longest a link's destination and title may be, which
+// bounds the work done for text full of unfinished links.
+const maxLinkTarget = 4096
+
+// parseLinkTarget parses the destination and optional title of an inline link
+// starting at i, just after the opening parenthesis. It returns the position
+// after the closing parenthesis.
+func parseLinkTarget(s string, i int) (destination, title string, next int, ok bool) {
+	s = s[:min(len(s), i+maxLinkTarget)]
+
+	skipSpace := func() {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+			i++
+		}
+	}
+
+	skipSpace()
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexAny(s[i+1:], ">\n")
+		if end < 0 || s[i+1+end] != '>' {
+			return "", "", 0, false
+		}
+		destination = s[i+1 : i+1+end]
+		i += end + 2
+	} else {
+		start := i
+		depth := 0
+		for i < len(s) && s[i] != ' ' && s[i] != '\n' && !(s[i] == ')' && depth == 0) {
+			switch s[i] {
+			case '\\':
+				i++
+			case '(':
+				// Like CommonMark, allow at most 32 levels of nested
+				// parentheses in a destination.
+				depth++
+				if depth > 32 {
+					return "", "", 0, false
+				}
+			case ')':
+				depth--
+			}
+			i++
+		}
+		if i > len(s) {
+			return "", "", 0, false
+		}
+		destination = unescape(s[start:i])
+	}
+
+	skipSpace()
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+		quote := s[i]
+		end := strings.IndexByte(s[i+1:], quote)
+		if end < 0 {
+			return "", "", 0, false
+		}
+		title = unescape(s[i+1 : i+1+end])
+		i += end + 2
+		skipSpace()
+	}
+
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return destination, title, i + 1, true
+}
+
+// autolink parses a URL or email address in angle brackets, such as
+// <https://example.com>.
+func (p *inlineParser) autolink() bool {
+	end := strings.IndexAny(p.src[p.pos+1:], "<> \n")
+	if end < 0 || p.src[p.pos+1+end] != '>' {
+		return false
+	}
+	target := p.src[p.pos+1 : p.pos+1+end]
+
+	destination := target
+	switch {
+	case isAbsoluteURL(target):
+	case isEmailAddress(target):
+		destination = "mailto:" + target
+	default:
+		return false
+	}
+
+	p.add(&Node{
+		Kind:        Link,
+		Destination: destination,
+		Children:    []*Node{{Kind: Text, Literal: target}},
+	})
+	p.pos += end + 2
+	return true
+}
+
+// htmlTag parses an inline HTML tag of an element HTML has, leaving it to the
+// renderer to decide whether to keep it.
+func (p *inlineParser) htmlTag() bool {
+	tok, n := readHTMLTag(p.src[p.pos:])
+	if n == 0 || tok.typ == commentToken || !strings.HasSuffix(tok.raw, ">") || !slices.Contains(knownElements, tok.name) {
+		return false
+	}
+
+	p.add(&Node{Kind: HTML, Literal: tok.raw})
+	p.pos += n
+	return true
+}
+
+// entity decodes an HTML entity or numeric character reference, such as the
+// "&lt;" Sanitize escapes tags with, into the text it stands for.
+func (p *inlineParser) entity() bool {
+	end := strings.IndexByte(p.src[p.pos:], ';')
+	if end < 2 || end > 32 {
+		return false
+	}
+
+	ref := p.src[p.pos : p.pos+end+1]
+	for i := 1; i < end; i++ {
+		c := ref[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '#' && i == 1) {
+			return false
+		}
+	}
+
+	decoded := html.UnescapeString(ref)
+	if decoded == ref {
+		return false
+	}
+
+	p.text.WriteString(decoded)
+	p.pos += end + 1
+	return true
+}
+
+// isAbsoluteURL reports whether s starts with a URL scheme followed by a colon.
+func isAbsoluteURL(s string) bool {
+	scheme, _, found := strings.Cut(s, ":")
+	if !found || len(scheme) < 2 || len(scheme) > 32 {
+		return false
+	}
+	for i := 0; i < len(scheme); i++ {
+		c := scheme[i]
+		letter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || !(c >= '0' && c <= '9' || c == '+' || c == '.' || c == '-')) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmailAddress reports whether s looks like an email address.
+func isEmailAddress(s string) bool {
+	local, domain, found := strings.Cut(s, "@")
+	return found && local != "" && strings.Contains(domain, ".") &&
+		!strings.ContainsAny(s[len(local)+1:], "@") &&
+		!strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// unescape removes backslashes that escape ASCII punctuation.
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
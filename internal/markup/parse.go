@@ -0,0 +1,368 @@
+package markup
+
+import (
+	"strings"
+)
+
+// maxNesting limits how deeply block quotes, lists and inline elements may be
+// nested. Deeper markup is kept as text, which bounds the recursion of the
+// parser.
+const maxNesting = 32
+
+// Parse parses src into a Document node. Parsing never fails: input that is
+// not valid markup is kept as text.
+func Parse(src string) *Node {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+
+	return &Node{
+		Kind:     Document,
+		Children: parseBlocks(strings.Split(src, "\n"), 0),
+	}
+}
+
+// parseBlocks parses lines into a sequence of block nodes. depth is the number
+// of containers the lines are nested in.
+func parseBlocks(lines []string, depth int) []*Node {
+	var blocks []*Node
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, &Node{
+				Kind:     Paragraph,
+				Children: parseInlines(strings.Join(paragraph, "\n"), false, 0),
+			})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if isBlank(line) {
+			flush()
+			i++
+			continue
+		}
+
+		if node, n := parseBlockStart(lines[i:], depth); node != nil {
+			flush()
+			blocks = append(blocks, node)
+			i += n
+			continue
+		}
+
+		paragraph = append(paragraph, strings.TrimSpace(line))
+		i++
+	}
+	flush()
+
+	return blocks
+}
+
+// parseBlockStart parses the block starting at lines[0] if it is anything
+// other than a paragraph, returning the node and the number of lines used.
+func parseBlockStart(lines []string, depth int) (*Node, int) {
+	line := lines[0]
+	if indentation(line) >= 4 {
+		return nil, 0
+	}
+
+	if level, content, ok := parseHeading(line); ok {
+		return &Node{Kind: Heading, Level: level, Children: parseInlines(content, false, 0)}, 1
+	}
+	if _, _, ok := openingFence(line); ok {
+		return parseCodeBlock(lines)
+	}
+	if depth >= maxNesting {
+		return nil, 0
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), ">") {
+		return parseBlockquote(lines, depth)
+	}
+	if _, ok := parseListMarker(line); ok {
+		return parseList(lines, depth)
+	}
+
+	return nil, 0
+}
+
+// startsBlock reports whether line starts a block other than a paragraph,
+// and so ends any paragraph it follows.
+func startsBlock(line string) bool {
+	if indentation(line) >= 4 {
+		return false
+	}
+
+	_, _, heading := parseHeading(line)
+	_, _, fence := openingFence(line)
+	_, listItem := parseListMarker(line)
+	return heading || fence || listItem || strings.HasPrefix(strings.TrimSpace(line), ">")
+}
+
+// parseHeading parses an ATX heading such as "## Title ##", returning its level
+// and content.
+func parseHeading(line string) (int, string, bool) {
+	trimmed := strings.TrimSpace(line)
+
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+
+	content := trimmed[level:]
+	if content != "" && content[0] != ' ' && content[0] != '\t' {
+		return 0, "", false
+	}
+	content = strings.TrimSpace(content)
+
+	// Remove an optional closing sequence of #s.
+	if stripped := strings.TrimRight(content, "#"); stripped == "" {
+		content = ""
+	} else if strings.HasSuffix(stripped, " ") || strings.HasSuffix(stripped, "\t") {
+		content = strings.TrimSpace(stripped)
+	}
+
+	return level, content, true
+}
+
+// openingFence reports whether line opens a fenced code block, returning the
+// fence and the info string after it.
+func openingFence(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", "", false
+	}
+
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if n < 3 {
+		return "", "", false
+	}
+
+	info := strings.TrimSpace(trimmed[n:])
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+
+	return trimmed[:n], info, true
+}
+
+// parseCodeBlock parses a fenced code block. A block without a closing fence
+// runs to the end of the input.
+func parseCodeBlock(lines []string) (*Node, int) {
+	fence, info, _ := openingFence(lines[0])
+	indent := indentation(lines[0])
+
+	// Only the first word of the info string names the language.
+	if fields := strings.Fields(info); len(fields) > 0 {
+		info = fields[0]
+	}
+
+	var content []string
+	i := 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if indentation(lines[i]) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		content = append(content, dedent(lines[i], indent))
+	}
+
+	literal := strings.Join(content, "\n")
+	if len(content) > 0 {
+		literal += "\n"
+	}
+
+	return &Node{Kind: CodeBlock, Info: info, Literal: literal}, i
+}
+
+// parseBlockquote parses a block quote. Lines of a quoted paragraph may omit
+// the ">" marker.
+func parseBlockquote(lines []string, depth int) (*Node, int) {
+	var content []string
+
+	i := 0
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+
+		if indentation(lines[i]) < 4 && strings.HasPrefix(trimmed, ">") {
+			trimmed = strings.TrimPrefix(trimmed, ">")
+			trimmed = strings.TrimPrefix(trimmed, " ")
+			content = append(content, trimmed)
+			continue
+		}
+
+		lazy := trimmed != "" && len(content) > 0 && !isBlank(content[len(content)-1]) && !startsBlock(lines[i])
+		if !lazy {
+			break
+		}
+		content = append(content, trimmed)
+	}
+
+	return &Node{Kind: Blockquote, Children: parseBlocks(content, depth+1)}, i
+}
+
+// listMarker describes the marker that starts a list item.
+type listMarker struct {
+	ordered bool
+	start   int
+	// delimiter is the bullet character, or the "." or ")" after the number
+	// of an ordered item. Items with a different delimiter start a new list.
+	delimiter byte
+	// width is the column the item's content starts at. Lines indented at
+	// least this far continue the item.
+	width int
+	// content is the rest of the line after the marker.
+	content string
+}
+
+// parseListMarker parses a bullet ("-", "*", "+") or ordered ("1.", "1)")
+// list marker at the start of line.
+func parseListMarker(line string) (listMarker, bool) {
+	indent := indentation(line)
+	if indent >= 4 {
+		return listMarker{}, false
+	}
+	rest := strings.TrimLeft(line, " \t")
+
+	var m listMarker
+	n := 0
+	switch {
+	case rest != "" && strings.IndexByte("-*+", rest[0]) >= 0:
+		m.delimiter = rest[0]
+		n = 1
+	default:
+		for n < len(rest) && n < 9 && rest[n] >= '0' && rest[n] <= '9' {
+			m.start = m.start*10 + int(rest[n]-'0')
+			n++
+		}
+		if n == 0 || n >= len(rest) || (rest[n] != '.' && rest[n] != ')') {
+			return listMarker{}, false
+		}
+		m.ordered = true
+		m.delimiter = rest[n]
+		n++
+	}
+
+	// The marker must be followed by a space, or end the line.
+	if n < len(rest) && rest[n] != ' ' && rest[n] != '\t' {
+		return listMarker{}, false
+	}
+
+	// Content indented by more than four spaces is indented code in
+	// Markdown, which isn't supported, so only the first space is dropped.
+	spaces := len(rest[n:]) - len(strings.TrimLeft(rest[n:], " \t"))
+	if spaces == 0 || spaces > 4 || n+spaces == len(rest) {
+		spaces = min(1, len(rest)-n)
+	}
+	m.width = indent + n + max(spaces, 1)
+	m.content = rest[n+spaces:]
+
+	return m, true
+}
+
+// parseList parses consecutive list items with the same kind of marker.
+func parseList(lines []string, depth int) (*Node, int) {
+	first, _ := parseListMarker(lines[0])
+	list := &Node{Kind: List, Ordered: first.ordered, Start: first.start, Tight: true}
+
+	i := 0
+	for i < len(lines) {
+		m, ok := parseListMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.delimiter != first.delimiter {
+			break
+		}
+
+		item := []string{m.content}
+		i++
+	continuation:
+		for i < len(lines) {
+			line := lines[i]
+			previousBlank := isBlank(item[len(item)-1])
+
+			switch {
+			case isBlank(line):
+				item = append(item, "")
+			case indentation(line) >= m.width:
+				item = append(item, dedent(line, m.width))
+			case !previousBlank && !startsBlock(line):
+				// A lazy continuation of the item's paragraph.
+				item = append(item, strings.TrimSpace(line))
+			default:
+				break continuation
+			}
+			i++
+		}
+
+		// Blank lines at the end of an item separate it from the next one.
+		trailing := 0
+		for len(item) > 1 && isBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+			trailing++
+		}
+		if trailing > 0 && i < len(lines) {
+			if next, ok := parseListMarker(lines[i]); ok && next.ordered == first.ordered && next.delimiter == first.delimiter {
+				list.Tight = false
+			}
+		}
+		for _, line := range item {
+			if isBlank(line) {
+				list.Tight = false
+			}
+		}
+
+		list.Children = append(list.Children, &Node{Kind: ListItem, Children: parseBlocks(item, depth+1)})
+	}
+
+	// Leave the blank lines after the last item to the enclosing block.
+	for i > 0 && isBlank(lines[i-1]) {
+		i--
+	}
+
+	return list, i
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// indentation returns the width of the leading whitespace of line, counting
+// tabs as four columns.
+func indentation(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// dedent removes up to width columns of leading whitespace from line.
+func dedent(line string, width int) string {
+	removed := 0
+	for i, c := range line {
+		if removed >= width || (c != ' ' && c != '\t') {
+			return line[i:]
+		}
+		if c == '\t' {
+			removed += 4
+		} else {
+			removed++
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{name: "Empty", src: "", expected: ""},
+		{name: "Heading And Paragraph", src: "# Title\n\nSome *emphasis* and **strong** text.", expected: "<h1>Title</h1>\n<p>Some <em>emphasis</em> and <strong>strong</strong> text.</p>\n"},
+		{name: "Heading Levels", src: "###### Six\n####### Seven", expected: "<h6>Six</h6>\n<p>####### Seven</p>\n"},
+		{name: "Heading Without Space", src: "#NoSpace", expected: "<p>#NoSpace</p>\n"},
+		{name: "Soft Break", src: "Line one\nline two", expected: "<p>Line one\nline two</p>\n"},
+		{name: "Line Endings", src: "a\r\nb\rc", expected: "<p>a\nb\nc</p>\n"},
+		{name: "Tight List", src: "- one\n- two", expected: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n"},
+		{name: "Loose List", src: "- one\n\n- two", expected: "<ul>\n<li>\n<p>one</p>\n</li>\n<li>\n<p>two</p>\n</li>\n</ul>\n"},
+		{name: "Ordered List Start", src: "3. three\n4. four", expected: "<ol start=\"3\">\n<li>three</li>\n<li>four</li>\n</ol>\n"},
+		{name: "Blockquote", src: "> quoted\n> text", expected: "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n"},
+		{name: "Fenced Code", src: "```go\nfmt.Println(\"<b>\")\n```", expected: "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;b&gt;&#34;)\n</code></pre>\n"},
+		{name: "Unclosed Fence", src: "```\nunclosed", expected: "<pre><code>unclosed\n</code></pre>\n"},
+		{name: "Info String Words", src: "```js onclick\ncode\n```", expected: "<pre><code class=\"language-js\">code\n</code></pre>\n"},
+		{name: "Code Span", src: "Use `a < b` here", expected: "<p>Use <code>a &lt; b</code> here</p>\n"},
+		{name: "Link With Title", src: "[link](https://example.com \"Title\")", expected: "<p><a href=\"https://example.com\" title=\"Title\">link</a></p>\n"},
+		{name: "Unsafe Link", src: "[bad](javascript:alert(1))", expected: "<p>bad</p>\n"},
+		{name: "Unbalanced Link", src: "[unbalanced](link", expected: "<p>[unbalanced](link</p>\n"},
+		{name: "Autolink", src: "<https://example.com>", expected: "<p><a href=\"https://example.com\">https://example.com</a></p>\n"},
+		{name: "Raw HTML Escaped", src: "<b>raw</b> & stuff", expected: "<p>&lt;b&gt;raw&lt;/b&gt; &amp; stuff</p>\n"},
+		{name: "Unclosed Emphasis", src: "*unclosed emphasis", expected: "<p>*unclosed emphasis</p>\n"},
+		{name: "Nested Emphasis", src: "**nested *em* inside**", expected: "<p><strong>nested <em>em</em> inside</strong></p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RenderHTML(Parse(tt.src), Policy{InternalHosts: []string{"example.com"}}))
+		})
+	}
+}
+
+func TestRenderHTMLPolicy(t *testing.T) {
+	policy := Policy{Elements: DefaultElements, InternalHosts: []string{"example.com"}}
+
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{name: "External Link", src: "[x](https://other.org/a)", expected: "<p><a href=\"https://other.org/a\" rel=\"noopener nofollow\">x</a></p>\n"},
+		{name: "Internal Link", src: "[x](https://example.com/a) [y](/b)", expected: "<p><a href=\"https://example.com/a\">x</a> <a href=\"/b\">y</a></p>\n"},
+		{name: "Inline HTML", src: "Some <b>bold</b> and <a href=\"https://other.org\" rel=\"noopener nofollow\">linked</a> text", expected: "<p>Some <b>bold</b> and <a href=\"https://other.org\" rel=\"noopener nofollow\">linked</a> text</p>\n"},
+		{name: "Attributes Cleaned", src: "<a href=\"https://other.org\" onclick=\"x()\">link</a>", expected: "<p><a href=\"https://other.org\" rel=\"noopener nofollow\">link</a></p>\n"},
+		{name: "Void Element", src: "one<br>two", expected: "<p>one<br/>two</p>\n"},
+		{name: "Unclosed Element", src: "<b>bold\n\nplain", expected: "<p><b>bold</b></p>\n<p>plain</p>\n"},
+		{name: "Overlapping Markup", src: "*a <i>b* c</i>", expected: "<p><em>a <i>b</i></em> c</p>\n"},
+		{name: "Stray End Tag", src: "a</b> b", expected: "<p>a b</p>\n"},
+		{name: "Nested Links", src: "<a href=\"/a\">[x](/b) <a href=\"/c\">y</a></a>", expected: "<p><a href=\"/a\">x y</a></p>\n"},
+		{name: "Block Element", src: "<p>para</p>", expected: "<p>para</p>\n"},
+		{name: "Disallowed Element", src: "<span>x</span>", expected: "<p>&lt;span&gt;x&lt;/span&gt;</p>\n"},
+		{name: "Entities", src: "x&lt;y> &amp; &#65; &nope; & more", expected: "<p>x&lt;y&gt; &amp; A &amp;nope; &amp; more</p>\n"},
+		{name: "Code Kept", src: "`<b>&lt;`", expected: "<p><code>&lt;b&gt;&amp;lt;</code></p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RenderHTML(Parse(tt.src), policy))
+		})
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{name: "Empty", src: "", expected: ""},
+		{name: "Blocks", src: "# Title\n\nSome *emphasis* and **strong** text.", expected: "Title\n\nSome emphasis and strong text."},
+		{name: "Soft Break", src: "Line one\nline two", expected: "Line one line two"},
+		{name: "List Items", src: "- one\n\n- two", expected: "one\ntwo"},
+		{name: "Code Kept", src: "```go\nfmt.Println(\"<b>\")\n```", expected: "fmt.Println(\"<b>\")"},
+		{name: "Link Text", src: "[link](https://example.com) and <https://example.org>", expected: "link and https://example.org"},
+		{name: "HTML Tags Removed", src: "<b>raw</b> &amp; stuff", expected: "raw & stuff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RenderText(Parse(tt.src)))
+		})
+	}
+}
+
+func TestParseNesting(t *testing.T) {
+	// Markup nested too deeply is kept as text rather than parsed further.
+	tests := []struct {
+		name     string
+		src      string
+		tag      string
+		expected int
+		text     string
+	}{
+		{name: "Blockquotes", src: strings.Repeat(">", 100) + " deep", tag: "<blockquote>", expected: maxNesting, text: "&gt; deep"},
+		{name: "Lists", src: strings.Repeat("- ", 100) + "deep", tag: "<ul>", expected: maxNesting, text: "- deep"},
+		{name: "Emphasis", src: strings.Repeat("*", 50) + "x" + strings.Repeat("*", 50), tag: "<em>", expected: 0, text: "*x*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := RenderHTML(Parse(tt.src), Policy{})
+			assert.Equal(t, tt.expected, strings.Count(html, tt.tag))
+			assert.Contains(t, html, tt.text)
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "/path", expected: true},
+		{url: "https://example.com", expected: true},
+		{url: "HTTPS://example.com", expected: true},
+		{url: " mailto:someone@example.com", expected: true},
+		{url: "a/b:c", expected: true},
+		{url: "?q=a:b", expected: true},
+		{url: "#fragment:x", expected: true},
+		{url: "javascript:alert(1)", expected: false},
+		{url: "java\tscript:alert(1)", expected: false},
+		{url: "data:text/html,x", expected: false},
+		{url: "ftp://example.com", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SafeURL(tt.url))
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package markup
+
+import (
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultElements are the HTML elements allowed in article bodies unless a
+// policy says otherwise.
+var DefaultElements = []string{
+	"a", "abbr", "b", "blockquote", "br", "code", "em", "h1", "h2", "h3", "h4",
+	"h5", "h6", "hr", "i", "li", "ol", "p", "pre", "s", "strong", "sub", "sup",
+	"u", "ul",
+}
+
+// forbiddenElements can never be allowed, because they run code, load other
+// content or change how the rest of a page is parsed.
+var forbiddenElements = []string{
+	"applet", "base", "button", "embed", "form", "frame", "frameset", "iframe",
+	"input", "link", "math", "meta", "noembed", "noframes", "noscript", "object",
+	"plaintext", "script", "select", "style", "svg", "template", "textarea",
+	"title", "xmp",
+}
+
+// dropContentElements have their content removed along with them, since it
+// isn't meant to be shown as text.
+var dropContentElements = append([]string{"applet", "math", "object", "svg", "template"}, rawTextElements...)
+
+// knownElements are the elements of HTML, including obsolete ones browsers
+// still parse. A tag with any other name is taken to be text that looks like
+// a tag, such as "x<y>z", and is escaped rather than removed.
+var knownElements = []string{
+	"a", "abbr", "acronym", "address", "applet", "area", "article", "aside",
+	"audio", "b", "base", "basefont", "bdi", "bdo", "bgsound", "big", "blink",
+	"blockquote", "body", "br", "button", "canvas", "caption", "center", "cite",
+	"code", "col", "colgroup", "data", "datalist", "dd", "del", "details", "dfn",
+	"dialog", "dir", "div", "dl", "dt", "em", "embed", "fieldset", "figcaption",
+	"figure", "font", "footer", "form", "frame", "frameset", "h1", "h2", "h3",
+	"h4", "h5", "h6", "head", "header", "hgroup", "hr", "html", "i", "iframe",
+	"image", "img", "input", "ins", "isindex", "kbd", "keygen", "label",
+	"legend", "li", "link", "listing", "main", "map", "mark", "marquee", "math",
+	"menu", "menuitem", "meta", "meter", "multicol", "nav", "nextid", "nobr",
+	"noembed", "noframes", "noscript", "object", "ol", "optgroup", "option",
+	"output", "p", "param", "picture", "plaintext", "portal", "pre", "progress",
+	"q", "rb", "rp", "rt", "rtc", "ruby", "s", "samp", "script", "search",
+	"section", "select", "slot", "small", "source", "spacer", "span", "strike",
+	"strong", "style", "sub", "summary", "sup", "svg", "table", "tbody", "td",
+	"template", "textarea", "tfoot", "th", "thead", "time", "title", "tr",
+	"track", "tt", "u", "ul", "var", "video", "wbr", "xmp",
+}
+
+// allowedAttributes lists the attributes each allowed element may keep. Every
+// other attribute, including event handlers and styles, is removed.
+var allowedAttributes = map[string][]string{
+	"a":    {"href", "title", "rel"},
+	"abbr": {"title"},
+	"img":  {"src", "alt", "title", "width", "height"},
+	"ol":   {"start"},
+	"td":   {"colspan", "rowspan"},
+	"th":   {"colspan", "rowspan"},
+}
+
+// urlAttributes hold URLs, which must be relative or use an allowed scheme.
+var urlAttributes = []string{"href", "src"}
+
+// externalLinkRel is the rel attribute given to links to other sites.
+const externalLinkRel = "noopener nofollow"
+
+// Policy decides which HTML is allowed in an article body.
+type Policy struct {
+	// Elements lists the allowed elements.
+	Elements []string
+	// InternalHosts lists the hosts of the site itself. Links to any other
+	// host are external.
+	InternalHosts []string
+}
+
+// CanAllow reports whether a policy may allow the named element. It must be a
+// valid lowercase element name and not one of the forbidden elements.
+func CanAllow(name string) bool {
+	if name == "" || slices.Contains(forbiddenElements, name) {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Actions recorded by Sanitize.
+const (
+	RemovedElement   = "removed_element"
+	RemovedAttribute = "removed_attribute"
+	RemovedComment   = "removed_comment"
+	EscapedTag       = "escaped_tag"
+	SetLinkRel       = "set_link_rel"
+)
+
+// Change describes something Sanitize altered, and how many times.
+type Change struct {
+	Action    string `json:"action"`
+	Element   string `json:"element,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+	Count     int    `json:"count"`
+}
+
+// Removal reports whether the change removed content, as opposed to adjusting
+// an allowed element or escaping text.
+func (c Change) Removal() bool {
+	return c.Action != SetLinkRel && c.Action != EscapedTag
+}
+
+// Sanitize removes the HTML that policy doesn't allow from src, returning the
+// result and a list of the changes made in order of first occurrence.
+//
+// Disallowed elements are removed, keeping their content unless it is
+// script, style or similar and the element is closed. Unfinished tags and
+// tags of elements HTML doesn't have are escaped, keeping them as text.
+// Disallowed attributes, attributes holding unsafe URLs, and comments are
+// removed too. Links to external hosts are given rel="noopener nofollow".
+// Everything else, including text, entities and Markdown code, is left
+// exactly as it was.
+func Sanitize(src string, policy Policy) (string, []Change) {
+	s := sanitizer{policy: policy}
+
+	// skip is the element whose content is being dropped, and depth the
+	// nesting depth of that element.
+	var skip string
+	var depth int
+
+	tokens := tokenizeMarkdown(src)
+
+	// closed records which start tags have a matching end tag. Without one,
+	// the content of a removed element is kept, as there is no telling where
+	// the element was meant to end.
+	closed := make(map[int]bool)
+	open := make(map[string][]int)
+	for i, tok := range tokens {
+		switch stack := open[tok.name]; {
+		case tok.typ == startTagToken:
+			open[tok.name] = append(stack, i)
+		case tok.typ == endTagToken && len(stack) > 0:
+			closed[stack[len(stack)-1]] = true
+			open[tok.name] = stack[:len(stack)-1]
+		}
+	}
+
+	for i, tok := range tokens {
+		if skip != "" {
+			switch {
+			case tok.typ == startTagToken && tok.name == skip:
+				depth++
+			case tok.typ == endTagToken && tok.name == skip:
+				depth--
+				if depth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+
+		switch tok.typ {
+		case textToken:
+			s.writeText(tok.raw)
+		case codeToken:
+			s.write(tok.raw)
+		case commentToken:
+			s.record(RemovedComment, "", "")
+		case startTagToken, selfClosingTagToken:
+			switch {
+			case isAutolink(tok):
+				s.write(tok.raw)
+			case !slices.Contains(knownElements, tok.name) || !strings.HasSuffix(tok.raw, ">"):
+				s.writeEscapedTag(tok)
+			case slices.Contains(policy.Elements, tok.name):
+				s.writeStartTag(tok)
+			default:
+				s.record(RemovedElement, tok.name, "")
+				if tok.typ == startTagToken && slices.Contains(dropContentElements, tok.name) && closed[i] {
+					skip = tok.name
+					depth = 1
+				}
+			}
+		case endTagToken:
+			switch {
+			case !slices.Contains(knownElements, tok.name) || !strings.HasSuffix(tok.raw, ">"):
+				s.writeEscapedTag(tok)
+			case slices.Contains(policy.Elements, tok.name):
+				s.write("</" + tok.name + ">")
+			case !s.removed(tok.name):
+				// A stray end tag of an element that was never opened.
+				s.record(RemovedElement, tok.name, "")
+			}
+		}
+	}
+
+	return string(s.out), s.changes
+}
+
+// sanitizer holds the state of a call to Sanitize.
+type sanitizer struct {
+	policy  Policy
+	out     []byte
+	changes []Change
+}
+
+func (s *sanitizer) write(str string) {
+	s.out = append(s.out, str...)
+}
+
+// writeText writes a text token. Removing markup can bring a "<" or "</" at
+// the end of one piece of text next to text that completes a tag, as in
+// "<<script>script>", so such a "<" is escaped first.
+func (s *sanitizer) writeText(text string) {
+	if text[0] == '/' || text[0] == '!' || text[0] == '?' || isASCIILetter(text[0]) {
+		i := len(s.out) - 1
+		if i > 0 && s.out[i] == '/' {
+			i--
+		}
+		if i >= 0 && s.out[i] == '<' {
+			s.out = append(s.out[:i], append([]byte("&lt;"), s.out[i+1:]...)...)
+		}
+	}
+	s.write(text)
+}
+
+// writeEscapedTag writes a tag as text, escaping every "<" in it, since an
+// unfinished tag may have swallowed others.
+func (s *sanitizer) writeEscapedTag(tok htmlToken) {
+	s.record(EscapedTag, tok.name, "")
+	s.write(strings.ReplaceAll(tok.raw, "<", "&lt;"))
+}
+
+// record counts a change.
+func (s *sanitizer) record(action, element, attribute string) {
+	for i := range s.changes {
+		c := &s.changes[i]
+		if c.Action == action && c.Element == element && c.Attribute == attribute {
+			c.Count++
+			return
+		}
+	}
+	s.changes = append(s.changes, Change{Action: action, Element: element, Attribute: attribute, Count: 1})
+}
+
+// removed reports whether an element has already been removed.
+func (s *sanitizer) removed(element string) bool {
+	return slices.ContainsFunc(s.changes, func(c Change) bool {
+		return c.Action == RemovedElement && c.Element == element
+	})
+}
+
+// writeStartTag writes an allowed start tag with only its allowed attributes.
+func (s *sanitizer) writeStartTag(tok htmlToken) {
+	s.write("<" + tok.name)
+
+	var seen []string
+	var rel string
+	hasRel, external := false, false
+
+	for _, attr := range tok.attrs {
+		value := html.UnescapeString(attr.value)
+
+		switch {
+		case slices.Contains(seen, attr.name):
+			// Browsers ignore repeated attributes.
+			s.record(RemovedAttribute, tok.name, attr.name)
+			continue
+		case !slices.Contains(allowedAttributes[tok.name], attr.name):
+			s.record(RemovedAttribute, tok.name, attr.name)
+			continue
+		case slices.Contains(urlAttributes, attr.name) && !SafeURL(value):
+			s.record(RemovedAttribute, tok.name, attr.name)
+			continue
+		}
+		seen = append(seen, attr.name)
+
+		switch {
+		case tok.name == "a" && attr.name == "rel":
+			// Written last, once it is known whether the link is external.
+			rel, hasRel = value, true
+			continue
+		case tok.name == "a" && attr.name == "href":
+			external = s.policy.isExternal(value)
+		}
+
+		s.write(" " + attr.name)
+		if attr.hasValue {
+			s.write(`="` + html.EscapeString(value) + `"`)
+		}
+	}
+
+	if external && rel != externalLinkRel {
+		s.record(SetLinkRel, tok.name, "rel")
+		rel, hasRel = externalLinkRel, true
+	}
+	if hasRel {
+		s.write(` rel="` + html.EscapeString(rel) + `"`)
+	}
+
+	if tok.typ == selfClosingTagToken {
+		s.write("/")
+	}
+	s.write(">")
+}
+
+// isExternal reports whether a link points to a host other than the site's.
+func (p Policy) isExternal(href string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return true
+	}
+	return u.Host != "" && !slices.Contains(p.InternalHosts, strings.ToLower(u.Hostname()))
+}
+
+// isAutolink reports whether a tag is really a Markdown autolink such as
+// <https://example.com> or <me@example.com>. Browsers read these as elements
+// with odd names, which are harmless as long as they carry no event handlers,
+// styles or URLs, so they are left alone.
+func isAutolink(tok htmlToken) bool {
+	if tok.typ != startTagToken || !strings.HasSuffix(tok.raw, ">") || !strings.ContainsAny(tok.name, ":@") {
+		return false
+	}
+
+	for _, attr := range tok.attrs {
+		if strings.HasPrefix(attr.name, "on") || attr.name == "style" || slices.Contains(urlAttributes, attr.name) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,235 @@
+package markup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	policy := Policy{Elements: DefaultElements, InternalHosts: []string{"example.com"}}
+
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+		changes  []Change
+	}{
+		{
+			name:     "Allowed",
+			src:      "Chips <b>are</b> <em>fine</em> &amp; <https://example.org>.",
+			expected: "Chips <b>are</b> <em>fine</em> &amp; <https://example.org>.",
+		},
+		{
+			name:     "Script",
+			src:      "Run <script>alert(1)</script> now.",
+			expected: "Run  now.",
+			changes:  []Change{{Action: RemovedElement, Element: "script", Count: 1}},
+		},
+		{
+			name:     "UnclosedScript",
+			src:      "Run <script>alert(1) and <b onclick=\"x()\">then</b> more.",
+			expected: "Run alert(1) and <b>then</b> more.",
+			changes: []Change{
+				{Action: RemovedElement, Element: "script", Count: 1},
+				{Action: RemovedAttribute, Element: "b", Attribute: "onclick", Count: 1},
+			},
+		},
+		{
+			name:     "UnclosedNestedSVG",
+			src:      "<svg><svg>drawing</svg> caption",
+			expected: " caption",
+			changes:  []Change{{Action: RemovedElement, Element: "svg", Count: 2}},
+		},
+		{
+			name:     "Attributes",
+			src:      `<a href="javascript:alert(1)" onclick="x()">this</a> <a href="https://other.org/">that</a> <a href="/here">here</a>`,
+			expected: `<a>this</a> <a href="https://other.org/" rel="noopener nofollow">that</a> <a href="/here">here</a>`,
+			changes: []Change{
+				{Action: RemovedAttribute, Element: "a", Attribute: "href", Count: 1},
+				{Action: RemovedAttribute, Element: "a", Attribute: "onclick", Count: 1},
+				{Action: SetLinkRel, Element: "a", Attribute: "rel", Count: 1},
+			},
+		},
+		{
+			name:     "Comment",
+			src:      "a<!-- hidden -->b",
+			expected: "ab",
+			changes:  []Change{{Action: RemovedComment, Count: 1}},
+		},
+		{
+			name:     "UnknownTag",
+			src:      "x<y>z and List<String>",
+			expected: "x&lt;y>z and List&lt;String>",
+			changes: []Change{
+				{Action: EscapedTag, Element: "y", Count: 1},
+				{Action: EscapedTag, Element: "string", Count: 1},
+			},
+		},
+		{
+			name:     "UnfinishedUnknownTag",
+			src:      "x<y then <script>alert(1)</script>",
+			expected: "x&lt;y then &lt;script>alert(1)",
+			changes: []Change{
+				{Action: EscapedTag, Element: "y", Count: 1},
+				{Action: RemovedElement, Element: "script", Count: 1},
+			},
+		},
+		{
+			name:     "UnfinishedTag",
+			src:      "Chips <b>are</b> tasty, as a<b shows",
+			expected: "Chips <b>are</b> tasty, as a&lt;b shows",
+			changes:  []Change{{Action: EscapedTag, Element: "b", Count: 1}},
+		},
+		{
+			name:     "RejoinedTag",
+			src:      "<<script>script>alert(1)<</script>/script>",
+			expected: "&lt;/script>",
+			changes:  []Change{{Action: RemovedElement, Element: "script", Count: 1}},
+		},
+		{
+			name:     "CodeSpan",
+			src:      "Use `<script>` tags carefully. More text here.",
+			expected: "Use `<script>` tags carefully. More text here.",
+		},
+		{
+			name:     "CodeSpanDoubleBackticks",
+			src:      "Use `` `<div>` `` and <span>more</span>.",
+			expected: "Use `` `<div>` `` and more.",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "UnmatchedBacktick",
+			src:      "A ` then <span>text</span>",
+			expected: "A ` then text",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "EscapedBacktick",
+			src:      "\\`<span onclick=\"x()\">`",
+			expected: "\\``",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "TagBeforeCodeSpan",
+			src:      "<a title=\"`\" onclick=\"x()\">`</a>",
+			expected: "<a title=\"`\">`</a>",
+			changes:  []Change{{Action: RemovedAttribute, Element: "a", Attribute: "onclick", Count: 1}},
+		},
+		{
+			name:     "CodeSpanAcrossParagraphs",
+			src:      "`one\n\n<span>two</span>`",
+			expected: "`one\n\ntwo`",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "CodeSpanAcrossHeading",
+			src:      "# `one\ntwo <span>three</span>`",
+			expected: "# `one\ntwo three`",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "CodeSpanAcrossListItems",
+			src:      "- `one\n- <span>two</span>`",
+			expected: "- `one\n- two`",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "CodeSpanInHTMLBlock",
+			src:      "Text\n<div>\n`<span>`\n</div>",
+			expected: "Text\n\n``\n",
+			changes: []Change{
+				{Action: RemovedElement, Element: "div", Count: 1},
+				{Action: RemovedElement, Element: "span", Count: 1},
+			},
+		},
+		{
+			name:     "CodeSpanWithPipe",
+			src:      "| `a | <span>b</span>` |",
+			expected: "| `a | b` |",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "FencedCode",
+			src:      "Example:\n\n```html\n<div onclick=\"x()\">\n<script>\n```\n\nAfter <span>it</span>.",
+			expected: "Example:\n\n```html\n<div onclick=\"x()\">\n<script>\n```\n\nAfter it.",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "TildeFenceInQuote",
+			src:      "> ~~~~\n> <script>\n> ~~~~\n<span>x</span>",
+			expected: "> ~~~~\n> <script>\n> ~~~~\nx",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "FenceEndsWithQuote",
+			src:      "> ```\n> <span>\nafter <span>x</span>",
+			expected: "> ```\n> <span>\nafter x",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "FenceEndsWithListItem",
+			src:      "- ```\n  <span>\n<span>x</span>",
+			expected: "- ```\n  <span>\nx",
+			changes:  []Change{{Action: RemovedElement, Element: "span", Count: 1}},
+		},
+		{
+			name:     "UnclosedFence",
+			src:      "```\n<script>",
+			expected: "```\n<script>",
+		},
+		{
+			name:     "FenceInHTMLBlock",
+			src:      "<div>\n```\n<script>alert(1)</script>\n```\n</div>",
+			expected: "\n```\n\n```\n",
+			changes: []Change{
+				{Action: RemovedElement, Element: "div", Count: 1},
+				{Action: RemovedElement, Element: "script", Count: 1},
+			},
+		},
+		{
+			name:     "FenceAfterScriptBlock",
+			src:      "<script>\n\n```\n</script>\n```",
+			expected: "\n```",
+			changes:  []Change{{Action: RemovedElement, Element: "script", Count: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, changes := Sanitize(tt.src, policy)
+			assert.Equal(t, tt.expected, out)
+			assert.Equal(t, tt.changes, changes)
+		})
+	}
+}
+
+func TestSanitizeLargeInput(t *testing.T) {
+	// An unmatched backtick run and unclosed elements must not make
+	// sanitizing take quadratic time.
+	src := "`` " + strings.Repeat("<svg> x ", 100000)
+	out, changes := Sanitize(src, Policy{Elements: DefaultElements})
+	assert.Equal(t, "`` "+strings.Repeat(" x ", 100000), out)
+	assert.Equal(t, []Change{{Action: RemovedElement, Element: "svg", Count: 100000}}, changes)
+}
+
+func TestCanAllow(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"img", true},
+		{"h1", true},
+		{"", false},
+		{"script", false},
+		{"Img", false},
+		{"my-widget", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CanAllow(tt.name))
+		})
+	}
+}
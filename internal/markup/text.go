@@ -0,0 +1,57 @@
+package markup
+
+import (
+	"strings"
+)
+
+// RenderText renders a parsed document as plain text with the markup removed,
+// for uses such as search indexing. Blocks are separated by blank lines, list
+// items are put on lines of their own, and links are replaced by their text.
+func RenderText(doc *Node) string {
+	return strings.Join(textBlocks(doc.Children), "\n\n")
+}
+
+// textBlocks renders each block in blocks.
+func textBlocks(blocks []*Node) []string {
+	var texts []string
+	for _, block := range blocks {
+		var text string
+
+		switch block.Kind {
+		case List:
+			var items []string
+			for _, item := range block.Children {
+				items = append(items, strings.Join(textBlocks(item.Children), "\n"))
+			}
+			text = strings.Join(items, "\n")
+		case Blockquote:
+			text = strings.Join(textBlocks(block.Children), "\n\n")
+		case CodeBlock:
+			text = strings.TrimSuffix(block.Literal, "\n")
+		default:
+			text = textInlines(block.Children)
+		}
+
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// textInlines renders inline nodes, joining the lines of a paragraph with
+// spaces.
+func textInlines(nodes []*Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		switch node.Kind {
+		case Text, Code:
+			sb.WriteString(node.Literal)
+		case SoftBreak:
+			sb.WriteByte(' ')
+		default:
+			sb.WriteString(textInlines(node.Children))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,221 @@
+package openapi
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is a JSON Schema, as used by OpenAPI 3.1, restricted to the keywords
+// this API needs. Type is either a type name or, for values that may also be
+// null, a list of them.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+}
+
+// Ptr returns a pointer to v, for setting the optional keywords of a schema.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Object returns the schema of an object with the given properties, all of
+// which are required.
+func Object(properties map[string]*Schema) *Schema {
+	s := &Schema{Type: "object", Properties: properties}
+	for name := range properties {
+		s.Required = append(s.Required, name)
+	}
+	slices.Sort(s.Required)
+	return s
+}
+
+// Generator generates schemas from Go types, following the rules
+// encoding/json uses to encode them. Named struct types are defined once in
+// Schemas and referred to from elsewhere.
+type Generator struct {
+	// Schemas holds the component schemas generated so far, by name.
+	Schemas map[string]*Schema
+	// names holds the component name of each struct type generated so far.
+	names map[reflect.Type]string
+	// types holds schemas given for types that encode themselves.
+	types map[reflect.Type]*Schema
+}
+
+// NewGenerator returns a generator with no schemas. Values of time.Time are
+// described as date-time strings.
+func NewGenerator() *Generator {
+	g := &Generator{
+		Schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+		types:   make(map[reflect.Type]*Schema),
+	}
+	g.Define(time.Time{}, &Schema{Type: "string", Format: "date-time"})
+	return g
+}
+
+// Define sets the schema of the type of v, for types that are encoded in a
+// way reflection can't reveal, such as by a MarshalJSON method.
+func (g *Generator) Define(v any, schema *Schema) {
+	g.types[reflect.TypeOf(v)] = schema
+}
+
+// Name sets the component name of the schema of the type of v, a named
+// struct, in place of its Go name.
+func (g *Generator) Name(v any, name string) {
+	g.names[reflect.TypeOf(v)] = name
+}
+
+// Schema returns the schema of the type of v. Named struct types are
+// referred to by reference, generating their component schema if needed.
+func (g *Generator) Schema(v any) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *Generator) schema(t reflect.Type) *Schema {
+	if s, ok := g.types[t]; ok {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.define(t)}
+	}
+
+	// Interfaces can hold anything.
+	return &Schema{}
+}
+
+// define generates the component schema of a named struct type, returning its
+// name.
+func (g *Generator) define(t reflect.Type) string {
+	name, named := g.names[t]
+	if _, ok := g.Schemas[name]; named && ok {
+		return name
+	}
+
+	// Unexported types are named as if they were exported, and types of the
+	// same name in different packages are told apart by package.
+	if !named {
+		name = exported(t.Name())
+		if _, taken := g.Schemas[name]; taken {
+			name = exported(t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]) + name
+		}
+	}
+
+	// Register the name before generating the schema, so that recursive
+	// types refer to themselves.
+	g.names[t] = name
+	g.Schemas[name] = &Schema{}
+	*g.Schemas[name] = *g.object(t)
+	return name
+}
+
+// object returns the schema of a struct's fields, as encoding/json encodes
+// them. Fields are required unless they are omitted when empty.
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// The fields of embedded structs without a name are promoted.
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := g.object(ft)
+				for key, prop := range embedded.Properties {
+					s.Properties[key] = prop
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schema(f.Type)
+		omitempty := strings.Contains(opts, "omitempty") || strings.Contains(opts, "omitzero")
+		if f.Type.Kind() == reflect.Pointer && !omitempty {
+			prop = nullable(prop)
+		}
+
+		s.Properties[name] = prop
+		if !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	slices.Sort(s.Required)
+	return s
+}
+
+// nullable returns a schema that also allows null.
+func nullable(s *Schema) *Schema {
+	if t, ok := s.Type.(string); ok {
+		n := *s
+		n.Type = []string{t, "null"}
+		return &n
+	}
+	return &Schema{OneOf: []*Schema{s, {Type: "null"}}}
+}
+
+// exported returns name with its first letter in upper case.
+func exported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,118 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTimestamps struct {
+	Created time.Time `json:"created"`
+}
+
+type testAuthor struct {
+	Name string `json:"name"`
+}
+
+type testArticle struct {
+	testTimestamps
+	ID       int64           `json:"id"`
+	Title    string          `json:"title"`
+	Tags     []string        `json:"tags,omitempty"`
+	Score    float64         `json:"score"`
+	Draft    bool            `json:"draft,omitzero"`
+	Views    uint32          `json:"-"`
+	Meta     map[string]any  `json:"meta"`
+	Author   *testAuthor     `json:"author"`
+	Editor   *testAuthor     `json:"editor,omitempty"`
+	Summary  *string         `json:"summary"`
+	Related  []*testArticle  `json:"related"`
+	Extra    json.RawMessage `json:"extra"`
+	Untagged string
+	hidden   string
+	Counts   map[string]uint16 `json:"counts"`
+}
+
+func TestGeneratorSchema(t *testing.T) {
+	g := NewGenerator()
+	g.Name(testAuthor{}, "Author")
+	g.Define(json.RawMessage{}, &Schema{Description: "Any JSON value."})
+
+	s := g.Schema(&testArticle{})
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/TestArticle"}, s)
+
+	assert.Equal(t, map[string]*Schema{
+		"Author": Object(map[string]*Schema{"name": {Type: "string"}}),
+		"TestArticle": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"created":  {Type: "string", Format: "date-time"},
+				"id":       {Type: "integer", Format: "int64"},
+				"title":    {Type: "string"},
+				"tags":     {Type: "array", Items: &Schema{Type: "string"}},
+				"score":    {Type: "number"},
+				"draft":    {Type: "boolean"},
+				"meta":     {Type: "object", AdditionalProperties: &Schema{}},
+				"author":   {OneOf: []*Schema{{Ref: "#/components/schemas/Author"}, {Type: "null"}}},
+				"editor":   {Ref: "#/components/schemas/Author"},
+				"summary":  {Type: []string{"string", "null"}},
+				"related":  {Type: "array", Items: &Schema{Ref: "#/components/schemas/TestArticle"}},
+				"extra":    {Description: "Any JSON value."},
+				"Untagged": {Type: "string"},
+				"counts":   {Type: "object", AdditionalProperties: &Schema{Type: "integer"}},
+			},
+			Required: []string{"Untagged", "author", "counts", "created", "extra", "id", "meta", "related", "score", "summary", "title"},
+		},
+	}, g.Schemas)
+}
+
+func TestGeneratorAnonymousStruct(t *testing.T) {
+	g := NewGenerator()
+
+	s := g.Schema(struct {
+		Article testArticle `json:"article"`
+		Count   int         `json:"count"`
+	}{})
+
+	assert.Equal(t, Object(map[string]*Schema{
+		"article": {Ref: "#/components/schemas/TestArticle"},
+		"count":   {Type: "integer"},
+	}), s)
+	assert.Contains(t, g.Schemas, "TestArticle")
+}
+
+func TestGeneratorNameClash(t *testing.T) {
+	g := NewGenerator()
+
+	// A type whose name is already taken by another component is named
+	// after its package too.
+	type Time struct {
+		Zone string `json:"zone"`
+	}
+	g.Schemas["Time"] = &Schema{}
+
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/OpenapiTime"}, g.Schema(Time{}))
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/OpenapiTime"}, g.Schema(Time{}))
+	assert.Len(t, g.Schemas, 2)
+}
+
+func TestObject(t *testing.T) {
+	s := Object(map[string]*Schema{"b": {Type: "string"}, "a": {Type: "integer"}, "c": {}})
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"a", "b", "c"}, s.Required)
+}
+
+func TestSchemaJSON(t *testing.T) {
+	s := &Schema{
+		Type:     []string{"integer", "null"},
+		Minimum:  Ptr(0.0),
+		Default:  nil,
+		MinItems: Ptr(0),
+	}
+
+	js, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type": ["integer", "null"], "minimum": 0, "minItems": 0}`, string(js))
+}
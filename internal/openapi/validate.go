@@ -0,0 +1,367 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError reports a value that doesn't match its schema. Pointer is
+// the JSON pointer of the value within the one validated, such as "/tags/0",
+// or "" for the value itself.
+type ValidationError struct {
+	Pointer string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Pointer == "" {
+		return e.Message
+	}
+	return e.Pointer + ": " + e.Message
+}
+
+// Operation returns the operation on a path template, such as
+// "/v1/articles/{id}", for an HTTP method, or nil if the document has none.
+func (d *Document) Operation(method, path string) *Operation {
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
+
+// Parameter returns the parameter p refers to in the components, or p itself
+// if it isn't a reference.
+func (d *Document) Parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	if target, found := d.Components.Parameters[name]; ok && found {
+		return target, nil
+	}
+	return nil, fmt.Errorf("openapi: %s does not resolve", p.Ref)
+}
+
+// ValidateParameter checks the raw value of a path or query parameter
+// against the parameter's schema. The value is converted to the type the
+// schema names before it's checked.
+func (d *Document) ValidateParameter(p *Parameter, raw string) []ValidationError {
+	if p.Schema == nil {
+		return nil
+	}
+
+	s, err := d.resolve(p.Schema)
+	if err != nil {
+		return []ValidationError{{Message: err.Error()}}
+	}
+
+	var value any = raw
+	ts := types(s)
+	switch {
+	case slices.Contains(ts, "integer") || slices.Contains(ts, "number"):
+		f, err := strconv.ParseFloat(raw, 64)
+		if err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			value = json.Number(raw)
+		}
+	case slices.Contains(ts, "boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err == nil {
+			value = b
+		}
+	}
+
+	return d.Validate(s, value)
+}
+
+// Validate checks a value decoded from JSON, into any with numbers as
+// float64 or json.Number, against s. References to component schemas are
+// resolved from the document. It returns an error for each value that breaks
+// a rule, in the order they were found.
+//
+// A value's type is checked first. The other keywords only apply to values
+// of the types they concern, and aren't checked if the type is wrong.
+func (d *Document) Validate(s *Schema, value any) []ValidationError {
+	var errs []ValidationError
+	d.validate(s, value, "", &errs)
+	return errs
+}
+
+func (d *Document) validate(s *Schema, value any, pointer string, errs *[]ValidationError) {
+	s, err := d.resolve(s)
+	if err != nil {
+		*errs = append(*errs, ValidationError{pointer, err.Error()})
+		return
+	}
+
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, ValidationError{pointer, fmt.Sprintf(format, args...)})
+	}
+
+	if allowed := types(s); len(allowed) > 0 && !slices.ContainsFunc(allowed, func(t string) bool { return hasType(value, t) }) {
+		fail("must be %s", typeNames(allowed))
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(e, value) }) {
+		values := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			values[i] = fmt.Sprint(e)
+		}
+		fail("must be one of %s", list(values, "or"))
+		return
+	}
+
+	switch value := value.(type) {
+	case string:
+		validateString(s, value, fail)
+	case float64, json.Number:
+		validateNumber(s, number(value), fail)
+	case []any:
+		d.validateArray(s, value, pointer, fail, errs)
+	case map[string]any:
+		d.validateObject(s, value, pointer, errs)
+	}
+
+	if len(s.OneOf) > 0 {
+		d.validateOneOf(s.OneOf, value, pointer, fail, errs)
+	}
+}
+
+func validateString(s *Schema, value string, fail func(string, ...any)) {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		if *s.MinLength == 1 {
+			fail("must not be empty")
+		} else {
+			fail("must be at least %d characters long", *s.MinLength)
+		}
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		fail("must not be more than %d characters long", *s.MaxLength)
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		switch {
+		case err != nil:
+			fail("cannot be checked against the pattern %s: %v", s.Pattern, err)
+		case !re.MatchString(value):
+			fail("must match the pattern %s", s.Pattern)
+		}
+	}
+
+	switch s.Format {
+	case "date":
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			fail("must be a date in the format YYYY-MM-DD")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			fail("must be a date and time in RFC 3339 format")
+		}
+	}
+}
+
+func validateNumber(s *Schema, value float64, fail func(string, ...any)) {
+	if s.Minimum != nil && value < *s.Minimum {
+		fail("must be at least %s", formatNumber(*s.Minimum))
+	}
+	if s.ExclusiveMinimum != nil && value <= *s.ExclusiveMinimum {
+		fail("must be greater than %s", formatNumber(*s.ExclusiveMinimum))
+	}
+	if s.Maximum != nil && value > *s.Maximum {
+		fail("must not be more than %s", formatNumber(*s.Maximum))
+	}
+}
+
+func (d *Document) validateArray(s *Schema, value []any, pointer string, fail func(string, ...any), errs *[]ValidationError) {
+	if s.MinItems != nil && len(value) < *s.MinItems {
+		fail("must contain at least %d %s", *s.MinItems, plural(*s.MinItems, "item"))
+	}
+	if s.MaxItems != nil && len(value) > *s.MaxItems {
+		fail("must not contain more than %d %s", *s.MaxItems, plural(*s.MaxItems, "item"))
+	}
+
+	if s.UniqueItems {
+		for i := range value {
+			if slices.ContainsFunc(value[:i], func(v any) bool { return equal(v, value[i]) }) {
+				fail("must not contain duplicate values")
+				break
+			}
+		}
+	}
+
+	if s.Items != nil {
+		for i, item := range value {
+			d.validate(s.Items, item, pointer+"/"+strconv.Itoa(i), errs)
+		}
+	}
+}
+
+func (d *Document) validateObject(s *Schema, value map[string]any, pointer string, errs *[]ValidationError) {
+	for _, name := range s.Required {
+		if _, ok := value[name]; !ok {
+			*errs = append(*errs, ValidationError{pointer + "/" + escape(name), "must be provided"})
+		}
+	}
+
+	// Members are checked in order of name, so that errors are reported in
+	// the same order every time.
+	for _, name := range slices.Sorted(maps.Keys(value)) {
+		member := pointer + "/" + escape(name)
+		if prop, ok := s.Properties[name]; ok {
+			d.validate(prop, value[name], member, errs)
+		} else if s.AdditionalProperties != nil {
+			d.validate(s.AdditionalProperties, value[name], member, errs)
+		}
+	}
+}
+
+// validateOneOf checks that value matches exactly one of the schemas. If it
+// matches none, the errors are reported from the one schema of the value's
+// type, when there is just one, as that's the one that was meant.
+func (d *Document) validateOneOf(schemas []*Schema, value any, pointer string, fail func(string, ...any), errs *[]ValidationError) {
+	var matches int
+	var typed [][]ValidationError
+	for _, schema := range schemas {
+		var schemaErrs []ValidationError
+		d.validate(schema, value, pointer, &schemaErrs)
+		if len(schemaErrs) == 0 {
+			matches++
+			continue
+		}
+
+		resolved, err := d.resolve(schema)
+		if err == nil && slices.ContainsFunc(types(resolved), func(t string) bool { return hasType(value, t) }) {
+			typed = append(typed, schemaErrs)
+		}
+	}
+
+	switch {
+	case matches == 1:
+	case matches == 0 && len(typed) == 1:
+		*errs = append(*errs, typed[0]...)
+	default:
+		fail("must match exactly one of the allowed schemas")
+	}
+}
+
+// resolve returns the component schema s refers to, or s itself if it isn't
+// a reference.
+func (d *Document) resolve(s *Schema) (*Schema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+
+	name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+	if target, found := d.Components.Schemas[name]; ok && found {
+		return target, nil
+	}
+	return nil, fmt.Errorf("openapi: %s does not resolve", s.Ref)
+}
+
+// types returns the types a schema allows, or none if it allows any.
+func types(s *Schema) []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+// hasType reports whether a value decoded from JSON is of a JSON Schema type.
+// Numbers with no fractional part are integers.
+func hasType(value any, t string) bool {
+	switch value := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64, json.Number:
+		n := number(value)
+		return t == "number" || (t == "integer" && n == math.Trunc(n) && !math.IsInf(n, 0))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+// typeNames describes a list of types, such as "a string or null".
+func typeNames(ts []string) string {
+	names := make([]string, len(ts))
+	for i, t := range ts {
+		switch t {
+		case "null":
+			names[i] = t
+		case "array", "integer", "object":
+			names[i] = "an " + t
+		default:
+			names[i] = "a " + t
+		}
+	}
+	return list(names, "or")
+}
+
+// list joins items as in "a, b or c".
+func list(items []string, conjunction string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+}
+
+// number returns the value of a number decoded from JSON.
+func number(value any) float64 {
+	switch value := value.(type) {
+	case float64:
+		return value
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	}
+	return math.NaN()
+}
+
+// equal reports whether two values decoded from JSON are the same, comparing
+// numbers by value.
+func equal(a, b any) bool {
+	if hasType(a, "number") && hasType(b, "number") {
+		return number(a) == number(b)
+	}
+
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
+
+// escape escapes a member name for use in a JSON pointer.
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
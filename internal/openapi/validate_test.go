@@ -0,0 +1,267 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDocument returns a document with an article schema to validate against.
+func testDocument() *Document {
+	return &Document{
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Article": {
+					Type: "object",
+					Properties: map[string]*Schema{
+						"id":    {Type: "integer", Minimum: Ptr(1.0)},
+						"title": {Type: "string", MinLength: Ptr(1), MaxLength: Ptr(10)},
+						"date":  {Type: "string", Format: "date"},
+						"tags": {
+							Type:        "array",
+							Items:       &Schema{Type: "string", Pattern: "^[a-z]+$"},
+							MinItems:    Ptr(1),
+							MaxItems:    Ptr(3),
+							UniqueItems: true,
+						},
+						"author": {Ref: "#/components/schemas/Author"},
+						"meta":   {Type: "object", AdditionalProperties: &Schema{Type: "number", ExclusiveMinimum: Ptr(0.0)}},
+					},
+					Required: []string{"id", "title"},
+				},
+				"Author": {Type: []string{"string", "null"}, MinLength: Ptr(2)},
+			},
+			Parameters: map[string]*Parameter{
+				"page": {Name: "page", In: "query", Schema: &Schema{Type: "integer", Minimum: Ptr(1.0), Maximum: Ptr(100.0)}},
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	article := &Schema{Ref: "#/components/schemas/Article"}
+
+	tests := []struct {
+		name     string
+		schema   *Schema
+		value    string
+		expected []ValidationError
+	}{
+		{
+			name:   "Valid",
+			schema: article,
+			value:  `{"id": 1, "title": "Chips", "date": "2016-09-22", "tags": ["a", "b"], "author": null, "meta": {"score": 0.5}, "extra": true}`,
+		},
+		{
+			name:     "Wrong Type",
+			schema:   article,
+			value:    `[]`,
+			expected: []ValidationError{{"", "must be an object"}},
+		},
+		{
+			name:   "Missing Members",
+			schema: article,
+			value:  `{}`,
+			expected: []ValidationError{
+				{"/id", "must be provided"},
+				{"/title", "must be provided"},
+			},
+		},
+		{
+			name:   "Members In Order Of Name",
+			schema: article,
+			value:  `{"title": "", "id": 1.5, "date": "22/09/2016"}`,
+			expected: []ValidationError{
+				{"/date", "must be a date in the format YYYY-MM-DD"},
+				{"/id", "must be an integer"},
+				{"/title", "must not be empty"},
+			},
+		},
+		{
+			name:   "Bounds",
+			schema: article,
+			value:  `{"id": 0, "title": "Potato chips", "meta": {"a": 0, "b": -1, "c": "x"}}`,
+			expected: []ValidationError{
+				{"/id", "must be at least 1"},
+				{"/meta/a", "must be greater than 0"},
+				{"/meta/b", "must be greater than 0"},
+				{"/meta/c", "must be a number"},
+				{"/title", "must not be more than 10 characters long"},
+			},
+		},
+		{
+			name:     "Length Counts Characters",
+			schema:   article,
+			value:    `{"id": 1, "title": "ééééééééééé"}`,
+			expected: []ValidationError{{"/title", "must not be more than 10 characters long"}},
+		},
+		{
+			name:   "Array Rules",
+			schema: article,
+			value:  `{"id": 1, "title": "x", "tags": ["a", "B", "a", "c"]}`,
+			expected: []ValidationError{
+				{"/tags", "must not contain more than 3 items"},
+				{"/tags", "must not contain duplicate values"},
+				{"/tags/1", "must match the pattern ^[a-z]+$"},
+			},
+		},
+		{
+			name:     "Empty Array",
+			schema:   article,
+			value:    `{"id": 1, "title": "x", "tags": []}`,
+			expected: []ValidationError{{"/tags", "must contain at least 1 item"}},
+		},
+		{
+			name:     "Nullable Reference",
+			schema:   article,
+			value:    `{"id": 1, "title": "x", "author": 5}`,
+			expected: []ValidationError{{"/author", "must be a string or null"}},
+		},
+		{
+			name:     "Nullable Reference Rules",
+			schema:   article,
+			value:    `{"id": 1, "title": "x", "author": "A"}`,
+			expected: []ValidationError{{"/author", "must be at least 2 characters long"}},
+		},
+		{
+			name:     "Escaped Pointer",
+			schema:   &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			value:    `{"a/b~c": 1}`,
+			expected: []ValidationError{{"/a~1b~0c", "must be a string"}},
+		},
+		{
+			name:     "Enum",
+			schema:   &Schema{Enum: []any{"asc", "desc", 1.0}},
+			value:    `"up"`,
+			expected: []ValidationError{{"", "must be one of asc, desc or 1"}},
+		},
+		{
+			name:   "Enum Compares Numbers By Value",
+			schema: &Schema{Enum: []any{1.0}},
+			value:  `1.0`,
+		},
+		{
+			name:     "Date Time",
+			schema:   &Schema{Type: "string", Format: "date-time"},
+			value:    `"2016-09-22"`,
+			expected: []ValidationError{{"", "must be a date and time in RFC 3339 format"}},
+		},
+		{
+			name:     "Bad Pattern",
+			schema:   &Schema{Type: "string", Pattern: "("},
+			value:    `"x"`,
+			expected: []ValidationError{{"", "cannot be checked against the pattern (: error parsing regexp: missing closing ): `(`"}},
+		},
+		{
+			name:     "Several Types",
+			schema:   &Schema{Type: []string{"integer", "array", "object"}},
+			value:    `true`,
+			expected: []ValidationError{{"", "must be an integer, an array or an object"}},
+		},
+		{
+			name:   "Any Type",
+			schema: &Schema{},
+			value:  `[null, {"a": 1}]`,
+		},
+		{
+			name:     "Unresolved Reference",
+			schema:   &Schema{Ref: "#/components/schemas/Missing"},
+			value:    `1`,
+			expected: []ValidationError{{"", "openapi: #/components/schemas/Missing does not resolve"}},
+		},
+		{
+			name:     "Reference Outside Schemas",
+			schema:   &Schema{Ref: "#/components/parameters/page"},
+			value:    `1`,
+			expected: []ValidationError{{"", "openapi: #/components/parameters/page does not resolve"}},
+		},
+		{
+			name:   "One Of Matches",
+			schema: &Schema{OneOf: []*Schema{{Type: "integer"}, {Type: "string"}}},
+			value:  `"x"`,
+		},
+		{
+			name:     "One Of Matches Both",
+			schema:   &Schema{OneOf: []*Schema{{Type: "number"}, {Type: "integer"}}},
+			value:    `1`,
+			expected: []ValidationError{{"", "must match exactly one of the allowed schemas"}},
+		},
+		{
+			name:     "One Of Matches None",
+			schema:   &Schema{OneOf: []*Schema{{Type: "integer"}, {Type: "string"}}},
+			value:    `true`,
+			expected: []ValidationError{{"", "must match exactly one of the allowed schemas"}},
+		},
+		{
+			name:     "One Of Reports The Schema Of The Value's Type",
+			schema:   &Schema{OneOf: []*Schema{{Type: "integer", Minimum: Ptr(1.0)}, {Type: "string"}}},
+			value:    `0`,
+			expected: []ValidationError{{"", "must be at least 1"}},
+		},
+	}
+
+	d := testDocument()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := json.NewDecoder(strings.NewReader(tt.value))
+			dec.UseNumber()
+
+			var value any
+			assert.NoError(t, dec.Decode(&value))
+			assert.Equal(t, tt.expected, d.Validate(tt.schema, value))
+		})
+	}
+}
+
+func TestValidateParameter(t *testing.T) {
+	d := testDocument()
+	page, err := d.Parameter(&Parameter{Ref: "#/components/parameters/page"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		parameter *Parameter
+		raw       string
+		expected  []ValidationError
+	}{
+		{name: "Integer", parameter: page, raw: "2"},
+		{name: "Too Small", parameter: page, raw: "0", expected: []ValidationError{{"", "must be at least 1"}}},
+		{name: "Too Large", parameter: page, raw: "1e3", expected: []ValidationError{{"", "must not be more than 100"}}},
+		{name: "Fraction", parameter: page, raw: "1.5", expected: []ValidationError{{"", "must be an integer"}}},
+		{name: "Not A Number", parameter: page, raw: "two", expected: []ValidationError{{"", "must be an integer"}}},
+		{name: "Not Finite", parameter: page, raw: "NaN", expected: []ValidationError{{"", "must be an integer"}}},
+		{name: "Boolean", parameter: &Parameter{Schema: &Schema{Type: "boolean"}}, raw: "true"},
+		{name: "Not A Boolean", parameter: &Parameter{Schema: &Schema{Type: "boolean"}}, raw: "yes", expected: []ValidationError{{"", "must be a boolean"}}},
+		{name: "String", parameter: &Parameter{Schema: &Schema{Type: "string", Enum: []any{"en", "fr"}}}, raw: "de", expected: []ValidationError{{"", "must be one of en or fr"}}},
+		{name: "No Schema", parameter: &Parameter{}, raw: "anything"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, d.ValidateParameter(tt.parameter, tt.raw))
+		})
+	}
+}
+
+func TestDocumentLookups(t *testing.T) {
+	d := testDocument()
+	get := &Operation{OperationID: "getArticle"}
+	d.Paths = map[string]*PathItem{"/v1/articles/{id}": {"get": get}}
+
+	assert.Same(t, get, d.Operation("GET", "/v1/articles/{id}"))
+	assert.Nil(t, d.Operation("DELETE", "/v1/articles/{id}"))
+	assert.Nil(t, d.Operation("GET", "/v1/articles"))
+
+	inline := &Parameter{Name: "id"}
+	p, err := d.Parameter(inline)
+	assert.NoError(t, err)
+	assert.Same(t, inline, p)
+
+	_, err = d.Parameter(&Parameter{Ref: "#/components/parameters/missing"})
+	assert.EqualError(t, err, "openapi: #/components/parameters/missing does not resolve")
+
+	_, err = d.Parameter(&Parameter{Ref: "#/components/schemas/Article"})
+	assert.Error(t, err)
+}
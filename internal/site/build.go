@@ -0,0 +1,205 @@
+package site
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+)
+
+// ManifestFile is the name of the file, in the output directory, recording
+// what each page of the last build was rendered from.
+const ManifestFile = ".site-manifest.json"
+
+// DefaultPageSize is the number of articles on each index page when the
+// options don't set one.
+const DefaultPageSize = 20
+
+// ErrNoBaseURL is returned by Build when the options have no absolute base
+// URL, which the feeds need for their links.
+var ErrNoBaseURL = errors.New("site: an absolute base URL is required")
+
+// manifest maps the path of each file written by a build to its page's key.
+type manifest map[string]string
+
+// Build renders the articles into a static site in dir, creating it if
+// needed. Pages that were written by an earlier build from the same article
+// versions are left alone, and files an earlier build wrote that the site no
+// longer has are removed.
+func Build(dir string, articles *data.ArticleDAO, options Options) (Stats, error) {
+	u, err := url.Parse(options.BaseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return Stats{}, ErrNoBaseURL
+	}
+	if options.PageSize < 1 {
+		options.PageSize = DefaultPageSize
+	}
+	if options.FeedItems < 1 {
+		options.FeedItems = options.PageSize
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	previous, err := readManifest(dir)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	b := newBuilder(articles.GetAll(), options)
+	b.plan()
+
+	var stats Stats
+	current := make(manifest, len(b.pages))
+
+	for _, p := range b.pages {
+		name := filepath.Join(dir, filepath.FromSlash(p.path))
+
+		if previous[p.path] == p.key && fileExists(name) {
+			current[p.path] = p.key
+			stats.Unchanged++
+			continue
+		}
+
+		var buf bytes.Buffer
+		err := p.render(&buf)
+		if err != nil {
+			return stats, fmt.Errorf("site: rendering %s: %w", p.path, err)
+		}
+
+		err = writeFile(name, buf.Bytes())
+		if err != nil {
+			return stats, err
+		}
+		current[p.path] = p.key
+		stats.Written++
+	}
+
+	// Save the manifest before removing stale files, so that an interrupted
+	// build still knows about the pages it wrote.
+	err = writeManifest(dir, current)
+	if err != nil {
+		return stats, err
+	}
+
+	for path := range previous {
+		if _, ok := current[path]; ok {
+			continue
+		}
+
+		err := removeFile(dir, path)
+		if err != nil {
+			return stats, err
+		}
+		stats.Removed++
+	}
+
+	return stats, nil
+}
+
+// readManifest reads the manifest of the last build in dir, which is empty if
+// there hasn't been one.
+func readManifest(dir string) (manifest, error) {
+	js, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	err = json.Unmarshal(js, &m)
+	if err != nil {
+		// A damaged manifest only costs a full rebuild.
+		return manifest{}, nil
+	}
+	return m, nil
+}
+
+func writeManifest(dir string, m manifest) error {
+	js, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, ManifestFile), append(js, '\n'))
+}
+
+// writeFile writes a file by renaming a temporary file over it, so that a
+// CDN syncing the directory never sees it half written.
+func writeFile(name string, content []byte) error {
+	err := os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(content)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Chmod(0o644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// removeFile removes a file written by an earlier build, along with any
+// directories that removing it leaves empty.
+func removeFile(dir, path string) error {
+	// The manifest only holds paths the builder made, but don't trust one
+	// that would reach outside the output directory.
+	if !filepath.IsLocal(filepath.FromSlash(path)) {
+		return nil
+	}
+
+	err := os.Remove(filepath.Join(dir, filepath.FromSlash(path)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	parts := strings.Split(path, "/")
+	for i := len(parts) - 1; i > 0; i-- {
+		sub := filepath.Join(dir, filepath.Join(parts[:i]...))
+
+		entries, err := os.ReadDir(sub)
+		if err != nil || len(entries) > 0 {
+			break
+		}
+		err = os.Remove(sub)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func fileExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,542 @@
+// Package site renders the article store as a static HTML site, so that an
+// archive can be served from a CDN without the API.
+//
+// The site has a page per article, paginated indexes of all articles and of
+// each tag's articles, a page per tag and date mirroring
+// /v1/tags/:tagName/:date, and Atom, RSS and JSON feeds for the site and each
+// tag. Links between pages are relative, so the site can be served from any
+// path.
+//
+// Builds are incremental: a manifest in the output directory records the
+// article versions each page was rendered from, and pages whose articles
+// haven't changed are left as they are.
+package site
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+	"github.com/des-ant/2024-article-api/internal/feed"
+	"github.com/des-ant/2024-article-api/internal/markup"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// templates holds a template set for each kind of page, keyed by the name of
+// the file defining its content.
+var templates = map[string]*template.Template{}
+
+// templatesKey is a hash of the template sources, so that changing them
+// invalidates every page.
+var templatesKey string
+
+func init() {
+	h := sha256.New()
+
+	names, err := fs.Glob(templateFS, "templates/*.html")
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range names {
+		src, err := templateFS.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		h.Write(src)
+
+		if name == "templates/base.html" {
+			continue
+		}
+		templates[strings.TrimPrefix(name, "templates/")] = template.Must(template.ParseFS(templateFS, "templates/base.html", name))
+	}
+
+	templatesKey = hex.EncodeToString(h.Sum(nil))
+}
+
+// Options configure a site build.
+type Options struct {
+	// Title is the name of the site.
+	Title string
+	// BaseURL is the absolute URL the site is served from, which feeds need
+	// for their links.
+	BaseURL string
+	// PageSize is the number of articles on each index page.
+	PageSize int
+	// FeedItems is the number of articles in each feed.
+	FeedItems int
+	// HTML is the policy article bodies were sanitized with, which decides
+	// the inline HTML kept when they are rendered.
+	HTML markup.Policy
+}
+
+// Stats reports what a build did.
+type Stats struct {
+	// Written counts the files rendered and written.
+	Written int `json:"written"`
+	// Unchanged counts the files skipped because their articles haven't
+	// changed since the last build.
+	Unchanged int `json:"unchanged"`
+	// Removed counts the files from the last build that no longer exist,
+	// such as the pages of deleted articles.
+	Removed int `json:"removed"`
+}
+
+// page is a file in the site.
+type page struct {
+	// path is the file's path relative to the output directory.
+	path string
+	// key identifies everything the page is rendered from. The page only
+	// needs rendering again when it changes.
+	key    string
+	render func(w io.Writer) error
+}
+
+// builder works out the pages of a site.
+type builder struct {
+	options  Options
+	articles []data.Article
+	// slugs holds the directory name of each tag.
+	slugs map[string]string
+	pages []page
+}
+
+// newBuilder prepares to build a site of articles, which are listed newest
+// first.
+func newBuilder(articles []data.Article, options Options) *builder {
+	b := &builder{
+		options:  options,
+		articles: slices.Clone(articles),
+		slugs:    make(map[string]string),
+	}
+
+	slices.SortStableFunc(b.articles, func(x, y data.Article) int {
+		if c := y.Date.ToTime().Compare(x.Date.ToTime()); c != 0 {
+			return c
+		}
+		return cmp.Compare(y.ID, x.ID)
+	})
+
+	used := make(map[string]bool)
+	for _, tag := range b.tags() {
+		slug := slugify(tag)
+		for i := 2; used[slug]; i++ {
+			slug = fmt.Sprintf("%s-%d", slugify(tag), i)
+		}
+		used[slug] = true
+		b.slugs[tag] = slug
+	}
+
+	return b
+}
+
+// tags returns every tag in use, sorted.
+func (b *builder) tags() []string {
+	var tags []string
+	for _, article := range b.articles {
+		tags = append(tags, article.Tags...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// slugify turns a tag into a directory name made of lowercase letters, digits
+// and dashes.
+func slugify(tag string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(tag) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if sb.Len() == 0 {
+		return "tag"
+	}
+	return sb.String()
+}
+
+// articlePath returns the directory of an article's page.
+func articlePath(id int64) string {
+	return fmt.Sprintf("articles/%d/", id)
+}
+
+// tagPath returns the directory of a tag's index pages.
+func (b *builder) tagPath(tag string) string {
+	return "tags/" + b.slugs[tag] + "/"
+}
+
+// key hashes the parts a page is rendered from, along with the options and
+// templates every page depends on.
+func (b *builder) key(parts ...any) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%#v\x00", templatesKey, b.options)
+	for _, part := range parts {
+		fmt.Fprintf(h, "%v\x00", part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// articlesKey describes the versions of a list of articles, and the tag
+// directories they link to. Versions tell a page's articles apart within a
+// process, but the store is held in memory, so a process that reloads it
+// starts them again at 1: a digest of each article's content tells them apart
+// across builds.
+func (b *builder) articlesKey(articles []data.Article) string {
+	var sb strings.Builder
+	for _, article := range articles {
+		digest := sha256.Sum256(fmt.Appendf(nil, "%q %q %q %q %s", article.Title, article.Body, article.Summary, article.Tags, article.Date))
+		fmt.Fprintf(&sb, "%d@%d#%x", article.ID, article.Version, digest[:8])
+		for _, tag := range article.Tags {
+			fmt.Fprintf(&sb, ":%s", b.slugs[tag])
+		}
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// add adds a page rendered from a template.
+func (b *builder) add(path, key, templateName string, view func(root string) any) {
+	b.pages = append(b.pages, page{
+		path: path,
+		key:  key,
+		render: func(w io.Writer) error {
+			return templates[templateName].ExecuteTemplate(w, "base", view(rootOf(path)))
+		},
+	})
+}
+
+// rootOf returns the relative path from the file at path to the site's root.
+func rootOf(path string) string {
+	depth := strings.Count(path, "/")
+	if depth == 0 {
+		return "./"
+	}
+	return strings.Repeat("../", depth)
+}
+
+// plan works out every page of the site.
+func (b *builder) plan() {
+	b.addIndex("", "", b.options.Title, b.articles)
+	b.addFeeds("", b.options.Title, b.articles)
+
+	var tagCounts []tagView
+	for _, tag := range b.tags() {
+		var tagged []data.Article
+		for _, article := range b.articles {
+			if slices.Contains(article.Tags, tag) {
+				tagged = append(tagged, article)
+			}
+		}
+		tagCounts = append(tagCounts, tagView{link: link{Text: tag, Href: b.tagPath(tag)}, Count: len(tagged)})
+
+		title := fmt.Sprintf("%s: %s", b.options.Title, tag)
+		b.addIndex(b.tagPath(tag), tag, tag, tagged)
+		b.addFeeds(b.tagPath(tag), title, tagged)
+		b.addTagDates(tag, tagged)
+	}
+
+	b.add("tags/index.html", b.key("tags", fmt.Sprint(tagCounts)), "tags.html", func(root string) any {
+		tags := slices.Clone(tagCounts)
+		for i := range tags {
+			tags[i].Href = root + tags[i].Href
+		}
+		return tagsView{pageView: b.pageView(root, "Tags"), Tags: tags}
+	})
+
+	for _, article := range b.articles {
+		b.add(articlePath(article.ID)+"index.html", b.key("article", b.articlesKey([]data.Article{article})), "article.html", func(root string) any {
+			view := articleView{pageView: b.pageView(root, article.Title), Article: b.articleView(root, article)}
+			view.Article.Body = template.HTML(markup.RenderHTML(markup.Parse(article.Body), b.options.HTML))
+			return view
+		})
+	}
+}
+
+// addIndex adds the index pages listing articles under dir, paginated.
+func (b *builder) addIndex(dir, tag, heading string, articles []data.Article) {
+	pageSize := max(b.options.PageSize, 1)
+	pages := max((len(articles)+pageSize-1)/pageSize, 1)
+
+	pagePath := func(n int) string {
+		if n == 1 {
+			return dir
+		}
+		return fmt.Sprintf("%spage/%d/", dir, n)
+	}
+
+	for n := 1; n <= pages; n++ {
+		listed := articles[(n-1)*pageSize : min(n*pageSize, len(articles))]
+		path := pagePath(n) + "index.html"
+
+		b.add(path, b.key("index", dir, n, pages, b.articlesKey(listed)), "index.html", func(root string) any {
+			title := heading
+			if tag == "" {
+				title = ""
+			}
+			if n > 1 {
+				title = fmt.Sprintf("%s (page %d)", heading, n)
+			}
+
+			view := indexView{
+				pageView: b.pageView(root, title),
+				Heading:  heading,
+				Articles: b.articleViews(root, listed),
+				Page:     n,
+				Pages:    pages,
+			}
+			if tag != "" {
+				view.Feeds = append(view.Feeds, b.feedLinks(root, dir, heading)...)
+			}
+			if n > 1 {
+				view.Prev = root + pagePath(n-1)
+			}
+			if n < pages {
+				view.Next = root + pagePath(n+1)
+			}
+			return view
+		})
+	}
+}
+
+// addTagDates adds a page for each date a tag's articles were published on,
+// summarising them as /v1/tags/:tagName/:date does.
+func (b *builder) addTagDates(tag string, tagged []data.Article) {
+	byDate := make(map[string][]data.Article)
+	var dates []string
+	for _, article := range tagged {
+		date := article.Date.ToTime().Format("20060102")
+		if _, ok := byDate[date]; !ok {
+			dates = append(dates, date)
+		}
+		byDate[date] = append(byDate[date], article)
+	}
+
+	for _, date := range dates {
+		articles := byDate[date]
+
+		path := b.tagPath(tag) + date + "/index.html"
+		b.add(path, b.key("tagdate", tag, date, b.articlesKey(articles)), "tagdate.html", func(root string) any {
+			// Like the API, count the tag itself but don't list it as related.
+			var related []string
+			for _, article := range articles {
+				related = append(related, article.Tags...)
+			}
+			slices.Sort(related)
+			related = slices.Compact(related)
+
+			view := tagDateView{
+				pageView:    b.pageView(root, fmt.Sprintf("%s on %s", tag, articles[0].Date.String())),
+				Tag:         link{Text: tag, Href: root + b.tagPath(tag)},
+				Date:        articles[0].Date.String(),
+				Count:       len(related),
+				Articles:    b.articleViews(root, articles),
+				TopKeywords: data.TopKeywords(articles, 5),
+			}
+			for _, t := range related {
+				if t != tag {
+					view.RelatedTags = append(view.RelatedTags, link{Text: t, Href: root + b.tagPath(t)})
+				}
+			}
+			return view
+		})
+	}
+}
+
+// feedFormats lists the feed files written for the site and each tag.
+var feedFormats = []struct {
+	name      string
+	mediaType string
+	write     func(io.Writer, feed.Feed) error
+}{
+	{"feed.atom", feed.AtomMediaType, feed.WriteAtom},
+	{"feed.rss", feed.RSSMediaType, feed.WriteRSS},
+	{"feed.json", feed.JSONFeedMediaType, feed.WriteJSONFeed},
+}
+
+// addFeeds adds feeds of the newest articles under dir.
+func (b *builder) addFeeds(dir, title string, articles []data.Article) {
+	items := articles[:min(max(b.options.FeedItems, 1), len(articles))]
+	baseURL := strings.TrimSuffix(b.options.BaseURL, "/") + "/"
+
+	for _, format := range feedFormats {
+		path := dir + format.name
+		b.pages = append(b.pages, page{
+			path: path,
+			key:  b.key("feed", path, b.articlesKey(items)),
+			render: func(w io.Writer) error {
+				f := feed.Feed{
+					Title:       title,
+					Description: title,
+					Link:        baseURL + dir,
+					SelfLink:    baseURL + path,
+					Author:      b.options.Title,
+				}
+				for _, article := range items {
+					f.Items = append(f.Items, feed.Item{
+						Link:        baseURL + articlePath(article.ID),
+						Title:       article.Title,
+						Published:   article.Date.ToTime(),
+						Updated:     article.Date.ToTime(),
+						Categories:  article.Tags,
+						Summary:     article.Summary,
+						ContentHTML: markup.RenderHTML(markup.Parse(article.Body), b.options.HTML),
+					})
+				}
+				if len(f.Items) > 0 {
+					f.Updated = f.Items[0].Updated
+				}
+				return format.write(w, f)
+			},
+		})
+	}
+}
+
+// feedLinks returns links to the feeds under dir.
+func (b *builder) feedLinks(root, dir, title string) []feedLink {
+	links := make([]feedLink, len(feedFormats))
+	for i, format := range feedFormats {
+		links[i] = feedLink{link: link{Text: title, Href: root + dir + format.name}, Type: format.mediaType}
+	}
+	return links
+}
+
+// pageView returns the parts of a page's view every page has.
+func (b *builder) pageView(root, title string) pageView {
+	return pageView{
+		Site:  b.options.Title,
+		Title: title,
+		Root:  root,
+		Feeds: b.feedLinks(root, "", b.options.Title),
+	}
+}
+
+// articleViews describes articles for a list on a page at root.
+func (b *builder) articleViews(root string, articles []data.Article) []articleSummary {
+	views := make([]articleSummary, len(articles))
+	for i, article := range articles {
+		views[i] = b.articleView(root, article)
+		views[i].Summary = excerpt(article)
+	}
+	return views
+}
+
+// articleView describes an article for a page at root.
+func (b *builder) articleView(root string, article data.Article) articleSummary {
+	view := articleSummary{
+		Title: article.Title,
+		Href:  root + articlePath(article.ID),
+		Date:  article.Date.String(),
+	}
+	for _, tag := range article.Tags {
+		view.Tags = append(view.Tags, link{Text: tag, Href: root + b.tagPath(tag)})
+	}
+	return view
+}
+
+// maxExcerpt is the length, in runes, beyond which excerpts are cut short.
+const maxExcerpt = 200
+
+// excerpt returns the summary stored with an article, or else the start of
+// its first paragraph as plain text.
+func excerpt(article data.Article) string {
+	if article.Summary != "" {
+		return article.Summary
+	}
+
+	text, _, _ := strings.Cut(markup.RenderText(markup.Parse(article.Body)), "\n\n")
+	if utf8.RuneCountInString(text) <= maxExcerpt {
+		return text
+	}
+
+	runes := []rune(text)[:maxExcerpt]
+	cut := strings.LastIndexByte(string(runes), ' ')
+	if cut <= 0 {
+		cut = len(string(runes))
+	}
+	return strings.TrimRight(string(runes)[:cut], " ,.;:") + "…"
+}
+
+// link is a link to another page.
+type link struct {
+	Text string
+	Href string
+}
+
+// feedLink is a link to a feed, for a page's head.
+type feedLink struct {
+	link
+	Type string
+}
+
+// pageView holds what every page's template is given.
+type pageView struct {
+	// Site is the name of the site.
+	Site string
+	// Title is the page's title, or empty on the home page.
+	Title string
+	// Root is the relative path to the site's root, ending in a slash.
+	Root  string
+	Feeds []feedLink
+}
+
+// articleSummary describes an article on a page.
+type articleSummary struct {
+	Title   string
+	Href    string
+	Date    string
+	Tags    []link
+	Summary string
+	Body    template.HTML
+}
+
+type articleView struct {
+	pageView
+	Article articleSummary
+}
+
+type indexView struct {
+	pageView
+	Heading  string
+	Articles []articleSummary
+	Page     int
+	Pages    int
+	Prev     string
+	Next     string
+}
+
+type tagView struct {
+	link
+	Count int
+}
+
+type tagsView struct {
+	pageView
+	Tags []tagView
+}
+
+type tagDateView struct {
+	pageView
+	Tag         link
+	Date        string
+	Count       int
+	RelatedTags []link
+	TopKeywords []string
+	Articles    []articleSummary
+}
@@ -0,0 +1,202 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/des-ant/2024-article-api/internal/data"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{tag: "health", expected: "health"},
+		{tag: "Health & Fitness", expected: "health-fitness"},
+		{tag: "  padded  ", expected: "padded"},
+		{tag: "C++", expected: "c"},
+		{tag: "2016", expected: "2016"},
+		{tag: "café", expected: "caf"},
+		{tag: "../etc", expected: "etc"},
+		{tag: "日本", expected: "tag"},
+		{tag: "", expected: "tag"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.expected, slugify(tt.tag))
+		})
+	}
+}
+
+func TestTagSlugsAreUnique(t *testing.T) {
+	b := newBuilder([]data.Article{
+		{ID: 1, Tags: []string{"Go", "go", "GO!"}},
+		{ID: 2, Tags: []string{"日本", "中国"}},
+	}, Options{})
+
+	assert.Equal(t, map[string]string{
+		"GO!": "go",
+		"Go":  "go-2",
+		"go":  "go-3",
+		"中国":  "tag",
+		"日本":  "tag-2",
+	}, b.slugs)
+}
+
+func TestRootOf(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "index.html", expected: "./"},
+		{path: "tags/index.html", expected: "../"},
+		{path: "articles/1/index.html", expected: "../../"},
+		{path: "tags/health/page/2/index.html", expected: "../../../../"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rootOf(tt.path))
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("word ", 60)
+
+	tests := []struct {
+		name     string
+		article  data.Article
+		expected string
+	}{
+		{name: "Stored Summary", article: data.Article{Summary: "The summary.", Body: "The body."}, expected: "The summary."},
+		{name: "First Paragraph", article: data.Article{Body: "# Heading\n\nSecond *paragraph*."}, expected: "Heading"},
+		{name: "Markup Removed", article: data.Article{Body: "Some **strong** [link](https://example.com)."}, expected: "Some strong link."},
+		{name: "Cut At A Word", article: data.Article{Body: long}, expected: strings.TrimSpace(strings.Repeat("word ", 40)) + "…"},
+		{name: "Cut Without Spaces", article: data.Article{Body: strings.Repeat("x", 250)}, expected: strings.Repeat("x", 200) + "…"},
+		{name: "Empty", article: data.Article{}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, excerpt(tt.article))
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dao := data.NewArticleDAO()
+	for _, article := range []*data.Article{
+		{ID: 1, Title: "First <post>", Date: articleDate(20), Body: "Potato *chips*.", Tags: []string{"health"}},
+		{ID: 2, Title: "Second", Date: articleDate(22), Body: "More news.", Tags: []string{"health", "science"}},
+		{ID: 3, Title: "Third", Date: articleDate(21), Body: "Other news.", Tags: []string{"science"}},
+	} {
+		require.NoError(t, dao.Insert(article))
+	}
+
+	options := Options{Title: "Archive", BaseURL: "https://example.com/archive", PageSize: 2}
+	dir := t.TempDir()
+
+	t.Run("No Base URL", func(t *testing.T) {
+		for _, baseURL := range []string{"", "/archive", "example.com"} {
+			_, err := Build(t.TempDir(), dao, Options{BaseURL: baseURL})
+			assert.ErrorIs(t, err, ErrNoBaseURL, baseURL)
+		}
+	})
+
+	t.Run("First Build", func(t *testing.T) {
+		stats, err := Build(dir, dao, options)
+		require.NoError(t, err)
+		assert.Zero(t, stats.Unchanged)
+		assert.Zero(t, stats.Removed)
+
+		for _, path := range []string{
+			"index.html", "page/2/index.html", "feed.atom", "feed.rss", "feed.json",
+			"articles/1/index.html", "articles/2/index.html", "articles/3/index.html",
+			"tags/index.html", "tags/health/index.html", "tags/health/feed.atom",
+			"tags/health/20160920/index.html", "tags/science/20160921/index.html",
+			ManifestFile,
+		} {
+			assert.FileExists(t, filepath.Join(dir, path))
+		}
+
+		// Pages escape article content and link relatively.
+		page := readFile(t, dir, "articles/1/index.html")
+		assert.Contains(t, page, "First &lt;post&gt;")
+		assert.Contains(t, page, "<em>chips</em>")
+		assert.Contains(t, page, `href="../../tags/health/"`)
+
+		// The newest articles are listed first.
+		index := readFile(t, dir, "index.html")
+		assert.Less(t, strings.Index(index, "Second"), strings.Index(index, "Third"))
+		assert.NotContains(t, index, "First &lt;post&gt;")
+
+		// Feeds link absolutely.
+		assert.Contains(t, readFile(t, dir, "feed.rss"), "<link>https://example.com/archive/articles/2/</link>")
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		stats, err := Build(dir, dao, options)
+		require.NoError(t, err)
+		assert.Zero(t, stats.Written)
+		assert.Zero(t, stats.Removed)
+		assert.Positive(t, stats.Unchanged)
+	})
+
+	t.Run("Deleted Article", func(t *testing.T) {
+		require.NoError(t, dao.Delete(1))
+
+		stats, err := Build(dir, dao, options)
+		require.NoError(t, err)
+		assert.Positive(t, stats.Written)
+		assert.Positive(t, stats.Removed)
+
+		assert.NoFileExists(t, filepath.Join(dir, "articles/1/index.html"))
+		assert.NoDirExists(t, filepath.Join(dir, "articles/1"))
+		assert.NoFileExists(t, filepath.Join(dir, "tags/health/20160920/index.html"))
+		assert.NoFileExists(t, filepath.Join(dir, "page/2/index.html"))
+		assert.FileExists(t, filepath.Join(dir, "articles/2/index.html"))
+	})
+
+	t.Run("Damaged Manifest", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte("{"), 0o644))
+
+		stats, err := Build(dir, dao, options)
+		require.NoError(t, err)
+		assert.Zero(t, stats.Unchanged)
+		assert.Positive(t, stats.Written)
+	})
+
+	t.Run("Manifest Outside Directory", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "keep.txt")
+		require.NoError(t, os.WriteFile(outside, []byte("keep"), 0o644))
+
+		rel, err := filepath.Rel(dir, outside)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"`+filepath.ToSlash(rel)+`": "x"}`), 0o644))
+
+		_, err = Build(dir, dao, options)
+		require.NoError(t, err)
+		assert.FileExists(t, outside)
+	})
+}
+
+func articleDate(day int) data.ArticleDate {
+	return data.ArticleDate(time.Date(2016, 9, day, 0, 0, 0, 0, time.UTC))
+}
+
+func readFile(t *testing.T, dir, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+	require.NoError(t, err)
+	return string(content)
+}
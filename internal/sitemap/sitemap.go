@@ -0,0 +1,229 @@
+// Package sitemap maintains XML sitemaps for search engines.
+//
+// A Sitemap holds a list of URLs split across a series of sitemap files, each
+// holding at most MaxURLs. Once there is more than one file, a sitemap index
+// listing them is served in their place. URLs are kept as paths and only made
+// absolute when written, so that the same sitemap can be served under
+// whichever base URL a request arrived at.
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MaxURLs is the largest number of URLs the sitemap protocol allows in one
+// sitemap file.
+const MaxURLs = 50_000
+
+const (
+	urlsetHeader = xml.Header + `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	urlsetFooter = "</urlset>\n"
+	indexHeader  = xml.Header + `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	indexFooter  = "</sitemapindex>\n"
+)
+
+// URL is a page listed in a sitemap.
+type URL struct {
+	// Loc is the page's path, which is appended to the base URL the sitemap
+	// is written with.
+	Loc string
+	// LastMod is when the page last changed. It is omitted if zero.
+	LastMod time.Time
+}
+
+// Sitemap is a list of URLs split across sitemap files. It is safe for
+// concurrent use.
+type Sitemap struct {
+	maxURLs int
+	urls    []URL
+	// index maps the path of each URL to its position in urls.
+	index    map[string]int
+	modified time.Time
+	mutex    sync.RWMutex
+}
+
+// New creates an empty sitemap whose files hold at most maxURLs URLs each.
+// Pass MaxURLs unless testing.
+func New(maxURLs int) *Sitemap {
+	return &Sitemap{maxURLs: max(maxURLs, 1), index: make(map[string]int)}
+}
+
+// Set replaces the URLs in the sitemap. The sitemap only counts as modified if
+// they differ from the URLs it already had.
+func (s *Sitemap) Set(urls []URL) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if slices.EqualFunc(s.urls, urls, URL.equal) {
+		return
+	}
+
+	s.urls = slices.Clone(urls)
+	s.index = make(map[string]int, len(urls))
+	for i, u := range s.urls {
+		s.index[u.Loc] = i
+	}
+	s.modified = time.Now()
+}
+
+// Apply changes some of the URLs in the sitemap, without going through the
+// rest: it adds the URLs in put, replacing any already listed at the same
+// paths, and removes the URLs at the paths in remove. New URLs go at the end,
+// and the last URL moves into the place of a removed one, so that other URLs
+// stay in the files they are in. The sitemap only counts as modified if this
+// changes it.
+func (s *Sitemap) Apply(put []URL, remove []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	modified := false
+
+	for _, u := range put {
+		i, ok := s.index[u.Loc]
+		switch {
+		case !ok:
+			s.index[u.Loc] = len(s.urls)
+			s.urls = append(s.urls, u)
+		case !s.urls[i].equal(u):
+			s.urls[i] = u
+		default:
+			continue
+		}
+		modified = true
+	}
+
+	for _, loc := range remove {
+		i, ok := s.index[loc]
+		if !ok {
+			continue
+		}
+
+		last := len(s.urls) - 1
+		s.urls[i] = s.urls[last]
+		s.index[s.urls[i].Loc] = i
+		s.urls = s.urls[:last]
+		delete(s.index, loc)
+		modified = true
+	}
+
+	if modified {
+		s.modified = time.Now()
+	}
+}
+
+// Len returns the number of sitemap files. An empty sitemap has none.
+func (s *Sitemap) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return (len(s.urls) + s.maxURLs - 1) / s.maxURLs
+}
+
+// Modified returns when the URLs last changed, or the zero time if they never
+// have.
+func (s *Sitemap) Modified() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.modified
+}
+
+// WriteFile writes the sitemap file at index i, counting from zero, with its
+// URLs appended to baseURL. Writing file 0 of an empty sitemap gives an empty
+// URL set. It returns false if there is no such file.
+func (s *Sitemap) WriteFile(w io.Writer, i int, baseURL string) (bool, error) {
+	s.mutex.RLock()
+	var urls []URL
+	exists := i == 0 || (i > 0 && i*s.maxURLs < len(s.urls))
+	if exists {
+		urls = s.file(i)
+	}
+	s.mutex.RUnlock()
+
+	if !exists {
+		return false, nil
+	}
+
+	var entries []byte
+	for _, u := range urls {
+		entries = appendEntry(entries, "url", baseURL+u.Loc, u.LastMod)
+	}
+
+	return true, writeAll(w, urlsetHeader, entries, urlsetFooter)
+}
+
+// WriteIndex writes a sitemap index listing every sitemap file. fileURL gives
+// the URL each file, identified by its index, is served at.
+func (s *Sitemap) WriteIndex(w io.Writer, fileURL func(i int) string) error {
+	s.mutex.RLock()
+	var lastMods []time.Time
+	for i := 0; i*s.maxURLs < len(s.urls); i++ {
+		var lastMod time.Time
+		for _, u := range s.file(i) {
+			if u.LastMod.After(lastMod) {
+				lastMod = u.LastMod
+			}
+		}
+		lastMods = append(lastMods, lastMod)
+	}
+	s.mutex.RUnlock()
+
+	var entries []byte
+	for i, lastMod := range lastMods {
+		entries = appendEntry(entries, "sitemap", fileURL(i), lastMod)
+	}
+
+	return writeAll(w, indexHeader, entries, indexFooter)
+}
+
+// file returns the URLs in the sitemap file at index i. It must be called with
+// the mutex held.
+func (s *Sitemap) file(i int) []URL {
+	start := min(i*s.maxURLs, len(s.urls))
+	end := min(start+s.maxURLs, len(s.urls))
+	return s.urls[start:end]
+}
+
+// equal reports whether u and other describe the same page as of the same
+// time.
+func (u URL) equal(other URL) bool {
+	return u.Loc == other.Loc && u.LastMod.Equal(other.LastMod)
+}
+
+// appendEntry appends a <url> or <sitemap> element for the location to b.
+func appendEntry(b []byte, element, loc string, lastMod time.Time) []byte {
+	var escaped bytes.Buffer
+	// Escaping into a bytes.Buffer can't fail.
+	_ = xml.EscapeText(&escaped, []byte(loc))
+
+	b = append(b, "  <"+element+">\n    <loc>"...)
+	b = append(b, escaped.Bytes()...)
+	b = append(b, "</loc>\n"...)
+	if !lastMod.IsZero() {
+		b = append(b, "    <lastmod>"...)
+		b = lastMod.UTC().AppendFormat(b, time.DateOnly)
+		b = append(b, "</lastmod>\n"...)
+	}
+	return append(b, "  </"+element+">\n"...)
+}
+
+// writeAll writes a document made up of a header, entries and a footer.
+func writeAll(w io.Writer, header string, entries []byte, footer string) error {
+	_, err := io.WriteString(w, header)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, footer)
+	return err
+}
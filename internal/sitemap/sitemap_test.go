@@ -0,0 +1,180 @@
+package sitemap
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(day int) time.Time {
+	return time.Date(2016, 9, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestWriteFile(t *testing.T) {
+	urls := []URL{
+		{Loc: "/articles/1", LastMod: date(20)},
+		{Loc: "/articles/2?a=1&b=2"},
+		{Loc: "/articles/3", LastMod: date(22)},
+	}
+
+	tests := []struct {
+		name     string
+		urls     []URL
+		file     int
+		exists   bool
+		expected string
+	}{
+		{
+			name:     "Empty",
+			file:     0,
+			exists:   true,
+			expected: urlsetHeader + urlsetFooter,
+		},
+		{
+			name:   "First File",
+			urls:   urls,
+			file:   0,
+			exists: true,
+			expected: urlsetHeader +
+				"  <url>\n    <loc>https://example.com/articles/1</loc>\n    <lastmod>2016-09-20</lastmod>\n  </url>\n" +
+				"  <url>\n    <loc>https://example.com/articles/2?a=1&amp;b=2</loc>\n  </url>\n" +
+				urlsetFooter,
+		},
+		{
+			name:   "Last File",
+			urls:   urls,
+			file:   1,
+			exists: true,
+			expected: urlsetHeader +
+				"  <url>\n    <loc>https://example.com/articles/3</loc>\n    <lastmod>2016-09-22</lastmod>\n  </url>\n" +
+				urlsetFooter,
+		},
+		{name: "Past The End", urls: urls, file: 2},
+		{name: "Negative", urls: urls, file: -1},
+		{name: "Past The End Of Empty", file: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(2)
+			s.Set(tt.urls)
+
+			var buf bytes.Buffer
+			exists, err := s.WriteFile(&buf, tt.file, "https://example.com")
+			require.NoError(t, err)
+			assert.Equal(t, tt.exists, exists)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	s := New(2)
+	s.Set([]URL{
+		{Loc: "/articles/1", LastMod: date(20)},
+		{Loc: "/articles/2", LastMod: date(21)},
+		{Loc: "/articles/3"},
+	})
+	assert.Equal(t, 2, s.Len())
+
+	var buf bytes.Buffer
+	err := s.WriteIndex(&buf, func(i int) string {
+		return fmt.Sprintf("https://example.com/sitemaps/%d.xml", i+1)
+	})
+	require.NoError(t, err)
+
+	// A file whose URLs have no dates gets none either.
+	assert.Equal(t, indexHeader+
+		"  <sitemap>\n    <loc>https://example.com/sitemaps/1.xml</loc>\n    <lastmod>2016-09-21</lastmod>\n  </sitemap>\n"+
+		"  <sitemap>\n    <loc>https://example.com/sitemaps/2.xml</loc>\n  </sitemap>\n"+
+		indexFooter, buf.String())
+}
+
+func TestLen(t *testing.T) {
+	tests := []struct {
+		urls     int
+		expected int
+	}{
+		{urls: 0, expected: 0},
+		{urls: 1, expected: 1},
+		{urls: 3, expected: 1},
+		{urls: 4, expected: 2},
+		{urls: 7, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.urls), func(t *testing.T) {
+			s := New(3)
+			s.Set(make([]URL, tt.urls))
+			assert.Equal(t, tt.expected, s.Len())
+		})
+	}
+}
+
+func TestSetModified(t *testing.T) {
+	s := New(MaxURLs)
+	assert.True(t, s.Modified().IsZero())
+
+	urls := []URL{{Loc: "/articles/1", LastMod: date(20)}}
+	s.Set(urls)
+	modified := s.Modified()
+	assert.False(t, modified.IsZero())
+
+	// Setting the same URLs again, even as an equal time in another zone,
+	// leaves the sitemap unmodified.
+	s.Set([]URL{{Loc: "/articles/1", LastMod: date(20).In(time.FixedZone("AEST", 10*60*60))}})
+	assert.Equal(t, modified, s.Modified())
+
+	// The sitemap keeps its own copy of the URLs.
+	urls[0].Loc = "/articles/2"
+	var buf bytes.Buffer
+	_, err := s.WriteFile(&buf, 0, "")
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "<loc>/articles/1</loc>")
+
+	s.Set(nil)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestApply(t *testing.T) {
+	s := New(MaxURLs)
+	s.Set([]URL{
+		{Loc: "/articles/1", LastMod: date(20)},
+		{Loc: "/articles/2", LastMod: date(21)},
+		{Loc: "/articles/3", LastMod: date(22)},
+	})
+	modified := s.Modified()
+
+	locs := func() []string {
+		var locs []string
+		for _, u := range s.urls {
+			locs = append(locs, u.Loc)
+		}
+		return locs
+	}
+
+	// Putting URLs already listed, and removing ones that aren't, leaves the
+	// sitemap unmodified.
+	s.Apply([]URL{{Loc: "/articles/2", LastMod: date(21)}}, []string{"/articles/9"})
+	assert.Equal(t, modified, s.Modified())
+
+	// New URLs go at the end, and changed ones stay where they are.
+	s.Apply([]URL{{Loc: "/articles/4", LastMod: date(23)}, {Loc: "/articles/2", LastMod: date(24)}}, nil)
+	assert.Equal(t, []string{"/articles/1", "/articles/2", "/articles/3", "/articles/4"}, locs())
+	assert.Equal(t, date(24), s.urls[1].LastMod)
+	assert.True(t, s.Modified().After(modified))
+
+	// The last URL takes the place of a removed one.
+	s.Apply(nil, []string{"/articles/1"})
+	assert.Equal(t, []string{"/articles/4", "/articles/2", "/articles/3"}, locs())
+
+	// Removing the last URL, and putting a URL back, still finds each by path.
+	s.Apply([]URL{{Loc: "/articles/1", LastMod: date(20)}}, []string{"/articles/3"})
+	assert.Equal(t, []string{"/articles/4", "/articles/2", "/articles/1"}, locs())
+	s.Apply(nil, []string{"/articles/4", "/articles/1", "/articles/2"})
+	assert.Empty(t, locs())
+}
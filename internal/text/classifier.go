@@ -0,0 +1,114 @@
+package text
+
+import (
+	"math"
+	"sort"
+)
+
+// TagClassifier is a multinomial naive Bayes model that learns which terms are
+// associated with which tags. Every tag on a training document counts as a
+// separate observation, so documents with several tags train each of them.
+type TagClassifier struct {
+	docCount   map[string]int
+	termCount  map[string]map[string]int
+	totalTerms map[string]int
+	vocabulary map[string]struct{}
+	totalDocs  int
+}
+
+// TagSuggestion is a tag proposed by the classifier together with its
+// posterior probability.
+type TagSuggestion struct {
+	Tag   string  `json:"tag"`
+	Score float64 `json:"score"`
+}
+
+// NewTagClassifier returns an empty classifier.
+func NewTagClassifier() *TagClassifier {
+	return &TagClassifier{
+		docCount:   make(map[string]int),
+		termCount:  make(map[string]map[string]int),
+		totalTerms: make(map[string]int),
+		vocabulary: make(map[string]struct{}),
+	}
+}
+
+// Train adds a document and its tags to the model.
+func (c *TagClassifier) Train(document string, tags []string) {
+	terms := Terms(document)
+
+	for _, tag := range tags {
+		c.totalDocs++
+		c.docCount[tag]++
+
+		counts, ok := c.termCount[tag]
+		if !ok {
+			counts = make(map[string]int)
+			c.termCount[tag] = counts
+		}
+
+		for _, term := range terms {
+			counts[term]++
+			c.totalTerms[tag]++
+			c.vocabulary[term] = struct{}{}
+		}
+	}
+}
+
+// Suggest ranks every known tag against the document and returns up to limit
+// suggestions, most likely first. Tags listed in exclude are never suggested.
+func (c *TagClassifier) Suggest(document string, limit int, exclude []string) []TagSuggestion {
+	if c.totalDocs == 0 || limit < 1 {
+		return nil
+	}
+
+	excluded := toSet(exclude)
+	terms := Terms(document)
+	vocabularySize := float64(len(c.vocabulary))
+
+	// Compute log posteriors for each tag using Laplace smoothing.
+	logScores := make(map[string]float64, len(c.docCount))
+	maxLog := math.Inf(-1)
+	for tag, docs := range c.docCount {
+		score := math.Log(float64(docs) / float64(c.totalDocs))
+		denominator := float64(c.totalTerms[tag]) + vocabularySize
+		for _, term := range terms {
+			score += math.Log((float64(c.termCount[tag][term]) + 1) / denominator)
+		}
+
+		logScores[tag] = score
+		if score > maxLog {
+			maxLog = score
+		}
+	}
+
+	// Normalise into probabilities, shifting by the maximum to avoid underflow.
+	var sum float64
+	for _, score := range logScores {
+		sum += math.Exp(score - maxLog)
+	}
+
+	var suggestions []TagSuggestion
+	for tag, score := range logScores {
+		if _, ok := excluded[tag]; ok {
+			continue
+		}
+		suggestions = append(suggestions, TagSuggestion{
+			Tag:   tag,
+			Score: math.Exp(score-maxLog) / sum,
+		})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
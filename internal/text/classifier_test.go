@@ -0,0 +1,64 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagClassifierSuggest(t *testing.T) {
+	classifier := NewTagClassifier()
+	classifier.Train("Potato chips are fried in oil and salted", []string{"food"})
+	classifier.Train("Baked chips with less oil and salt", []string{"food", "health"})
+	classifier.Train("Rockets launch satellites into orbit", []string{"space"})
+	classifier.Train("Astronauts train for a long orbit around the moon", []string{"space", "science"})
+
+	tests := []struct {
+		name     string
+		document string
+		limit    int
+		exclude  []string
+		expected []string
+	}{
+		{name: "Most Likely First", document: "salted potato chips", limit: 2, expected: []string{"food", "health"}},
+		{name: "Other Topic", document: "rockets in orbit", limit: 1, expected: []string{"space"}},
+		{name: "Excluded", document: "salted potato chips", limit: 1, exclude: []string{"food"}, expected: []string{"health"}},
+		{name: "Limit Above Tags", document: "chips", limit: 10, expected: []string{"food", "health", "space", "science"}},
+		{name: "No Limit", document: "chips", limit: 0, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := classifier.Suggest(tt.document, tt.limit, tt.exclude)
+
+			var tags []string
+			var total float64
+			for _, suggestion := range suggestions {
+				tags = append(tags, suggestion.Tag)
+				total += suggestion.Score
+				assert.Greater(t, suggestion.Score, 0.0)
+			}
+			assert.Equal(t, tt.expected, tags)
+			assert.LessOrEqual(t, total, 1+1e-9)
+		})
+	}
+}
+
+func TestTagClassifierUntrained(t *testing.T) {
+	assert.Nil(t, NewTagClassifier().Suggest("potato chips", 5, nil))
+}
+
+func TestTagClassifierNoTerms(t *testing.T) {
+	classifier := NewTagClassifier()
+	classifier.Train("chips", []string{"food"})
+	classifier.Train("rockets", []string{"space"})
+	classifier.Train("orbit", []string{"space"})
+
+	// With only stop words to go on, tags are ranked by how many documents
+	// have them.
+	suggestions := classifier.Suggest("it is what it is", 2, nil)
+	assert.Equal(t, "space", suggestions[0].Tag)
+	assert.InDelta(t, 2.0/3, suggestions[0].Score, 1e-9)
+	assert.Equal(t, "food", suggestions[1].Tag)
+	assert.InDelta(t, 1.0/3, suggestions[1].Score, 1e-9)
+}
@@ -0,0 +1,123 @@
+package text
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Entities returns candidate named entities found in s: runs of capitalised
+// words such as "Pacific Ocean" or "AI". Leading articles are dropped, and a
+// single capitalised word at the start of a sentence is only kept if it also
+// appears capitalised mid-sentence, since otherwise it is likely just the
+// first word. Candidates are ordered by frequency, then by first appearance.
+func Entities(s string) []string {
+	type candidate struct {
+		count int
+		first int
+	}
+
+	candidates := make(map[string]*candidate)
+	midSentence := make(map[string]bool)
+	var order int
+
+	add := func(run []string, sentenceStart bool) {
+		for len(run) > 0 && isArticle(run[0]) {
+			run = run[1:]
+			sentenceStart = false
+		}
+		if len(run) == 0 {
+			return
+		}
+
+		entity := strings.Join(run, " ")
+		if len(run) == 1 && !sentenceStart {
+			midSentence[entity] = true
+		}
+		if len(run) == 1 && sentenceStart {
+			entity = "^" + entity
+		}
+
+		c, ok := candidates[entity]
+		if !ok {
+			c = &candidate{first: order}
+			candidates[entity] = c
+			order++
+		}
+		c.count++
+	}
+
+	for _, sentence := range Sentences(s) {
+		var run []string
+		runAtStart := true
+		for i, token := range strings.Fields(sentence) {
+			word := strings.TrimFunc(token, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+
+			if isCapitalised(word) {
+				if len(run) == 0 {
+					runAtStart = i == 0
+				}
+				run = append(run, word)
+			} else {
+				add(run, runAtStart)
+				run = nil
+			}
+
+			// Punctuation inside a sentence, such as a comma, ends a run.
+			if word != "" && strings.IndexFunc(token[strings.LastIndex(token, word)+len(word):], unicode.IsPunct) >= 0 {
+				add(run, runAtStart)
+				run = nil
+			}
+		}
+		add(run, runAtStart)
+	}
+
+	// Merge sentence-start singletons into their mid-sentence counterparts, or
+	// drop them if the word never appears capitalised elsewhere.
+	for entity, c := range candidates {
+		name, ok := strings.CutPrefix(entity, "^")
+		if !ok {
+			continue
+		}
+		delete(candidates, entity)
+		if midSentence[name] {
+			candidates[name].count += c.count
+			candidates[name].first = min(candidates[name].first, c.first)
+		}
+	}
+
+	entities := make([]string, 0, len(candidates))
+	for entity := range candidates {
+		entities = append(entities, entity)
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		ci, cj := candidates[entities[i]], candidates[entities[j]]
+		if ci.count != cj.count {
+			return ci.count > cj.count
+		}
+		return ci.first < cj.first
+	})
+
+	return entities
+}
+
+// isCapitalised reports whether word starts with an upper-case letter.
+func isCapitalised(word string) bool {
+	for _, r := range word {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+// isArticle reports whether word is an English article or demonstrative, which
+// often starts a capitalised run without being part of the entity.
+func isArticle(word string) bool {
+	switch strings.ToLower(word) {
+	case "a", "an", "the", "this", "that", "these", "those":
+		return true
+	}
+	return false
+}
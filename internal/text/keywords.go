@@ -0,0 +1,107 @@
+package text
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxKeyphraseWords is the longest candidate phrase RAKE will consider.
+const maxKeyphraseWords = 3
+
+// Keyword is a keyphrase extracted from a text with its RAKE score.
+type Keyword struct {
+	Phrase string  `json:"phrase"`
+	Score  float64 `json:"score"`
+}
+
+// Keywords extracts up to limit keyphrases from s using RAKE (Rapid Automatic
+// Keyword Extraction). Candidate phrases are runs of words between stop words
+// and punctuation; each word scores its co-occurrence degree divided by its
+// frequency, and a phrase scores the sum of its words.
+func Keywords(s string, limit int) []Keyword {
+	var phrases [][]string
+	for _, sentence := range Sentences(s) {
+		for _, fragment := range strings.FieldsFunc(sentence, isPhraseBoundary) {
+			var phrase []string
+			for _, w := range Words(fragment) {
+				if IsStopWord(w) || isNumber(w) {
+					phrases = appendPhrase(phrases, phrase)
+					phrase = nil
+					continue
+				}
+				phrase = append(phrase, w)
+			}
+			phrases = appendPhrase(phrases, phrase)
+		}
+	}
+
+	frequency := make(map[string]int)
+	degree := make(map[string]int)
+	for _, phrase := range phrases {
+		for _, w := range phrase {
+			frequency[w]++
+			degree[w] += len(phrase)
+		}
+	}
+
+	scores := make(map[string]float64)
+	for _, phrase := range phrases {
+		var score float64
+		for _, w := range phrase {
+			score += float64(degree[w]) / float64(frequency[w])
+		}
+		scores[strings.Join(phrase, " ")] = score
+	}
+
+	keywords := make([]Keyword, 0, len(scores))
+	for phrase, score := range scores {
+		keywords = append(keywords, Keyword{Phrase: phrase, Score: score})
+	}
+
+	SortKeywords(keywords)
+
+	if len(keywords) > limit {
+		keywords = keywords[:limit]
+	}
+
+	return keywords
+}
+
+// SortKeywords orders keywords by descending score, then alphabetically.
+func SortKeywords(keywords []Keyword) {
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Phrase < keywords[j].Phrase
+	})
+}
+
+// appendPhrase adds a candidate phrase to phrases, splitting phrases that are
+// longer than maxKeyphraseWords.
+func appendPhrase(phrases [][]string, phrase []string) [][]string {
+	for len(phrase) > maxKeyphraseWords {
+		phrases = append(phrases, phrase[:maxKeyphraseWords])
+		phrase = phrase[maxKeyphraseWords:]
+	}
+	if len(phrase) > 0 {
+		phrases = append(phrases, phrase)
+	}
+	return phrases
+}
+
+// isPhraseBoundary reports whether r ends a candidate phrase.
+func isPhraseBoundary(r rune) bool {
+	return r != '\'' && r != '’' && r != '-' && unicode.IsPunct(r)
+}
+
+// isNumber reports whether w consists only of digits.
+func isNumber(w string) bool {
+	for _, r := range w {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
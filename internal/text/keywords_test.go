@@ -0,0 +1,87 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		limit    int
+		expected []Keyword
+	}{
+		{name: "Empty", src: "", limit: 5, expected: []Keyword{}},
+		{
+			name:  "Split At Stop Words",
+			src:   "Compatibility of systems of linear constraints.",
+			limit: 5,
+			expected: []Keyword{
+				{Phrase: "linear constraints", Score: 4},
+				{Phrase: "compatibility", Score: 1},
+				{Phrase: "systems", Score: 1},
+			},
+		},
+		{
+			name:  "Split At Punctuation And Numbers",
+			src:   "Potato chips, 2016 crisps; salted snacks",
+			limit: 5,
+			expected: []Keyword{
+				{Phrase: "potato chips", Score: 4},
+				{Phrase: "salted snacks", Score: 4},
+				{Phrase: "crisps", Score: 1},
+			},
+		},
+		{
+			name:  "Long Phrases Split",
+			src:   "crunchy golden salted potato chips",
+			limit: 5,
+			expected: []Keyword{
+				{Phrase: "crunchy golden salted", Score: 9},
+				{Phrase: "potato chips", Score: 4},
+			},
+		},
+		{
+			name:     "Hyphen Kept",
+			src:      "Low-fat chips",
+			limit:    1,
+			expected: []Keyword{{Phrase: "low fat chips", Score: 9}},
+		},
+		{
+			name:     "Limit",
+			src:      "Compatibility of systems of linear constraints.",
+			limit:    1,
+			expected: []Keyword{{Phrase: "linear constraints", Score: 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Keywords(tt.src, tt.limit))
+		})
+	}
+}
+
+func TestEntities(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{name: "Empty", src: "", expected: []string{}},
+		{name: "Multi Word", src: "Birds live near the Pacific Ocean.", expected: []string{"Pacific Ocean"}},
+		{name: "Leading Article Dropped", src: "We sailed across The Pacific Ocean.", expected: []string{"Pacific Ocean"}},
+		{name: "Sentence Start Dropped", src: "Birds fly south.", expected: []string{}},
+		{name: "Sentence Start Kept", src: "Sydney is sunny. I like Sydney.", expected: []string{"Sydney"}},
+		{name: "Comma Ends Run", src: "We visited Paris, London and Rome.", expected: []string{"Paris", "London", "Rome"}},
+		{name: "Most Frequent First", src: "We met NASA and ESA. We saw NASA leave.", expected: []string{"NASA", "ESA"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Entities(tt.src))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package text
+
+import (
+	"embed"
+	"math"
+	"path"
+	"sort"
+	"strings"
+)
+
+// profileFiles holds a sample text for each supported language, named by its
+// ISO 639-1 code. The samples are turned into character trigram profiles the
+// first time the package is loaded.
+//
+//go:embed profiles/*.txt
+var profileFiles embed.FS
+
+// languageProfile is a normalised trigram frequency vector for one language.
+type languageProfile struct {
+	code    string
+	weights map[string]float64
+}
+
+var languageProfiles = loadLanguageProfiles()
+
+// loadLanguageProfiles builds a trigram profile from every embedded sample.
+func loadLanguageProfiles() []languageProfile {
+	entries, err := profileFiles.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+
+	var profiles []languageProfile
+	for _, entry := range entries {
+		sample, err := profileFiles.ReadFile(path.Join("profiles", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		profiles = append(profiles, languageProfile{
+			code:    strings.TrimSuffix(entry.Name(), ".txt"),
+			weights: normalise(trigrams(string(sample))),
+		})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].code < profiles[j].code
+	})
+
+	return profiles
+}
+
+// Languages returns the ISO 639-1 codes DetectLanguage can return.
+func Languages() []string {
+	codes := make([]string, 0, len(languageProfiles))
+	for _, profile := range languageProfiles {
+		codes = append(codes, profile.code)
+	}
+	return codes
+}
+
+// DetectLanguage returns the ISO 639-1 code of the language s is most likely
+// written in, comparing its character trigrams with the embedded profiles by
+// cosine similarity. It returns an empty string if s contains no words.
+func DetectLanguage(s string) string {
+	counts := trigrams(s)
+	if len(counts) == 0 {
+		return ""
+	}
+	weights := normalise(counts)
+
+	best, bestScore := "", -1.0
+	for _, profile := range languageProfiles {
+		var score float64
+		for gram, weight := range weights {
+			score += weight * profile.weights[gram]
+		}
+		if score > bestScore {
+			best, bestScore = profile.code, score
+		}
+	}
+
+	return best
+}
+
+// trigrams counts the character trigrams of every word in s, padding each word
+// with spaces so that prefixes and suffixes are captured.
+func trigrams(s string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range Words(s) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+	return counts
+}
+
+// normalise scales a frequency vector to unit length.
+func normalise(counts map[string]int) map[string]float64 {
+	var sum float64
+	for _, c := range counts {
+		sum += float64(c * c)
+	}
+	norm := math.Sqrt(sum)
+
+	weights := make(map[string]float64, len(counts))
+	for gram, c := range counts {
+		weights[gram] = float64(c) / norm
+	}
+	return weights
+}
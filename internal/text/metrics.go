@@ -0,0 +1,62 @@
+package text
+
+import (
+	"math"
+	"strings"
+)
+
+// wordsPerMinute is the average adult reading speed used for reading times.
+const wordsPerMinute = 200
+
+// ReadingTime returns the estimated number of minutes needed to read a text
+// with the given number of words, rounded up. Any non-empty text takes at
+// least a minute.
+func ReadingTime(wordCount int) int {
+	return int(math.Ceil(float64(wordCount) / wordsPerMinute))
+}
+
+// FleschReadingEase scores how easy s is to read. Higher scores are easier;
+// plain English typically scores between 60 and 70. Texts without any words
+// score 0.
+func FleschReadingEase(s string) float64 {
+	words := Words(s)
+	if len(words) == 0 {
+		return 0
+	}
+
+	sentences := max(len(Sentences(s)), 1)
+
+	var syllables int
+	for _, w := range words {
+		syllables += CountSyllables(w)
+	}
+
+	score := 206.835 -
+		1.015*float64(len(words))/float64(sentences) -
+		84.6*float64(syllables)/float64(len(words))
+
+	// Round to one decimal place; the formula is not more precise than that.
+	return math.Round(score*10) / 10
+}
+
+// CountSyllables estimates the number of syllables in an English word by
+// counting groups of vowels, ignoring a silent trailing "e".
+func CountSyllables(word string) int {
+	word = strings.ToLower(word)
+
+	var count int
+	previousVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+
+	return max(count, 1)
+}
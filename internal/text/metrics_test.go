@@ -0,0 +1,96 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		wordCount int
+		expected  int
+	}{
+		{wordCount: 0, expected: 0},
+		{wordCount: 1, expected: 1},
+		{wordCount: 200, expected: 1},
+		{wordCount: 201, expected: 2},
+		{wordCount: 1000, expected: 5},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, ReadingTime(tt.wordCount), "%d words", tt.wordCount)
+	}
+}
+
+func TestCountSyllables(t *testing.T) {
+	tests := []struct {
+		word     string
+		expected int
+	}{
+		{word: "chip", expected: 1},
+		{word: "potato", expected: 3},
+		{word: "Sugar", expected: 2},
+		{word: "make", expected: 1},
+		{word: "the", expected: 1},
+		{word: "table", expected: 2},
+		{word: "rhythm", expected: 1},
+		{word: "queue", expected: 1},
+		{word: "2016", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CountSyllables(tt.word))
+		})
+	}
+}
+
+func TestFleschReadingEase(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected float64
+	}{
+		{name: "Empty", src: "", expected: 0},
+		{name: "No Words", src: "-- !", expected: 0},
+		// 4 words, 1 sentence and 4 syllables.
+		{name: "Simple", src: "The cat sat down.", expected: 118.2},
+		// 2 words, 1 sentence and 11 syllables.
+		{name: "Hard", src: "Extraordinary conceptualisation.", expected: -260.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FleschReadingEase(tt.src))
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{name: "Empty", src: "", expected: ""},
+		{name: "No Words", src: "... !!", expected: ""},
+		{name: "English", src: "The latest science shows that potato chips are better for you than sugar.", expected: "en"},
+		{name: "German", src: "Die neueste Wissenschaft zeigt, dass Kartoffelchips besser für dich sind als Zucker.", expected: "de"},
+		{name: "Spanish", src: "La ciencia más reciente muestra que las papas fritas son mejores para ti que el azúcar.", expected: "es"},
+		{name: "French", src: "La science la plus récente montre que les chips sont meilleures pour vous que le sucre.", expected: "fr"},
+		{name: "Italian", src: "La scienza più recente dimostra che le patatine sono migliori per te dello zucchero.", expected: "it"},
+		{name: "Dutch", src: "De nieuwste wetenschap laat zien dat chips beter voor je zijn dan suiker.", expected: "nl"},
+		{name: "Portuguese", src: "A ciência mais recente mostra que as batatas fritas são melhores para você do que o açúcar.", expected: "pt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectLanguage(tt.src))
+		})
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	assert.Equal(t, []string{"de", "en", "es", "fr", "it", "nl", "pt"}, Languages())
+}
@@ -0,0 +1,64 @@
+package text
+
+import (
+	"hash/fnv"
+	"math/bits"
+)
+
+// SimHash computes a 64-bit SimHash fingerprint of s. Similar documents produce
+// fingerprints that differ in only a few bits, so the Hamming distance between
+// two fingerprints approximates how different the documents are.
+//
+// The features hashed are the non-stop-word terms plus every pair of adjacent
+// words, which keeps word order significant for short texts.
+func SimHash(s string) uint64 {
+	words := Words(s)
+
+	var features []string
+	for _, w := range words {
+		if !IsStopWord(w) {
+			features = append(features, w)
+		}
+	}
+	for i := 0; i+1 < len(words); i++ {
+		features = append(features, words[i]+" "+words[i+1])
+	}
+
+	if len(features) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	for _, f := range features {
+		h := hash64(f)
+		for bit := 0; bit < 64; bit++ {
+			if h&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+
+	return fingerprint
+}
+
+// Similarity returns a score between 0 and 1 describing how alike two SimHash
+// fingerprints are, where 1 means the fingerprints are identical.
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+// hash64 returns the 64-bit FNV-1a hash of s.
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
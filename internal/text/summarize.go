@@ -0,0 +1,166 @@
+package text
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Sentences splits s into sentences. A sentence ends at '.', '!' or '?' followed
+// by whitespace or the end of the text, or at a blank line.
+func Sentences(s string) []string {
+	var sentences []string
+
+	flush := func(sentence string) {
+		sentence = strings.Join(strings.Fields(sentence), " ")
+		if sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n\n") {
+		runes := []rune(paragraph)
+		start := 0
+		for i, r := range runes {
+			if r != '.' && r != '!' && r != '?' {
+				continue
+			}
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				flush(string(runes[start : i+1]))
+				start = i + 1
+			}
+		}
+		flush(string(runes[start:]))
+	}
+
+	return sentences
+}
+
+// maxRankedSentences caps the number of sentences Summarize ranks, as
+// comparing every pair of them takes time quadratic in their number.
+const maxRankedSentences = 500
+
+// edge is an edge of the sentence graph, to sentence to.
+type edge struct {
+	to     int
+	weight float64
+}
+
+// Summarize returns up to n sentences from s chosen with TextRank: sentences
+// are vertices in a graph weighted by word overlap, and the most central ones
+// according to PageRank are kept. The sentences are returned in the order
+// they appear in s. Only the first maxRankedSentences sentences are ranked, so
+// a long text is summarised from its beginning.
+func Summarize(s string, n int) []string {
+	sentences := Sentences(s)
+	if n < 1 {
+		return nil
+	}
+	if len(sentences) > maxRankedSentences {
+		sentences = sentences[:maxRankedSentences]
+	}
+	if len(sentences) <= n {
+		return sentences
+	}
+
+	terms := make([]map[string]struct{}, len(sentences))
+	for i, sentence := range sentences {
+		terms[i] = toSet(Terms(sentence))
+	}
+
+	// Build the weighted, undirected similarity graph. Most pairs of
+	// sentences share no terms, so only the edges between those that do are
+	// kept.
+	edges := make([][]edge, len(sentences))
+	outWeight := make([]float64, len(sentences))
+	for i := range sentences {
+		for j := i + 1; j < len(sentences); j++ {
+			w := sentenceSimilarity(terms[i], terms[j])
+			if w == 0 {
+				continue
+			}
+			edges[i] = append(edges[i], edge{to: j, weight: w})
+			edges[j] = append(edges[j], edge{to: i, weight: w})
+			outWeight[i] += w
+			outWeight[j] += w
+		}
+	}
+
+	scores := pageRank(edges, outWeight)
+
+	indexes := make([]int, len(sentences))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	// Rank by score, falling back to position so earlier sentences win ties.
+	// Scores within rounding error of each other count as ties.
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return scores[indexes[a]]-scores[indexes[b]] > 1e-9
+	})
+
+	chosen := indexes[:n]
+	sort.Ints(chosen)
+
+	summary := make([]string, 0, n)
+	for _, i := range chosen {
+		summary = append(summary, sentences[i])
+	}
+
+	return summary
+}
+
+// sentenceSimilarity is the TextRank overlap measure: the number of shared
+// terms normalised by the log of each sentence's length.
+func sentenceSimilarity(a, b map[string]struct{}) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+
+	var overlap int
+	for term := range a {
+		if _, ok := b[term]; ok {
+			overlap++
+		}
+	}
+
+	return float64(overlap) / (math.Log(float64(len(a))) + math.Log(float64(len(b))))
+}
+
+// pageRank runs weighted PageRank over the graph until the scores settle.
+func pageRank(edges [][]edge, outWeight []float64) []float64 {
+	const (
+		damping    = 0.85
+		tolerance  = 1e-6
+		iterations = 100
+	)
+
+	n := len(edges)
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+
+	for range iterations {
+		next := make([]float64, n)
+		var delta float64
+		for i := range n {
+			// The graph is undirected, so the edges from i are also the
+			// edges into it.
+			var sum float64
+			for _, e := range edges[i] {
+				sum += e.weight / outWeight[e.to] * scores[e.to]
+			}
+			next[i] = (1 - damping) + damping*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+
+		scores = next
+		if delta < tolerance {
+			break
+		}
+	}
+
+	return scores
+}
@@ -0,0 +1,67 @@
+package text
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{name: "Empty", src: "", expected: nil},
+		{name: "Punctuation", src: "Chips are great. Are they?  Yes!", expected: []string{"Chips are great.", "Are they?", "Yes!"}},
+		{name: "Decimal", src: "It costs 3.50 dollars. Cheap.", expected: []string{"It costs 3.50 dollars.", "Cheap."}},
+		{name: "Blank Line", src: "A heading\n\nA paragraph\nover two lines", expected: []string{"A heading", "A paragraph over two lines"}},
+		{name: "Windows Line Endings", src: "One\r\n\r\nTwo", expected: []string{"One", "Two"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Sentences(tt.src))
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	src := "Potatoes are grown around the world. " +
+		"Potato chips are made from thinly sliced potatoes. " +
+		"The weather was nice. " +
+		"Chips made from potatoes are fried or baked."
+
+	tests := []struct {
+		name     string
+		n        int
+		expected []string
+	}{
+		{name: "None", n: 0, expected: nil},
+		{name: "Central Sentences In Order", n: 2, expected: []string{
+			"Potato chips are made from thinly sliced potatoes.",
+			"Chips made from potatoes are fried or baked.",
+		}},
+		{name: "More Than The Text", n: 10, expected: Sentences(src)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Summarize(src, tt.n))
+		})
+	}
+}
+
+func TestSummarizeLongText(t *testing.T) {
+	// Every pair of sentences is similar, and only the first sentences are
+	// ranked, so this doesn't take long.
+	src := strings.Repeat("Potato chips are tasty. ", 200000) + "Unranked potato chips sentence."
+
+	start := time.Now()
+	summary := Summarize(src, 3)
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	assert.Equal(t, []string{"Potato chips are tasty.", "Potato chips are tasty.", "Potato chips are tasty."}, summary)
+}
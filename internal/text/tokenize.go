@@ -0,0 +1,74 @@
+package text
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Words splits s into lowercase word tokens. Letters and digits form words,
+// and apostrophes are kept when they sit inside a word (e.g. "year's").
+// Everything else, including markup characters, is treated as a separator.
+func Words(s string) []string {
+	var words []string
+	var sb strings.Builder
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(unicode.ToLower(r))
+		case (r == '\'' || r == '’') && sb.Len() > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			sb.WriteRune('\'')
+		default:
+			if sb.Len() > 0 {
+				words = append(words, sb.String())
+				sb.Reset()
+			}
+		}
+	}
+
+	if sb.Len() > 0 {
+		words = append(words, sb.String())
+	}
+
+	return words
+}
+
+// Terms returns the words in s with stop words removed. It is the token stream
+// used for statistical comparisons, where function words only add noise.
+func Terms(s string) []string {
+	var terms []string
+	for _, w := range Words(s) {
+		if !IsStopWord(w) {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// IsStopWord reports whether w is a common English function word.
+func IsStopWord(w string) bool {
+	_, ok := stopWords[w]
+	return ok
+}
+
+var stopWords = toSet(strings.Fields(`
+	a about above after again against all am an and any are as at be because
+	been before being below between both but by can could did do does doing down
+	during each few for from further had has have having he her here hers herself
+	him himself his how i if in into is it its itself just me more most my myself
+	no nor not now of off on once only or other our ours ourselves out over own
+	same she should so some such than that the their theirs them themselves then
+	there these they this those through to too under until up very was we were
+	what when where which while who whom why will with would you your yours
+	yourself yourselves also many much new one s t us way
+`))
+
+// toSet converts a slice of strings into a set.
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
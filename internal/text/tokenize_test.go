@@ -0,0 +1,96 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{name: "Empty", src: "", expected: nil},
+		{name: "Separators Only", src: " -- ** ", expected: nil},
+		{name: "Lowercase", src: "Potato CHIPS", expected: []string{"potato", "chips"}},
+		{name: "Digits", src: "Top 10 chips of 2016", expected: []string{"top", "10", "chips", "of", "2016"}},
+		{name: "Markup", src: "**bold** and <em>this</em>", expected: []string{"bold", "and", "em", "this", "em"}},
+		{name: "Inner Apostrophe", src: "the year's best", expected: []string{"the", "year's", "best"}},
+		{name: "Curly Apostrophe", src: "don’t", expected: []string{"don't"}},
+		{name: "Trailing Apostrophe", src: "the chips' salt", expected: []string{"the", "chips", "salt"}},
+		{name: "Leading Apostrophe", src: "'tis", expected: []string{"tis"}},
+		{name: "Unicode Letters", src: "Crème brûlée", expected: []string{"crème", "brûlée"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Words(tt.src))
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{name: "Empty", src: "", expected: nil},
+		{name: "Only Stop Words", src: "It is what it is", expected: nil},
+		{name: "Stop Words Removed", src: "The chips are in the bag", expected: []string{"chips", "bag"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Terms(tt.src))
+		})
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	base := "Potato chips are better for you than sugar, the latest science shows"
+
+	tests := []struct {
+		name          string
+		a, b          string
+		minSimilarity float64
+		maxSimilarity float64
+	}{
+		{name: "Identical", a: base, b: base, minSimilarity: 1, maxSimilarity: 1},
+		{name: "Case And Punctuation", a: base, b: "potato chips ARE better for you than sugar; the latest science shows!", minSimilarity: 1, maxSimilarity: 1},
+		{name: "One Word Changed", a: base, b: "Potato chips are better for you than candy, the latest science shows", minSimilarity: 0.8, maxSimilarity: 1},
+		{name: "Unrelated", a: base, b: "Space agencies explore the solar system with new rocket technology", minSimilarity: 0, maxSimilarity: 0.8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similarity := Similarity(SimHash(tt.a), SimHash(tt.b))
+			assert.GreaterOrEqual(t, similarity, tt.minSimilarity)
+			assert.LessOrEqual(t, similarity, tt.maxSimilarity)
+		})
+	}
+
+	// Text without words has no features to hash.
+	assert.Zero(t, SimHash(" -- "))
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     uint64
+		expected float64
+	}{
+		{name: "Same", a: 0xdeadbeef, b: 0xdeadbeef, expected: 1},
+		{name: "One Bit", a: 0, b: 1, expected: 63.0 / 64},
+		{name: "Opposite", a: 0, b: ^uint64(0), expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Similarity(tt.a, tt.b))
+			assert.Equal(t, tt.expected, Similarity(tt.b, tt.a))
+		})
+	}
+}